@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"octopus/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	apikeyName            string
+	apikeyExpire          time.Duration
+	apikeyMaxCost         float64
+	apikeySupportedModels string
+)
+
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage client API keys",
+}
+
+var apikeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API key and print it once",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := &model.APIKey{
+			Name:            apikeyName,
+			Enabled:         true,
+			MaxCost:         apikeyMaxCost,
+			SupportedModels: apikeySupportedModels,
+		}
+		if apikeyExpire > 0 {
+			key.ExpireAt = time.Now().Add(apikeyExpire).Unix()
+		}
+
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if err := m.APIKeyCreate(context.Background(), key); err != nil {
+			return err
+		}
+		if manageJSON {
+			return printJSON(key)
+		}
+		fmt.Printf("id: %d\nname: %s\nkey: %s\n", key.ID, key.Name, key.APIKey)
+		fmt.Println("store the key now, it will not be shown again")
+		return nil
+	},
+}
+
+var apikeyRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Delete an API key",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid api key id %q", args[0])
+		}
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if err := m.APIKeyDelete(context.Background(), id); err != nil {
+			return err
+		}
+		fmt.Printf("api key %d revoked\n", id)
+		return nil
+	},
+}
+
+func init() {
+	apikeyCreateCmd.Flags().StringVar(&apikeyName, "name", "", "key name")
+	apikeyCreateCmd.Flags().DurationVar(&apikeyExpire, "expire", 0, "lifetime of the key, e.g. 720h (default never expires)")
+	apikeyCreateCmd.Flags().Float64Var(&apikeyMaxCost, "max-cost", 0, "maximum total cost, 0 means unlimited")
+	apikeyCreateCmd.Flags().StringVar(&apikeySupportedModels, "models", "", "comma-separated models the key may use (default all)")
+	_ = apikeyCreateCmd.MarkFlagRequired("name")
+
+	apikeyCmd.AddCommand(apikeyCreateCmd, apikeyRevokeCmd)
+	addManageFlags(apikeyCmd)
+	rootCmd.AddCommand(apikeyCmd)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"octopus/internal/db"
+	"octopus/internal/declarative"
+	"octopus/internal/op"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFile   string
+	applyDryRun bool
+	applyYes    bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Converge channels, groups, aliases, API keys and settings to a declarative YAML/JSON file",
+	Long: "Compare the config file with the database, print the plan and apply it.\n" +
+		"Only sections present in the file are managed; resources missing from a managed section are deleted.\n" +
+		"Channel keys, base URLs, proxies, headers and API keys may reference environment variables as ${NAME}.\n" +
+		"All changes are applied in a single transaction. Plans that delete resources ask for confirmation unless --yes is given.\n" +
+		"A running server reloads the changed caches at its next cache check.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if applyFile == "" {
+			return fmt.Errorf("--file is required")
+		}
+		spec, err := declarative.Load(applyFile)
+		if err != nil {
+			return err
+		}
+
+		if err := openStore(); err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := op.InitCache(); err != nil {
+			return err
+		}
+
+		ctx := op.WithAuditActor(context.Background(), "cli", "")
+		plan, err := declarative.Build(ctx, spec)
+		if err != nil {
+			return err
+		}
+		plan.Print(os.Stdout)
+		if applyDryRun || plan.Empty() {
+			return nil
+		}
+
+		if n := plan.Destructive(); n > 0 && !applyYes {
+			ok, err := confirm(fmt.Sprintf("\n%d resources will be deleted. Apply this plan? [y/N] ", n))
+			if err != nil {
+				return fmt.Errorf("%w, rerun with --yes to apply without confirmation", err)
+			}
+			if !ok {
+				fmt.Println("apply canceled")
+				return nil
+			}
+		}
+
+		fmt.Println()
+		if err := plan.Apply(ctx, os.Stdout); err != nil {
+			return err
+		}
+		if err := op.CachePublish(ctx); err != nil {
+			return fmt.Errorf("apply complete, but failed to notify running servers (restart them to pick up the changes): %w", err)
+		}
+		fmt.Println("apply complete, a running server picks up the changes at its next cache check")
+		return nil
+	},
+}
+
+// confirm 在终端中询问用户，只有输入 y 或 yes 时返回 true
+func confirm(prompt string) (bool, error) {
+	fmt.Print(prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false, fmt.Errorf("failed to read confirmation: %w", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes", nil
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "declarative config file (YAML or JSON)")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "only print the plan")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "apply plans that delete resources without asking for confirmation")
+	rootCmd.AddCommand(applyCmd)
+}
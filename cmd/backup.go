@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"octopus/internal/backup"
+	"octopus/internal/conf"
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/op"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	restoreWipe     bool
+	restoreDryRun   bool
+	restoreChannels []string
+	restoreGroups   []string
+	restoreAPIKeys  []string
+	restoreKey      string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Create a backup now using the backup section of the config",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := openStore(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		res, err := backup.Run(context.Background(), conf.Get().Backup)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("backup %s written to %s (%d bytes)\n", res.Object.Name, res.Storage, res.Object.Size)
+		for _, name := range res.Deleted {
+			fmt.Printf("removed expired backup %s\n", name)
+		}
+		return nil
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List backups in the configured storage",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := conf.Load(cfgFile); err != nil {
+			return err
+		}
+		objects, storage, err := backup.List(context.Background(), conf.Get().Backup)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: %d backup(s)\n", storage, len(objects))
+		for _, o := range objects {
+			fmt.Printf("%-36s  %10d  %s\n", o.Name, o.Size, o.Time.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	},
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup-name|file>",
+	Short: "Restore the database from a backup (the server must be stopped)",
+	Long: `Restore the database from a backup name in the configured storage or a local file.
+
+Dump backups (.json.gz, or a .json file from "octopus export") are imported
+incrementally by default; --wipe clears the tables covered by the dump first.
+Dumps written by older versions are migrated to the current format.
+
+--channel, --group and --apikey restore only the selected entities (by name or
+ID in the dump). Existing entities with the same name are overwritten, and IDs
+that collide with other rows are remapped. --dry-run previews what would be
+inserted, updated or skipped without committing anything.
+
+Dumps keep channel credentials encrypted and carry their data keys wrapped
+with the master key. If the master key was changed with "octopus rekey" after
+the dump was written, pass the old one with --master-key.
+
+SQLite snapshots (.db.gz) replace the database file, keeping the old file as
+<path>.bak-<time>.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := context.Background()
+		source := args[0]
+
+		if backup.IsSnapshot(source) {
+			if restoreDryRun || restoreWipe || len(restoreChannels)+len(restoreGroups)+len(restoreAPIKeys) > 0 {
+				return fmt.Errorf("snapshot restores do not support --wipe, --dry-run or selective restore")
+			}
+			if err := conf.Load(cfgFile); err != nil {
+				return err
+			}
+			if conf.Get().Database.Type != "sqlite" {
+				return fmt.Errorf("snapshot backups can only be restored to a sqlite database")
+			}
+			data, err := backup.Load(ctx, conf.Get().Backup, source)
+			if err != nil {
+				return err
+			}
+			bak, err := backup.RestoreSnapshot(data, conf.Get().Database.Path)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("restored %s to %s\n", source, conf.Get().Database.Path)
+			if bak != "" {
+				fmt.Printf("previous database kept as %s\n", bak)
+			}
+			return nil
+		}
+
+		if err := openStore(); err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := op.InitCache(); err != nil {
+			return err
+		}
+		data, err := backup.Load(ctx, conf.Get().Backup, source)
+		if err != nil {
+			return err
+		}
+		dump, err := backup.DecodeDump(data)
+		if err != nil {
+			return err
+		}
+
+		ctx = op.WithAuditActor(ctx, "cli", "")
+		res, err := op.DBImport(ctx, dump, model.DBImportOptions{
+			Replace:   restoreWipe,
+			DryRun:    restoreDryRun,
+			Channels:  restoreChannels,
+			Groups:    restoreGroups,
+			APIKeys:   restoreAPIKeys,
+			MasterKey: restoreKey,
+		})
+		if err != nil {
+			return err
+		}
+		printImportResult(source, res)
+		if !res.DryRun {
+			if err := op.CachePublish(ctx); err != nil {
+				return fmt.Errorf("failed to notify running servers, restart them to pick up the restore: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+func printImportResult(source string, res *model.DBImportResult) {
+	if res.DryRun {
+		fmt.Printf("dry run of %s, nothing was committed (wipe: %t)\n", source, res.Replaced)
+	} else {
+		fmt.Printf("restored %s (wipe: %t)\n", source, res.Replaced)
+	}
+	tables := make([]string, 0, len(res.Tables))
+	for t := range res.Tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	fmt.Printf("  %-14s %9s %9s %9s\n", "TABLE", "INSERTED", "UPDATED", "SKIPPED")
+	for _, t := range tables {
+		r := res.Tables[t]
+		fmt.Printf("  %-14s %9d %9d %9d\n", t, r.Inserted, r.Updated, r.Skipped)
+	}
+	for _, r := range res.Remapped {
+		fmt.Printf("  remapped %s %q: id %d -> %d\n", r.Table, r.Name, r.From, r.To)
+	}
+	for _, w := range res.Warnings {
+		fmt.Printf("  warning: %s\n", w)
+	}
+}
+
+func init() {
+	restoreCmd.Flags().BoolVar(&restoreWipe, "wipe", false, "clear the tables covered by the dump before importing")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "preview the changes without committing them")
+	restoreCmd.Flags().StringArrayVar(&restoreChannels, "channel", nil, "restore only this channel, by name or ID (repeatable)")
+	restoreCmd.Flags().StringArrayVar(&restoreGroups, "group", nil, "restore only this group, by name or ID (repeatable)")
+	restoreCmd.Flags().StringArrayVar(&restoreAPIKeys, "apikey", nil, "restore only this API key, by name or ID (repeatable)")
+	restoreCmd.Flags().StringVar(&restoreKey, "master-key", "", "master key the dump was exported with, if it was changed since")
+
+	backupCmd.AddCommand(backupListCmd)
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"octopus/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	channelAddName     string
+	channelAddType     string
+	channelAddBaseURLs []string
+	channelAddKeys     []string
+	channelAddModels   string
+	channelAddCustom   string
+	channelAddProxy    bool
+	channelAddAutoSync bool
+)
+
+var channelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Manage channels",
+}
+
+var channelListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List channels",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		channels, err := m.ChannelList(context.Background())
+		if err != nil {
+			return err
+		}
+		sort.Slice(channels, func(i, j int) bool { return channels[i].ID < channels[j].ID })
+		if manageJSON {
+			return printJSON(channels)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tENABLED\tKEYS\tMODELS")
+		for _, c := range channels {
+			enabledKeys := 0
+			for _, k := range c.Keys {
+				if k.Enabled {
+					enabledKeys++
+				}
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%d/%d\t%s\n", c.ID, c.Name, model.ChannelTypeName(c.Type), c.Enabled,
+				enabledKeys, len(c.Keys), truncate(c.Model, 60))
+		}
+		return w.Flush()
+	},
+}
+
+var channelAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Create a channel",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		channelType, err := model.ParseChannelType(channelAddType)
+		if err != nil {
+			return err
+		}
+		if len(channelAddBaseURLs) == 0 {
+			return fmt.Errorf("at least one --base-url is required")
+		}
+		channel := &model.Channel{
+			Name:        channelAddName,
+			Type:        channelType,
+			Enabled:     true,
+			Model:       channelAddModels,
+			CustomModel: channelAddCustom,
+			Proxy:       channelAddProxy,
+			AutoSync:    channelAddAutoSync,
+		}
+		for _, u := range channelAddBaseURLs {
+			channel.BaseUrls = append(channel.BaseUrls, model.BaseUrl{URL: u})
+		}
+		for _, k := range channelAddKeys {
+			channel.Keys = append(channel.Keys, model.ChannelKey{Enabled: true, ChannelKey: k})
+		}
+
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if err := m.ChannelCreate(context.Background(), channel); err != nil {
+			return err
+		}
+		fmt.Printf("channel %q created with id %d\n", channel.Name, channel.ID)
+		return nil
+	},
+}
+
+func channelEnableCmd(enabled bool) *cobra.Command {
+	use, short := "enable <id>", "Enable a channel"
+	if !enabled {
+		use, short = "disable <id>", "Disable a channel"
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid channel id %q", args[0])
+			}
+			m, closeFn, err := openManager()
+			if err != nil {
+				return err
+			}
+			defer closeFn()
+			if err := m.ChannelEnable(context.Background(), id, enabled); err != nil {
+				return err
+			}
+			fmt.Printf("channel %d enabled: %t\n", id, enabled)
+			return nil
+		},
+	}
+}
+
+var channelTestCmd = &cobra.Command{
+	Use:   "test <id>",
+	Short: "Probe every key of a channel against the upstream model list endpoint",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid channel id %q", args[0])
+		}
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		results, err := m.ChannelTest(context.Background(), id)
+		if err != nil {
+			return err
+		}
+		if manageJSON {
+			return printJSON(results)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY ID\tENABLED\tHEALTH\tREASON")
+		for _, r := range results {
+			fmt.Fprintf(w, "%d\t%t\t%s\t%s\n", r.KeyID, r.Enabled, r.Health, truncate(r.Reason, 80))
+		}
+		return w.Flush()
+	},
+}
+
+// truncate 截断过长的单行输出
+func truncate(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
+
+func init() {
+	channelAddCmd.Flags().StringVar(&channelAddName, "name", "", "channel name")
+	channelAddCmd.Flags().StringVar(&channelAddType, "type", "openai_chat", "channel type name or value")
+	channelAddCmd.Flags().StringArrayVar(&channelAddBaseURLs, "base-url", nil, "upstream base URL (repeatable)")
+	channelAddCmd.Flags().StringArrayVar(&channelAddKeys, "key", nil, "upstream API key (repeatable)")
+	channelAddCmd.Flags().StringVar(&channelAddModels, "model", "", "comma-separated model list")
+	channelAddCmd.Flags().StringVar(&channelAddCustom, "custom-model", "", "comma-separated custom model list")
+	channelAddCmd.Flags().BoolVar(&channelAddProxy, "proxy", false, "use the system proxy")
+	channelAddCmd.Flags().BoolVar(&channelAddAutoSync, "auto-sync", false, "sync models from upstream automatically")
+	_ = channelAddCmd.MarkFlagRequired("name")
+
+	channelCmd.AddCommand(channelListCmd, channelAddCmd, channelEnableCmd(true), channelEnableCmd(false), channelTestCmd)
+	addManageFlags(channelCmd)
+	rootCmd.AddCommand(channelCmd)
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/op"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput       string
+	exportIncludeLogs  bool
+	exportIncludeStats bool
+	exportDecrypt      bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the database to a JSON dump",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := openStore(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		dump, err := op.DBExportAll(context.Background(), exportIncludeLogs, exportIncludeStats, exportDecrypt)
+		if err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(dump, "", "  ")
+		if err != nil {
+			return err
+		}
+		if exportOutput == "" {
+			exportOutput = "octopus-export-" + time.Now().Format("20060102150405") + ".json"
+		}
+		if err := os.WriteFile(exportOutput, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("exported to %s (encrypted credentials: %t)\n", exportOutput, dump.Encrypted)
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "output file (default is ./octopus-export-<time>.json)")
+	exportCmd.Flags().BoolVar(&exportIncludeLogs, "include-logs", false, "include relay logs")
+	exportCmd.Flags().BoolVar(&exportIncludeStats, "include-stats", false, "include statistics")
+	exportCmd.Flags().BoolVar(&exportDecrypt, "decrypt", false, "export channel credentials in plaintext")
+	rootCmd.AddCommand(exportCmd)
+}
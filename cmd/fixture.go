@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"octopus/internal/fixture"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixtureInbound string
+	fixtureRequest string
+	fixtureGolden  string
+	fixtureUpdate  bool
+)
+
+var fixtureCmd = &cobra.Command{
+	Use:   "fixture",
+	Short: "Replay recorded upstream fixtures",
+}
+
+var fixtureReplayCmd = &cobra.Command{
+	Use:   "replay <fixture.json>",
+	Short: "Run a client request through the transformers against a recorded upstream response",
+	Long: `Run a client request through the transformers without any network access.
+
+The request in --request is parsed with the --inbound format, converted for the
+channel type the fixture was recorded from, answered with the recorded upstream
+response and converted back. The client-facing output is printed, or compared
+with --golden; --update rewrites the golden file instead.
+
+Fixtures are recorded with the "record" section of the config.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inboundType, ok := fixture.InboundTypes[fixtureInbound]
+		if !ok {
+			return fmt.Errorf("invalid inbound %q, expected one of %s", fixtureInbound, strings.Join(fixture.InboundTypeNames(), ", "))
+		}
+		f, err := fixture.Load(args[0])
+		if err != nil {
+			return err
+		}
+		body, err := os.ReadFile(fixtureRequest)
+		if err != nil {
+			return err
+		}
+		out, err := fixture.Replay(context.Background(), f, inboundType, body)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case fixtureGolden == "":
+			os.Stdout.Write(out)
+			if len(out) > 0 && out[len(out)-1] != '\n' {
+				fmt.Println()
+			}
+		case fixtureUpdate:
+			if err := os.WriteFile(fixtureGolden, out, 0644); err != nil {
+				return err
+			}
+			fmt.Printf("golden file %s updated\n", fixtureGolden)
+		default:
+			want, err := os.ReadFile(fixtureGolden)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(want, out) {
+				return fmt.Errorf("output differs from %s:\n%s", fixtureGolden, fixture.FirstDiff(want, out))
+			}
+			fmt.Printf("output matches %s\n", fixtureGolden)
+		}
+		return nil
+	},
+}
+
+func init() {
+	fixtureReplayCmd.Flags().StringVar(&fixtureInbound, "inbound", "openai_chat", "inbound format of the request: "+strings.Join(fixture.InboundTypeNames(), ", "))
+	fixtureReplayCmd.Flags().StringVar(&fixtureRequest, "request", "", "client request body file")
+	fixtureReplayCmd.Flags().StringVar(&fixtureGolden, "golden", "", "golden output file to compare with")
+	fixtureReplayCmd.Flags().BoolVar(&fixtureUpdate, "update", false, "write the output to the golden file instead of comparing")
+	fixtureReplayCmd.MarkFlagRequired("request")
+	fixtureCmd.AddCommand(fixtureReplayCmd)
+	rootCmd.AddCommand(fixtureCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	"octopus/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	groupItemChannel  int
+	groupItemModel    string
+	groupItemPriority int
+	groupItemWeight   int
+)
+
+var groupModeLabels = map[model.GroupMode]string{
+	model.GroupModeRoundRobin: "round_robin",
+	model.GroupModeRandom:     "random",
+	model.GroupModeFailover:   "failover",
+	model.GroupModeWeighted:   "weighted",
+}
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage groups",
+}
+
+var groupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List groups and their items",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		groups, err := m.GroupList(context.Background())
+		if err != nil {
+			return err
+		}
+		sort.Slice(groups, func(i, j int) bool { return groups[i].ID < groups[j].ID })
+		if manageJSON {
+			return printJSON(groups)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tMODE\tITEMS")
+		for _, g := range groups {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d\n", g.ID, g.Name, groupModeLabels[g.Mode], len(g.Items))
+			for _, item := range g.Items {
+				fmt.Fprintf(w, "\t  channel=%d model=%s\tpriority=%d\tweight=%d\n", item.ChannelID, item.ModelName, item.Priority, item.Weight)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+var groupAddItemCmd = &cobra.Command{
+	Use:   "add-item <group-id>",
+	Short: "Add a channel model to a group",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		groupID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid group id %q", args[0])
+		}
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		item := model.GroupItemAddRequest{
+			ChannelID: groupItemChannel,
+			ModelName: groupItemModel,
+			Priority:  groupItemPriority,
+			Weight:    groupItemWeight,
+		}
+		if err := m.GroupAddItem(context.Background(), groupID, item); err != nil {
+			return err
+		}
+		fmt.Printf("added channel %d model %s to group %d\n", item.ChannelID, item.ModelName, groupID)
+		return nil
+	},
+}
+
+func init() {
+	groupAddItemCmd.Flags().IntVar(&groupItemChannel, "channel", 0, "channel id")
+	groupAddItemCmd.Flags().StringVar(&groupItemModel, "model", "", "model name")
+	groupAddItemCmd.Flags().IntVar(&groupItemPriority, "priority", 0, "item priority")
+	groupAddItemCmd.Flags().IntVar(&groupItemWeight, "weight", 1, "item weight")
+	_ = groupAddItemCmd.MarkFlagRequired("channel")
+	_ = groupAddItemCmd.MarkFlagRequired("model")
+
+	groupCmd.AddCommand(groupListCmd, groupAddItemCmd)
+	addManageFlags(groupCmd)
+	rootCmd.AddCommand(groupCmd)
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"octopus/internal/model"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	logTailLines  int
+	logTailFollow bool
+)
+
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Inspect relay logs",
+}
+
+var logTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print the latest relay logs, optionally following new ones (requires --server)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if logTailLines < 1 || logTailLines > 100 {
+			return fmt.Errorf("-n must be between 1 and 100")
+		}
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+		if _, local := m.(localManager); local && logTailFollow {
+			return fmt.Errorf("--follow requires --server")
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		logs, err := m.LogList(ctx, logTailLines)
+		if err != nil {
+			return err
+		}
+		// 接口按时间倒序返回，输出时按时间正序
+		for i := len(logs) - 1; i >= 0; i-- {
+			printRelayLog(logs[i])
+		}
+		if !logTailFollow {
+			return nil
+		}
+		return m.LogFollow(ctx, printRelayLog)
+	},
+}
+
+func printRelayLog(l model.RelayLog) {
+	if manageJSON {
+		data, _ := json.Marshal(l)
+		fmt.Println(string(data))
+		return
+	}
+	status := "ok"
+	if l.Error != "" {
+		status = "error: " + truncate(l.Error, 80)
+	}
+	fmt.Printf("%s  %-24s -> %s/%s  in=%d out=%d  %dms  $%.6f  %s\n",
+		formatTime(l.Time), l.RequestModelName, l.ChannelName, l.ActualModelName,
+		l.InputTokens, l.OutputTokens, l.UseTime, l.Cost, status)
+}
+
+func init() {
+	logTailCmd.Flags().IntVarP(&logTailLines, "lines", "n", 20, "number of recent logs to print (1-100)")
+	logTailCmd.Flags().BoolVarP(&logTailFollow, "follow", "f", false, "stream new logs as they arrive")
+
+	logCmd.AddCommand(logTailCmd)
+	addManageFlags(logCmd)
+	rootCmd.AddCommand(logCmd)
+}
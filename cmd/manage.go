@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/helper"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/server/auth"
+	"octopus/internal/utils/xstrings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	manageServer string
+	manageToken  string
+	manageJSON   bool
+)
+
+// manager 管理命令的执行方式：未指定 --server 时直接读写数据库（服务需停止），
+// 否则通过管理 API 操作运行中的服务
+type manager interface {
+	ChannelList(ctx context.Context) ([]model.Channel, error)
+	ChannelCreate(ctx context.Context, channel *model.Channel) error
+	ChannelEnable(ctx context.Context, id int, enabled bool) error
+	ChannelTest(ctx context.Context, id int) ([]model.ChannelKeyTestResult, error)
+	GroupList(ctx context.Context) ([]model.Group, error)
+	GroupAddItem(ctx context.Context, groupID int, item model.GroupItemAddRequest) error
+	APIKeyCreate(ctx context.Context, key *model.APIKey) error
+	APIKeyDelete(ctx context.Context, id int) error
+	StatsToday(ctx context.Context) (model.StatsDaily, error)
+	LogList(ctx context.Context, n int) ([]model.RelayLog, error)
+	LogFollow(ctx context.Context, fn func(model.RelayLog)) error
+}
+
+// addManageFlags 为管理命令注册 --server、--token 与 --json
+func addManageFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&manageServer, "server", "", "management API address of a running server, e.g. http://127.0.0.1:8080 (env OCTOPUS_SERVER)")
+	cmd.PersistentFlags().StringVar(&manageToken, "token", "", "management API token (env OCTOPUS_TOKEN)")
+	cmd.PersistentFlags().BoolVar(&manageJSON, "json", false, "print raw JSON")
+}
+
+// openManager 返回管理命令使用的 manager，调用方负责调用 close
+func openManager() (manager, func(), error) {
+	if manageServer == "" {
+		manageServer = os.Getenv("OCTOPUS_SERVER")
+	}
+	if manageToken == "" {
+		manageToken = os.Getenv("OCTOPUS_TOKEN")
+	}
+	if manageServer != "" {
+		if manageToken == "" {
+			return nil, nil, fmt.Errorf("--token is required with --server")
+		}
+		return &remoteManager{
+			server: strings.TrimRight(manageServer, "/"),
+			token:  manageToken,
+			client: &http.Client{},
+		}, func() {}, nil
+	}
+	if err := openStore(); err != nil {
+		return nil, nil, err
+	}
+	if err := op.InitCache(); err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+	return localManager{}, func() { db.Close() }, nil
+}
+
+func printJSON(v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// localManager 直接通过 internal/op 读写数据库
+type localManager struct{}
+
+func (localManager) ctx(ctx context.Context) context.Context {
+	return op.WithAuditActor(ctx, "cli", "")
+}
+
+func (localManager) ChannelList(ctx context.Context) ([]model.Channel, error) {
+	return op.ChannelList(ctx)
+}
+
+func (m localManager) ChannelCreate(ctx context.Context, channel *model.Channel) error {
+	ctx = m.ctx(ctx)
+	if err := op.ChannelCreate(channel, ctx); err != nil {
+		return err
+	}
+	return helper.LLMPriceAddToDB(xstrings.SplitTrimCompact(",", channel.Model+","+channel.CustomModel), channel.ID, ctx)
+}
+
+func (m localManager) ChannelEnable(ctx context.Context, id int, enabled bool) error {
+	return op.ChannelEnabled(id, enabled, m.ctx(ctx))
+}
+
+func (localManager) ChannelTest(ctx context.Context, id int) ([]model.ChannelKeyTestResult, error) {
+	channel, err := op.ChannelGet(id, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return helper.ChannelTest(ctx, *channel), nil
+}
+
+func (localManager) GroupList(ctx context.Context) ([]model.Group, error) {
+	return op.GroupList(ctx)
+}
+
+func (m localManager) GroupAddItem(ctx context.Context, groupID int, item model.GroupItemAddRequest) error {
+	_, err := op.GroupUpdate(&model.GroupUpdateRequest{ID: groupID, ItemsToAdd: []model.GroupItemAddRequest{item}}, m.ctx(ctx))
+	return err
+}
+
+func (m localManager) APIKeyCreate(ctx context.Context, key *model.APIKey) error {
+	key.APIKey = auth.GenerateAPIKey()
+	return op.APIKeyCreate(key, m.ctx(ctx))
+}
+
+func (m localManager) APIKeyDelete(ctx context.Context, id int) error {
+	return op.APIKeyDelete(id, m.ctx(ctx))
+}
+
+func (localManager) StatsToday(ctx context.Context) (model.StatsDaily, error) {
+	return op.StatsTodayGet(), nil
+}
+
+func (localManager) LogList(ctx context.Context, n int) ([]model.RelayLog, error) {
+	return op.RelayLogList(ctx, nil, nil, 1, n)
+}
+
+// LogFollow 服务停止时没有新的日志产生
+func (localManager) LogFollow(ctx context.Context, fn func(model.RelayLog)) error {
+	return fmt.Errorf("--follow requires --server")
+}
+
+// remoteManager 通过管理 API 操作运行中的服务
+type remoteManager struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// call 发送请求并将响应中的 data 解析到 out
+func (m *remoteManager) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%s %s: unexpected response (status %d)", method, path, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s (status %d)", method, path, result.Message, resp.StatusCode)
+	}
+	if out == nil || len(result.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+func (m *remoteManager) ChannelList(ctx context.Context) ([]model.Channel, error) {
+	var channels []model.Channel
+	err := m.call(ctx, http.MethodGet, "/api/v1/channel/list", nil, &channels)
+	return channels, err
+}
+
+func (m *remoteManager) ChannelCreate(ctx context.Context, channel *model.Channel) error {
+	return m.call(ctx, http.MethodPost, "/api/v1/channel/create", channel, channel)
+}
+
+func (m *remoteManager) ChannelEnable(ctx context.Context, id int, enabled bool) error {
+	return m.call(ctx, http.MethodPost, "/api/v1/channel/enable", map[string]any{"id": id, "enabled": enabled}, nil)
+}
+
+func (m *remoteManager) ChannelTest(ctx context.Context, id int) ([]model.ChannelKeyTestResult, error) {
+	var results []model.ChannelKeyTestResult
+	err := m.call(ctx, http.MethodPost, fmt.Sprintf("/api/v1/channel/test/%d", id), nil, &results)
+	return results, err
+}
+
+func (m *remoteManager) GroupList(ctx context.Context) ([]model.Group, error) {
+	var groups []model.Group
+	err := m.call(ctx, http.MethodGet, "/api/v1/group/list", nil, &groups)
+	return groups, err
+}
+
+func (m *remoteManager) GroupAddItem(ctx context.Context, groupID int, item model.GroupItemAddRequest) error {
+	return m.call(ctx, http.MethodPost, "/api/v1/group/update",
+		model.GroupUpdateRequest{ID: groupID, ItemsToAdd: []model.GroupItemAddRequest{item}}, nil)
+}
+
+func (m *remoteManager) APIKeyCreate(ctx context.Context, key *model.APIKey) error {
+	return m.call(ctx, http.MethodPost, "/api/v1/apikey/create", key, key)
+}
+
+func (m *remoteManager) APIKeyDelete(ctx context.Context, id int) error {
+	return m.call(ctx, http.MethodDelete, fmt.Sprintf("/api/v1/apikey/delete/%d", id), nil, nil)
+}
+
+func (m *remoteManager) StatsToday(ctx context.Context) (model.StatsDaily, error) {
+	var stats model.StatsDaily
+	err := m.call(ctx, http.MethodGet, "/api/v1/stats/today", nil, &stats)
+	return stats, err
+}
+
+func (m *remoteManager) LogList(ctx context.Context, n int) ([]model.RelayLog, error) {
+	var logs []model.RelayLog
+	err := m.call(ctx, http.MethodGet, fmt.Sprintf("/api/v1/log/list?page=1&page_size=%d", n), nil, &logs)
+	return logs, err
+}
+
+// LogFollow 订阅日志 SSE 流，直到 ctx 取消或连接断开
+func (m *remoteManager) LogFollow(ctx context.Context, fn func(model.RelayLog)) error {
+	var stream struct {
+		Token string `json:"token"`
+	}
+	if err := m.call(ctx, http.MethodGet, "/api/v1/log/stream-token", nil, &stream); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.server+"/api/v1/log/stream?token="+url.QueryEscape(stream.Token), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("log stream: status %d", resp.StatusCode)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var l model.RelayLog
+		if err := json.Unmarshal([]byte(data), &l); err != nil {
+			continue
+		}
+		fn(l)
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return scanner.Err()
+}
+
+func formatTime(ts int64) string {
+	if ts == 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Format(time.DateTime)
+}
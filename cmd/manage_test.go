@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestRemoteManagerCall(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":401,"message":"unauthorized"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/channel/list":
+			w.Write([]byte(`{"code":200,"data":[{"id":1,"name":"openai"}]}`))
+		case "/api/v1/channel/enable":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":400,"message":"channel not found"}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>bad gateway</html>"))
+		}
+	}))
+	defer srv.Close()
+
+	m := &remoteManager{server: srv.URL, token: "test-token", client: srv.Client()}
+	channels, err := m.ChannelList(context.Background())
+	if err != nil || len(channels) != 1 || channels[0].Name != "openai" {
+		t.Fatalf("expected one channel, got %+v, %v", channels, err)
+	}
+
+	tests := []struct {
+		name    string
+		manager *remoteManager
+		call    func(m *remoteManager) error
+		wantErr string
+	}{
+		{name: "api error", manager: m, call: func(m *remoteManager) error { return m.ChannelEnable(context.Background(), 9, false) }, wantErr: "channel not found (status 400)"},
+		{name: "non json response", manager: m, call: func(m *remoteManager) error { _, err := m.StatsToday(context.Background()); return err }, wantErr: "unexpected response (status 502)"},
+		{
+			name:    "wrong token",
+			manager: &remoteManager{server: srv.URL, token: "wrong", client: srv.Client()},
+			call:    func(m *remoteManager) error { return m.APIKeyCreate(context.Background(), &model.APIKey{Name: "k"}) },
+			wantErr: "unauthorized (status 401)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(tt.manager)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		n     int
+		want  string
+	}{
+		{name: "short", input: "ok", n: 10, want: "ok"},
+		{name: "newlines", input: "a\nb", n: 10, want: "a b"},
+		{name: "long", input: "abcdefghij", n: 6, want: "abc..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.input, tt.n); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"octopus/internal/db"
+	"octopus/internal/op"
+	"octopus/internal/secret"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	rekeyNewKey        string
+	rekeyNewKeyFile    string
+	rekeyGenerate      bool
+	rekeyRotateDataKey bool
+)
+
+var rekeyCmd = &cobra.Command{
+	Use:   "rekey",
+	Short: "Re-wrap data keys with a new master key and optionally rotate the data key",
+	Long: "Re-wrap the stored data keys with a new master key. The server should be stopped.\n" +
+		"With --rotate-data-key all channel credentials are re-encrypted with a fresh data key.\n" +
+		"After a successful rekey, point security.master_key or security.master_key_file at the new key.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var newKey []byte
+		var pendingKeyFile string
+		switch {
+		case rekeyGenerate:
+			if rekeyNewKeyFile == "" {
+				return fmt.Errorf("--generate requires --new-master-key-file")
+			}
+			if _, err := os.Stat(rekeyNewKeyFile); err == nil {
+				return fmt.Errorf("new master key file %s already exists", rekeyNewKeyFile)
+			}
+			raw, err := secret.GenerateMasterKey()
+			if err != nil {
+				return err
+			}
+			if newKey, err = secret.ParseMasterKey(raw); err != nil {
+				return err
+			}
+			// 新密钥先写入临时文件，数据库提交后再改名，失败时不留下与数据库不匹配的密钥文件
+			if pendingKeyFile, err = writeTempKeyFile(rekeyNewKeyFile, raw); err != nil {
+				return err
+			}
+			defer func() {
+				if pendingKeyFile != "" {
+					os.Remove(pendingKeyFile)
+				}
+			}()
+		case rekeyNewKey != "":
+			var err error
+			if newKey, err = secret.ParseMasterKey(rekeyNewKey); err != nil {
+				return err
+			}
+		case rekeyNewKeyFile != "":
+			var err error
+			if newKey, err = secret.ReadMasterKeyFile(rekeyNewKeyFile); err != nil {
+				return err
+			}
+		case !rekeyRotateDataKey:
+			return fmt.Errorf("nothing to do: specify a new master key or --rotate-data-key")
+		}
+
+		if err := openStore(); err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if rekeyRotateDataKey {
+			if err := secret.RotateDataKey(db.GetDB(), op.CredentialReencrypt); err != nil {
+				return err
+			}
+			fmt.Println("data key rotated, credentials re-encrypted")
+		}
+		if newKey != nil {
+			if err := secret.Rekey(db.GetDB(), newKey); err != nil {
+				return err
+			}
+			if pendingKeyFile != "" {
+				if err := os.Rename(pendingKeyFile, rekeyNewKeyFile); err != nil {
+					kept := pendingKeyFile
+					pendingKeyFile = ""
+					return fmt.Errorf("data keys were re-wrapped but the new key could not be moved into place, it is kept at %s: %w", kept, err)
+				}
+				pendingKeyFile = ""
+				fmt.Printf("new master key written to %s\n", rekeyNewKeyFile)
+			}
+			fmt.Println("data keys re-wrapped with the new master key, update your config before starting the server")
+		}
+		return nil
+	},
+}
+
+// writeTempKeyFile 把主密钥写入 path 所在目录的临时文件，返回临时文件路径
+func writeTempKeyFile(path, key string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create new master key file: %w", err)
+	}
+	_, err = f.WriteString(key + "\n")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write new master key file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+func init() {
+	rekeyCmd.Flags().StringVar(&rekeyNewKey, "new-master-key", "", "new master key (base64/hex 32 bytes or passphrase)")
+	rekeyCmd.Flags().StringVar(&rekeyNewKeyFile, "new-master-key-file", "", "file containing the new master key")
+	rekeyCmd.Flags().BoolVar(&rekeyGenerate, "generate", false, "generate a new master key into --new-master-key-file")
+	rekeyCmd.Flags().BoolVar(&rekeyRotateDataKey, "rotate-data-key", false, "re-encrypt all credentials with a new data key")
+	rootCmd.AddCommand(rekeyCmd)
+}
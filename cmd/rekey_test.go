@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteTempKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "new.key")
+
+	tmp, err := writeTempKeyFile(target, "secret-key")
+	if err != nil {
+		t.Fatalf("failed to write temp key file: %v", err)
+	}
+	if filepath.Dir(tmp) != dir {
+		t.Errorf("expected temp file in %s, got %s", dir, tmp)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to exist before rename", target)
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected mode 0600, got %o", perm)
+	}
+	content, _ := os.ReadFile(tmp)
+	if string(content) != "secret-key\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+
+	if _, err := writeTempKeyFile(filepath.Join(dir, "missing", "new.key"), "k"); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
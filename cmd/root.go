@@ -7,11 +7,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var cfgFile string
+
 var rootCmd = &cobra.Command{
 	Use:   conf.APP_NAME,
 	Short: conf.APP_DESC,
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
+}
+
 func Execute() {
 	err := rootCmd.Execute()
 	if err != nil {
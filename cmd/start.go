@@ -1,10 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
 	"octopus/internal/conf"
 	"octopus/internal/db"
+	"octopus/internal/mcp"
 	"octopus/internal/op"
+	"octopus/internal/secret"
 	"octopus/internal/server"
+	"octopus/internal/server/handlers"
 	"octopus/internal/server/middleware"
 	"octopus/internal/task"
 	"octopus/internal/utils/log"
@@ -12,30 +20,23 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var cfgFile string
-
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start " + conf.APP_NAME,
 	PreRun: func(cmd *cobra.Command, args []string) {
 		conf.PrintBanner()
 		conf.Load(cfgFile)
-		log.SetLevel(conf.AppConfig.Log.Level)
-		middleware.InitRateLimit(
-			conf.AppConfig.RateLimit.MaxConcurrentRequests,
-			conf.AppConfig.RateLimit.FastMaxConcurrent,
-			conf.AppConfig.RateLimit.SlowMaxConcurrent,
-			conf.AppConfig.RateLimit.MigrateAfterSeconds,
-			conf.AppConfig.RateLimit.RateLimitPerSecond,
-			conf.AppConfig.RateLimit.RateLimitBurst,
-			conf.AppConfig.RateLimit.MaxQueueSize,
-			conf.AppConfig.RateLimit.MaxQueueWaitSeconds,
-		)
+		log.SetLevel(conf.Get().Log.Level)
+		initRateLimit(conf.Get().RateLimit)
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		shutdown.Init(log.Logger)
 		defer shutdown.Listen()
-		if err := db.InitDB(conf.AppConfig.Database.Type, conf.AppConfig.Database.Path, conf.IsDebug()); err != nil {
+		if err := secret.Init(conf.Get().Security.MasterKey, conf.Get().Security.MasterKeyFile); err != nil {
+			log.Errorf("secret init error: %v", err)
+			return
+		}
+		if err := db.InitDB(conf.Get().Database.Type, conf.Get().Database.Path, conf.IsDebug()); err != nil {
 			log.Errorf("database init error: %v", err)
 			return
 		}
@@ -52,6 +53,16 @@ var startCmd = &cobra.Command{
 			return
 		}
 
+		if conf.Get().Cluster.Enabled {
+			if err := initCluster(conf.Get().Cluster); err != nil {
+				log.Errorf("cluster init error: %v", err)
+				return
+			}
+			shutdown.Register(op.ClusterClose)
+		}
+
+		shutdown.Register(mcp.Close)
+
 		if err := server.Start(); err != nil {
 			log.Errorf("server start error: %v", err)
 			return
@@ -60,10 +71,62 @@ var startCmd = &cobra.Command{
 
 		task.Init()
 		go task.RUN()
+
+		registerReloadHooks()
+		if err := conf.Watch(); err != nil {
+			log.Warnf("config hot reload disabled: %v", err)
+		}
 	},
 }
 
+// initCluster 开启集群模式，雪花 ID 的节点号由 op.ClusterInit 通过租约分配，保证各实例生成的日志 ID 不冲突
+func initCluster(cfg conf.Cluster) error {
+	if cfg.SyncIntervalSeconds <= 0 || cfg.LeaseSeconds < 2*cfg.SyncIntervalSeconds {
+		return fmt.Errorf("cluster.lease_seconds must be at least twice cluster.sync_interval_seconds")
+	}
+	nodeID := cfg.NodeID
+	if nodeID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("cluster.node_id is empty and the hostname is unavailable: %w", err)
+		}
+		nodeID = host
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return op.ClusterInit(ctx, nodeID, time.Duration(cfg.LeaseSeconds)*time.Second)
+}
+
+func initRateLimit(cfg conf.RateLimit) {
+	middleware.InitRateLimit(
+		cfg.MaxConcurrentRequests,
+		cfg.FastMaxConcurrent,
+		cfg.SlowMaxConcurrent,
+		cfg.MigrateAfterSeconds,
+		cfg.RateLimitPerSecond,
+		cfg.RateLimitBurst,
+		cfg.MaxQueueSize,
+		cfg.MaxQueueWaitSeconds,
+	)
+}
+
+// registerReloadHooks 配置热重载时应用可在运行时修改的配置项
+func registerReloadHooks() {
+	conf.OnReload(func(old, cur conf.Config) {
+		if old.Log.Level != cur.Log.Level {
+			log.SetLevel(cur.Log.Level)
+		}
+		if old.RateLimit != cur.RateLimit {
+			initRateLimit(cur.RateLimit)
+		}
+		handlers.ReloadAmp(old.AmpCode, cur.AmpCode)
+		mcp.Reload(cur.MCP)
+		if cur.Backup.Enabled && old.Backup.IntervalHours != cur.Backup.IntervalHours {
+			task.Update(task.TaskBackup, time.Duration(cur.Backup.IntervalHours)*time.Hour)
+		}
+	})
+}
+
 func init() {
-	startCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./data/config.json)")
 	rootCmd.AddCommand(startCmd)
 }
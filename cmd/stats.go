@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show usage statistics",
+}
+
+var statsTodayCmd = &cobra.Command{
+	Use:   "today",
+	Short: "Show today's request, token and cost totals",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, closeFn, err := openManager()
+		if err != nil {
+			return err
+		}
+		defer closeFn()
+
+		stats, err := m.StatsToday(context.Background())
+		if err != nil {
+			return err
+		}
+		if manageJSON {
+			return printJSON(stats)
+		}
+		requests := stats.RequestSuccess + stats.RequestFailed
+		fmt.Printf("date:          %s\n", stats.Date)
+		fmt.Printf("requests:      %d (success %d, failed %d)\n", requests, stats.RequestSuccess, stats.RequestFailed)
+		fmt.Printf("input tokens:  %d\n", stats.InputToken)
+		fmt.Printf("output tokens: %d\n", stats.OutputToken)
+		fmt.Printf("cache tokens:  read %d, write %d (hit rate %.1f%%)\n", stats.CacheReadToken, stats.CacheWriteToken, stats.CacheHitRate()*100)
+		fmt.Printf("cost:          %.6f (input %.6f, output %.6f)\n", stats.InputCost+stats.OutputCost, stats.InputCost, stats.OutputCost)
+		if requests > 0 {
+			fmt.Printf("avg wait:      %d ms\n", stats.WaitTime/requests)
+		}
+		return nil
+	},
+}
+
+func init() {
+	statsCmd.AddCommand(statsTodayCmd)
+	addManageFlags(statsCmd)
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"fmt"
+
+	"octopus/internal/conf"
+	"octopus/internal/db"
+	"octopus/internal/secret"
+)
+
+// openStore 为离线命令加载配置、主密钥并连接数据库，调用方负责 db.Close
+func openStore() error {
+	if err := conf.Load(cfgFile); err != nil {
+		return err
+	}
+	if err := secret.Init(conf.Get().Security.MasterKey, conf.Get().Security.MasterKeyFile); err != nil {
+		return fmt.Errorf("secret init error: %w", err)
+	}
+	if err := db.InitDB(conf.Get().Database.Type, conf.Get().Database.Path, conf.IsDebug()); err != nil {
+		return fmt.Errorf("database init error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/utils/log"
+)
+
+// defaultCooldown 规则未配置冷却时间时，同一目标两次通知的最小间隔
+const defaultCooldown = 60 * time.Minute
+
+type alertState struct {
+	firing   bool
+	lastSent time.Time
+	message  string
+}
+
+var (
+	states     = make(map[string]*alertState)
+	statesLock sync.Mutex
+)
+
+func stateKey(ruleID int, subject string) string {
+	return fmt.Sprintf("%d:%s", ruleID, subject)
+}
+
+func cooldownOf(rule model.AlertRule) time.Duration {
+	if rule.Cooldown <= 0 {
+		return defaultCooldown
+	}
+	return time.Duration(rule.Cooldown) * time.Minute
+}
+
+// Trigger 上报一次性事件（同步失败、新版本等），匹配该事件的所有规则都会收到
+// 同一规则同一目标的相同内容在冷却时间内只发送一次
+func Trigger(event model.AlertEvent, subject, title, message string) {
+	for _, rule := range op.AlertRuleListByEvent(event) {
+		key := stateKey(rule.ID, subject)
+		now := time.Now()
+
+		statesLock.Lock()
+		st, ok := states[key]
+		if ok && st.message == message && now.Sub(st.lastSent) < cooldownOf(rule) {
+			statesLock.Unlock()
+			continue
+		}
+		states[key] = &alertState{lastSent: now, message: message}
+		statesLock.Unlock()
+
+		dispatch(rule, model.Alert{
+			Event:    event,
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Subject:  subject,
+			Title:    title,
+			Message:  message,
+			Time:     now.Unix(),
+		})
+	}
+}
+
+// evaluate 更新状态类告警（错误率、Key 失败、预算）
+// 进入告警状态时立即发送，持续告警时每个冷却周期重复发送一次，恢复后清除状态
+func evaluate(rule model.AlertRule, subject string, firing bool, value float64, title, message string) {
+	key := stateKey(rule.ID, subject)
+	now := time.Now()
+
+	statesLock.Lock()
+	st, ok := states[key]
+	if !firing {
+		if ok {
+			delete(states, key)
+		}
+		statesLock.Unlock()
+		return
+	}
+	if ok && st.firing && now.Sub(st.lastSent) < cooldownOf(rule) {
+		statesLock.Unlock()
+		return
+	}
+	states[key] = &alertState{firing: true, lastSent: now, message: message}
+	statesLock.Unlock()
+
+	dispatch(rule, model.Alert{
+		Event:    rule.Event,
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Subject:  subject,
+		Title:    title,
+		Message:  message,
+		Value:    value,
+		Time:     now.Unix(),
+	})
+}
+
+// dispatch 异步发送到规则配置的所有通知目标
+func dispatch(rule model.AlertRule, a model.Alert) {
+	log.Infof("alert fired: rule=%s subject=%s title=%s", rule.Name, a.Subject, a.Title)
+	for _, id := range rule.NotifierIDs {
+		n, err := op.AlertNotifierGet(id, context.Background())
+		if err != nil || !n.Enabled {
+			continue
+		}
+		go func(n model.AlertNotifier) {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if err := Send(ctx, n, a); err != nil {
+				log.Warnf("failed to send alert to notifier %s: %v", n.Name, err)
+			}
+		}(n)
+	}
+}
@@ -0,0 +1,175 @@
+package alert
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/secret"
+)
+
+// setupTestDB 在临时目录中初始化 sqlite 数据库与密钥，测试结束后关闭
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	if err := secret.Init("test passphrase", ""); err != nil {
+		t.Fatalf("failed to init secret: %v", err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(t.TempDir(), "test.db"), false); err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
+
+// sentAt 返回规则与目标最近一次发送的时间，没有状态时返回零值
+func sentAt(ruleID int, subject string) time.Time {
+	statesLock.Lock()
+	defer statesLock.Unlock()
+	if st, ok := states[stateKey(ruleID, subject)]; ok {
+		return st.lastSent
+	}
+	return time.Time{}
+}
+
+// age 将最近一次发送的时间提前 d，模拟时间流逝
+func age(ruleID int, subject string, d time.Duration) {
+	statesLock.Lock()
+	defer statesLock.Unlock()
+	if st, ok := states[stateKey(ruleID, subject)]; ok {
+		st.lastSent = st.lastSent.Add(-d)
+	}
+}
+
+// resetStates 清除之前测试留下的告警状态
+func resetStates(t *testing.T) {
+	t.Helper()
+	statesLock.Lock()
+	defer statesLock.Unlock()
+	states = make(map[string]*alertState)
+}
+
+type step struct {
+	elapsed  time.Duration // 距上一次发送经过的时间
+	firing   bool
+	message  string
+	wantSent bool
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cooldown int
+		steps    []step
+	}{
+		{
+			name: "fires once within default cooldown",
+			steps: []step{
+				{firing: true, wantSent: true},
+				{elapsed: time.Minute, firing: true},
+				{elapsed: 30 * time.Minute, firing: true},
+			},
+		},
+		{
+			name: "repeats after default cooldown",
+			steps: []step{
+				{firing: true, wantSent: true},
+				{elapsed: defaultCooldown, firing: true, wantSent: true},
+			},
+		},
+		{
+			name:     "rule cooldown",
+			cooldown: 5,
+			steps: []step{
+				{firing: true, wantSent: true},
+				{elapsed: 4 * time.Minute, firing: true},
+				{elapsed: 2 * time.Minute, firing: true, wantSent: true},
+			},
+		},
+		{
+			name: "resolves and fires again",
+			steps: []step{
+				{firing: true, wantSent: true},
+				{elapsed: time.Minute},
+				{firing: true, wantSent: true},
+			},
+		},
+		{
+			name:  "not firing",
+			steps: []step{{}, {}},
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetStates(t)
+			rule := model.AlertRule{ID: 1000 + i, Name: tt.name, Event: model.AlertEventChannelErrorRate, Cooldown: tt.cooldown}
+			for j, s := range tt.steps {
+				age(rule.ID, "channel:1", s.elapsed)
+				before := sentAt(rule.ID, "channel:1")
+				evaluate(rule, "channel:1", s.firing, 0, "title", "message")
+				after := sentAt(rule.ID, "channel:1")
+				if sent := !after.IsZero() && !after.Equal(before); sent != s.wantSent {
+					t.Fatalf("step %d: expected sent %v, got %v", j, s.wantSent, sent)
+				}
+			}
+		})
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		cooldown int
+		steps    []step
+	}{
+		{
+			name: "same message is deduplicated",
+			steps: []step{
+				{message: "failed", wantSent: true},
+				{elapsed: time.Minute, message: "failed"},
+				{elapsed: defaultCooldown, message: "failed", wantSent: true},
+			},
+		},
+		{
+			name: "new message is sent",
+			steps: []step{
+				{message: "failed: timeout", wantSent: true},
+				{elapsed: time.Minute, message: "failed: 502", wantSent: true},
+			},
+		},
+		{
+			name:     "rule cooldown",
+			cooldown: 10,
+			steps: []step{
+				{message: "failed", wantSent: true},
+				{elapsed: 9 * time.Minute, message: "failed"},
+				{elapsed: 2 * time.Minute, message: "failed", wantSent: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetStates(t)
+			rule := &model.AlertRule{Name: tt.name, Event: model.AlertEventBackupFailed, Enabled: true, Cooldown: tt.cooldown}
+			if err := op.AlertRuleCreate(rule, ctx); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { _ = op.AlertRuleDelete(rule.ID, ctx) })
+
+			for j, s := range tt.steps {
+				age(rule.ID, "backup", s.elapsed)
+				before := sentAt(rule.ID, "backup")
+				Trigger(model.AlertEventBackupFailed, "backup", "Backup failed", s.message)
+				after := sentAt(rule.ID, "backup")
+				if sent := !after.IsZero() && !after.Equal(before); sent != s.wantSent {
+					t.Fatalf("step %d: expected sent %v, got %v", j, s.wantSent, sent)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+)
+
+// maxWindowMinutes 错误率统计保留的最长时间窗口
+const maxWindowMinutes = 60
+
+type minuteBucket struct {
+	minute  int64
+	success int
+	failed  int
+}
+
+// channelWindow 按分钟滚动的渠道请求计数
+type channelWindow struct {
+	buckets [maxWindowMinutes]minuteBucket
+}
+
+var (
+	channelWindows     = make(map[int]*channelWindow)
+	channelWindowsLock sync.Mutex
+)
+
+// ObserveRelay 记录一次渠道请求结果，用于计算错误率
+func ObserveRelay(channelID int, success bool) {
+	if channelID == 0 {
+		return
+	}
+	minute := time.Now().Unix() / 60
+
+	channelWindowsLock.Lock()
+	defer channelWindowsLock.Unlock()
+	w, ok := channelWindows[channelID]
+	if !ok {
+		w = &channelWindow{}
+		channelWindows[channelID] = w
+	}
+	b := &w.buckets[minute%maxWindowMinutes]
+	if b.minute != minute {
+		*b = minuteBucket{minute: minute}
+	}
+	if success {
+		b.success++
+	} else {
+		b.failed++
+	}
+}
+
+// channelCounts 返回最近 window 分钟内的成功与失败次数
+func channelCounts(channelID int, window int) (success, failed int) {
+	minute := time.Now().Unix() / 60
+
+	channelWindowsLock.Lock()
+	defer channelWindowsLock.Unlock()
+	w, ok := channelWindows[channelID]
+	if !ok {
+		return 0, 0
+	}
+	for _, b := range w.buckets {
+		if b.minute > minute-int64(window) && b.minute <= minute {
+			success += b.success
+			failed += b.failed
+		}
+	}
+	return success, failed
+}
+
+// Check 评估所有状态类告警规则，由定时任务周期调用
+func Check(ctx context.Context) {
+	channels, _ := op.ChannelList(ctx)
+
+	for _, rule := range op.AlertRuleListByEvent(model.AlertEventChannelErrorRate) {
+		for _, ch := range channels {
+			success, failed := channelCounts(ch.ID, rule.Window)
+			total := success + failed
+			rate := 0.0
+			if total > 0 {
+				rate = float64(failed) / float64(total) * 100
+			}
+			firing := ch.Enabled && total > 0 && total >= rule.MinRequests && rate >= rule.Threshold
+			evaluate(rule, fmt.Sprintf("channel:%d", ch.ID), firing, rate,
+				fmt.Sprintf("Channel %s error rate %.1f%%", ch.Name, rate),
+				fmt.Sprintf("Channel %s (id %d): %d of %d requests failed in the last %d minutes (threshold %.1f%%).",
+					ch.Name, ch.ID, failed, total, rule.Window, rule.Threshold))
+		}
+	}
+
+	for _, rule := range op.AlertRuleListByEvent(model.AlertEventChannelKeysFailing) {
+		for _, ch := range channels {
+			firing, codes := channelKeysFailing(ch)
+			evaluate(rule, fmt.Sprintf("channel:%d", ch.ID), firing, 0,
+				fmt.Sprintf("All keys of channel %s are failing", ch.Name),
+				fmt.Sprintf("Channel %s (id %d): the last request of every enabled key failed (status codes: %s).",
+					ch.Name, ch.ID, codes))
+		}
+	}
+
+	budgetRules := op.AlertRuleListByEvent(model.AlertEventAPIKeyBudget)
+	if len(budgetRules) > 0 {
+		apiKeys, _ := op.APIKeyList(ctx)
+		for _, rule := range budgetRules {
+			for _, k := range apiKeys {
+				if k.MaxCost <= 0 {
+					continue
+				}
+				stats := op.StatsAPIKeyGet(k.ID)
+				cost := stats.StatsMetrics.InputCost + stats.StatsMetrics.OutputCost
+				pct := cost / k.MaxCost * 100
+				evaluate(rule, fmt.Sprintf("apikey:%d", k.ID), pct >= rule.Threshold, pct,
+					fmt.Sprintf("API key %s reached %.0f%% of its budget", k.Name, pct),
+					fmt.Sprintf("API key %s (%s) has spent %.4f of %.4f (%.1f%%, threshold %.0f%%).",
+						k.Name, k.KeyPrefix, cost, k.MaxCost, pct, rule.Threshold))
+			}
+		}
+	}
+}
+
+// channelKeysFailing 判断渠道所有启用的 Key 最近一次请求是否都失败
+func channelKeysFailing(ch model.Channel) (bool, string) {
+	if !ch.Enabled {
+		return false, ""
+	}
+	codes := make([]string, 0, len(ch.Keys))
+	for _, k := range ch.Keys {
+		if !k.Enabled {
+			continue
+		}
+		if k.StatusCode < 400 {
+			return false, ""
+		}
+		codes = append(codes, fmt.Sprint(k.StatusCode))
+	}
+	if len(codes) == 0 {
+		return false, ""
+	}
+	return true, strings.Join(codes, ", ")
+}
@@ -0,0 +1,101 @@
+package alert
+
+import (
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestChannelKeysFailing(t *testing.T) {
+	tests := []struct {
+		name      string
+		channel   model.Channel
+		expected  bool
+		wantCodes string
+	}{
+		{
+			name:      "all enabled keys failing",
+			channel:   model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: true, StatusCode: 401}, {Enabled: true, StatusCode: 429}}},
+			expected:  true,
+			wantCodes: "401, 429",
+		},
+		{
+			name:     "one key succeeding",
+			channel:  model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: true, StatusCode: 500}, {Enabled: true, StatusCode: 200}}},
+			expected: false,
+		},
+		{
+			name:     "status below threshold",
+			channel:  model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: true, StatusCode: 399}}},
+			expected: false,
+		},
+		{
+			name:      "status at threshold",
+			channel:   model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: true, StatusCode: 400}}},
+			expected:  true,
+			wantCodes: "400",
+		},
+		{
+			name:     "key not used yet",
+			channel:  model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: true}}},
+			expected: false,
+		},
+		{
+			name:      "disabled keys ignored",
+			channel:   model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: false, StatusCode: 200}, {Enabled: true, StatusCode: 503}}},
+			expected:  true,
+			wantCodes: "503",
+		},
+		{
+			name:     "no enabled keys",
+			channel:  model.Channel{Enabled: true, Keys: []model.ChannelKey{{Enabled: false, StatusCode: 401}}},
+			expected: false,
+		},
+		{
+			name:     "disabled channel",
+			channel:  model.Channel{Enabled: false, Keys: []model.ChannelKey{{Enabled: true, StatusCode: 401}}},
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, codes := channelKeysFailing(tt.channel)
+			if got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			if codes != tt.wantCodes {
+				t.Errorf("expected codes %q, got %q", tt.wantCodes, codes)
+			}
+		})
+	}
+}
+
+func TestChannelCounts(t *testing.T) {
+	channelWindowsLock.Lock()
+	delete(channelWindows, 9001)
+	channelWindowsLock.Unlock()
+
+	for _, success := range []bool{true, true, false} {
+		ObserveRelay(9001, success)
+	}
+	ObserveRelay(0, false)
+
+	tests := []struct {
+		name        string
+		channelID   int
+		window      int
+		wantSuccess int
+		wantFailed  int
+	}{
+		{name: "current window", channelID: 9001, window: 5, wantSuccess: 2, wantFailed: 1},
+		{name: "unknown channel", channelID: 9002, window: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			success, failed := channelCounts(tt.channelID, tt.window)
+			if success != tt.wantSuccess || failed != tt.wantFailed {
+				t.Errorf("expected %d/%d, got %d/%d", tt.wantSuccess, tt.wantFailed, success, failed)
+			}
+		})
+	}
+}
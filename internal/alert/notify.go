@@ -0,0 +1,176 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"octopus/internal/client"
+	"octopus/internal/model"
+	"octopus/internal/utils/log"
+)
+
+var templateFuncs = template.FuncMap{
+	// json 将值编码为 JSON，在模板中用于安全地嵌入字符串
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"time": func(ts int64) string {
+		return time.Unix(ts, 0).Format(time.RFC3339)
+	},
+}
+
+// Send 按通知类型格式化并发送告警
+func Send(ctx context.Context, n model.AlertNotifier, a model.Alert) error {
+	switch n.Type {
+	case model.AlertNotifierWebhook:
+		body, err := renderWebhook(n.Template, a)
+		if err != nil {
+			return err
+		}
+		return postJSON(ctx, n.URL, body)
+	case model.AlertNotifierSlack:
+		body, _ := json.Marshal(map[string]string{"text": fmt.Sprintf("*%s*\n%s", a.Title, a.Message)})
+		return postJSON(ctx, n.URL, body)
+	case model.AlertNotifierDiscord:
+		body, _ := json.Marshal(map[string]string{"content": fmt.Sprintf("**%s**\n%s", a.Title, a.Message)})
+		return postJSON(ctx, n.URL, body)
+	case model.AlertNotifierTelegram:
+		body, _ := json.Marshal(map[string]string{
+			"chat_id": n.TelegramChatID,
+			"text":    a.Title + "\n" + a.Message,
+		})
+		return postJSON(ctx, "https://api.telegram.org/bot"+n.TelegramBotToken+"/sendMessage", body)
+	case model.AlertNotifierEmail:
+		return sendEmail(n, a)
+	default:
+		return fmt.Errorf("unsupported notifier type: %s", n.Type)
+	}
+}
+
+// renderWebhook 渲染 webhook 请求体，模板为空时发送告警的 JSON
+func renderWebhook(tpl string, a model.Alert) ([]byte, error) {
+	if strings.TrimSpace(tpl) == "" {
+		return json.Marshal(a)
+	}
+	t, err := template.New("webhook").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, a); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// postJSON 先直连发送，失败时使用系统代理重试
+func postJSON(ctx context.Context, url string, body []byte) error {
+	err := doPost(ctx, url, body, false)
+	if err == nil {
+		return nil
+	}
+	log.Debugf("alert direct request failed, trying with proxy: %v", err)
+	if proxyErr := doPost(ctx, url, body, true); proxyErr != nil {
+		return err
+	}
+	return nil
+}
+
+func doPost(ctx context.Context, url string, body []byte, useProxy bool) error {
+	hc, err := client.GetHTTPClientSystemProxy(useProxy)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func sendEmail(n model.AlertNotifier, a model.Alert) error {
+	to := make([]string, 0)
+	for _, addr := range strings.Split(n.EmailTo, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no email recipient")
+	}
+	var msg strings.Builder
+	msg.WriteString("From: " + n.EmailFrom + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + a.Title + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(a.Message + "\r\n")
+
+	addr := net.JoinHostPort(n.SMTPHost, strconv.Itoa(n.SMTPPort))
+	var auth smtp.Auth
+	if n.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", n.SMTPUsername, n.SMTPPassword, n.SMTPHost)
+	}
+	if n.SMTPPort != 465 {
+		// 其他端口由 SendMail 在服务端支持时自动升级 STARTTLS
+		return smtp.SendMail(addr, auth, n.EmailFrom, to, []byte(msg.String()))
+	}
+
+	// 465 端口使用隐式 TLS
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: n.SMTPHost})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, n.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.EmailFrom); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(msg.String())); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,54 @@
+package alert
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestRenderWebhook(t *testing.T) {
+	a := model.Alert{
+		Event:    model.AlertEventBackupFailed,
+		RuleID:   1,
+		RuleName: "backup",
+		Subject:  "backup",
+		Title:    `Backup "nightly" failed`,
+		Message:  "disk full",
+		Time:     0,
+	}
+	raw, _ := json.Marshal(a)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+		wantErr  string
+	}{
+		{name: "empty template", template: "", expected: string(raw)},
+		{name: "blank template", template: "  \n", expected: string(raw)},
+		{name: "json func", template: `{"text": {{json .Title}}}`, expected: `{"text": "Backup \"nightly\" failed"}`},
+		{name: "fields", template: `{{.RuleName}}: {{.Message}}`, expected: "backup: disk full"},
+		{name: "parse error", template: `{{.Title`, wantErr: "invalid webhook template"},
+		{name: "unknown function", template: `{{upper .Title}}`, wantErr: "invalid webhook template"},
+		{name: "unknown field", template: `{{.Missing}}`, wantErr: "failed to render webhook template"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderWebhook(tt.template, a)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"octopus/internal/conf"
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/utils/log"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Result 一次备份的结果
+type Result struct {
+	Object  Object   `json:"object"`
+	Storage string   `json:"storage"`
+	Deleted []string `json:"deleted,omitempty"` // 轮转删除的旧备份
+}
+
+// Run 按配置生成一份备份，写入存储后重新读取校验，最后按保留策略清理旧备份
+func Run(ctx context.Context, cfg conf.Backup) (*Result, error) {
+	storage, err := NewStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var (
+		data   []byte
+		verify func([]byte) error
+	)
+	switch cfg.Format {
+	case formatDump, "":
+		data, verify, err = createDump(ctx, cfg)
+	case formatSQLite:
+		data, verify, err = createSnapshot(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported backup format: %s", cfg.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := backupName(now, cfg.Format)
+	if err := storage.Put(ctx, name, data); err != nil {
+		return nil, fmt.Errorf("write backup %s: %w", name, err)
+	}
+	readback, err := storage.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("read back backup %s: %w", name, err)
+	}
+	if err := verify(readback); err != nil {
+		// 校验失败的备份不可用，删除以免被轮转当作有效备份保留
+		if delErr := storage.Delete(ctx, name); delErr != nil {
+			log.Warnf("failed to delete invalid backup %s: %v", name, delErr)
+		}
+		return nil, fmt.Errorf("verify backup %s: %w", name, err)
+	}
+
+	res := &Result{
+		Object:  Object{Name: name, Size: int64(len(data)), Time: now},
+		Storage: storage.String(),
+	}
+	objects, err := storage.List(ctx)
+	if err != nil {
+		log.Warnf("failed to list backups for rotation: %v", err)
+		return res, nil
+	}
+	for _, o := range expired(objects, cfg.KeepDaily, cfg.KeepWeekly) {
+		if o.Name == name {
+			continue
+		}
+		if err := storage.Delete(ctx, o.Name); err != nil {
+			log.Warnf("failed to delete expired backup %s: %v", o.Name, err)
+			continue
+		}
+		res.Deleted = append(res.Deleted, o.Name)
+	}
+	return res, nil
+}
+
+// createDump 导出数据库为 gzip 压缩的 JSON，凭据保持加密并附带包装后的数据密钥；校验时重新解析并比对各表行数
+func createDump(ctx context.Context, cfg conf.Backup) ([]byte, func([]byte) error, error) {
+	dump, err := op.DBExportAll(ctx, cfg.IncludeLogs, cfg.IncludeStats, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := json.Marshal(dump)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := gzipBytes(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	want := dumpCounts(dump)
+	verify := func(b []byte) error {
+		got, err := decodeDump(b)
+		if err != nil {
+			return err
+		}
+		for table, n := range dumpCounts(got) {
+			if want[table] != n {
+				return fmt.Errorf("%s: expected %d rows, got %d", table, want[table], n)
+			}
+		}
+		return nil
+	}
+	return data, verify, nil
+}
+
+// createSnapshot 使用 VACUUM INTO 生成一致的 SQLite 快照，压缩前先检查快照完整性
+func createSnapshot(ctx context.Context) ([]byte, func([]byte) error, error) {
+	conn := db.GetDB()
+	if conn.Dialector.Name() != "sqlite" {
+		return nil, nil, fmt.Errorf("backup format %q requires a sqlite database, use %q instead", formatSQLite, formatDump)
+	}
+	dir, err := os.MkdirTemp("", "octopus-backup-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	// VACUUM INTO 要求目标文件不存在
+	path := filepath.Join(dir, "snapshot.db")
+	if err := conn.WithContext(ctx).Exec("VACUUM INTO ?", path).Error; err != nil {
+		return nil, nil, fmt.Errorf("vacuum into snapshot: %w", err)
+	}
+	if err := checkSnapshot(path); err != nil {
+		return nil, nil, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := gzipBytes(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	sum := sha256.Sum256(raw)
+	verify := func(b []byte) error {
+		got, err := gunzipBytes(b)
+		if err != nil {
+			return err
+		}
+		if sha256.Sum256(got) != sum {
+			return fmt.Errorf("snapshot checksum mismatch")
+		}
+		return nil
+	}
+	return data, verify, nil
+}
+
+// checkSnapshot 对快照文件执行 integrity_check
+func checkSnapshot(path string) error {
+	conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	var result string
+	if err := conn.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("snapshot integrity check: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("snapshot integrity check: %s", result)
+	}
+	if !conn.Migrator().HasTable(&model.Channel{}) {
+		return fmt.Errorf("snapshot is not an octopus database")
+	}
+	return nil
+}
+
+// expired 返回超出保留策略的备份：保留最近 keepDaily 天中每天最新的一份，
+// 以及最近 keepWeekly 周中每周最新的一份；两者都不大于 0 时全部保留
+func expired(objects []Object, keepDaily, keepWeekly int) []Object {
+	if keepDaily <= 0 && keepWeekly <= 0 {
+		return nil
+	}
+	sorted := append([]Object(nil), objects...)
+	sortObjects(sorted)
+
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	var result []Object
+	for _, o := range sorted {
+		keep := false
+		day := o.Time.Format(time.DateOnly)
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		year, week := o.Time.ISOWeek()
+		weekKey := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[weekKey] && len(weeks) < keepWeekly {
+			weeks[weekKey] = true
+			keep = true
+		}
+		if !keep {
+			result = append(result, o)
+		}
+	}
+	return result
+}
+
+// List 列出存储中的备份，按时间从新到旧排序
+func List(ctx context.Context, cfg conf.Backup) ([]Object, string, error) {
+	storage, err := NewStorage(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	objects, err := storage.List(ctx)
+	return objects, storage.String(), err
+}
+
+// Load 读取待恢复的备份，source 可以是本地文件路径或存储中的备份名
+func Load(ctx context.Context, cfg conf.Backup, source string) ([]byte, error) {
+	if _, err := os.Stat(source); err == nil {
+		return os.ReadFile(source)
+	}
+	if source != filepath.Base(source) {
+		return nil, fmt.Errorf("backup file %s not found", source)
+	}
+	storage, err := NewStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return storage.Get(ctx, source)
+}
+
+// IsSnapshot 根据文件名判断备份是否为 SQLite 快照
+func IsSnapshot(source string) bool {
+	return strings.HasSuffix(source, extSnapshot)
+}
+
+// DecodeDump 解析 dump 备份并迁移到当前版本，支持 gzip 压缩与 octopus export 生成的未压缩 JSON
+func DecodeDump(data []byte) (*model.DBDump, error) {
+	return decodeDump(data)
+}
+
+// RestoreSnapshot 用快照替换 SQLite 数据库文件，原文件保留为 .bak-<time>；调用前必须停止服务
+func RestoreSnapshot(data []byte, dbPath string) (string, error) {
+	raw, err := gunzipBytes(data)
+	if err != nil {
+		return "", err
+	}
+	tmp := dbPath + ".restore"
+	if err := os.WriteFile(tmp, raw, 0600); err != nil {
+		return "", err
+	}
+	if err := checkSnapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	bak := ""
+	if _, err := os.Stat(dbPath); err == nil {
+		bak = dbPath + ".bak-" + time.Now().Format(nameLayout)
+		if err := os.Rename(dbPath, bak); err != nil {
+			os.Remove(tmp)
+			return "", err
+		}
+	}
+	// 旧库的 WAL 文件不属于新库，必须一并移走
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dbPath + suffix); err != nil {
+			continue
+		}
+		if bak == "" {
+			err = os.Remove(dbPath + suffix)
+		} else {
+			err = os.Rename(dbPath+suffix, bak+suffix)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(tmp, dbPath); err != nil {
+		return "", err
+	}
+	return bak, nil
+}
+
+func decodeDump(data []byte) (*model.DBDump, error) {
+	raw := data
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		var err error
+		if raw, err = gunzipBytes(data); err != nil {
+			return nil, err
+		}
+	}
+	return op.DBDumpDecode(raw)
+}
+
+func dumpCounts(d *model.DBDump) map[string]int {
+	return map[string]int{
+		"channels":      len(d.Channels),
+		"channel_keys":  len(d.ChannelKeys),
+		"groups":        len(d.Groups),
+		"group_items":   len(d.GroupItems),
+		"llm_infos":     len(d.LLMInfos),
+		"api_keys":      len(d.APIKeys),
+		"settings":      len(d.Settings),
+		"model_aliases": len(d.ModelAliases),
+		"stats_total":   len(d.StatsTotal),
+		"stats_daily":   len(d.StatsDaily),
+		"stats_hourly":  len(d.StatsHourly),
+		"stats_model":   len(d.StatsModel),
+		"stats_channel": len(d.StatsChannel),
+		"stats_api_key": len(d.StatsAPIKey),
+		"relay_logs":    len(d.RelayLogs),
+		"data_keys":     len(d.DataKeys),
+	}
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(data []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip data: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"octopus/internal/conf"
+)
+
+// Object 存储中的一份备份
+type Object struct {
+	Name string    `json:"name"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"` // 由文件名解析的备份时间
+}
+
+// Storage 备份存储，本地目录或 S3 兼容的对象存储
+type Storage interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	List(ctx context.Context) ([]Object, error)
+	Delete(ctx context.Context, name string) error
+	String() string
+}
+
+// NewStorage 根据配置创建存储，配置了 s3.bucket 时使用对象存储
+func NewStorage(cfg conf.Backup) (Storage, error) {
+	if cfg.S3.Bucket != "" {
+		return newS3Storage(cfg.S3)
+	}
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("backup dir is not configured")
+	}
+	return &localStorage{dir: cfg.Dir}, nil
+}
+
+type localStorage struct {
+	dir string
+}
+
+func (s *localStorage) String() string {
+	return s.dir
+}
+
+// Put 先写入临时文件再重命名，避免中断时留下不完整的备份
+func (s *localStorage) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+func (s *localStorage) Get(ctx context.Context, name string) ([]byte, error) {
+	if name != filepath.Base(name) {
+		return nil, fmt.Errorf("invalid backup name %q", name)
+	}
+	return os.ReadFile(filepath.Join(s.dir, name))
+}
+
+func (s *localStorage) List(ctx context.Context) ([]Object, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var objects []Object
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t, ok := parseName(e.Name())
+		if !ok {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		objects = append(objects, Object{Name: e.Name(), Size: info.Size(), Time: t})
+	}
+	sortObjects(objects)
+	return objects, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, name string) error {
+	if name != filepath.Base(name) {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+const (
+	namePrefix   = "octopus-"
+	nameLayout   = "20060102-150405"
+	extDump      = ".json.gz"
+	extSnapshot  = ".db.gz"
+	formatDump   = "dump"
+	formatSQLite = "sqlite"
+)
+
+func backupName(t time.Time, format string) string {
+	ext := extDump
+	if format == formatSQLite {
+		ext = extSnapshot
+	}
+	return namePrefix + t.Format(nameLayout) + ext
+}
+
+// parseName 解析备份文件名中的时间，非备份文件返回 false
+func parseName(name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, namePrefix) {
+		return time.Time{}, false
+	}
+	rest := strings.TrimPrefix(name, namePrefix)
+	switch {
+	case strings.HasSuffix(rest, extDump):
+		rest = strings.TrimSuffix(rest, extDump)
+	case strings.HasSuffix(rest, extSnapshot):
+		rest = strings.TrimSuffix(rest, extSnapshot)
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(nameLayout, rest, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// sortObjects 按时间从新到旧排序
+func sortObjects(objects []Object) {
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Time.After(objects[j].Time) })
+}
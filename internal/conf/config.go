@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"octopus/internal/utils/log"
 	"github.com/spf13/viper"
@@ -12,6 +13,8 @@ import (
 type Server struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+	// DrainTimeoutSeconds 停机时等待进行中的中继请求完成的最长时间
+	DrainTimeoutSeconds int `mapstructure:"drain_timeout_seconds"`
 }
 
 type Log struct {
@@ -41,15 +44,117 @@ type RateLimit struct {
 	MaxQueueWaitSeconds   int `mapstructure:"max_queue_wait_seconds"`  // 最大排队时间
 }
 
+type Security struct {
+	MasterKey     string `mapstructure:"master_key"`      // 主密钥(base64/hex 32字节或任意口令)，优先于密钥文件
+	MasterKeyFile string `mapstructure:"master_key_file"` // 主密钥文件，不存在时自动生成
+}
+
+type Backup struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	IntervalHours int      `mapstructure:"interval_hours"` // 备份间隔(小时)
+	Format        string   `mapstructure:"format"`         // dump: gzip 压缩的 JSON 导出; sqlite: VACUUM INTO 快照(仅 sqlite)
+	IncludeStats  bool     `mapstructure:"include_stats"`  // dump 格式是否包含统计数据
+	IncludeLogs   bool     `mapstructure:"include_logs"`   // dump 格式是否包含请求日志
+	Dir           string   `mapstructure:"dir"`            // 本地备份目录，配置 s3.bucket 时不使用
+	KeepDaily     int      `mapstructure:"keep_daily"`     // 保留最近 N 天每天最新的一份
+	KeepWeekly    int      `mapstructure:"keep_weekly"`    // 保留最近 M 周每周最新的一份
+	S3            BackupS3 `mapstructure:"s3"`
+}
+
+type BackupS3 struct {
+	Endpoint  string `mapstructure:"endpoint"` // 例如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	Prefix    string `mapstructure:"prefix"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	PathStyle bool   `mapstructure:"path_style"` // MinIO 等兼容服务通常需要开启
+}
+
+// Cluster 多个实例共享同一个 MySQL/PostgreSQL 数据库时开启
+type Cluster struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	NodeID              string `mapstructure:"node_id"`               // 实例标识，为空时使用主机名，各实例必须不同
+	SyncIntervalSeconds int    `mapstructure:"sync_interval_seconds"` // 同步配置变更、续约主节点租约的间隔(秒)
+	LeaseSeconds        int    `mapstructure:"lease_seconds"`         // 主节点租约时长(秒)，主节点失联超过该时长后由其他实例接管定时任务
+}
+
 type Config struct {
-	Server    Server    `mapstructure:"server"`
-	Log       Log       `mapstructure:"log"`
-	Database  Database  `mapstructure:"database"`
-	AmpCode   AmpCode   `mapstructure:"ampcode"`
-	RateLimit RateLimit `mapstructure:"ratelimit"`
+	Server      Server      `mapstructure:"server"`
+	Log         Log         `mapstructure:"log"`
+	Database    Database    `mapstructure:"database"`
+	AmpCode     AmpCode     `mapstructure:"ampcode"`
+	RateLimit   RateLimit   `mapstructure:"ratelimit"`
+	Security    Security    `mapstructure:"security"`
+	Backup      Backup      `mapstructure:"backup"`
+	Cluster     Cluster     `mapstructure:"cluster"`
+	Record      Record      `mapstructure:"record"`
+	ServerTools ServerTools `mapstructure:"server_tools"`
+	MCP         MCP         `mapstructure:"mcp"`
+	Script      Script      `mapstructure:"script"`
+	Gemini      Gemini      `mapstructure:"gemini"`
+}
+
+// Gemini Gemini 渠道的配置
+type Gemini struct {
+	ContextCache bool `mapstructure:"context_cache"` // 自动为请求中稳定且足够大的前缀创建 cachedContents 并复用
+}
+
+// Record 录制上游的原始请求与响应为夹具文件，用于格式转换的回归测试
+type Record struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Dir          string   `mapstructure:"dir"`            // 夹具目录，每个渠道一个子目录
+	Channels     []string `mapstructure:"channels"`       // 只录制这些渠道(名称)，为空时录制全部渠道
+	MaxBodyBytes int64    `mapstructure:"max_body_bytes"` // 单个响应最多录制的字节数，0 表示不限制
+	MaxFiles     int      `mapstructure:"max_files"`      // 每个渠道最多保留的夹具数，超出时删除最早的，0 表示不限制
 }
 
-var AppConfig Config
+// ServerTools 服务端工具（网页搜索与网页抓取）的配置，分组通过 server_tools 启用
+type ServerTools struct {
+	SearchURL      string `mapstructure:"search_url"`            // SearXNG 兼容的搜索服务地址，需要开启 json 输出格式
+	SearchResults  int    `mapstructure:"search_results"`        // 每次搜索返回给模型的结果数
+	FetchTimeout   int    `mapstructure:"fetch_timeout_seconds"` // 抓取网页的超时时间(秒)
+	FetchMaxBytes  int64  `mapstructure:"fetch_max_bytes"`       // 抓取网页时最多读取的字节数
+	MaxResultChars int    `mapstructure:"max_result_chars"`      // 单次工具结果返回给模型的最大字符数
+	AllowPrivate   bool   `mapstructure:"allow_private"`         // 允许抓取内网、回环等私有地址
+	MaxIterations  int    `mapstructure:"max_iterations"`        // 单个请求最多执行的工具轮数
+}
+
+// MCP 网关连接的 MCP 服务器，分组通过 mcp_servers 按名称启用
+type MCP struct {
+	Servers     []MCPServer `mapstructure:"servers"`
+	CallTimeout int         `mapstructure:"call_timeout_seconds"` // 单次工具调用的超时时间(秒)
+}
+
+// MCPServer 单个 MCP 服务器，stdio 类型启动子进程，http 类型使用 Streamable HTTP 传输
+type MCPServer struct {
+	Name    string            `mapstructure:"name"`
+	Type    string            `mapstructure:"type"` // stdio 或 http
+	Command string            `mapstructure:"command"`
+	Args    []string          `mapstructure:"args"`
+	Env     []string          `mapstructure:"env"` // KEY=VALUE，追加到当前进程的环境变量
+	Dir     string            `mapstructure:"dir"`
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+}
+
+// Script 分组与渠道脚本的执行限制，均需大于 0
+type Script struct {
+	MaxSteps       int64 `mapstructure:"max_steps"`        // 单次调用最多执行的步数
+	TimeoutMs      int   `mapstructure:"timeout_ms"`       // 单次调用的超时时间(毫秒)
+	MaxOutputBytes int64 `mapstructure:"max_output_bytes"` // 单次调用的返回值与 print() 输出各自的最大字节数
+}
+
+// appConfig 当前生效的配置，热重载时整体替换
+var appConfig atomic.Pointer[Config]
+
+// Get 返回当前生效的配置，返回值在热重载后不会变化，调用方不得修改
+func Get() *Config {
+	if c := appConfig.Load(); c != nil {
+		return c
+	}
+	return &Config{}
+}
 
 func Load(path string) error {
 	if path != "" {
@@ -82,15 +187,18 @@ func Load(path string) error {
 		}
 	}
 
-	if err := viper.Unmarshal(&AppConfig); err != nil {
+	var c Config
+	if err := viper.Unmarshal(&c); err != nil {
 		return fmt.Errorf("unable to decode config into struct: %w", err)
 	}
+	appConfig.Store(&c)
 	return nil
 }
 
 func setDefaults() {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.port", 8080)
+	viper.SetDefault("server.drain_timeout_seconds", 30)
 	viper.SetDefault("database.type", "sqlite")
 	viper.SetDefault("database.path", "data/data.db")
 	viper.SetDefault("log.level", "info")
@@ -107,4 +215,51 @@ func setDefaults() {
 	viper.SetDefault("ratelimit.rate_limit_burst", 0)
 	viper.SetDefault("ratelimit.max_queue_size", 1000)
 	viper.SetDefault("ratelimit.max_queue_wait_seconds", 120)
+	// Security defaults
+	viper.SetDefault("security.master_key", "")
+	viper.SetDefault("security.master_key_file", "data/master.key")
+	// Backup defaults
+	viper.SetDefault("backup.enabled", false)
+	viper.SetDefault("backup.interval_hours", 24)
+	viper.SetDefault("backup.format", "dump")
+	viper.SetDefault("backup.include_stats", true)
+	viper.SetDefault("backup.include_logs", false)
+	viper.SetDefault("backup.dir", "data/backups")
+	viper.SetDefault("backup.keep_daily", 7)
+	viper.SetDefault("backup.keep_weekly", 4)
+	viper.SetDefault("backup.s3.endpoint", "")
+	viper.SetDefault("backup.s3.region", "us-east-1")
+	viper.SetDefault("backup.s3.bucket", "")
+	viper.SetDefault("backup.s3.prefix", "")
+	viper.SetDefault("backup.s3.access_key", "")
+	viper.SetDefault("backup.s3.secret_key", "")
+	viper.SetDefault("backup.s3.path_style", true)
+	// Cluster defaults
+	viper.SetDefault("cluster.enabled", false)
+	viper.SetDefault("cluster.node_id", "")
+	viper.SetDefault("cluster.sync_interval_seconds", 5)
+	viper.SetDefault("cluster.lease_seconds", 30)
+	// Record defaults
+	viper.SetDefault("record.enabled", false)
+	viper.SetDefault("record.dir", "data/fixtures")
+	viper.SetDefault("record.channels", []string{})
+	viper.SetDefault("record.max_body_bytes", 4*1024*1024)
+	viper.SetDefault("record.max_files", 200)
+	// ServerTools defaults
+	viper.SetDefault("server_tools.search_url", "")
+	viper.SetDefault("server_tools.search_results", 5)
+	viper.SetDefault("server_tools.fetch_timeout_seconds", 15)
+	viper.SetDefault("server_tools.fetch_max_bytes", 2*1024*1024)
+	viper.SetDefault("server_tools.max_result_chars", 20000)
+	viper.SetDefault("server_tools.allow_private", false)
+	viper.SetDefault("server_tools.max_iterations", 5)
+	// Gemini defaults
+	viper.SetDefault("gemini.context_cache", true)
+	// MCP defaults
+	viper.SetDefault("mcp.servers", []map[string]any{})
+	viper.SetDefault("mcp.call_timeout_seconds", 60)
+	// Script defaults
+	viper.SetDefault("script.max_steps", 1000000)
+	viper.SetDefault("script.timeout_ms", 100)
+	viper.SetDefault("script.max_output_bytes", 8<<20)
 }
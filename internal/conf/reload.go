@@ -0,0 +1,239 @@
+package conf
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"octopus/internal/utils/log"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+	"go.uber.org/zap/zapcore"
+)
+
+// immutableKeys 无法在运行时修改的配置项，前缀匹配
+var immutableKeys = []string{
+	"server.",
+	"database.",
+	"security.",
+	"ampcode.enabled",
+	"backup.enabled",
+	"cluster.",
+}
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(old, cur Config)
+)
+
+// OnReload 注册配置热重载回调，按注册顺序在配置替换后执行
+func OnReload(fn func(old, cur Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Reload 重新读取配置文件，校验通过后替换当前配置并执行回调。
+// 无法在运行时修改的配置项发生变化时返回错误，当前配置保持不变
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	var next Config
+	if err := viper.Unmarshal(&next); err != nil {
+		return fmt.Errorf("unable to decode config into struct: %w", err)
+	}
+	if err := validate(next); err != nil {
+		return err
+	}
+
+	old := Get()
+	changed := changedKeys("", reflect.ValueOf(*old), reflect.ValueOf(next))
+	var rejected []string
+	for _, key := range changed {
+		for _, prefix := range immutableKeys {
+			if strings.HasPrefix(key, prefix) {
+				rejected = append(rejected, key)
+				break
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return fmt.Errorf("%s cannot be changed without a restart", strings.Join(rejected, ", "))
+	}
+	if len(changed) == 0 {
+		log.Infof("config reloaded: no changes")
+		return nil
+	}
+
+	appConfig.Store(&next)
+	for _, fn := range reloadHooks {
+		fn(*old, next)
+	}
+	log.Infof("config reloaded: %s", strings.Join(changed, ", "))
+	return nil
+}
+
+var mcpNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// validate 校验可热重载的配置项
+func validate(c Config) error {
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		return fmt.Errorf("invalid log.level %q", c.Log.Level)
+	}
+	r := c.RateLimit
+	for key, v := range map[string]int{
+		"ratelimit.max_concurrent_requests": r.MaxConcurrentRequests,
+		"ratelimit.fast_max_concurrent":     r.FastMaxConcurrent,
+		"ratelimit.slow_max_concurrent":     r.SlowMaxConcurrent,
+		"ratelimit.migrate_after_seconds":   r.MigrateAfterSeconds,
+		"ratelimit.rate_limit_per_second":   r.RateLimitPerSecond,
+		"ratelimit.rate_limit_burst":        r.RateLimitBurst,
+		"ratelimit.max_queue_size":          r.MaxQueueSize,
+		"ratelimit.max_queue_wait_seconds":  r.MaxQueueWaitSeconds,
+	} {
+		if v < 0 {
+			return fmt.Errorf("%s must not be negative", key)
+		}
+	}
+	if c.AmpCode.UpstreamURL != "" {
+		if u, err := url.Parse(c.AmpCode.UpstreamURL); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid ampcode.upstream_url %q", c.AmpCode.UpstreamURL)
+		}
+	}
+	if c.Backup.Enabled && c.Backup.IntervalHours <= 0 {
+		return fmt.Errorf("backup.interval_hours must be greater than 0")
+	}
+	if c.Record.MaxBodyBytes < 0 || c.Record.MaxFiles < 0 {
+		return fmt.Errorf("record values must not be negative")
+	}
+	t := c.ServerTools
+	if t.SearchURL != "" {
+		if u, err := url.Parse(t.SearchURL); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid server_tools.search_url %q", t.SearchURL)
+		}
+	}
+	if t.SearchResults < 0 || t.FetchTimeout < 0 || t.FetchMaxBytes < 0 || t.MaxResultChars < 0 || t.MaxIterations < 0 {
+		return fmt.Errorf("server_tools values must not be negative")
+	}
+	if c.Script.MaxSteps <= 0 || c.Script.TimeoutMs <= 0 || c.Script.MaxOutputBytes <= 0 {
+		return fmt.Errorf("script.max_steps, script.timeout_ms and script.max_output_bytes must be greater than 0")
+	}
+	return validateMCP(c.MCP)
+}
+
+// validateMCP 校验 MCP 服务器配置，名称会出现在工具名中，只允许字母、数字、下划线与连字符
+func validateMCP(c MCP) error {
+	if c.CallTimeout < 0 {
+		return fmt.Errorf("mcp.call_timeout_seconds must not be negative")
+	}
+	seen := make(map[string]struct{}, len(c.Servers))
+	for _, s := range c.Servers {
+		if !mcpNamePattern.MatchString(s.Name) {
+			return fmt.Errorf("invalid mcp server name %q", s.Name)
+		}
+		if _, ok := seen[s.Name]; ok {
+			return fmt.Errorf("duplicate mcp server %q", s.Name)
+		}
+		seen[s.Name] = struct{}{}
+		switch s.Type {
+		case "stdio":
+			if s.Command == "" {
+				return fmt.Errorf("mcp server %q: command is required", s.Name)
+			}
+		case "http":
+			if u, err := url.Parse(s.URL); err != nil || u.Host == "" {
+				return fmt.Errorf("mcp server %q: invalid url %q", s.Name, s.URL)
+			}
+		default:
+			return fmt.Errorf("mcp server %q: type must be stdio or http", s.Name)
+		}
+	}
+	return nil
+}
+
+// changedKeys 按 mapstructure 标签比较两份配置，返回发生变化的配置项
+func changedKeys(prefix string, a, b reflect.Value) []string {
+	if a.Kind() != reflect.Struct {
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return nil
+		}
+		return []string{strings.TrimSuffix(prefix, ".")}
+	}
+	var keys []string
+	for i := 0; i < a.NumField(); i++ {
+		tag := a.Type().Field(i).Tag.Get("mapstructure")
+		keys = append(keys, changedKeys(prefix+tag+".", a.Field(i), b.Field(i))...)
+	}
+	return keys
+}
+
+// Watch 监听配置文件变化与 SIGHUP 并热重载配置，重载失败时保留当前配置
+func Watch() error {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return fmt.Errorf("no config file to watch")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// 监听所在目录，编辑器通过重命名保存文件时仍能收到事件
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		// 保存文件通常会触发多次写事件，合并后再重载
+		debounce := time.NewTimer(time.Hour)
+		debounce.Stop()
+		reload := func(reason string) {
+			if err := Reload(); err != nil {
+				log.Warnf("config reload (%s) rejected: %v", reason, err)
+			}
+		}
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) == path && ev.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					debounce.Reset(500 * time.Millisecond)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("config watcher error: %v", err)
+			case <-debounce.C:
+				reload("file changed")
+			case <-hup:
+				reload("SIGHUP")
+			}
+		}
+	}()
+	log.Infof("watching config file %s for changes", path)
+	return nil
+}
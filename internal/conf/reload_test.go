@@ -0,0 +1,97 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// writeConfig 写入配置文件并加载为当前配置
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReload(t *testing.T) {
+	const base = `{"log":{"level":"info"},"server":{"port":8080}}`
+	tests := []struct {
+		name     string
+		next     string
+		wantErr  string
+		expected string // 重载后的 log.level
+	}{
+		{name: "no changes", next: base, expected: "info"},
+		{name: "mutable key", next: `{"log":{"level":"debug"},"server":{"port":8080}}`, expected: "debug"},
+		{name: "immutable key", next: `{"log":{"level":"debug"},"server":{"port":9090}}`, wantErr: "server.port cannot be changed", expected: "info"},
+		{name: "invalid value", next: `{"log":{"level":"loud"},"server":{"port":8080}}`, wantErr: "invalid log.level", expected: "info"},
+		{name: "negative record cap", next: `{"log":{"level":"debug"},"server":{"port":8080},"record":{"max_files":-1}}`, wantErr: "record values must not be negative", expected: "info"},
+		{name: "zero script timeout", next: `{"log":{"level":"debug"},"server":{"port":8080},"script":{"timeout_ms":0}}`, wantErr: "script.timeout_ms", expected: "info"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfig(t, base)
+			prev := Get()
+			if err := os.WriteFile(path, []byte(tt.next), 0600); err != nil {
+				t.Fatal(err)
+			}
+			err := Reload()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if got := Get().Log.Level; got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			// 重载替换整个配置，之前取得的快照保持不变
+			if prev.Log.Level != "info" {
+				t.Fatalf("expected previous snapshot to stay %q, got %q", "info", prev.Log.Level)
+			}
+		})
+	}
+}
+
+// TestReloadConcurrentReads 在 -race 下检查重载与读取之间没有数据竞争
+func TestReloadConcurrentReads(t *testing.T) {
+	path := writeConfig(t, `{"log":{"level":"info"}}`)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					_ = Get().Log.Level
+				}
+			}
+		}()
+	}
+	for i, level := range []string{"debug", "warn", "info", "error"} {
+		if err := os.WriteFile(path, []byte(`{"log":{"level":"`+level+`"}}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := Reload(); err != nil {
+			t.Fatalf("reload %d: %v", i, err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if got := Get().Log.Level; got != "error" {
+		t.Fatalf("expected %v, got %v", "error", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"octopus/internal/db/migrate"
 	"octopus/internal/model"
+	"octopus/internal/secret"
 	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -67,10 +68,22 @@ func InitDB(dbType, dsn string, debug bool) error {
 		&model.StatsChannel{},
 		&model.StatsAPIKey{},
 		&model.RelayLog{},
+		&model.DataKey{},
+		&model.AuditLog{},
+		&model.AlertNotifier{},
+		&model.AlertRule{},
+		&model.ModelSyncPending{},
+		&model.ModelAlias{},
+		&model.CacheVersion{},
+		&model.ClusterLease{},
 		&migrate.MigrationRecord{},
 	); err != nil {
 		return err
 	}
+	// 数据密钥需要在 AfterAutoMigrate 之前加载，迁移中会加密历史明文凭据
+	if err := secret.LoadDataKeys(db); err != nil {
+		return err
+	}
 	if err := migrate.AfterAutoMigrate(db); err != nil {
 		return err
 	}
@@ -0,0 +1,61 @@
+package migrate
+
+import (
+	"fmt"
+
+	"octopus/internal/secret"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterAfterAutoMigration(Migration{
+		Version: 3,
+		Up:      encryptChannelCredentials,
+	})
+}
+
+// 003: encrypt legacy plaintext channel_keys.channel_key and channels.channel_proxy.
+// Only values that authenticate with the keyring are kept as they are; plaintext that merely
+// starts with the ciphertext prefix is encrypted too, otherwise it would fail to decrypt on read.
+func encryptChannelCredentials(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	if !secret.Ready() {
+		return secret.ErrNotInitialized
+	}
+
+	type row struct {
+		ID    int     `gorm:"column:id"`
+		Value *string `gorm:"column:value"`
+	}
+
+	encryptColumn := func(tx *gorm.DB, table, column string) error {
+		rows := make([]row, 0)
+		if err := tx.Raw(fmt.Sprintf("SELECT id, %s AS value FROM %s WHERE %s IS NOT NULL AND %s != ''", column, table, column, column)).
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", table, column, err)
+		}
+		for _, r := range rows {
+			if r.Value == nil || secret.IsEncrypted(*r.Value) {
+				continue
+			}
+			encrypted, err := secret.Encrypt(*r.Value)
+			if err != nil {
+				return fmt.Errorf("failed to encrypt %s.%s for id=%d: %w", table, column, r.ID, err)
+			}
+			if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", table, column), encrypted, r.ID).Error; err != nil {
+				return fmt.Errorf("failed to update %s.%s for id=%d: %w", table, column, r.ID, err)
+			}
+		}
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := encryptColumn(tx, "channel_keys", "channel_key"); err != nil {
+			return err
+		}
+		return encryptColumn(tx, "channels", "channel_proxy")
+	})
+}
@@ -0,0 +1,91 @@
+package migrate
+
+import (
+	"fmt"
+
+	"octopus/internal/model"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	RegisterAfterAutoMigration(Migration{
+		Version: 4,
+		Up:      hashAPIKeys,
+	})
+}
+
+// 004: hash legacy plaintext api_keys.api_key into key_prefix/key_hash and drop api_keys.api_key
+func hashAPIKeys(db *gorm.DB) error {
+	if db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	dialect := db.Dialector.Name()
+
+	// column existence helper
+	hasColumn := func(table, column string) bool {
+		switch dialect {
+		case "sqlite":
+			var name string
+			db.Raw("SELECT name FROM pragma_table_info(?) WHERE name = ? LIMIT 1", table, column).Scan(&name)
+			return name == column
+		case "mysql":
+			var count int64
+			db.Raw("SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, column).Scan(&count)
+			return count > 0
+		case "postgres":
+			var count int64
+			db.Raw("SELECT COUNT(*) FROM information_schema.columns WHERE table_name = ? AND column_name = ?", table, column).Scan(&count)
+			return count > 0
+		default:
+			return db.Migrator().HasColumn(table, column)
+		}
+	}
+
+	// fresh DB: api_key column never existed
+	if !hasColumn("api_keys", "api_key") {
+		return nil
+	}
+
+	type row struct {
+		ID     int    `gorm:"column:id"`
+		APIKey string `gorm:"column:api_key"`
+	}
+	rows := make([]row, 0)
+	if err := db.Raw(`
+SELECT id, api_key
+FROM api_keys
+WHERE api_key IS NOT NULL AND api_key != '' AND (key_hash IS NULL OR key_hash = '')
+`).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to read api_keys.api_key: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range rows {
+			hash, err := model.HashAPIKey(r.APIKey)
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("UPDATE api_keys SET key_prefix = ?, key_hash = ? WHERE id = ?",
+				model.APIKeyPrefix(r.APIKey), hash, r.ID).Error; err != nil {
+				return fmt.Errorf("failed to update api_keys.key_hash for id=%d: %w", r.ID, err)
+			}
+		}
+
+		var sql string
+		switch dialect {
+		case "mysql":
+			sql = "ALTER TABLE `api_keys` DROP COLUMN `api_key`"
+		case "postgres":
+			sql = "ALTER TABLE api_keys DROP COLUMN IF EXISTS api_key"
+		default:
+			// SQLite 3.35.0+
+			sql = "ALTER TABLE api_keys DROP COLUMN api_key"
+		}
+		if err := tx.Exec(sql).Error; err != nil {
+			return fmt.Errorf("failed to drop api_keys.api_key: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+var savepointSeq atomic.Uint64
+
+// WithTx 返回携带事务的 ctx，之后通过 Conn(ctx) 与 Begin(ctx) 获取的连接都在该事务中执行
+func WithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// Conn 返回 ctx 对应的数据库连接，ctx 中携带事务时使用该事务
+func Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// Tx 由 Begin 开启的事务。ctx 中已经携带事务时 Tx 对应其中的一个保存点，
+// Commit 不做任何事，Rollback 只回滚到该保存点，最终由外层事务提交或回滚
+type Tx struct {
+	*gorm.DB
+	savepoint string
+}
+
+// Begin 开启事务，ctx 中已经携带事务时改为创建保存点
+func Begin(ctx context.Context) *Tx {
+	outer, ok := ctx.Value(txKey{}).(*gorm.DB)
+	if !ok {
+		return &Tx{DB: db.WithContext(ctx).Begin()}
+	}
+	name := fmt.Sprintf("sp_%d", savepointSeq.Add(1))
+	tx := outer.WithContext(ctx)
+	if err := tx.SavePoint(name).Error; err != nil {
+		tx.AddError(err)
+	}
+	return &Tx{DB: tx, savepoint: name}
+}
+
+func (t *Tx) Commit() *gorm.DB {
+	if t.savepoint == "" {
+		return t.DB.Commit()
+	}
+	return t.DB
+}
+
+func (t *Tx) Rollback() *gorm.DB {
+	if t.savepoint == "" {
+		return t.DB.Rollback()
+	}
+	return t.DB.RollbackTo(t.savepoint)
+}
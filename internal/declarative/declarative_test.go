@@ -0,0 +1,154 @@
+package declarative
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/secret"
+)
+
+// setupTestDB 在临时目录中初始化 sqlite 数据库、密钥与缓存，测试结束后关闭
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	if err := secret.Init("test passphrase", ""); err != nil {
+		t.Fatalf("failed to init secret: %v", err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(t.TempDir(), "test.db"), false); err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := op.InitCache(); err != nil {
+		t.Fatalf("failed to init cache: %v", err)
+	}
+}
+
+const testSpec = `
+channels:
+  - name: openai
+    type: openai_chat
+    base_urls: [https://api.openai.com/v1]
+    keys: [sk-test-key-0001]
+    models: [gpt-4o]
+groups:
+  - name: gpt
+    mode: failover
+    items:
+      - {channel: openai, model: gpt-4o}
+aliases:
+  - {pattern: gpt-latest, group: gpt}
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "valid", input: testSpec},
+		{name: "unknown field", input: "channel: []", wantErr: "field channel not found"},
+		{name: "missing base urls", input: "channels:\n  - {name: a, type: openai_chat}", wantErr: "base_urls is required"},
+		{name: "invalid group mode", input: "groups:\n  - {name: g, mode: fastest}", wantErr: "invalid mode"},
+		{name: "alias without group", input: "aliases:\n  - {pattern: x}", wantErr: "group is required"},
+		{name: "missing env", input: "api_keys:\n  - {name: k, key: '${OCTOPUS_TEST_UNSET_ENV}'}", wantErr: "OCTOPUS_TEST_UNSET_ENV"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	spec, err := Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Build(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 3 || plan.Destructive() != 0 {
+		t.Fatalf("expected 3 creates, got %+v", plan.Changes)
+	}
+	if err := plan.Apply(ctx, io.Discard); err != nil {
+		t.Fatalf("failed to apply: %v", err)
+	}
+	if group, alias, err := op.ModelAliasResolve("gpt-latest", ctx); err != nil || alias == nil || group.Name != "gpt" || len(group.Items) != 1 {
+		t.Fatalf("expected alias to resolve to group gpt with 1 item, got %+v, %v", group, err)
+	}
+
+	// 再次生成计划时没有变化
+	if plan, err = Build(ctx, spec); err != nil || !plan.Empty() {
+		t.Fatalf("expected an empty plan, got %+v, %v", plan, err)
+	}
+
+	// 删除计划中的渠道与分组删除通过保存点在同一事务中执行
+	empty, err := Parse([]byte("channels: []\ngroups: []\naliases: []"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = Build(ctx, empty); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Destructive() != 3 {
+		t.Fatalf("expected 3 deletes, got %+v", plan.Changes)
+	}
+	if err := plan.Apply(ctx, io.Discard); err != nil {
+		t.Fatalf("failed to apply deletes: %v", err)
+	}
+	channels, _ := op.ChannelList(ctx)
+	groups, _ := op.GroupList(ctx)
+	if len(channels) != 0 || len(groups) != 0 {
+		t.Errorf("expected everything to be deleted, got %d channels and %d groups", len(channels), len(groups))
+	}
+}
+
+func TestPlanApplyRollback(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	spec, err := Parse([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := Build(ctx, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: "setting", Name: "broken",
+		apply: func(ctx context.Context) (string, error) { return "", errors.New("boom") }})
+	if err := plan.Apply(ctx, io.Discard); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the failing change to abort the apply, got %v", err)
+	}
+
+	var count int64
+	db.GetDB().Model(&model.Channel{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected channel creation to be rolled back, got %d channels", count)
+	}
+	channels, _ := op.ChannelList(ctx)
+	groups, _ := op.GroupList(ctx)
+	aliases, _ := op.ModelAliasList(ctx)
+	if len(channels) != 0 || len(groups) != 0 || len(aliases) != 0 {
+		t.Errorf("expected caches to be reloaded after rollback, got %d channels, %d groups, %d aliases", len(channels), len(groups), len(aliases))
+	}
+}
@@ -0,0 +1,497 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/utils/diff"
+
+	"gorm.io/gorm"
+)
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change 计划中的单个变更
+type Change struct {
+	Action Action        `json:"action"`
+	Kind   string        `json:"kind"` // channel、group、alias、api_key、setting
+	Name   string        `json:"name"`
+	Fields []diff.Change `json:"fields,omitempty"`
+
+	// apply 执行变更，返回需要提示给用户的信息（例如新生成的 API Key）
+	apply func(ctx context.Context) (string, error)
+}
+
+// Plan 当前数据库收敛到配置文件所需的变更，按执行顺序排列
+type Plan struct {
+	Changes []Change `json:"changes"`
+}
+
+// Build 比较配置文件与当前缓存中的数据生成计划，调用前需要完成 op.InitCache
+func Build(ctx context.Context, spec *Spec) (*Plan, error) {
+	s := &state{}
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.checkReferences(spec); err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	steps := []func(*Spec, *Plan) error{
+		s.planChannels,
+		s.planGroups,
+		s.planAliases,
+		s.planDeletes,
+		s.planAPIKeys,
+		s.planSettings,
+	}
+	for _, step := range steps {
+		if err := step(spec, plan); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// managedCaches 计划可能修改的缓存，包括新建渠道时写入的模型价格
+var managedCaches = []string{"setting", "channel", "group", "model_alias", "api_key", "llm"}
+
+// Destructive 计划中需要删除的资源数量
+func (p *Plan) Destructive() int {
+	n := 0
+	for _, c := range p.Changes {
+		if c.Action == ActionDelete {
+			n++
+		}
+	}
+	return n
+}
+
+// Apply 在同一个事务中按顺序执行计划，任一变更失败时全部回滚。
+// 执行过程中修改过的内存缓存在事务结束后从数据库重新加载
+func (p *Plan) Apply(ctx context.Context, w io.Writer) error {
+	notes := make([]string, len(p.Changes))
+	err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		txCtx := db.WithTx(ctx, tx)
+		for i, c := range p.Changes {
+			note, err := c.apply(txCtx)
+			if err != nil {
+				return fmt.Errorf("failed to %s %s %q: %w", c.Action, c.Kind, c.Name, err)
+			}
+			notes[i] = note
+		}
+		return nil
+	})
+	if reloadErr := op.CacheReload(ctx, managedCaches); reloadErr != nil && err == nil {
+		err = fmt.Errorf("changes were committed but caches failed to reload: %w", reloadErr)
+	}
+	if err != nil {
+		return err
+	}
+	for i, c := range p.Changes {
+		fmt.Fprintf(w, "%s %s %q: done\n", c.Action, c.Kind, c.Name)
+		if notes[i] != "" {
+			fmt.Fprintf(w, "  %s\n", notes[i])
+		}
+	}
+	return nil
+}
+
+// Print 以类似 diff 的格式输出计划
+func (p *Plan) Print(w io.Writer) {
+	if p.Empty() {
+		fmt.Fprintln(w, "No changes. The database matches the config.")
+		return
+	}
+	counts := map[Action]int{}
+	for _, c := range p.Changes {
+		counts[c.Action]++
+		switch c.Action {
+		case ActionCreate:
+			fmt.Fprintf(w, "+ %s %q\n", c.Kind, c.Name)
+			for _, f := range c.Fields {
+				fmt.Fprintf(w, "    %s: %s\n", f.Field, formatValue(f.After))
+			}
+		case ActionUpdate:
+			fmt.Fprintf(w, "~ %s %q\n", c.Kind, c.Name)
+			for _, f := range c.Fields {
+				fmt.Fprintf(w, "    %s: %s -> %s\n", f.Field, formatValue(f.Before), formatValue(f.After))
+			}
+		case ActionDelete:
+			fmt.Fprintf(w, "- %s %q\n", c.Kind, c.Name)
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d to delete.\n",
+		counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+}
+
+func formatValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// state 生成计划时的数据库快照
+type state struct {
+	channels map[string]model.Channel
+	groups   map[string]model.Group
+	aliases  []model.ModelAlias
+	apiKeys  []model.APIKey
+	// channelNames 与 groupNames 用于将 ID 转换为名称展示
+	channelNames map[int]string
+	groupNames   map[int]string
+}
+
+func (s *state) load(ctx context.Context) error {
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		return err
+	}
+	s.channels = make(map[string]model.Channel, len(channels))
+	s.channelNames = make(map[int]string, len(channels))
+	for _, c := range channels {
+		s.channels[c.Name] = c
+		s.channelNames[c.ID] = c.Name
+	}
+
+	groups, err := op.GroupList(ctx)
+	if err != nil {
+		return err
+	}
+	s.groups = make(map[string]model.Group, len(groups))
+	s.groupNames = make(map[int]string, len(groups))
+	for _, g := range groups {
+		s.groups[g.Name] = g
+		s.groupNames[g.ID] = g.Name
+	}
+
+	if s.aliases, err = op.ModelAliasList(ctx); err != nil {
+		return err
+	}
+	if s.apiKeys, err = op.APIKeyList(ctx); err != nil {
+		return err
+	}
+	sort.Slice(s.apiKeys, func(i, j int) bool { return s.apiKeys[i].ID < s.apiKeys[j].ID })
+	return nil
+}
+
+// checkReferences 确认分组引用的渠道与别名引用的分组在应用后存在
+func (s *state) checkReferences(spec *Spec) error {
+	channelExists := func(name string) bool {
+		if spec.Channels == nil {
+			_, ok := s.channels[name]
+			return ok
+		}
+		for _, c := range *spec.Channels {
+			if c.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	groupExists := func(name string) bool {
+		if spec.Groups == nil {
+			_, ok := s.groups[name]
+			return ok
+		}
+		for _, g := range *spec.Groups {
+			if g.Name == name {
+				return true
+			}
+		}
+		return false
+	}
+	if spec.Groups != nil {
+		for _, g := range *spec.Groups {
+			for _, item := range g.Items {
+				if !channelExists(item.Channel) {
+					return fmt.Errorf("group %q: channel %q not found", g.Name, item.Channel)
+				}
+			}
+		}
+	}
+	if spec.Aliases != nil {
+		for _, a := range *spec.Aliases {
+			if !groupExists(a.Group) {
+				return fmt.Errorf("alias %q: group %q not found", a.Pattern, a.Group)
+			}
+		}
+	}
+	if spec.APIKeys != nil {
+		names := make(map[string]int)
+		for _, k := range s.apiKeys {
+			names[k.Name]++
+		}
+		for _, k := range *spec.APIKeys {
+			if names[k.Name] > 1 {
+				return fmt.Errorf("api key %q: multiple keys with this name exist, rename or delete them first", k.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *state) planChannels(spec *Spec, plan *Plan) error {
+	if spec.Channels == nil {
+		return nil
+	}
+	for _, cs := range *spec.Channels {
+		cs := cs
+		current, ok := s.channels[cs.Name]
+		if !ok {
+			desired := desiredChannel(cs, nil)
+			fields, err := diff.Fields(nil, viewChannel(&desired))
+			if err != nil {
+				return err
+			}
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: "channel", Name: cs.Name, Fields: fields,
+				apply: func(ctx context.Context) (string, error) { return "", applyChannel(ctx, cs) }})
+			continue
+		}
+		desired := desiredChannel(cs, &current)
+		if _, changed := channelUpdateRequest(&current, &desired); !changed {
+			continue
+		}
+		fields, err := diff.Fields(viewChannel(&current), viewChannel(&desired))
+		if err != nil {
+			return err
+		}
+		plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: "channel", Name: cs.Name, Fields: fields,
+			apply: func(ctx context.Context) (string, error) { return "", applyChannel(ctx, cs) }})
+	}
+	return nil
+}
+
+func (s *state) planGroups(spec *Spec, plan *Plan) error {
+	if spec.Groups == nil {
+		return nil
+	}
+	for _, gs := range *spec.Groups {
+		gs := gs
+		after := viewGroupSpec(gs)
+		current, ok := s.groups[gs.Name]
+		action := ActionCreate
+		var before any
+		if ok {
+			action = ActionUpdate
+			before = s.viewGroup(&current)
+		}
+		fields, err := diff.Fields(before, after)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: action, Kind: "group", Name: gs.Name, Fields: fields,
+			apply: func(ctx context.Context) (string, error) { return "", applyGroup(ctx, gs) }})
+	}
+	return nil
+}
+
+func (s *state) planAliases(spec *Spec, plan *Plan) error {
+	if spec.Aliases == nil {
+		return nil
+	}
+	current := make(map[string]model.ModelAlias, len(s.aliases))
+	for _, a := range s.aliases {
+		id := aliasID(string(a.MatchType), a.Pattern)
+		if _, ok := current[id]; !ok {
+			current[id] = a
+		}
+	}
+	for _, as := range *spec.Aliases {
+		as := as
+		after := viewAliasSpec(as)
+		existing, ok := current[aliasID(as.MatchType, as.Pattern)]
+		action := ActionCreate
+		var before any
+		if ok {
+			action = ActionUpdate
+			before = viewAlias(&existing, s.groupNames[existing.GroupID])
+		}
+		fields, err := diff.Fields(before, after)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: action, Kind: "alias", Name: aliasID(as.MatchType, as.Pattern), Fields: fields,
+			apply: func(ctx context.Context) (string, error) { return "", applyAlias(ctx, as, existing.ID) }})
+	}
+	return nil
+}
+
+// planDeletes 按依赖倒序删除配置文件中不存在的别名、分组与渠道
+func (s *state) planDeletes(spec *Spec, plan *Plan) error {
+	if spec.Aliases != nil {
+		keep := make(map[string]struct{}, len(*spec.Aliases))
+		for _, a := range *spec.Aliases {
+			keep[aliasID(a.MatchType, a.Pattern)] = struct{}{}
+		}
+		seen := make(map[string]struct{})
+		for _, a := range s.aliases {
+			id := aliasID(string(a.MatchType), a.Pattern)
+			_, keepIt := keep[id]
+			_, dup := seen[id]
+			seen[id] = struct{}{}
+			// 同一规则的重复别名只保留第一条
+			if keepIt && !dup {
+				continue
+			}
+			aliasID := a.ID
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: "alias", Name: id,
+				apply: func(ctx context.Context) (string, error) { return "", op.ModelAliasDelete(aliasID, ctx) }})
+		}
+	}
+	if spec.Groups != nil {
+		keep := make(map[string]struct{}, len(*spec.Groups))
+		for _, g := range *spec.Groups {
+			keep[g.Name] = struct{}{}
+		}
+		for _, name := range sortedKeys(s.groups) {
+			if _, ok := keep[name]; ok {
+				continue
+			}
+			groupID := s.groups[name].ID
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: "group", Name: name,
+				apply: func(ctx context.Context) (string, error) { return "", op.GroupDel(groupID, ctx) }})
+		}
+	}
+	if spec.Channels != nil {
+		keep := make(map[string]struct{}, len(*spec.Channels))
+		for _, c := range *spec.Channels {
+			keep[c.Name] = struct{}{}
+		}
+		for _, name := range sortedKeys(s.channels) {
+			if _, ok := keep[name]; ok {
+				continue
+			}
+			channelID := s.channels[name].ID
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: "channel", Name: name,
+				apply: func(ctx context.Context) (string, error) { return "", op.ChannelDel(channelID, ctx) }})
+		}
+	}
+	return nil
+}
+
+func (s *state) planAPIKeys(spec *Spec, plan *Plan) error {
+	if spec.APIKeys == nil {
+		return nil
+	}
+	keep := make(map[string]struct{}, len(*spec.APIKeys))
+	for _, ks := range *spec.APIKeys {
+		ks := ks
+		keep[ks.Name] = struct{}{}
+		var existing *model.APIKey
+		for i := range s.apiKeys {
+			if s.apiKeys[i].Name == ks.Name {
+				existing = &s.apiKeys[i]
+				break
+			}
+		}
+		after := viewAPIKeySpec(ks)
+		action := ActionCreate
+		var before any
+		if existing != nil {
+			action = ActionUpdate
+			view := viewAPIKey(existing)
+			// 未指定 key 或 key 未变化时不展示
+			if ks.Key == "" || existing.Verify(ks.Key) {
+				view.Key = after.Key
+			}
+			before = view
+		}
+		fields, err := diff.Fields(before, after)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: action, Kind: "api_key", Name: ks.Name, Fields: fields,
+			apply: func(ctx context.Context) (string, error) { return applyAPIKey(ctx, ks) }})
+	}
+	for _, k := range s.apiKeys {
+		if _, ok := keep[k.Name]; ok {
+			continue
+		}
+		keyID := k.ID
+		plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: "api_key", Name: k.Name,
+			apply: func(ctx context.Context) (string, error) { return "", op.APIKeyDelete(keyID, ctx) }})
+	}
+	return nil
+}
+
+func (s *state) planSettings(spec *Spec, plan *Plan) error {
+	for _, key := range sortedKeys(spec.Settings) {
+		value := spec.Settings[key]
+		settingKey := model.SettingKey(key)
+		current, err := op.SettingGetString(settingKey)
+		if err != nil {
+			return fmt.Errorf("unknown setting %q", key)
+		}
+		if current == value {
+			continue
+		}
+		plan.Changes = append(plan.Changes, Change{Action: ActionUpdate, Kind: "setting", Name: key,
+			Fields: []diff.Change{{Field: "value", Before: current, After: value}},
+			apply: func(ctx context.Context) (string, error) {
+				return "", op.SettingSetString(settingKey, value, ctx)
+			}})
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// maskSecret 仅保留首尾少量字符用于识别
+func maskSecret(s string) string {
+	if len(s) <= 12 {
+		return "****"
+	}
+	return s[:6] + "..." + s[len(s)-4:]
+}
+
+func formatExpireAt(ts int64) string {
+	if ts == 0 {
+		return ""
+	}
+	return time.Unix(ts, 0).Format(time.RFC3339)
+}
+
+func joinModels(models []string) string {
+	return strings.Join(models, ",")
+}
@@ -0,0 +1,607 @@
+package declarative
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"octopus/internal/helper"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/server/auth"
+	"octopus/internal/utils/log"
+	"octopus/internal/utils/xstrings"
+)
+
+// channelView 用于展示渠道差异，密钥与请求头只展示掩码
+type channelView struct {
+	Type          string                   `json:"type"`
+	Enabled       bool                     `json:"enabled"`
+	BaseURLs      []string                 `json:"base_urls"`
+	Keys          []string                 `json:"keys"`
+	Models        string                   `json:"models"`
+	CustomModels  string                   `json:"custom_models"`
+	Proxy         bool                     `json:"proxy"`
+	ChannelProxy  string                   `json:"channel_proxy"`
+	AutoSync      bool                     `json:"auto_sync"`
+	AutoGroup     string                   `json:"auto_group"`
+	CustomHeaders map[string]string        `json:"custom_headers"`
+	ParamOverride string                   `json:"param_override"`
+	PromptCache   *model.PromptCachePolicy `json:"prompt_cache"`
+	Script        string                   `json:"script"`
+}
+
+func viewChannel(c *model.Channel) channelView {
+	v := channelView{
+		Type:          model.ChannelTypeName(c.Type),
+		Enabled:       c.Enabled,
+		Models:        c.Model,
+		CustomModels:  c.CustomModel,
+		Proxy:         c.Proxy,
+		AutoSync:      c.AutoSync,
+		CustomHeaders: map[string]string{},
+		ParamOverride: derefString(c.ParamOverride),
+		PromptCache:   activePromptCache(c.PromptCache),
+		Script:        c.Script,
+	}
+	for _, u := range c.BaseUrls {
+		v.BaseURLs = append(v.BaseURLs, u.URL)
+	}
+	for _, k := range c.Keys {
+		key := maskSecret(k.ChannelKey)
+		if !k.Enabled {
+			key += " (disabled)"
+		}
+		v.Keys = append(v.Keys, key)
+	}
+	sort.Strings(v.Keys)
+	if p := derefString(c.ChannelProxy); p != "" {
+		v.ChannelProxy = maskSecret(p)
+	}
+	for name, t := range autoGroupNames {
+		if name != "" && t == c.AutoGroup {
+			v.AutoGroup = name
+		}
+	}
+	for _, h := range c.CustomHeader {
+		v.CustomHeaders[h.HeaderKey] = maskSecret(h.HeaderValue)
+	}
+	return v
+}
+
+// desiredChannel 根据配置生成渠道的目标状态，current 不为空时保留测得的地址延迟与未管理的模型列表
+func desiredChannel(s ChannelSpec, current *model.Channel) model.Channel {
+	channelType, _ := model.ParseChannelType(s.Type)
+	c := model.Channel{
+		Name:        s.Name,
+		Type:        channelType,
+		Enabled:     boolOr(s.Enabled, true),
+		Model:       joinModels(s.Models),
+		CustomModel: joinModels(s.CustomModels),
+		Proxy:       s.Proxy,
+		AutoSync:    s.AutoSync,
+		AutoGroup:   autoGroupNames[s.AutoGroup],
+		PromptCache: activePromptCache(s.PromptCache),
+		Script:      s.Script,
+	}
+	delays := map[string]int{}
+	if current != nil {
+		c.ID = current.ID
+		for _, u := range current.BaseUrls {
+			delays[u.URL] = u.Delay
+		}
+		if s.Models == nil {
+			c.Model = current.Model
+		}
+	}
+	for _, u := range s.BaseURLs {
+		c.BaseUrls = append(c.BaseUrls, model.BaseUrl{URL: u, Delay: delays[u]})
+	}
+	for _, k := range s.Keys {
+		c.Keys = append(c.Keys, model.ChannelKey{ChannelKey: k.Key, Enabled: boolOr(k.Enabled, true)})
+	}
+	for _, name := range sortedKeys(s.CustomHeaders) {
+		c.CustomHeader = append(c.CustomHeader, model.CustomHeader{HeaderKey: name, HeaderValue: s.CustomHeaders[name]})
+	}
+	if s.ChannelProxy != "" {
+		c.ChannelProxy = &s.ChannelProxy
+	}
+	if s.ParamOverride != "" {
+		c.ParamOverride = &s.ParamOverride
+	}
+	return c
+}
+
+// channelUpdateRequest 生成将 current 更新为 desired 所需的请求，密钥按明文匹配
+func channelUpdateRequest(current, desired *model.Channel) (*model.ChannelUpdateRequest, bool) {
+	req := &model.ChannelUpdateRequest{ID: current.ID}
+	changed := false
+	if current.Type != desired.Type {
+		req.Type, changed = &desired.Type, true
+	}
+	if current.Enabled != desired.Enabled {
+		req.Enabled, changed = &desired.Enabled, true
+	}
+	if !sameBaseURLs(current.BaseUrls, desired.BaseUrls) {
+		req.BaseUrls, changed = &desired.BaseUrls, true
+	}
+	if strings.Join(xstrings.SplitTrimCompact(",", current.Model), ",") != desired.Model {
+		req.Model, changed = &desired.Model, true
+	}
+	if strings.Join(xstrings.SplitTrimCompact(",", current.CustomModel), ",") != desired.CustomModel {
+		req.CustomModel, changed = &desired.CustomModel, true
+	}
+	if current.Proxy != desired.Proxy {
+		req.Proxy, changed = &desired.Proxy, true
+	}
+	if current.AutoSync != desired.AutoSync {
+		req.AutoSync, changed = &desired.AutoSync, true
+	}
+	if current.AutoGroup != desired.AutoGroup {
+		req.AutoGroup, changed = &desired.AutoGroup, true
+	}
+	if !sameHeaders(current.CustomHeader, desired.CustomHeader) {
+		headers := desired.CustomHeader
+		if headers == nil {
+			headers = []model.CustomHeader{}
+		}
+		req.CustomHeader, changed = &headers, true
+	}
+	if derefString(current.ChannelProxy) != derefString(desired.ChannelProxy) {
+		proxy := derefString(desired.ChannelProxy)
+		req.ChannelProxy, changed = &proxy, true
+	}
+	if derefString(current.ParamOverride) != derefString(desired.ParamOverride) {
+		override := derefString(desired.ParamOverride)
+		req.ParamOverride, changed = &override, true
+	}
+	if !samePromptCache(current.PromptCache, desired.PromptCache) {
+		req.PromptCache, changed = promptCacheUpdate(desired.PromptCache), true
+	}
+	if current.Script != desired.Script {
+		req.Script, changed = &desired.Script, true
+	}
+
+	existing := make(map[string]model.ChannelKey, len(current.Keys))
+	for _, k := range current.Keys {
+		if _, ok := existing[k.ChannelKey]; ok {
+			req.KeysToDelete = append(req.KeysToDelete, k.ID)
+			continue
+		}
+		existing[k.ChannelKey] = k
+	}
+	for _, k := range desired.Keys {
+		old, ok := existing[k.ChannelKey]
+		if !ok {
+			req.KeysToAdd = append(req.KeysToAdd, model.ChannelKeyAddRequest{Enabled: k.Enabled, ChannelKey: k.ChannelKey})
+			continue
+		}
+		delete(existing, k.ChannelKey)
+		if old.Enabled != k.Enabled {
+			enabled := k.Enabled
+			req.KeysToUpdate = append(req.KeysToUpdate, model.ChannelKeyUpdateRequest{ID: old.ID, Enabled: &enabled})
+		}
+	}
+	for _, k := range existing {
+		req.KeysToDelete = append(req.KeysToDelete, k.ID)
+	}
+	sort.Ints(req.KeysToDelete)
+	if len(req.KeysToAdd) > 0 || len(req.KeysToUpdate) > 0 || len(req.KeysToDelete) > 0 {
+		changed = true
+	}
+	return req, changed
+}
+
+// applyChannel 按名称创建或更新渠道。
+// gorm 创建时会用默认值 true 覆盖 enabled=false，因此新建的渠道与密钥需要再收敛一次
+func applyChannel(ctx context.Context, s ChannelSpec) error {
+	current := findChannel(ctx, s.Name)
+	if current == nil {
+		desired := desiredChannel(s, nil)
+		if err := op.ChannelCreate(&desired, ctx); err != nil {
+			return err
+		}
+		if err := helper.LLMPriceAddToDB(xstrings.SplitTrimCompact(",", desired.Model+","+desired.CustomModel), desired.ID, ctx); err != nil {
+			log.Warnf("failed to add model prices for channel %s: %v", desired.Name, err)
+		}
+		current = &desired
+	}
+	for i := 0; i < 2; i++ {
+		desired := desiredChannel(s, current)
+		req, changed := channelUpdateRequest(current, &desired)
+		if !changed {
+			return nil
+		}
+		var err error
+		if current, err = op.ChannelUpdate(req, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func findChannel(ctx context.Context, name string) *model.Channel {
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, c := range channels {
+		if c.Name == name {
+			return &c
+		}
+	}
+	return nil
+}
+
+type groupView struct {
+	Mode              string                   `json:"mode"`
+	MatchRegex        string                   `json:"match_regex"`
+	FirstTokenTimeOut int                      `json:"first_token_time_out"`
+	PromptCache       *model.PromptCachePolicy `json:"prompt_cache"`
+	ServerTools       []string                 `json:"server_tools"`
+	MCPServers        []string                 `json:"mcp_servers"`
+	Reasoning         *model.ReasoningPolicy   `json:"reasoning"`
+	Guardrail         *model.GuardrailPolicy   `json:"guardrail"`
+	Script            string                   `json:"script"`
+	Items             []string                 `json:"items"`
+}
+
+// activePromptCache 未启用的策略与未设置等价
+func activePromptCache(p *model.PromptCachePolicy) *model.PromptCachePolicy {
+	if !p.Active() {
+		return nil
+	}
+	return p
+}
+
+func samePromptCache(a, b *model.PromptCachePolicy) bool {
+	a, b = activePromptCache(a), activePromptCache(b)
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// promptCacheUpdate 生成更新请求中的策略，空策略表示清除
+func promptCacheUpdate(p *model.PromptCachePolicy) *model.PromptCachePolicy {
+	if p = activePromptCache(p); p == nil {
+		return &model.PromptCachePolicy{}
+	}
+	return p
+}
+
+// activeReasoning 未启用的策略与未设置等价
+func activeReasoning(p *model.ReasoningPolicy) *model.ReasoningPolicy {
+	if !p.Active() {
+		return nil
+	}
+	return p
+}
+
+// sameReasoning 按 JSON 比较，未设置的 budgets 与空 budgets 等价
+func sameReasoning(a, b *model.ReasoningPolicy) bool {
+	x, _ := json.Marshal(activeReasoning(a))
+	y, _ := json.Marshal(activeReasoning(b))
+	return string(x) == string(y)
+}
+
+// reasoningUpdate 生成更新请求中的策略，空策略表示清除
+func reasoningUpdate(p *model.ReasoningPolicy) *model.ReasoningPolicy {
+	if p = activeReasoning(p); p == nil {
+		return &model.ReasoningPolicy{}
+	}
+	return p
+}
+
+// activeGuardrail 未启用的策略与未设置等价
+func activeGuardrail(p *model.GuardrailPolicy) *model.GuardrailPolicy {
+	if !p.Active() {
+		return nil
+	}
+	return p
+}
+
+func sameGuardrail(a, b *model.GuardrailPolicy) bool {
+	x, _ := json.Marshal(activeGuardrail(a))
+	y, _ := json.Marshal(activeGuardrail(b))
+	return string(x) == string(y)
+}
+
+// guardrailUpdate 生成更新请求中的策略，空策略表示清除
+func guardrailUpdate(p *model.GuardrailPolicy) *model.GuardrailPolicy {
+	if p = activeGuardrail(p); p == nil {
+		return &model.GuardrailPolicy{}
+	}
+	return p
+}
+
+// namesView 名称列表未设置与空列表等价
+func namesView(names []string) []string {
+	if len(names) == 0 {
+		return []string{}
+	}
+	return names
+}
+
+func formatGroupItem(channel, modelName string, priority, weight int) string {
+	return fmt.Sprintf("%s/%s priority=%d weight=%d", channel, modelName, priority, weight)
+}
+
+func groupModeName(mode model.GroupMode) string {
+	for name, m := range groupModeNames {
+		if m == mode {
+			return name
+		}
+	}
+	return fmt.Sprint(int(mode))
+}
+
+func (s *state) viewGroup(g *model.Group) groupView {
+	v := groupView{Mode: groupModeName(g.Mode), MatchRegex: g.MatchRegex, FirstTokenTimeOut: g.FirstTokenTimeOut, PromptCache: activePromptCache(g.PromptCache), ServerTools: namesView(g.ServerTools), MCPServers: namesView(g.MCPServers), Reasoning: activeReasoning(g.Reasoning), Guardrail: activeGuardrail(g.Guardrail), Script: g.Script, Items: []string{}}
+	for _, item := range g.Items {
+		channel, ok := s.channelNames[item.ChannelID]
+		if !ok {
+			channel = fmt.Sprintf("#%d", item.ChannelID)
+		}
+		v.Items = append(v.Items, formatGroupItem(channel, item.ModelName, item.Priority, item.Weight))
+	}
+	sort.Strings(v.Items)
+	return v
+}
+
+func viewGroupSpec(g GroupSpec) groupView {
+	v := groupView{Mode: g.Mode, MatchRegex: g.MatchRegex, FirstTokenTimeOut: g.FirstTokenTimeOut, PromptCache: activePromptCache(g.PromptCache), ServerTools: namesView(g.ServerTools), MCPServers: namesView(g.MCPServers), Reasoning: activeReasoning(g.Reasoning), Guardrail: activeGuardrail(g.Guardrail), Script: g.Script, Items: []string{}}
+	for _, item := range g.Items {
+		v.Items = append(v.Items, formatGroupItem(item.Channel, item.Model, item.Priority, item.Weight))
+	}
+	sort.Strings(v.Items)
+	return v
+}
+
+// applyGroup 按名称创建或更新分组，分组项按渠道与模型匹配
+func applyGroup(ctx context.Context, s GroupSpec) error {
+	channelIDs := map[string]int{}
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range channels {
+		channelIDs[c.Name] = c.ID
+	}
+	items := make([]model.GroupItem, 0, len(s.Items))
+	for _, item := range s.Items {
+		channelID, ok := channelIDs[item.Channel]
+		if !ok {
+			return fmt.Errorf("channel %q not found", item.Channel)
+		}
+		items = append(items, model.GroupItem{ChannelID: channelID, ModelName: item.Model, Priority: item.Priority, Weight: item.Weight})
+	}
+	mode := groupModeNames[s.Mode]
+
+	current, err := op.GroupGetMap(s.Name, ctx)
+	if err != nil {
+		return op.GroupCreate(&model.Group{
+			Name:              s.Name,
+			Mode:              mode,
+			MatchRegex:        s.MatchRegex,
+			FirstTokenTimeOut: s.FirstTokenTimeOut,
+			PromptCache:       activePromptCache(s.PromptCache),
+			ServerTools:       s.ServerTools,
+			MCPServers:        s.MCPServers,
+			Reasoning:         activeReasoning(s.Reasoning),
+			Guardrail:         activeGuardrail(s.Guardrail),
+			Script:            s.Script,
+			Items:             items,
+		}, ctx)
+	}
+
+	req := &model.GroupUpdateRequest{ID: current.ID}
+	if current.Mode != mode {
+		req.Mode = &mode
+	}
+	if current.MatchRegex != s.MatchRegex {
+		req.MatchRegex = &s.MatchRegex
+	}
+	if current.FirstTokenTimeOut != s.FirstTokenTimeOut {
+		req.FirstTokenTimeOut = &s.FirstTokenTimeOut
+	}
+	if !samePromptCache(current.PromptCache, s.PromptCache) {
+		req.PromptCache = promptCacheUpdate(s.PromptCache)
+	}
+	if tools := namesView(s.ServerTools); !slices.Equal(namesView(current.ServerTools), tools) {
+		req.ServerTools = &tools
+	}
+	if servers := namesView(s.MCPServers); !slices.Equal(namesView(current.MCPServers), servers) {
+		req.MCPServers = &servers
+	}
+	if !sameReasoning(current.Reasoning, s.Reasoning) {
+		req.Reasoning = reasoningUpdate(s.Reasoning)
+	}
+	if !sameGuardrail(current.Guardrail, s.Guardrail) {
+		req.Guardrail = guardrailUpdate(s.Guardrail)
+	}
+	if current.Script != s.Script {
+		req.Script = &s.Script
+	}
+	existing := make(map[model.GroupIDAndLLMName]model.GroupItem, len(current.Items))
+	for _, item := range current.Items {
+		existing[model.GroupIDAndLLMName{ChannelID: item.ChannelID, ModelName: item.ModelName}] = item
+	}
+	for _, item := range items {
+		key := model.GroupIDAndLLMName{ChannelID: item.ChannelID, ModelName: item.ModelName}
+		old, ok := existing[key]
+		if !ok {
+			req.ItemsToAdd = append(req.ItemsToAdd, model.GroupItemAddRequest{
+				ChannelID: item.ChannelID, ModelName: item.ModelName, Priority: item.Priority, Weight: item.Weight,
+			})
+			continue
+		}
+		delete(existing, key)
+		if old.Priority != item.Priority || old.Weight != item.Weight {
+			req.ItemsToUpdate = append(req.ItemsToUpdate, model.GroupItemUpdateRequest{ID: old.ID, Priority: item.Priority, Weight: item.Weight})
+		}
+	}
+	for _, item := range existing {
+		req.ItemsToDelete = append(req.ItemsToDelete, item.ID)
+	}
+	sort.Ints(req.ItemsToDelete)
+	_, err = op.GroupUpdate(req, ctx)
+	return err
+}
+
+type aliasView struct {
+	Group           string `json:"group"`
+	RewriteResponse bool   `json:"rewrite_response"`
+	Priority        int    `json:"priority"`
+	Enabled         bool   `json:"enabled"`
+}
+
+func viewAlias(a *model.ModelAlias, group string) aliasView {
+	return aliasView{Group: group, RewriteResponse: a.RewriteResponse, Priority: a.Priority, Enabled: a.Enabled}
+}
+
+func viewAliasSpec(a AliasSpec) aliasView {
+	return aliasView{Group: a.Group, RewriteResponse: a.RewriteResponse, Priority: a.Priority, Enabled: boolOr(a.Enabled, true)}
+}
+
+// applyAlias 创建或更新别名，id 为 0 表示新建
+func applyAlias(ctx context.Context, s AliasSpec, id int) error {
+	group, err := op.GroupGetMap(s.Group, ctx)
+	if err != nil {
+		return fmt.Errorf("group %q not found", s.Group)
+	}
+	a := &model.ModelAlias{
+		ID:              id,
+		Pattern:         s.Pattern,
+		MatchType:       model.ModelAliasMatchType(s.MatchType),
+		GroupID:         group.ID,
+		RewriteResponse: s.RewriteResponse,
+		Priority:        s.Priority,
+		Enabled:         boolOr(s.Enabled, true),
+	}
+	if id == 0 {
+		return op.ModelAliasCreate(a, ctx)
+	}
+	return op.ModelAliasUpdate(a, ctx)
+}
+
+type apiKeyView struct {
+	Key             string                 `json:"key"`
+	Enabled         bool                   `json:"enabled"`
+	ExpireAt        string                 `json:"expire_at"`
+	MaxCost         float64                `json:"max_cost"`
+	SupportedModels string                 `json:"supported_models"`
+	Guardrail       *model.GuardrailPolicy `json:"guardrail"`
+}
+
+func viewAPIKey(k *model.APIKey) apiKeyView {
+	return apiKeyView{
+		Key:             k.KeyPrefix,
+		Enabled:         k.Enabled,
+		ExpireAt:        formatExpireAt(k.ExpireAt),
+		MaxCost:         k.MaxCost,
+		SupportedModels: k.SupportedModels,
+		Guardrail:       activeGuardrail(k.Guardrail),
+	}
+}
+
+func viewAPIKeySpec(s APIKeySpec) apiKeyView {
+	expireAt, _ := parseExpireAt(s.ExpireAt)
+	v := apiKeyView{
+		Key:             "(generated)",
+		Enabled:         boolOr(s.Enabled, true),
+		ExpireAt:        formatExpireAt(expireAt),
+		MaxCost:         s.MaxCost,
+		SupportedModels: joinModels(s.SupportedModels),
+		Guardrail:       activeGuardrail(s.Guardrail),
+	}
+	if s.Key != "" {
+		v.Key = model.APIKeyPrefix(s.Key)
+	}
+	return v
+}
+
+// applyAPIKey 按名称创建或更新 API Key，指定的 key 与当前不一致时立即轮换
+func applyAPIKey(ctx context.Context, s APIKeySpec) (string, error) {
+	expireAt, _ := parseExpireAt(s.ExpireAt)
+	keys, err := op.APIKeyList(ctx)
+	if err != nil {
+		return "", err
+	}
+	var current *model.APIKey
+	for i := range keys {
+		if keys[i].Name == s.Name {
+			current = &keys[i]
+			break
+		}
+	}
+
+	note := ""
+	if current == nil {
+		key := &model.APIKey{Name: s.Name, APIKey: s.Key}
+		if key.APIKey == "" {
+			key.APIKey = auth.GenerateAPIKey()
+			note = "generated key: " + key.APIKey
+		}
+		if err := op.APIKeyCreate(key, ctx); err != nil {
+			return "", err
+		}
+		current = key
+	} else if s.Key != "" && !current.Verify(s.Key) {
+		rotated, err := op.APIKeyRotate(current.ID, s.Key, 0, ctx)
+		if err != nil {
+			return "", err
+		}
+		current = &rotated
+	}
+
+	updated := *current
+	updated.APIKey = ""
+	updated.Enabled = boolOr(s.Enabled, true)
+	updated.ExpireAt = expireAt
+	updated.MaxCost = s.MaxCost
+	updated.SupportedModels = joinModels(s.SupportedModels)
+	updated.Guardrail = activeGuardrail(s.Guardrail)
+	if err := op.APIKeyUpdate(&updated, ctx); err != nil {
+		return "", err
+	}
+	return note, nil
+}
+
+func sameBaseURLs(a, b []model.BaseUrl) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].URL != b[i].URL {
+			return false
+		}
+	}
+	return true
+}
+
+func sameHeaders(a, b []model.CustomHeader) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := make(map[string]string, len(a))
+	for _, h := range a {
+		m[h.HeaderKey] = h.HeaderValue
+	}
+	for _, h := range b {
+		if v, ok := m[h.HeaderKey]; !ok || v != h.HeaderValue {
+			return false
+		}
+	}
+	return true
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,346 @@
+package declarative
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"octopus/internal/guardrail"
+	"octopus/internal/mcp"
+	"octopus/internal/model"
+	"octopus/internal/script"
+	"octopus/internal/servertool"
+
+	"github.com/dlclark/regexp2"
+	"github.com/samber/lo"
+	"go.yaml.in/yaml/v3"
+)
+
+// Spec 声明式配置文件，YAML 或 JSON 格式。
+// 只有文件中出现的部分才会被管理：例如省略 channels 时不会修改任何渠道，
+// 而 channels: [] 表示删除所有渠道
+type Spec struct {
+	Channels *[]ChannelSpec    `yaml:"channels"`
+	Groups   *[]GroupSpec      `yaml:"groups"`
+	Aliases  *[]AliasSpec      `yaml:"aliases"`
+	APIKeys  *[]APIKeySpec     `yaml:"api_keys"`
+	Settings map[string]string `yaml:"settings"` // 设置只会被修改，不会被删除
+}
+
+type ChannelSpec struct {
+	Name          string                   `yaml:"name"`
+	Type          string                   `yaml:"type"`    // openai_chat、anthropic 等，参见 /api/v1/channel/types
+	Enabled       *bool                    `yaml:"enabled"` // 默认 true
+	BaseURLs      []string                 `yaml:"base_urls"`
+	Keys          []ChannelKeySpec         `yaml:"keys"`
+	Models        []string                 `yaml:"models"` // 省略时不管理模型列表，适用于开启 auto_sync 的渠道
+	CustomModels  []string                 `yaml:"custom_models"`
+	Proxy         bool                     `yaml:"proxy"`
+	ChannelProxy  string                   `yaml:"channel_proxy"`
+	AutoSync      bool                     `yaml:"auto_sync"`
+	AutoGroup     string                   `yaml:"auto_group"` // none、fuzzy、exact、regex
+	CustomHeaders map[string]string        `yaml:"custom_headers"`
+	ParamOverride string                   `yaml:"param_override"`
+	PromptCache   *model.PromptCachePolicy `yaml:"prompt_cache"` // enabled、turns、ttl，仅对 Anthropic 渠道生效
+	Script        string                   `yaml:"script"`       // Starlark 脚本，见 README 中的脚本一节
+}
+
+// ChannelKeySpec 渠道密钥，可以直接写成字符串，支持 ${ENV} 引用环境变量
+type ChannelKeySpec struct {
+	Key     string `yaml:"key"`
+	Enabled *bool  `yaml:"enabled"` // 默认 true
+}
+
+func (k *ChannelKeySpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		k.Key = node.Value
+		return nil
+	}
+	type plain ChannelKeySpec
+	return node.Decode((*plain)(k))
+}
+
+type GroupSpec struct {
+	Name              string                   `yaml:"name"`
+	Mode              string                   `yaml:"mode"` // round_robin、random、failover、weighted
+	MatchRegex        string                   `yaml:"match_regex"`
+	FirstTokenTimeOut int                      `yaml:"first_token_time_out"`
+	PromptCache       *model.PromptCachePolicy `yaml:"prompt_cache"` // 启用时覆盖渠道的策略
+	ServerTools       []string                 `yaml:"server_tools"` // web_search、web_fetch
+	MCPServers        []string                 `yaml:"mcp_servers"`  // 配置 mcp.servers 中的名称
+	Reasoning         *model.ReasoningPolicy   `yaml:"reasoning"`    // enabled、output、budgets
+	Guardrail         *model.GuardrailPolicy   `yaml:"guardrail"`    // enabled、rules、response
+	Script            string                   `yaml:"script"`       // Starlark 脚本，可以定义 on_request、on_response、on_chunk
+	Items             []GroupItemSpec          `yaml:"items"`
+}
+
+type GroupItemSpec struct {
+	Channel  string `yaml:"channel"` // 渠道名称
+	Model    string `yaml:"model"`
+	Priority int    `yaml:"priority"`
+	Weight   int    `yaml:"weight"`
+}
+
+type AliasSpec struct {
+	Pattern         string `yaml:"pattern"`
+	MatchType       string `yaml:"match_type"` // 默认 exact
+	Group           string `yaml:"group"`      // 分组名称
+	RewriteResponse bool   `yaml:"rewrite_response"`
+	Priority        int    `yaml:"priority"`
+	Enabled         *bool  `yaml:"enabled"` // 默认 true
+}
+
+// APIKeySpec 以名称标识 API Key。省略 key 时新建的密钥会随机生成，已有密钥保持不变
+type APIKeySpec struct {
+	Name            string                 `yaml:"name"`
+	Key             string                 `yaml:"key"`
+	Enabled         *bool                  `yaml:"enabled"`   // 默认 true
+	ExpireAt        string                 `yaml:"expire_at"` // RFC3339 或 2006-01-02，留空表示永不过期
+	MaxCost         float64                `yaml:"max_cost"`
+	SupportedModels []string               `yaml:"supported_models"`
+	Guardrail       *model.GuardrailPolicy `yaml:"guardrail"` // 与分组的策略同时生效
+}
+
+var groupModeNames = map[string]model.GroupMode{
+	"round_robin": model.GroupModeRoundRobin,
+	"random":      model.GroupModeRandom,
+	"failover":    model.GroupModeFailover,
+	"weighted":    model.GroupModeWeighted,
+}
+
+var autoGroupNames = map[string]model.AutoGroupType{
+	"":      model.AutoGroupTypeNone,
+	"none":  model.AutoGroupTypeNone,
+	"fuzzy": model.AutoGroupTypeFuzzy,
+	"exact": model.AutoGroupTypeExact,
+	"regex": model.AutoGroupTypeRegex,
+}
+
+// Load 读取并解析配置文件，JSON 作为 YAML 的子集同样可以解析
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := spec.expandEnv(); err != nil {
+		return nil, err
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// expandEnv 展开密钥、地址、代理与请求头中的 ${ENV} 引用，引用未设置的变量视为错误
+func (s *Spec) expandEnv() error {
+	var missing []string
+	expand := func(v string) string {
+		return os.Expand(v, func(name string) string {
+			value, ok := os.LookupEnv(name)
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+	if s.Channels != nil {
+		for i := range *s.Channels {
+			c := &(*s.Channels)[i]
+			for j := range c.BaseURLs {
+				c.BaseURLs[j] = expand(c.BaseURLs[j])
+			}
+			for j := range c.Keys {
+				c.Keys[j].Key = expand(c.Keys[j].Key)
+			}
+			c.ChannelProxy = expand(c.ChannelProxy)
+			for k, v := range c.CustomHeaders {
+				c.CustomHeaders[k] = expand(v)
+			}
+		}
+	}
+	if s.APIKeys != nil {
+		for i := range *s.APIKeys {
+			k := &(*s.APIKeys)[i]
+			k.Key = expand(k.Key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("environment variables not set: %s", strings.Join(lo.Uniq(missing), ", "))
+	}
+	return nil
+}
+
+func (s *Spec) validate() error {
+	if s.Channels != nil {
+		seen := make(map[string]struct{})
+		for _, c := range *s.Channels {
+			if c.Name == "" {
+				return fmt.Errorf("channel name is required")
+			}
+			if _, ok := seen[c.Name]; ok {
+				return fmt.Errorf("duplicate channel %q", c.Name)
+			}
+			seen[c.Name] = struct{}{}
+			if _, err := model.ParseChannelType(c.Type); err != nil {
+				return fmt.Errorf("channel %q: %w", c.Name, err)
+			}
+			if _, ok := autoGroupNames[c.AutoGroup]; !ok {
+				return fmt.Errorf("channel %q: invalid auto_group %q", c.Name, c.AutoGroup)
+			}
+			if len(c.BaseURLs) == 0 {
+				return fmt.Errorf("channel %q: base_urls is required", c.Name)
+			}
+			if err := c.PromptCache.Validate(); err != nil {
+				return fmt.Errorf("channel %q: %w", c.Name, err)
+			}
+			if err := script.Validate(c.Script); err != nil {
+				return fmt.Errorf("channel %q: %w", c.Name, err)
+			}
+			keys := make(map[string]struct{})
+			for _, k := range c.Keys {
+				if k.Key == "" {
+					return fmt.Errorf("channel %q: empty key", c.Name)
+				}
+				if _, ok := keys[k.Key]; ok {
+					return fmt.Errorf("channel %q: duplicate key", c.Name)
+				}
+				keys[k.Key] = struct{}{}
+			}
+		}
+	}
+	if s.Groups != nil {
+		seen := make(map[string]struct{})
+		for _, g := range *s.Groups {
+			if g.Name == "" {
+				return fmt.Errorf("group name is required")
+			}
+			if _, ok := seen[g.Name]; ok {
+				return fmt.Errorf("duplicate group %q", g.Name)
+			}
+			seen[g.Name] = struct{}{}
+			if _, ok := groupModeNames[g.Mode]; !ok {
+				return fmt.Errorf("group %q: invalid mode %q", g.Name, g.Mode)
+			}
+			if g.MatchRegex != "" {
+				if _, err := regexp2.Compile(g.MatchRegex, regexp2.ECMAScript); err != nil {
+					return fmt.Errorf("group %q: invalid match_regex: %w", g.Name, err)
+				}
+			}
+			if err := g.PromptCache.Validate(); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			if err := g.Reasoning.Validate(); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			if err := guardrail.Validate(g.Guardrail); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			if err := script.Validate(g.Script); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			if err := servertool.Validate(g.ServerTools); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			if err := mcp.Validate(g.MCPServers); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+			items := make(map[string]struct{})
+			for _, item := range g.Items {
+				if item.Channel == "" || item.Model == "" {
+					return fmt.Errorf("group %q: item channel and model are required", g.Name)
+				}
+				id := item.Channel + "/" + item.Model
+				if _, ok := items[id]; ok {
+					return fmt.Errorf("group %q: duplicate item %s", g.Name, id)
+				}
+				items[id] = struct{}{}
+			}
+		}
+	}
+	if s.Aliases != nil {
+		seen := make(map[string]struct{})
+		for i := range *s.Aliases {
+			a := &(*s.Aliases)[i]
+			if a.MatchType == "" {
+				a.MatchType = string(model.ModelAliasMatchExact)
+			}
+			if a.Group == "" {
+				return fmt.Errorf("alias %q: group is required", a.Pattern)
+			}
+			// 分组 ID 在计划阶段解析，这里仅用于校验规则本身
+			check := model.ModelAlias{Pattern: a.Pattern, MatchType: model.ModelAliasMatchType(a.MatchType), GroupID: -1}
+			if err := check.Validate(); err != nil {
+				return fmt.Errorf("alias %q: %w", a.Pattern, err)
+			}
+			id := aliasID(a.MatchType, a.Pattern)
+			if _, ok := seen[id]; ok {
+				return fmt.Errorf("duplicate alias %s", id)
+			}
+			seen[id] = struct{}{}
+		}
+	}
+	if s.APIKeys != nil {
+		seen := make(map[string]struct{})
+		for _, k := range *s.APIKeys {
+			if k.Name == "" {
+				return fmt.Errorf("api key name is required")
+			}
+			if _, ok := seen[k.Name]; ok {
+				return fmt.Errorf("duplicate api key %q", k.Name)
+			}
+			seen[k.Name] = struct{}{}
+			if _, err := parseExpireAt(k.ExpireAt); err != nil {
+				return fmt.Errorf("api key %q: %w", k.Name, err)
+			}
+			if err := guardrail.Validate(k.Guardrail); err != nil {
+				return fmt.Errorf("api key %q: %w", k.Name, err)
+			}
+		}
+	}
+	for key, value := range s.Settings {
+		setting := model.Setting{Key: model.SettingKey(key), Value: value}
+		if err := setting.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseExpireAt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, time.Local)
+	if err != nil {
+		return 0, fmt.Errorf("invalid expire_at %q", s)
+	}
+	return t.Unix(), nil
+}
+
+func aliasID(matchType, pattern string) string {
+	return matchType + ":" + pattern
+}
+
+func boolOr(v *bool, def bool) bool {
+	if v == nil {
+		return def
+	}
+	return *v
+}
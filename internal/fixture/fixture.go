@@ -0,0 +1,176 @@
+// Package fixture 录制上游的原始请求与响应（包括 SSE 事件序列）到夹具文件，
+// 并在回放时代替上游返回这些响应，用于检查各入站、出站格式之间转换的回归
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Redacted 替换密钥后的占位符
+const Redacted = "REDACTED"
+
+// sensitiveHeaders 录制时整体替换的请求头与响应头
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"Api-Key",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// Fixture 一次上游请求及其响应
+type Fixture struct {
+	Channel     string   `json:"channel"`
+	ChannelType string   `json:"channel_type"`
+	RecordedAt  int64    `json:"recorded_at"`
+	Request     Request  `json:"request"`
+	Response    Response `json:"response"`
+}
+
+type Request struct {
+	Method string            `json:"method"`
+	URL    string            `json:"url"`
+	Header map[string]string `json:"header,omitempty"`
+	Body   json.RawMessage   `json:"body,omitempty"` // JSON 请求体原样保存，其他内容保存为字符串
+}
+
+type Response struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header,omitempty"`
+	Body       json.RawMessage   `json:"body,omitempty"`      // 非流式响应的响应体
+	Events     []Event           `json:"events,omitempty"`    // 流式响应的 SSE 事件序列
+	Truncated  bool              `json:"truncated,omitempty"` // 客户端断开等原因导致响应没有完整读取
+}
+
+// Event 一个 SSE 事件，OffsetMs 为相对响应头到达的时间
+type Event struct {
+	Event    string `json:"event,omitempty"`
+	Data     string `json:"data"`
+	OffsetMs int64  `json:"offset_ms"`
+}
+
+// IsStream 响应是否为 SSE 流
+func (f *Fixture) IsStream() bool {
+	return len(f.Response.Events) > 0 || strings.HasPrefix(f.Response.Header["Content-Type"], "text/event-stream")
+}
+
+// Load 读取夹具文件
+func Load(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save 写入夹具文件
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// HTTPResponse 按夹具构建响应，流式响应重新编码为 SSE
+func (f *Fixture) HTTPResponse(req *http.Request) *http.Response {
+	header := make(http.Header, len(f.Response.Header))
+	for k, v := range f.Response.Header {
+		header.Set(k, v)
+	}
+	var body []byte
+	if f.IsStream() {
+		body = encodeEvents(f.Response.Events)
+		if header.Get("Content-Type") == "" {
+			header.Set("Content-Type", "text/event-stream")
+		}
+	} else {
+		body = decodeBody(f.Response.Body)
+	}
+	header.Del("Content-Length")
+	header.Del("Content-Encoding")
+	return &http.Response{
+		Status:        http.StatusText(f.Response.StatusCode),
+		StatusCode:    f.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func encodeEvents(events []Event) []byte {
+	var buf bytes.Buffer
+	for _, ev := range events {
+		buf.Write(encodeEvent(ev))
+	}
+	return buf.Bytes()
+}
+
+func encodeEvent(ev Event) []byte {
+	var buf bytes.Buffer
+	if ev.Event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", ev.Event)
+	}
+	for _, line := range strings.Split(ev.Data, "\n") {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// encodeBody JSON 内容原样保存以便阅读，其他内容保存为 JSON 字符串
+func encodeBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] != '"' && json.Valid(trimmed) {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, trimmed); err == nil {
+			return buf.Bytes()
+		}
+	}
+	data, _ := json.Marshal(string(body))
+	return data
+}
+
+func decodeBody(body json.RawMessage) []byte {
+	if len(body) > 0 && body[0] == '"' {
+		var s string
+		if err := json.Unmarshal(body, &s); err == nil {
+			return []byte(s)
+		}
+	}
+	return body
+}
+
+func flattenHeader(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(h))
+	for k := range h {
+		m[k] = h.Get(k)
+	}
+	for _, k := range sensitiveHeaders {
+		if _, ok := m[http.CanonicalHeaderKey(k)]; ok {
+			m[http.CanonicalHeaderKey(k)] = Redacted
+		}
+	}
+	return m
+}
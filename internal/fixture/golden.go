@@ -0,0 +1,119 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"octopus/internal/model"
+	"octopus/internal/transformer/inbound"
+	"octopus/internal/transformer/outbound"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// InboundTypes 可以用于回放的入站格式
+var InboundTypes = map[string]inbound.InboundType{
+	"openai_chat":      inbound.InboundTypeOpenAIChat,
+	"openai_response":  inbound.InboundTypeOpenAIResponse,
+	"anthropic":        inbound.InboundTypeAnthropic,
+	"openai_embedding": inbound.InboundTypeOpenAIEmbedding,
+}
+
+// InboundTypeNames 返回排序后的入站格式名称
+func InboundTypeNames() []string {
+	names := make([]string, 0, len(InboundTypes))
+	for name := range InboundTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Replay 不经过网络执行一次完整的转换：客户端请求按入站格式解析后交给夹具对应渠道类型的出站，
+// 上游响应由夹具提供，再依次转换回入站格式，返回客户端将会收到的内容。流式响应返回拼接后的 SSE 数据
+func Replay(ctx context.Context, f *Fixture, inboundType inbound.InboundType, body []byte) ([]byte, error) {
+	inAdapter := inbound.Get(inboundType)
+	if inAdapter == nil {
+		return nil, fmt.Errorf("unsupported inbound type: %d", inboundType)
+	}
+	channelType, err := model.ParseChannelType(f.ChannelType)
+	if err != nil {
+		return nil, err
+	}
+	outAdapter := outbound.Get(channelType)
+	if outAdapter == nil {
+		return nil, fmt.Errorf("unsupported channel type: %s", f.ChannelType)
+	}
+
+	internalRequest, err := inAdapter.TransformRequest(ctx, body)
+	if err != nil {
+		return nil, fmt.Errorf("inbound request: %w", err)
+	}
+	if err := internalRequest.Validate(); err != nil {
+		return nil, fmt.Errorf("inbound request: %w", err)
+	}
+	outboundRequest, err := outAdapter.TransformRequest(ctx, internalRequest, "replay://fixture", Redacted)
+	if err != nil {
+		return nil, fmt.Errorf("outbound request: %w", err)
+	}
+
+	stream := internalRequest.Stream != nil && *internalRequest.Stream
+	if stream != f.IsStream() {
+		return nil, fmt.Errorf("request stream=%t does not match the fixture stream=%t", stream, f.IsStream())
+	}
+	response := f.HTTPResponse(outboundRequest)
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		data, _ := io.ReadAll(response.Body)
+		return nil, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(data))
+	}
+
+	if !stream {
+		internalResponse, err := outAdapter.TransformResponse(ctx, response)
+		if err != nil {
+			return nil, fmt.Errorf("outbound response: %w", err)
+		}
+		return inAdapter.TransformResponse(ctx, internalResponse)
+	}
+
+	// 与中继一致：无法转换的事件被跳过
+	var out bytes.Buffer
+	for ev, err := range sse.Read(response.Body, &sse.ReadConfig{MaxEventSize: maxEventSize}) {
+		if err != nil {
+			return nil, fmt.Errorf("read fixture stream: %w", err)
+		}
+		internalStream, err := outAdapter.TransformStream(ctx, []byte(ev.Data))
+		if err != nil || internalStream == nil {
+			continue
+		}
+		data, err := inAdapter.TransformStream(ctx, internalStream)
+		if err != nil {
+			continue
+		}
+		out.Write(data)
+	}
+	return out.Bytes(), nil
+}
+
+// FirstDiff 返回两份输出中第一处不同的行
+func FirstDiff(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return fmt.Sprintf("line %d\n- %s\n+ %s", i+1, w, g)
+		}
+	}
+	return ""
+}
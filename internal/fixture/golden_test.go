@@ -0,0 +1,71 @@
+package fixture
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// TestReplayCorpus 回放 testdata 下的夹具。每个目录包含一个 fixture.json，
+// 以及任意数量的 <入站格式>.request.json 与对应的 <入站格式>.golden
+func TestReplayCorpus(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "*", "fixture.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) == 0 {
+		t.Fatal("expected fixtures under testdata")
+	}
+	for _, path := range dirs {
+		dir := filepath.Dir(path)
+		f, err := Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		requests, err := filepath.Glob(filepath.Join(dir, "*.request.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(requests) == 0 {
+			t.Fatalf("expected at least one request in %s", dir)
+		}
+		for _, reqPath := range requests {
+			name := strings.TrimSuffix(filepath.Base(reqPath), ".request.json")
+			t.Run(filepath.Base(dir)+"/"+name, func(t *testing.T) {
+				inboundType, ok := InboundTypes[name]
+				if !ok {
+					t.Fatalf("invalid inbound %q in %s", name, reqPath)
+				}
+				body, err := os.ReadFile(reqPath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := Replay(context.Background(), f, inboundType, body)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				golden := filepath.Join(dir, name+".golden")
+				if *update {
+					if err := os.WriteFile(golden, got, 0644); err != nil {
+						t.Fatal(err)
+					}
+					return
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatalf("%v (run with -update to create it)", err)
+				}
+				if !bytes.Equal(want, got) {
+					t.Fatalf("output differs from %s:\n%s", golden, FirstDiff(want, got))
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package fixture
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"octopus/internal/utils/log"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// maxEventSize 录制时单个 SSE 事件的最大大小，与中继保持一致
+const maxEventSize = 32 * 1024 * 1024
+
+var (
+	recordSeq   atomic.Int64
+	unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Recorder 包装上游请求，将请求与响应写入 Dir 下以渠道名称命名的目录。
+// Secrets 中的内容（渠道 key 等）在 URL、请求头与请求体、响应体中都会被替换为 REDACTED
+type Recorder struct {
+	Base         http.RoundTripper
+	Dir          string
+	Channel      string
+	ChannelType  string
+	Secrets      []string
+	MaxBodyBytes int64 // 单个响应最多录制的字节数，超出部分不录制并标记为 truncated，0 表示不限制
+	MaxFiles     int   // 每个渠道目录最多保留的夹具数，超出时删除最早的夹具，0 表示不限制
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := r.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// 只录制模型调用，测速与模型列表等探测请求直接转发
+	if req.Method != http.MethodPost {
+		return base.RoundTrip(req)
+	}
+
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &Fixture{
+		Channel:     r.Channel,
+		ChannelType: r.ChannelType,
+		RecordedAt:  time.Now().Unix(),
+		Request: Request{
+			Method: req.Method,
+			URL:    r.redact(req.URL.String()),
+			Header: r.redactHeader(req.Header),
+			Body:   encodeBody([]byte(r.redact(string(reqBody)))),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+		},
+	}
+	c := &capture{
+		ReadCloser: resp.Body,
+		recorder:   r,
+		fixture:    f,
+		endpoint:   path.Base(req.URL.Path),
+		start:      time.Now(),
+	}
+	if f.IsStream() {
+		c.startEvents()
+	}
+	resp.Body = c
+	return resp, nil
+}
+
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.Secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+func (r *Recorder) redactHeader(h http.Header) map[string]string {
+	m := flattenHeader(h)
+	for k, v := range m {
+		m[k] = r.redact(v)
+	}
+	return m
+}
+
+// capture 读取响应体的同时保存一份副本，读取结束或关闭时写入夹具文件
+type capture struct {
+	io.ReadCloser
+	recorder *Recorder
+	fixture  *Fixture
+	endpoint string
+	start    time.Time
+
+	body   bytes.Buffer
+	pipe   *io.PipeWriter
+	events chan []Event
+	once   sync.Once
+	eof    bool
+	size   int64
+	capped bool
+}
+
+// startEvents 流式响应在后台解析 SSE 事件，以便记录每个事件到达的时间
+func (c *capture) startEvents() {
+	pr, pw := io.Pipe()
+	c.pipe = pw
+	c.events = make(chan []Event, 1)
+	go func() {
+		var events []Event
+		for ev, err := range sse.Read(pr, &sse.ReadConfig{MaxEventSize: maxEventSize}) {
+			if err != nil {
+				break
+			}
+			events = append(events, Event{
+				Event:    ev.Type,
+				Data:     c.recorder.redact(ev.Data),
+				OffsetMs: time.Since(c.start).Milliseconds(),
+			})
+		}
+		io.Copy(io.Discard, pr)
+		c.events <- events
+	}()
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if n > 0 && !c.capped {
+		chunk := p[:n]
+		if max := c.recorder.MaxBodyBytes; max > 0 && c.size+int64(n) > max {
+			chunk = chunk[:max-c.size]
+			c.capped = true
+		}
+		c.size += int64(len(chunk))
+		if c.pipe != nil {
+			c.pipe.Write(chunk)
+		} else {
+			c.body.Write(chunk)
+		}
+	}
+	if err == io.EOF {
+		c.eof = true
+		c.finish()
+	}
+	return n, err
+}
+
+func (c *capture) Close() error {
+	err := c.ReadCloser.Close()
+	c.finish()
+	return err
+}
+
+func (c *capture) finish() {
+	c.once.Do(func() {
+		f := c.fixture
+		f.Response.Truncated = !c.eof || c.capped
+		if c.pipe != nil {
+			c.pipe.Close()
+			f.Response.Events = <-c.events
+		} else {
+			f.Response.Body = encodeBody([]byte(c.recorder.redact(c.body.String())))
+		}
+
+		dir := filepath.Join(c.recorder.Dir, safeName(c.recorder.Channel))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			log.Warnf("failed to create fixture dir: %v", err)
+			return
+		}
+		name := fmt.Sprintf("%s-%s-%06d.json", time.Now().Format("20060102-150405.000"), safeName(c.endpoint), recordSeq.Add(1))
+		if err := f.Save(filepath.Join(dir, name)); err != nil {
+			log.Warnf("failed to save fixture: %v", err)
+			return
+		}
+		rotate(dir, c.recorder.MaxFiles)
+	})
+}
+
+// rotate 删除目录中超出数量限制的最早的夹具，文件名以录制时间开头，按名称排序即按时间排序
+func rotate(dir string, maxFiles int) {
+	if maxFiles <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) <= maxFiles {
+		return
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-maxFiles] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			log.Warnf("failed to remove old fixture: %v", err)
+		}
+	}
+}
+
+func safeName(s string) string {
+	s = strings.Trim(unsafeChars.ReplaceAllString(s, "_"), "_")
+	if s == "" {
+		return "unnamed"
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package fixture
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// upstream 返回固定的响应体，流式请求(路径以 /stream 结尾)返回 SSE 事件
+func upstream(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/stream") {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, line := range strings.Split(body, "\n") {
+				fmt.Fprintf(w, "data: %s\n\n", line)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// record 通过 Recorder 发送一次请求并读完响应，返回写入的夹具
+func record(t *testing.T, r *Recorder, url, body string) *Fixture {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	resp, err := (&http.Client{Transport: r}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	files, err := filepath.Glob(filepath.Join(r.Dir, safeName(r.Channel), "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("expected a fixture file, got %v %v", files, err)
+	}
+	f, err := Load(files[len(files)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		response  string
+		maxBody   int64
+		truncated bool
+		check     func(t *testing.T, f *Fixture)
+	}{
+		{
+			name:     "json body",
+			path:     "/v1/chat/completions",
+			response: `{"answer": "sk-secret"}`,
+			check: func(t *testing.T, f *Fixture) {
+				if string(encodeBody(f.Response.Body)) != `{"answer":"REDACTED"}` {
+					t.Errorf("expected redacted compact body, got %s", f.Response.Body)
+				}
+			},
+		},
+		{
+			name:     "stream events",
+			path:     "/v1/stream",
+			response: "a\nb\nc",
+			check: func(t *testing.T, f *Fixture) {
+				if len(f.Response.Events) != 3 || f.Response.Events[2].Data != "c" {
+					t.Errorf("expected 3 events, got %+v", f.Response.Events)
+				}
+			},
+		},
+		{
+			name:      "body over cap",
+			path:      "/v1/chat/completions",
+			response:  `{"answer":"0123456789"}`,
+			maxBody:   10,
+			truncated: true,
+			check: func(t *testing.T, f *Fixture) {
+				if string(decodeBody(f.Response.Body)) != `{"answer":` {
+					t.Errorf("expected body cut at 10 bytes, got %s", f.Response.Body)
+				}
+			},
+		},
+		{
+			name:      "stream over cap",
+			path:      "/v1/stream",
+			response:  "first\nsecond\nthird",
+			maxBody:   int64(len("data: first\n\ndata: sec")),
+			truncated: true,
+			check: func(t *testing.T, f *Fixture) {
+				if len(f.Response.Events) != 1 || f.Response.Events[0].Data != "first" {
+					t.Errorf("expected only the complete first event, got %+v", f.Response.Events)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := upstream(t, tt.response)
+			r := &Recorder{
+				Dir:          t.TempDir(),
+				Channel:      "my channel",
+				ChannelType:  "openai_chat",
+				Secrets:      []string{"sk-secret"},
+				MaxBodyBytes: tt.maxBody,
+			}
+			f := record(t, r, srv.URL+tt.path+"?key=sk-secret", `{"key":"sk-secret"}`)
+
+			if f.Response.Truncated != tt.truncated {
+				t.Errorf("expected truncated %v, got %v", tt.truncated, f.Response.Truncated)
+			}
+			if strings.Contains(f.Request.URL, "sk-secret") || string(encodeBody(f.Request.Body)) != `{"key":"REDACTED"}` {
+				t.Errorf("expected redacted request, got %s %s", f.Request.URL, f.Request.Body)
+			}
+			if f.Request.Header["Authorization"] != Redacted {
+				t.Errorf("expected redacted authorization, got %q", f.Request.Header["Authorization"])
+			}
+			if c, ok := f.Response.Header["Set-Cookie"]; ok && c != Redacted {
+				t.Errorf("expected redacted cookie, got %q", c)
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestRecorderRotate(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxFiles int
+		requests int
+		expected int
+	}{
+		{name: "unlimited", maxFiles: 0, requests: 4, expected: 4},
+		{name: "under limit", maxFiles: 5, requests: 3, expected: 3},
+		{name: "over limit", maxFiles: 2, requests: 5, expected: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := upstream(t, `{}`)
+			r := &Recorder{Dir: t.TempDir(), Channel: "c", ChannelType: "openai_chat", MaxFiles: tt.maxFiles}
+			var last *Fixture
+			for i := 0; i < tt.requests; i++ {
+				last = record(t, r, srv.URL+"/v1/chat/completions", fmt.Sprintf(`{"n":%d}`, i))
+			}
+			files, _ := filepath.Glob(filepath.Join(r.Dir, "c", "*.json"))
+			if len(files) != tt.expected {
+				t.Fatalf("expected %d fixtures, got %d", tt.expected, len(files))
+			}
+			// 保留的是最新的夹具
+			if want := fmt.Sprintf(`{"n":%d}`, tt.requests-1); string(encodeBody(last.Request.Body)) != want {
+				t.Fatalf("expected newest fixture %s to be kept, got %s", want, last.Request.Body)
+			}
+			if _, err := os.Stat(filepath.Join(r.Dir, "c")); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
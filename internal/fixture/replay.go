@@ -0,0 +1,159 @@
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FixtureHeader 请求级别指定回放的夹具文件名，覆盖 base url 中的 fixture 参数
+const FixtureHeader = "X-Replay-Fixture"
+
+// Transport 代替上游回放夹具中的响应，base url 形如
+// replay://local?dir=data/fixtures/my-channel&fixture=xxx.json&realtime=true
+//
+//	dir       夹具目录
+//	fixture   固定回放的夹具文件名，未指定时按请求的接口与请求体在目录中查找
+//	realtime  按录制时的时间间隔发送 SSE 事件
+type Transport struct{}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	// 部分调用方直接在 base url 字符串后拼接路径，此时路径落在查询参数的末尾
+	u := *req.URL
+	if i := strings.LastIndex(u.RawQuery, "/"); i >= 0 && !strings.ContainsAny(u.RawQuery[i:], "=&") {
+		u.Path = strings.TrimSuffix(u.Path, "/") + u.RawQuery[i:]
+		u.RawQuery = u.RawQuery[:i]
+	}
+	query := u.Query()
+	dir := query.Get("dir")
+	name := query.Get("fixture")
+	if h := req.Header.Get(FixtureHeader); h != "" {
+		name = h
+	}
+
+	var f *Fixture
+	var err error
+	switch {
+	case name != "":
+		f, err = Load(filepath.Join(dir, filepath.Base(name)))
+	case dir != "":
+		f, err = match(dir, req.Method, u.Path, reqBody)
+	default:
+		err = fmt.Errorf("replay base url requires a dir or fixture parameter")
+	}
+	if err != nil {
+		// 只录制了模型调用，key 探测与测速等请求返回空的模型列表
+		if req.Method != http.MethodPost && name == "" {
+			return jsonResponse(req, http.StatusOK, map[string]any{"object": "list", "data": []any{}}), nil
+		}
+		return jsonResponse(req, http.StatusNotFound, map[string]any{"error": map[string]string{"message": err.Error(), "type": "replay_error"}}), nil
+	}
+
+	resp := f.HTTPResponse(req)
+	if realtime, _ := strconv.ParseBool(query.Get("realtime")); realtime && f.IsStream() {
+		resp.Body = realtimeBody(req, f.Response.Events)
+		resp.ContentLength = -1
+	}
+	return resp, nil
+}
+
+// match 在目录中查找与请求接口相同的夹具，请求体完全相同的夹具优先，否则使用文件名排序后的第一个
+func match(dir, method, urlPath string, body []byte) (*Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	want := endpoint(urlPath)
+	compact := encodeBody(body)
+	var first *Fixture
+	for _, name := range names {
+		f, err := Load(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		u, err := url.Parse(f.Request.URL)
+		if err != nil || f.Request.Method != method || endpoint(u.Path) != want {
+			continue
+		}
+		if bytes.Equal(encodeBody(f.Request.Body), compact) {
+			return f, nil
+		}
+		if first == nil {
+			first = f
+		}
+	}
+	if first == nil {
+		return nil, fmt.Errorf("no fixture in %s matches %s %s", dir, method, urlPath)
+	}
+	return first, nil
+}
+
+// endpoint 接口名称：路径的最后一段，Gemini 风格的 model:method 只取 method
+func endpoint(p string) string {
+	base := path.Base(p)
+	if i := strings.LastIndex(base, ":"); i >= 0 {
+		return base[i+1:]
+	}
+	return base
+}
+
+// realtimeBody 按录制时的时间间隔写出 SSE 事件
+func realtimeBody(req *http.Request, events []Event) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		start := time.Now()
+		for _, ev := range events {
+			if wait := time.Duration(ev.OffsetMs)*time.Millisecond - time.Since(start); wait > 0 {
+				select {
+				case <-req.Context().Done():
+					pw.CloseWithError(req.Context().Err())
+					return
+				case <-time.After(wait):
+				}
+			}
+			if _, err := pw.Write(encodeEvent(ev)); err != nil {
+				return
+			}
+		}
+		pw.Close()
+	}()
+	return pr
+}
+
+func jsonResponse(req *http.Request, status int, v any) *http.Response {
+	body, _ := json.Marshal(v)
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,94 @@
+package fixture
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFixtures 在临时目录中写入两个同接口、请求体不同的夹具
+func writeFixtures(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, answer := range map[string]string{"a.json": "first", "b.json": "second"} {
+		f := &Fixture{
+			ChannelType: "openai_chat",
+			Request: Request{
+				Method: http.MethodPost,
+				URL:    "https://api.example.com/v1/chat/completions",
+				Body:   json.RawMessage(`{"q":"` + answer + `"}`),
+			},
+			Response: Response{StatusCode: http.StatusOK, Body: json.RawMessage(`{"a":"` + answer + `"}`)},
+		}
+		if err := f.Save(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTransport(t *testing.T) {
+	dir := url.QueryEscape(writeFixtures(t))
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		header   string
+		body     string
+		status   int
+		expected string
+	}{
+		{name: "identical body", method: http.MethodPost, url: "replay://local/v1/chat/completions?dir=" + dir, body: `{"q": "second"}`, status: 200, expected: `{"a":"second"}`},
+		{name: "first by name", method: http.MethodPost, url: "replay://local/v1/chat/completions?dir=" + dir, body: `{"q":"other"}`, status: 200, expected: `{"a":"first"}`},
+		{name: "fixture parameter", method: http.MethodPost, url: "replay://local/v1/chat/completions?fixture=b.json&dir=" + dir, body: `{}`, status: 200, expected: `{"a":"second"}`},
+		{name: "fixture header", method: http.MethodPost, url: "replay://local/v1/chat/completions?dir=" + dir, header: "b.json", body: `{}`, status: 200, expected: `{"a":"second"}`},
+		{name: "other endpoint", method: http.MethodPost, url: "replay://local/v1/embeddings?dir=" + dir, body: `{}`, status: 404, expected: "no fixture"},
+		{name: "models probe", method: http.MethodGet, url: "replay://local?dir=" + dir + "/models", status: 200, expected: `"data":[]`},
+		{name: "no dir", method: http.MethodPost, url: "replay://local/v1/chat/completions", body: `{}`, status: 404, expected: "requires a dir"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set(FixtureHeader, tt.header)
+			}
+			resp, err := (&Transport{}).RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, resp.StatusCode, body)
+			}
+			if !strings.Contains(string(encodeBody(body)), tt.expected) {
+				t.Fatalf("expected body containing %s, got %s", tt.expected, body)
+			}
+		})
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/v1/chat/completions", expected: "completions"},
+		{path: "/v1beta/models/gemini-2.5-flash:streamGenerateContent", expected: "streamGenerateContent"},
+		{path: "/v1/messages", expected: "messages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := endpoint(tt.path); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
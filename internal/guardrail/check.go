@@ -0,0 +1,207 @@
+package guardrail
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+)
+
+// BlockedNotice 响应被拦截时返回给客户端的提示
+const BlockedNotice = "[The response was blocked by the content policy]"
+
+const finishContentFilter = "content_filter"
+
+// scan 检查一段文本，返回替换后的文本与是否发生替换
+func (c *Checker) scan(text string, report *Report) (string, bool) {
+	ms := c.find(text)
+	if len(ms) == 0 {
+		return text, false
+	}
+	masked := apply(text, ms, report)
+	return masked, masked != text
+}
+
+func (c *Checker) scanPtr(p *string, report *Report) (*string, bool) {
+	if p == nil {
+		return nil, false
+	}
+	s, ok := c.scan(*p, report)
+	if !ok {
+		return p, false
+	}
+	return &s, true
+}
+
+// scanArguments 检查工具调用的 JSON 参数，只替换字符串值中的命中内容并重新编码，替换后参数仍是合法的 JSON。
+// 参数不是合法的 JSON 时无法安全替换，mask 规则的命中按 block 处理
+func (c *Checker) scanArguments(args string, report *Report) (string, bool) {
+	if args == "" {
+		return args, false
+	}
+	dec := json.NewDecoder(strings.NewReader(args))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		ms := c.find(args)
+		for i := range ms {
+			if ms[i].rule.action == dbmodel.GuardrailActionMask {
+				escalated := *ms[i].rule
+				escalated.action = dbmodel.GuardrailActionBlock
+				ms[i].rule = &escalated
+			}
+		}
+		if len(ms) == 0 {
+			return args, false
+		}
+		masked := apply(args, ms, report)
+		return masked, masked != args
+	}
+	v, changed := c.scanValue(v, report)
+	if !changed {
+		return args, false
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return args, false
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), true
+}
+
+// scanValue 递归检查 JSON 值中的字符串
+func (c *Checker) scanValue(v any, report *Report) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		return c.scan(v, report)
+	case []any:
+		changed := false
+		for i := range v {
+			var ok bool
+			if v[i], ok = c.scanValue(v[i], report); ok {
+				changed = true
+			}
+		}
+		return v, changed
+	case map[string]any:
+		changed := false
+		for k := range v {
+			var ok bool
+			if v[k], ok = c.scanValue(v[k], report); ok {
+				changed = true
+			}
+		}
+		return v, changed
+	}
+	return v, false
+}
+
+// Request 检查消息正文、文本片段、工具调用参数与 Embedding 输入，返回替换命中内容后的请求副本，
+// 没有替换时返回原请求。命中 block 规则时返回的副本同样替换了命中的内容，可以安全地写入日志。
+// 思考内容带有上游签名，修改后无法通过校验，因此不检查
+func (c *Checker) Request(req *model.InternalLLMRequest) (*model.InternalLLMRequest, Report) {
+	var report Report
+	if c == nil {
+		return req, report
+	}
+	cp := *req
+	cp.Messages = slices.Clone(req.Messages)
+	changed := false
+	for i := range cp.Messages {
+		msg := &cp.Messages[i]
+		var ok bool
+		if msg.Content.Content, ok = c.scanPtr(msg.Content.Content, &report); ok {
+			changed = true
+		}
+		if len(msg.Content.MultipleContent) > 0 {
+			parts := slices.Clone(msg.Content.MultipleContent)
+			partsChanged := false
+			for j := range parts {
+				if parts[j].Text, ok = c.scanPtr(parts[j].Text, &report); ok {
+					partsChanged = true
+				}
+			}
+			if partsChanged {
+				msg.Content.MultipleContent = parts
+				changed = true
+			}
+		}
+		if len(msg.ToolCalls) > 0 {
+			calls := slices.Clone(msg.ToolCalls)
+			callsChanged := false
+			for j := range calls {
+				if args, ok := c.scanArguments(calls[j].Function.Arguments, &report); ok {
+					calls[j].Function.Arguments = args
+					callsChanged = true
+				}
+			}
+			if callsChanged {
+				msg.ToolCalls = calls
+				changed = true
+			}
+		}
+	}
+	if in := req.EmbeddingInput; in != nil {
+		input := model.EmbeddingInput{Multiple: slices.Clone(in.Multiple)}
+		inputChanged := false
+		if input.Single, inputChanged = c.scanPtr(in.Single, &report); inputChanged {
+			changed = true
+		}
+		for j := range input.Multiple {
+			if s, ok := c.scan(input.Multiple[j], &report); ok {
+				input.Multiple[j] = s
+				inputChanged = true
+			}
+		}
+		if inputChanged {
+			cp.EmbeddingInput = &input
+			changed = true
+		}
+	}
+	if !changed {
+		return req, report
+	}
+	return &cp, report
+}
+
+// Response 检查非流式响应的正文与思考内容，命中 block 规则的 choice 替换为拦截提示
+func (c *Checker) Response(resp *model.InternalLLMResponse) Report {
+	var report Report
+	if c == nil || !c.response {
+		return report
+	}
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		msg := choice.Message
+		if msg == nil {
+			continue
+		}
+		var r Report
+		msg.Content.Content, _ = c.scanPtr(msg.Content.Content, &r)
+		for j := range msg.Content.MultipleContent {
+			msg.Content.MultipleContent[j].Text, _ = c.scanPtr(msg.Content.MultipleContent[j].Text, &r)
+		}
+		if reasoning := msg.GetReasoningContent(); reasoning != "" {
+			if s, ok := c.scan(reasoning, &r); ok {
+				msg.ReasoningContent, msg.Reasoning = &s, nil
+			}
+		}
+		if r.Blocked != "" {
+			blockChoice(choice, msg)
+		}
+		report.merge(r)
+	}
+	return report
+}
+
+// blockChoice 把 choice 的内容替换为拦截提示并结束
+func blockChoice(choice *model.Choice, msg *model.Message) {
+	notice := BlockedNotice
+	*msg = model.Message{Role: msg.Role, Content: model.MessageContent{Content: &notice}}
+	reason := finishContentFilter
+	choice.FinishReason = &reason
+}
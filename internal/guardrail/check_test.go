@@ -0,0 +1,151 @@
+package guardrail
+
+import (
+	"encoding/json"
+	"testing"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// testChecker 替换邮箱，拦截 API Key，检查响应
+func testChecker() *Checker {
+	return New(&dbmodel.GuardrailPolicy{Enabled: true, Response: true, Rules: []dbmodel.GuardrailRule{
+		{Detector: "email", Action: dbmodel.GuardrailActionMask},
+		{Detector: "api_key", Action: dbmodel.GuardrailActionBlock},
+	}})
+}
+
+func TestScanArguments(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		expected string
+		changed  bool
+		blocked  string
+	}{
+		{name: "no match", args: `{"to":"team"}`, expected: `{"to":"team"}`},
+		{name: "string value", args: `{"to":"a@b.io","n":1}`, expected: `{"n":1,"to":"[EMAIL]"}`, changed: true},
+		{name: "nested values", args: `{"cc":["x <a@b.io>",{"m":"c@d.io"}],"big":12345678901234567890}`, expected: `{"big":12345678901234567890,"cc":["x <[EMAIL]>",{"m":"[EMAIL]"}]}`, changed: true},
+		{name: "key is not masked", args: `{"a@b.io":1}`, expected: `{"a@b.io":1}`},
+		{name: "mask in invalid json blocks", args: `{"to":"a@b.io"`, expected: `{"to":"[EMAIL]"`, changed: true, blocked: "email"},
+		{name: "trailing data is invalid", args: `{"to":"x"} a@b.io`, expected: `{"to":"x"} [EMAIL]`, changed: true, blocked: "email"},
+		{name: "empty", args: ``, expected: ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report Report
+			got, changed := testChecker().scanArguments(tt.args, &report)
+			if got != tt.expected || changed != tt.changed || report.Blocked != tt.blocked {
+				t.Errorf("expected %s %v %q, got %s %v %q", tt.expected, tt.changed, tt.blocked, got, changed, report.Blocked)
+			}
+			if changed && tt.blocked == "" && !json.Valid([]byte(got)) {
+				t.Errorf("expected valid json, got %s", got)
+			}
+		})
+	}
+}
+
+func TestCheckerRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.InternalLLMRequest
+		check   func(t *testing.T, got *model.InternalLLMRequest)
+		blocked string
+		same    bool // 没有替换时返回原请求
+	}{
+		{
+			name:    "clean request",
+			request: &model.InternalLLMRequest{Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("hello")}}}},
+			same:    true,
+		},
+		{
+			name: "message text and parts",
+			request: &model.InternalLLMRequest{Messages: []model.Message{
+				{Role: "system", Content: model.MessageContent{Content: strPtr("reply to a@b.io")}},
+				{Role: "user", Content: model.MessageContent{MultipleContent: []model.MessageContentPart{{Type: "text", Text: strPtr("me: c@d.io")}}}},
+			}},
+			check: func(t *testing.T, got *model.InternalLLMRequest) {
+				if *got.Messages[0].Content.Content != "reply to [EMAIL]" || *got.Messages[1].Content.MultipleContent[0].Text != "me: [EMAIL]" {
+					t.Errorf("expected masked messages, got %+v", got.Messages)
+				}
+			},
+		},
+		{
+			name: "tool call arguments",
+			request: &model.InternalLLMRequest{Messages: []model.Message{{Role: "assistant", ToolCalls: []model.ToolCall{
+				{ID: "1", Function: model.FunctionCall{Name: "send", Arguments: `{"to":"a@b.io"}`}},
+			}}}},
+			check: func(t *testing.T, got *model.InternalLLMRequest) {
+				if got.Messages[0].ToolCalls[0].Function.Arguments != `{"to":"[EMAIL]"}` {
+					t.Errorf("expected masked arguments, got %s", got.Messages[0].ToolCalls[0].Function.Arguments)
+				}
+			},
+		},
+		{
+			name:    "embedding input",
+			request: &model.InternalLLMRequest{EmbeddingInput: &model.EmbeddingInput{Multiple: []string{"x", "a@b.io"}}},
+			check: func(t *testing.T, got *model.InternalLLMRequest) {
+				if got.EmbeddingInput.Multiple[1] != "[EMAIL]" {
+					t.Errorf("expected masked input, got %v", got.EmbeddingInput.Multiple)
+				}
+			},
+		},
+		{
+			name:    "blocked request is masked for the log",
+			request: &model.InternalLLMRequest{Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("key sk-abcdefghijklmnopqrstuvwx")}}}},
+			blocked: "api_key",
+			check: func(t *testing.T, got *model.InternalLLMRequest) {
+				if *got.Messages[0].Content.Content != "key [API_KEY]" {
+					t.Errorf("expected masked content, got %s", *got.Messages[0].Content.Content)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := json.Marshal(tt.request)
+			got, report := testChecker().Request(tt.request)
+			if report.Blocked != tt.blocked {
+				t.Errorf("expected blocked %q, got %q", tt.blocked, report.Blocked)
+			}
+			if (got == tt.request) != tt.same {
+				t.Fatalf("expected same request %v, got %v", tt.same, got == tt.request)
+			}
+			if tt.check != nil {
+				tt.check(t, got)
+			}
+			// 原请求在切换渠道时仍会使用，不能被修改
+			if after, _ := json.Marshal(tt.request); string(after) != string(before) {
+				t.Fatalf("expected the original request to stay unchanged, got %s", after)
+			}
+		})
+	}
+}
+
+func TestCheckerResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		message   *model.Message
+		content   string
+		reasoning string
+		finish    string
+	}{
+		{name: "masked", message: &model.Message{Role: "assistant", Content: model.MessageContent{Content: strPtr("write to a@b.io")}, Reasoning: strPtr("a@b.io")}, content: "write to [EMAIL]", reasoning: "[EMAIL]", finish: "stop"},
+		{name: "blocked", message: &model.Message{Role: "assistant", Content: model.MessageContent{Content: strPtr("sk-abcdefghijklmnopqrstuvwx")}}, content: BlockedNotice, finish: finishContentFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &model.InternalLLMResponse{Choices: []model.Choice{{Message: tt.message, FinishReason: strPtr("stop")}}}
+			testChecker().Response(resp)
+			msg := resp.Choices[0].Message
+			if *msg.Content.Content != tt.content || msg.GetReasoningContent() != tt.reasoning || *resp.Choices[0].FinishReason != tt.finish {
+				t.Errorf("expected %q %q %q, got %q %q %q", tt.content, tt.reasoning, tt.finish, *msg.Content.Content, msg.GetReasoningContent(), *resp.Choices[0].FinishReason)
+			}
+		})
+	}
+}
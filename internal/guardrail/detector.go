@@ -0,0 +1,108 @@
+package guardrail
+
+import "regexp"
+
+// patternDetector 由一组正则组成的检测器，verify 不为空时用于排除格式相符但校验失败的命中
+type patternDetector struct {
+	name     string
+	patterns []*regexp.Regexp
+	verify   func(string) bool
+}
+
+func (d *patternDetector) Name() string { return d.name }
+
+func (d *patternDetector) Find(text string) [][]int {
+	var locs [][]int
+	for _, re := range d.patterns {
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if d.verify == nil || d.verify(text[loc[0]:loc[1]]) {
+				locs = append(locs, loc)
+			}
+		}
+	}
+	return locs
+}
+
+func init() {
+	Register(&patternDetector{
+		name:     "email",
+		patterns: []*regexp.Regexp{regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	})
+	Register(&patternDetector{
+		name: "phone",
+		patterns: []*regexp.Regexp{
+			// 中国大陆手机号，可带 +86 前缀
+			regexp.MustCompile(`(?:\+?86[- ]?|\b)1[3-9]\d{9}\b`),
+			// 国际 E.164 格式
+			regexp.MustCompile(`\+[1-9]\d{7,14}\b`),
+			// 北美格式 (555) 123-4567、555-123-4567
+			regexp.MustCompile(`(?:\(\d{3}\)\s?|\b\d{3}[-.])\d{3}[-.]\d{4}\b`),
+		},
+	})
+	Register(&patternDetector{
+		name: "id_number",
+		patterns: []*regexp.Regexp{
+			// 中国居民身份证号，校验末位
+			regexp.MustCompile(`\b[1-9]\d{5}(?:19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`),
+			// 美国社会安全号码
+			regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		},
+		verify: verifyIDNumber,
+	})
+	Register(&patternDetector{
+		name: "api_key",
+		patterns: []*regexp.Regexp{
+			regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),                 // OpenAI、Anthropic 等
+			regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),           // AWS Access Key
+			regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}`),            // GitHub Token
+			regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}`),          // GitHub Fine-grained Token
+			regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),          // Slack Token
+			regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),                 // Google API Key
+			regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`),      // 私钥
+			regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]{20,}=*`), // Authorization 头
+		},
+	})
+	Register(&patternDetector{
+		name:     "credit_card",
+		patterns: []*regexp.Regexp{regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)},
+		verify:   luhn,
+	})
+}
+
+// verifyIDNumber 校验身份证号的末位校验码，其他格式直接通过
+func verifyIDNumber(s string) bool {
+	if len(s) != 18 {
+		return true
+	}
+	weights := [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+	check := "10X98765432"[sum%11]
+	last := s[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return last == check
+}
+
+// luhn 使用 Luhn 算法校验银行卡号，排除普通的长数字
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && n <= 19 && sum%10 == 0
+}
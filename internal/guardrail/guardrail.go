@@ -0,0 +1,295 @@
+package guardrail
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/utils/log"
+)
+
+// 内容安全检查在请求转发前运行：按分组与 API Key 的策略检测消息中的敏感信息，
+// 命中后拒绝请求、替换命中的内容或仅记录日志。策略开启 response 时同样检查返回给客户端的内容
+
+const (
+	DetectorRegex    = "regex"
+	DetectorKeywords = "keywords"
+)
+
+// Detector 敏感信息检测器
+type Detector interface {
+	// Name 检测器名称，规则通过名称引用
+	Name() string
+	// Find 返回文本中所有命中的字节区间 [start, end)
+	Find(text string) [][]int
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Detector)
+)
+
+// Register 注册检测器，同名检测器会被替换
+func Register(d Detector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[d.Name()] = d
+}
+
+// Get 按名称获取检测器
+func Get(name string) (Detector, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	d, ok := registry[name]
+	return d, ok
+}
+
+// Names 返回已注册的检测器名称
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate 校验策略中的检测器、正则与动作
+func Validate(p *dbmodel.GuardrailPolicy) error {
+	if p == nil {
+		return nil
+	}
+	for i, r := range p.Rules {
+		if _, err := compileRule(r); err != nil {
+			return fmt.Errorf("guardrail.rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+type rule struct {
+	detector    Detector
+	action      dbmodel.GuardrailAction
+	replacement string
+}
+
+// 自定义正则与词典在每次请求时按规则编译，编译结果按内容缓存
+var compiled sync.Map
+
+func compileRule(r dbmodel.GuardrailRule) (rule, error) {
+	switch r.Action {
+	case dbmodel.GuardrailActionBlock, dbmodel.GuardrailActionMask, dbmodel.GuardrailActionLog:
+	default:
+		return rule{}, fmt.Errorf("action must be block, mask or log")
+	}
+
+	var d Detector
+	switch r.Detector {
+	case DetectorRegex:
+		if r.Pattern == "" {
+			return rule{}, fmt.Errorf("pattern is required for regex detector")
+		}
+		key := DetectorRegex + "\x00" + r.Pattern
+		if v, ok := compiled.Load(key); ok {
+			d = v.(Detector)
+			break
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid pattern: %w", err)
+		}
+		d = &patternDetector{name: DetectorRegex, patterns: []*regexp.Regexp{re}}
+		compiled.Store(key, d)
+	case DetectorKeywords:
+		words := make([]string, 0, len(r.Keywords))
+		for _, w := range r.Keywords {
+			if w = strings.TrimSpace(w); w != "" {
+				words = append(words, regexp.QuoteMeta(w))
+			}
+		}
+		if len(words) == 0 {
+			return rule{}, fmt.Errorf("keywords are required for keywords detector")
+		}
+		key := DetectorKeywords + "\x00" + strings.Join(words, "|")
+		if v, ok := compiled.Load(key); ok {
+			d = v.(Detector)
+			break
+		}
+		// 较长的词优先匹配，避免只替换较短词的部分
+		sort.SliceStable(words, func(i, j int) bool { return len(words[i]) > len(words[j]) })
+		d = &patternDetector{name: DetectorKeywords, patterns: []*regexp.Regexp{regexp.MustCompile(`(?i)` + strings.Join(words, "|"))}}
+		compiled.Store(key, d)
+	default:
+		var ok bool
+		if d, ok = Get(r.Detector); !ok {
+			return rule{}, fmt.Errorf("unknown detector %q, available: %v", r.Detector, append(Names(), DetectorRegex, DetectorKeywords))
+		}
+	}
+
+	replacement := r.Replacement
+	if replacement == "" {
+		replacement = "[" + strings.ToUpper(d.Name()) + "]"
+	}
+	return rule{detector: d, action: r.Action, replacement: replacement}, nil
+}
+
+// Checker 合并后的检查规则
+type Checker struct {
+	rules    []rule
+	response bool
+}
+
+// New 合并多个策略的规则，均未启用时返回 nil。策略保存时已校验，无效的规则跳过
+func New(policies ...*dbmodel.GuardrailPolicy) *Checker {
+	c := &Checker{}
+	for _, p := range policies {
+		if !p.Active() {
+			continue
+		}
+		for _, r := range p.Rules {
+			cr, err := compileRule(r)
+			if err != nil {
+				log.Warnf("guardrail: skip invalid rule %q: %v", r.Detector, err)
+				continue
+			}
+			c.rules = append(c.rules, cr)
+		}
+		c.response = c.response || p.Response
+	}
+	if len(c.rules) == 0 {
+		return nil
+	}
+	return c
+}
+
+// match 一段命中的文本，重叠的命中合并后取最严格的动作
+type match struct {
+	start, end int
+	rule       *rule
+}
+
+var actionRank = map[dbmodel.GuardrailAction]int{
+	dbmodel.GuardrailActionLog:   0,
+	dbmodel.GuardrailActionMask:  1,
+	dbmodel.GuardrailActionBlock: 2,
+}
+
+func (c *Checker) find(text string) []match {
+	if text == "" {
+		return nil
+	}
+	var ms []match
+	for i := range c.rules {
+		for _, loc := range c.rules[i].detector.Find(text) {
+			if loc[1] <= loc[0] {
+				continue
+			}
+			ms = append(ms, match{start: loc[0], end: loc[1], rule: &c.rules[i]})
+		}
+	}
+	if len(ms) < 2 {
+		return ms
+	}
+	sort.Slice(ms, func(i, j int) bool {
+		if ms[i].start != ms[j].start {
+			return ms[i].start < ms[j].start
+		}
+		return ms[i].end > ms[j].end
+	})
+	merged := ms[:1]
+	for _, m := range ms[1:] {
+		last := &merged[len(merged)-1]
+		if m.start >= last.end {
+			merged = append(merged, m)
+			continue
+		}
+		last.end = max(last.end, m.end)
+		if actionRank[m.rule.action] > actionRank[last.rule.action] {
+			last.rule = m.rule
+		}
+	}
+	return merged
+}
+
+// apply 替换 mask 与 block 的命中内容，并把命中记录到 report
+func apply(text string, ms []match, report *Report) string {
+	var sb strings.Builder
+	pos := 0
+	for _, m := range ms {
+		report.add(m.rule)
+		if m.rule.action == dbmodel.GuardrailActionLog {
+			continue
+		}
+		sb.WriteString(text[pos:m.start])
+		sb.WriteString(m.rule.replacement)
+		pos = m.end
+	}
+	if pos == 0 {
+		return text
+	}
+	sb.WriteString(text[pos:])
+	return sb.String()
+}
+
+// Hit 一个检测器的命中次数
+type Hit struct {
+	Detector string
+	Action   dbmodel.GuardrailAction
+	Count    int
+}
+
+// Report 检查结果，只记录检测器与次数，不包含命中的内容
+type Report struct {
+	Blocked string // 命中 block 规则时为检测器名称
+	Hits    []Hit
+}
+
+func (r *Report) add(ru *rule) {
+	if ru.action == dbmodel.GuardrailActionBlock && r.Blocked == "" {
+		r.Blocked = ru.detector.Name()
+	}
+	for i := range r.Hits {
+		if r.Hits[i].Detector == ru.detector.Name() && r.Hits[i].Action == ru.action {
+			r.Hits[i].Count++
+			return
+		}
+	}
+	r.Hits = append(r.Hits, Hit{Detector: ru.detector.Name(), Action: ru.action, Count: 1})
+}
+
+func (r *Report) merge(o Report) {
+	if r.Blocked == "" {
+		r.Blocked = o.Blocked
+	}
+	for _, h := range o.Hits {
+		found := false
+		for i := range r.Hits {
+			if r.Hits[i].Detector == h.Detector && r.Hits[i].Action == h.Action {
+				r.Hits[i].Count += h.Count
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.Hits = append(r.Hits, h)
+		}
+	}
+}
+
+// Empty 是否没有任何命中
+func (r Report) Empty() bool {
+	return len(r.Hits) == 0
+}
+
+func (r Report) String() string {
+	parts := make([]string, 0, len(r.Hits))
+	for _, h := range r.Hits {
+		parts = append(parts, fmt.Sprintf("%s(%s)x%d", h.Detector, h.Action, h.Count))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,129 @@
+package guardrail
+
+import (
+	"strings"
+	"testing"
+
+	dbmodel "octopus/internal/model"
+)
+
+func TestDetectors(t *testing.T) {
+	tests := []struct {
+		detector string
+		text     string
+		expected []string
+	}{
+		{detector: "email", text: "mail a.b+c@mail.example.com now", expected: []string{"a.b+c@mail.example.com"}},
+		{detector: "phone", text: "call 13812345678 or +86 13912345678", expected: []string{"13812345678", "+86 13912345678"}},
+		{detector: "phone", text: "us (555) 123-4567", expected: []string{"(555) 123-4567"}},
+		{detector: "id_number", text: "id 11010519491231002X ssn 123-45-6789", expected: []string{"11010519491231002X", "123-45-6789"}},
+		{detector: "id_number", text: "bad checksum 110105194912310021", expected: nil},
+		{detector: "api_key", text: "key sk-abcdefghijklmnopqrstuvwx end", expected: []string{"sk-abcdefghijklmnopqrstuvwx"}},
+		{detector: "api_key", text: "short sk-abc", expected: nil},
+		{detector: "credit_card", text: "card 4111 1111 1111 1111", expected: []string{"4111 1111 1111 1111"}},
+		{detector: "credit_card", text: "order 4111 1111 1111 1112", expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.detector+"/"+tt.text, func(t *testing.T) {
+			d, ok := Get(tt.detector)
+			if !ok {
+				t.Fatalf("expected detector %s to be registered", tt.detector)
+			}
+			var got []string
+			for _, loc := range d.Find(tt.text) {
+				got = append(got, tt.text[loc[0]:loc[1]])
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []dbmodel.GuardrailRule
+		wantErr string
+	}{
+		{name: "builtin", rules: []dbmodel.GuardrailRule{{Detector: "email", Action: dbmodel.GuardrailActionMask}}},
+		{name: "regex", rules: []dbmodel.GuardrailRule{{Detector: DetectorRegex, Pattern: `\d+`, Action: dbmodel.GuardrailActionLog}}},
+		{name: "unknown detector", rules: []dbmodel.GuardrailRule{{Detector: "dna", Action: dbmodel.GuardrailActionLog}}, wantErr: `unknown detector "dna"`},
+		{name: "invalid action", rules: []dbmodel.GuardrailRule{{Detector: "email", Action: "drop"}}, wantErr: "action must be"},
+		{name: "invalid pattern", rules: []dbmodel.GuardrailRule{{Detector: DetectorRegex, Pattern: `(`, Action: dbmodel.GuardrailActionLog}}, wantErr: "invalid pattern"},
+		{name: "empty keywords", rules: []dbmodel.GuardrailRule{{Detector: DetectorKeywords, Keywords: []string{" "}, Action: dbmodel.GuardrailActionLog}}, wantErr: "keywords are required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&dbmodel.GuardrailPolicy{Enabled: true, Rules: tt.rules})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestCheckerFind(t *testing.T) {
+	checker := New(&dbmodel.GuardrailPolicy{Enabled: true, Rules: []dbmodel.GuardrailRule{
+		{Detector: DetectorKeywords, Keywords: []string{"falcon", "project falcon"}, Action: dbmodel.GuardrailActionLog},
+		{Detector: DetectorRegex, Pattern: `falcon \d+`, Action: dbmodel.GuardrailActionMask, Replacement: "***"},
+		{Detector: "email", Action: dbmodel.GuardrailActionBlock},
+	}})
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		blocked  string
+		hits     string
+	}{
+		{name: "no match", text: "hello", expected: "hello"},
+		{name: "log only", text: "about Falcon", expected: "about Falcon", hits: "keywords(log)x1"},
+		{name: "longer keyword wins", text: "Project Falcon launch", expected: "Project Falcon launch", hits: "keywords(log)x1"},
+		{name: "overlap takes strictest action", text: "falcon 9 lifts", expected: "*** lifts", hits: "regex(mask)x1"},
+		{name: "block", text: "mail a@b.io", expected: "mail [EMAIL]", blocked: "email", hits: "email(block)x1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var report Report
+			got, _ := checker.scan(tt.text, &report)
+			if got != tt.expected || report.Blocked != tt.blocked || report.String() != tt.hits {
+				t.Errorf("expected %q %q %q, got %q %q %q", tt.expected, tt.blocked, tt.hits, got, report.Blocked, report.String())
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	email := dbmodel.GuardrailRule{Detector: "email", Action: dbmodel.GuardrailActionMask}
+	tests := []struct {
+		name     string
+		policies []*dbmodel.GuardrailPolicy
+		rules    int
+		response bool
+	}{
+		{name: "none", policies: []*dbmodel.GuardrailPolicy{nil, nil}},
+		{name: "disabled", policies: []*dbmodel.GuardrailPolicy{{Rules: []dbmodel.GuardrailRule{email}}}},
+		{name: "merged", policies: []*dbmodel.GuardrailPolicy{{Enabled: true, Rules: []dbmodel.GuardrailRule{email}}, {Enabled: true, Response: true, Rules: []dbmodel.GuardrailRule{email}}}, rules: 2, response: true},
+		{name: "invalid rule skipped", policies: []*dbmodel.GuardrailPolicy{{Enabled: true, Rules: []dbmodel.GuardrailRule{email, {Detector: "dna"}}}}, rules: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.policies...)
+			if tt.rules == 0 {
+				if c != nil {
+					t.Fatalf("expected nil checker, got %+v", c)
+				}
+				return
+			}
+			if c == nil || len(c.rules) != tt.rules || c.response != tt.response {
+				t.Fatalf("expected %d rules with response %v, got %+v", tt.rules, tt.response, c)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package guardrail
+
+import (
+	"unicode/utf8"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+)
+
+// windowSize 流式响应中暂存的文本长度(字节)。敏感信息可能被拆分到多个分片中，
+// 末尾的文本需要等后续分片到达、确认不会组成命中后再输出
+const windowSize = 128
+
+const (
+	fieldContent = iota
+	fieldReasoning
+)
+
+type windowKey struct {
+	index int
+	field int
+}
+
+// Stream 流式响应的检查状态：各 choice 的正文与思考内容分别使用滑动窗口
+type Stream struct {
+	checker *Checker
+	windows map[windowKey]string
+	blocked map[int]bool
+}
+
+// NewStream 返回流式检查状态，策略未开启响应检查时返回 nil
+func (c *Checker) NewStream() *Stream {
+	if c == nil || !c.response {
+		return nil
+	}
+	return &Stream{
+		checker: c,
+		windows: make(map[windowKey]string),
+		blocked: make(map[int]bool),
+	}
+}
+
+// Chunk 检查流式分片，安全的文本立即输出，窗口内的文本暂存到后续分片或结束分片。
+// 命中 block 规则的 choice 以拦截提示结束，之后的分片不再输出该 choice
+func (s *Stream) Chunk(chunk *model.InternalLLMResponse) Report {
+	return s.process(chunk, false)
+}
+
+// Flush 检查流式分片并输出分片中各 choice 暂存的全部文本，用于网关自行插入的完整内容
+func (s *Stream) Flush(chunk *model.InternalLLMResponse) Report {
+	return s.process(chunk, true)
+}
+
+func (s *Stream) process(chunk *model.InternalLLMResponse, flush bool) Report {
+	var report Report
+	if s == nil {
+		return report
+	}
+	kept := chunk.Choices[:0]
+	for _, choice := range chunk.Choices {
+		if s.blocked[choice.Index] {
+			continue
+		}
+		final := flush || choice.FinishReason != nil
+		if choice.Delta == nil {
+			if !final || !s.pending(choice.Index) {
+				kept = append(kept, choice)
+				continue
+			}
+			choice.Delta = &model.Message{}
+		}
+		delta := choice.Delta
+		hadText := delta.Content.Content != nil || delta.GetReasoningContent() != ""
+		var r Report
+
+		var content string
+		if delta.Content.Content != nil {
+			content = *delta.Content.Content
+		}
+		if delta.Content.Content != nil || final {
+			content = s.feed(windowKey{choice.Index, fieldContent}, content, final, &r)
+			setText(&delta.Content.Content, content)
+		}
+		reasoning := delta.GetReasoningContent()
+		if reasoning != "" || final {
+			reasoning = s.feed(windowKey{choice.Index, fieldReasoning}, reasoning, final, &r)
+			delta.Reasoning = nil
+			setText(&delta.ReasoningContent, reasoning)
+		}
+
+		if r.Blocked != "" {
+			s.blocked[choice.Index] = true
+			delete(s.windows, windowKey{choice.Index, fieldContent})
+			delete(s.windows, windowKey{choice.Index, fieldReasoning})
+			blockChoice(&choice, delta)
+		}
+		report.merge(r)
+		// 文本全部暂存在窗口中的分片不再输出
+		if hadText && choice.FinishReason == nil && isEmpty(delta) {
+			continue
+		}
+		kept = append(kept, choice)
+	}
+	chunk.Choices = kept
+	return report
+}
+
+func (s *Stream) pending(index int) bool {
+	return s.windows[windowKey{index, fieldContent}] != "" || s.windows[windowKey{index, fieldReasoning}] != ""
+}
+
+// feed 把文本追加到窗口，返回可以输出的部分。与窗口末尾重叠的命中整体留在窗口中，
+// 窗口内任意位置命中 block 规则时立即拦截
+func (s *Stream) feed(key windowKey, text string, final bool, report *Report) string {
+	buf := s.windows[key] + text
+	ms := s.checker.find(buf)
+	for _, m := range ms {
+		if m.rule.action == dbmodel.GuardrailActionBlock {
+			report.add(m.rule)
+			return ""
+		}
+	}
+
+	cut := len(buf)
+	if !final {
+		cut -= windowSize
+		if cut <= 0 {
+			s.windows[key] = buf
+			return ""
+		}
+		for cut > 0 && !utf8.RuneStart(buf[cut]) {
+			cut--
+		}
+		for _, m := range ms {
+			if m.start < cut && m.end > cut {
+				cut = m.start
+				break
+			}
+		}
+	}
+	emit := ms[:0]
+	for _, m := range ms {
+		if m.end <= cut {
+			emit = append(emit, m)
+		}
+	}
+	out := apply(buf[:cut], emit, report)
+	if rest := buf[cut:]; rest != "" {
+		s.windows[key] = rest
+	} else {
+		delete(s.windows, key)
+	}
+	return out
+}
+
+func isEmpty(msg *model.Message) bool {
+	return msg.Role == "" && msg.Content.Content == nil && len(msg.Content.MultipleContent) == 0 && msg.Refusal == "" &&
+		msg.ReasoningContent == nil && msg.Reasoning == nil && msg.ReasoningSignature == nil && len(msg.ToolCalls) == 0 && len(msg.Images) == 0
+}
+
+// setText 设置分片的文本，结果为空时清除该字段
+func setText(field **string, text string) {
+	if text == "" {
+		*field = nil
+		return
+	}
+	*field = &text
+}
@@ -0,0 +1,50 @@
+package guardrail
+
+import (
+	"strings"
+	"testing"
+
+	"octopus/internal/transformer/model"
+)
+
+func TestStream(t *testing.T) {
+	filler := strings.Repeat("x", windowSize)
+	tests := []struct {
+		name     string
+		chunks   []string // 空字符串表示结束分片
+		expected string
+		finish   string
+	}{
+		{name: "short text held until finish", chunks: []string{"hello", ""}, expected: "hello", finish: "stop"},
+		{name: "match split across chunks", chunks: []string{filler + "mail a@b", ".io " + filler, ""}, expected: filler + "mail [EMAIL] " + filler, finish: "stop"},
+		{name: "match at the end of a long chunk", chunks: []string{filler + filler + " a@b.io", ""}, expected: filler + filler + " [EMAIL]", finish: "stop"},
+		{name: "block ends the choice", chunks: []string{"key sk-abcdef", "ghijklmnopqrstuvwx", "more", ""}, expected: BlockedNotice, finish: finishContentFilter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testChecker().NewStream()
+			var content strings.Builder
+			finish := ""
+			for _, text := range tt.chunks {
+				chunk := &model.InternalLLMResponse{Choices: []model.Choice{{}}}
+				if text == "" {
+					chunk.Choices[0].FinishReason = strPtr("stop")
+				} else {
+					chunk.Choices[0].Delta = &model.Message{Content: model.MessageContent{Content: strPtr(text)}}
+				}
+				s.Chunk(chunk)
+				for _, choice := range chunk.Choices {
+					if choice.Delta != nil && choice.Delta.Content.Content != nil {
+						content.WriteString(*choice.Delta.Content.Content)
+					}
+					if choice.FinishReason != nil {
+						finish = *choice.FinishReason
+					}
+				}
+			}
+			if content.String() != tt.expected || finish != tt.finish {
+				t.Errorf("expected %q %q, got %q %q", tt.expected, tt.finish, content.String(), finish)
+			}
+		})
+	}
+}
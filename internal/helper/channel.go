@@ -4,11 +4,16 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"octopus/internal/client"
+	"octopus/internal/conf"
+	"octopus/internal/fixture"
 	"octopus/internal/model"
 	"octopus/internal/op"
+	"octopus/internal/transformer/outbound"
+	"octopus/internal/transformer/outbound/mock"
 	"octopus/internal/utils/log"
 	"octopus/internal/utils/xstrings"
 	"github.com/dlclark/regexp2"
@@ -18,13 +23,51 @@ func ChannelHttpClient(channel *model.Channel) (*http.Client, error) {
 	if channel == nil {
 		return nil, errors.New("channel is nil")
 	}
+	// 模拟渠道在进程内生成响应，请求、探测与测速都不访问网络
+	if channel.Type == outbound.OutboundTypeMock {
+		return &http.Client{Transport: &mock.Transport{}}, nil
+	}
+	// replay:// 渠道回放录制的夹具，不访问网络
+	if strings.HasPrefix(channel.GetBaseUrl(), "replay://") {
+		return &http.Client{Transport: &fixture.Transport{}}, nil
+	}
+	var httpClient *http.Client
+	var err error
 	if !channel.Proxy {
-		return client.GetHTTPClientSystemProxy(false)
+		httpClient, err = client.GetHTTPClientSystemProxy(false)
 	} else if channel.ChannelProxy == nil || strings.TrimSpace(*channel.ChannelProxy) == "" {
-		return client.GetHTTPClientSystemProxy(true)
+		httpClient, err = client.GetHTTPClientSystemProxy(true)
 	} else {
-		return client.GetHTTPClientCustomProxy(strings.TrimSpace(*channel.ChannelProxy))
+		httpClient, err = client.GetHTTPClientCustomProxy(strings.TrimSpace(*channel.ChannelProxy))
+	}
+	if err != nil || !recordEnabled(channel) {
+		return httpClient, err
+	}
+	secrets := make([]string, 0, len(channel.Keys))
+	for _, key := range channel.Keys {
+		secrets = append(secrets, key.ChannelKey)
+	}
+	// 共享的客户端不能修改，录制时包装一份新的客户端
+	cfg := conf.Get().Record
+	recorded := *httpClient
+	recorded.Transport = &fixture.Recorder{
+		Base:         httpClient.Transport,
+		Dir:          cfg.Dir,
+		Channel:      channel.Name,
+		ChannelType:  model.ChannelTypeName(channel.Type),
+		Secrets:      secrets,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		MaxFiles:     cfg.MaxFiles,
+	}
+	return &recorded, nil
+}
+
+func recordEnabled(channel *model.Channel) bool {
+	cfg := conf.Get().Record
+	if !cfg.Enabled || cfg.Dir == "" {
+		return false
 	}
+	return len(cfg.Channels) == 0 || slices.Contains(cfg.Channels, channel.Name)
 }
 
 func ChannelBaseUrlDelayUpdate(channel *model.Channel, ctx context.Context) {
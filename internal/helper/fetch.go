@@ -8,6 +8,7 @@ import (
 
 	"octopus/internal/model"
 	"octopus/internal/transformer/outbound"
+	"octopus/internal/transformer/outbound/gemini"
 )
 
 func FetchModels(ctx context.Context, request model.Channel) ([]string, error) {
@@ -66,7 +67,9 @@ func fetchGeminiModels(client *http.Client, ctx context.Context, request model.C
 			request.GetBaseUrl()+"/models",
 			nil,
 		)
-		req.Header.Set("X-Goog-Api-Key", request.GetChannelKey().ChannelKey)
+		if err := gemini.Authorize(gemini.WithHTTPClient(ctx, client), req, request.GetChannelKey().ChannelKey); err != nil {
+			return nil, err
+		}
 
 		if pageToken != "" {
 			q := req.URL.Query()
@@ -0,0 +1,90 @@
+package helper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/transformer/outbound"
+	"octopus/internal/transformer/outbound/gemini"
+)
+
+// ProbeChannelKey 使用模型列表接口探测单个 key 的可用性，返回分类结果与说明
+func ProbeChannelKey(ctx context.Context, channel model.Channel, key model.ChannelKey) (model.ChannelKeyHealth, string) {
+	client, err := ChannelHttpClient(&channel)
+	if err != nil {
+		return model.ChannelKeyHealthError, err.Error()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, channel.GetBaseUrl()+"/models", nil)
+	if err != nil {
+		return model.ChannelKeyHealthError, err.Error()
+	}
+	switch channel.Type {
+	case outbound.OutboundTypeAnthropic:
+		req.Header.Set("X-Api-Key", key.ChannelKey)
+		req.Header.Set("Anthropic-Version", "2023-06-01")
+	case outbound.OutboundTypeGemini:
+		if err := gemini.Authorize(gemini.WithHTTPClient(ctx, client), req, key.ChannelKey); err != nil {
+			return model.ChannelKeyHealthError, err.Error()
+		}
+	default:
+		req.Header.Set("Authorization", "Bearer "+key.ChannelKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return model.ChannelKeyHealthError, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return classifyProbeResponse(resp.StatusCode, string(body))
+}
+
+// ChannelTest 依次探测渠道的所有 key，只返回结果，不修改 key 的状态
+func ChannelTest(ctx context.Context, channel model.Channel) []model.ChannelKeyTestResult {
+	results := make([]model.ChannelKeyTestResult, 0, len(channel.Keys))
+	for _, key := range channel.Keys {
+		probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		health, reason := ProbeChannelKey(probeCtx, channel, key)
+		cancel()
+		results = append(results, model.ChannelKeyTestResult{
+			KeyID:   key.ID,
+			Enabled: key.Enabled,
+			Health:  health,
+			Reason:  reason,
+		})
+	}
+	return results
+}
+
+func classifyProbeResponse(statusCode int, body string) (model.ChannelKeyHealth, string) {
+	reason := http.StatusText(statusCode)
+	if msg := strings.TrimSpace(body); msg != "" {
+		if len(msg) > 256 {
+			msg = msg[:256]
+		}
+		reason = msg
+	}
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return model.ChannelKeyHealthValid, ""
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return model.ChannelKeyHealthRevoked, reason
+	case statusCode == http.StatusPaymentRequired:
+		return model.ChannelKeyHealthQuotaExhausted, reason
+	case statusCode == http.StatusTooManyRequests:
+		// 部分上游使用 429 表示额度耗尽，需要根据响应内容区分
+		lower := strings.ToLower(body)
+		for _, s := range []string{"insufficient_quota", "quota", "billing", "credit", "balance"} {
+			if strings.Contains(lower, s) {
+				return model.ChannelKeyHealthQuotaExhausted, reason
+			}
+		}
+		return model.ChannelKeyHealthRateLimited, reason
+	default:
+		return model.ChannelKeyHealthError, reason
+	}
+}
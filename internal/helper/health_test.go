@@ -0,0 +1,33 @@
+package helper
+
+import (
+	"net/http"
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestClassifyProbeResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       model.ChannelKeyHealth
+	}{
+		{name: "ok", statusCode: http.StatusOK, want: model.ChannelKeyHealthValid},
+		{name: "unauthorized", statusCode: http.StatusUnauthorized, want: model.ChannelKeyHealthRevoked},
+		{name: "forbidden", statusCode: http.StatusForbidden, body: `{"error":"key revoked"}`, want: model.ChannelKeyHealthRevoked},
+		{name: "payment required", statusCode: http.StatusPaymentRequired, want: model.ChannelKeyHealthQuotaExhausted},
+		{name: "429 quota", statusCode: http.StatusTooManyRequests, body: `{"error":{"code":"insufficient_quota"}}`, want: model.ChannelKeyHealthQuotaExhausted},
+		{name: "429 rate limit", statusCode: http.StatusTooManyRequests, body: "slow down", want: model.ChannelKeyHealthRateLimited},
+		{name: "server error", statusCode: http.StatusBadGateway, want: model.ChannelKeyHealthError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := classifyProbeResponse(tt.statusCode, tt.body)
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
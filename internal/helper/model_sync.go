@@ -0,0 +1,114 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/utils/diff"
+	"octopus/internal/utils/log"
+	"octopus/internal/utils/xstrings"
+)
+
+// ModelSyncPlan 比较渠道当前模型与上游返回的模型列表，生成变更计划。
+// protectDays > 0 时，已加入分组且最近 protectDays 天内有请求的待删除模型会被标记为受保护
+func ModelSyncPlan(ctx context.Context, channel *model.Channel, fetchModels []string, protectDays int) (*model.ModelSyncPending, error) {
+	removed, added := diff.Diff(xstrings.SplitTrimCompact(",", channel.Model), fetchModels)
+	plan := &model.ModelSyncPending{
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		Added:       added,
+		Removed:     removed,
+	}
+	if len(removed) == 0 || protectDays <= 0 {
+		return plan, nil
+	}
+
+	groups, err := op.GroupList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[string]struct{})
+	for _, g := range groups {
+		for _, item := range g.Items {
+			if item.ChannelID == channel.ID {
+				grouped[item.ModelName] = struct{}{}
+			}
+		}
+	}
+	used, err := op.RelayLogUsedModels(ctx, channel.ID, time.Now().AddDate(0, 0, -protectDays).Unix())
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range removed {
+		_, inGroup := grouped[m]
+		_, hasTraffic := used[m]
+		if inGroup && hasTraffic {
+			plan.Protected = append(plan.Protected, m)
+		}
+	}
+	return plan, nil
+}
+
+// ModelSyncApply 将变更计划应用到渠道；includeProtected 为 false 时跳过受保护的模型。
+// 新增模型的自动分组由调用方负责
+func ModelSyncApply(ctx context.Context, plan *model.ModelSyncPending, includeProtected bool) error {
+	channel, err := op.ChannelGet(plan.ChannelID, ctx)
+	if err != nil {
+		return err
+	}
+
+	skip := make(map[string]struct{}, len(plan.Protected))
+	if !includeProtected {
+		for _, m := range plan.Protected {
+			skip[m] = struct{}{}
+		}
+	}
+	removed := make(map[string]struct{}, len(plan.Removed))
+	for _, m := range plan.Removed {
+		if _, ok := skip[m]; !ok {
+			removed[m] = struct{}{}
+		}
+	}
+
+	oldModels := xstrings.SplitTrimCompact(",", channel.Model)
+	newModels := make([]string, 0, len(oldModels)+len(plan.Added))
+	seen := make(map[string]struct{}, len(oldModels)+len(plan.Added))
+	for _, m := range append(oldModels, plan.Added...) {
+		if _, ok := removed[m]; ok {
+			continue
+		}
+		if _, ok := seen[m]; ok {
+			continue
+		}
+		seen[m] = struct{}{}
+		newModels = append(newModels, m)
+	}
+	deletedModels, addedModels := diff.Diff(oldModels, newModels)
+	if len(deletedModels) == 0 && len(addedModels) == 0 {
+		return nil
+	}
+
+	modelStr := strings.Join(newModels, ",")
+	if _, err := op.ChannelUpdate(&model.ChannelUpdateRequest{
+		ID:    channel.ID,
+		Model: &modelStr,
+	}, ctx); err != nil {
+		return fmt.Errorf("failed to update channel %s: %w", channel.Name, err)
+	}
+	// 批量删除消失的模型对应的 GroupItem
+	if len(deletedModels) > 0 {
+		log.Infof("deleted channel %s models: %v", channel.Name, deletedModels)
+		keys := make([]model.GroupIDAndLLMName, len(deletedModels))
+		for i, m := range deletedModels {
+			keys[i] = model.GroupIDAndLLMName{ChannelID: channel.ID, ModelName: m}
+		}
+		if err := op.GroupItemBatchDelByChannelAndModels(keys, ctx); err != nil {
+			return fmt.Errorf("failed to batch delete group items for channel %s: %w", channel.Name, err)
+		}
+	}
+	return nil
+}
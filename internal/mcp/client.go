@@ -0,0 +1,353 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"octopus/internal/conf"
+)
+
+const (
+	protocolVersion = "2025-06-18"
+	// toolsTTL 工具列表的缓存时间，服务器发送 tools/list_changed 通知时立即失效
+	toolsTTL       = 5 * time.Minute
+	connectTimeout = 30 * time.Second
+	// listTimeout 刷新工具列表的超时时间，刷新由并发的请求共享，不随单个请求取消
+	listTimeout = 30 * time.Second
+)
+
+// errTransportClosed 传输已经断开，请求没有发出，可以重新连接后重试
+var errTransportClosed = errors.New("mcp transport closed")
+
+// errSessionExpired Streamable HTTP 的会话已失效（服务器返回 404），需要重新初始化
+var errSessionExpired = errors.New("mcp session expired")
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// responseID 返回响应对应的请求 ID，消息不是响应时返回 false
+func (m *rpcMessage) responseID() (int64, bool) {
+	if m.Method != "" || len(m.ID) == 0 {
+		return 0, false
+	}
+	var id int64
+	if err := json.Unmarshal(m.ID, &id); err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// transport JSON-RPC 传输
+type transport interface {
+	call(ctx context.Context, method string, params any) (json.RawMessage, error)
+	notify(ctx context.Context, method string, params any) error
+	close() error
+}
+
+type toolInfo struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"inputSchema"`
+}
+
+type callResult struct {
+	Content []struct {
+		Type     string `json:"type"`
+		Text     string `json:"text"`
+		MimeType string `json:"mimeType"`
+		URI      string `json:"uri"`
+		Resource *struct {
+			URI  string `json:"uri"`
+			Text string `json:"text"`
+		} `json:"resource"`
+	} `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+	IsError           bool            `json:"isError"`
+}
+
+// Client 单个 MCP 服务器的客户端，首次使用时连接，断开后在下次使用时重新连接
+type Client struct {
+	cfg conf.MCPServer
+
+	mu        sync.Mutex
+	transport transport
+	tools     []toolInfo
+	toolsAt   time.Time
+	stale     atomic.Bool
+	refresh   singleflight.Group
+}
+
+func newClient(cfg conf.MCPServer) *Client {
+	return &Client{cfg: cfg}
+}
+
+// session 返回已初始化的传输，必要时建立连接
+func (c *Client) session(ctx context.Context) (transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionLocked(ctx)
+}
+
+func (c *Client) sessionLocked(ctx context.Context) (transport, error) {
+	if c.transport != nil {
+		return c.transport, nil
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), connectTimeout)
+	defer cancel()
+
+	onNotify := func(method string) {
+		if method == "notifications/tools/list_changed" {
+			c.stale.Store(true)
+		}
+	}
+	var t transport
+	var err error
+	switch c.cfg.Type {
+	case "stdio":
+		t, err = startStdio(c.cfg, onNotify)
+	case "http":
+		t = newHTTPTransport(c.cfg, onNotify)
+	default:
+		err = fmt.Errorf("unsupported mcp server type %q", c.cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := t.call(ctx, "initialize", map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": conf.APP_NAME, "version": conf.Version},
+	})
+	if err != nil {
+		t.close()
+		return nil, fmt.Errorf("failed to initialize mcp server %s: %w", c.cfg.Name, err)
+	}
+	var initialized struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	_ = json.Unmarshal(result, &initialized)
+	if h, ok := t.(*httpTransport); ok {
+		h.setProtocolVersion(initialized.ProtocolVersion)
+	}
+	if err := t.notify(ctx, "notifications/initialized", nil); err != nil {
+		t.close()
+		return nil, fmt.Errorf("failed to initialize mcp server %s: %w", c.cfg.Name, err)
+	}
+	c.transport = t
+	c.stale.Store(true)
+	return t, nil
+}
+
+// reset 关闭已断开的传输，下次使用时重新连接
+func (c *Client) reset(t transport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resetLocked(t)
+}
+
+func (c *Client) resetLocked(t transport) {
+	if c.transport != nil && c.transport == t {
+		c.transport.close()
+		c.transport = nil
+	}
+}
+
+// Close 关闭连接，stdio 服务器的子进程随之退出
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.transport == nil {
+		return nil
+	}
+	err := c.transport.close()
+	c.transport = nil
+	return err
+}
+
+// listTools 返回服务器提供的工具，结果缓存 toolsTTL。
+// 刷新在锁外进行，并发的请求共享同一次刷新，刷新期间调用工具与关闭连接不会被阻塞
+func (c *Client) listTools(ctx context.Context) ([]toolInfo, error) {
+	c.mu.Lock()
+	if c.tools != nil && !c.stale.Load() && time.Since(c.toolsAt) < toolsTTL {
+		tools := c.tools
+		c.mu.Unlock()
+		return tools, nil
+	}
+	c.mu.Unlock()
+
+	ch := c.refresh.DoChan("tools", func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), listTimeout)
+		defer cancel()
+		return c.fetchTools(ctx)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]toolInfo), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchTools 请求完整的工具列表并更新缓存，连接断开时重新连接一次
+func (c *Client) fetchTools(ctx context.Context) ([]toolInfo, error) {
+	for attempt := 0; ; attempt++ {
+		t, err := c.session(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.stale.Store(false)
+		tools, err := listAll(ctx, t)
+		if err == nil {
+			c.mu.Lock()
+			c.tools, c.toolsAt = tools, time.Now()
+			c.mu.Unlock()
+			return tools, nil
+		}
+		if !reconnectable(err) {
+			return nil, err
+		}
+		c.reset(t)
+		if attempt > 0 {
+			return nil, err
+		}
+	}
+}
+
+// listAll 按游标读取 tools/list 的所有分页
+func listAll(ctx context.Context, t transport) ([]toolInfo, error) {
+	// 服务器没有工具时也返回非 nil 的列表，空列表同样缓存
+	tools := []toolInfo{}
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		result, err := t.call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tools      []toolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(result, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if cursor = page.NextCursor; cursor == "" {
+			return tools, nil
+		}
+	}
+}
+
+// callTool 调用工具并把结果转换为文本
+func (c *Client) callTool(ctx context.Context, name, arguments string) (string, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+	if !json.Valid([]byte(arguments)) {
+		return "", fmt.Errorf("invalid arguments: %s", arguments)
+	}
+	if timeout := conf.Get().MCP.CallTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+	params := map[string]any{"name": name, "arguments": json.RawMessage(arguments)}
+
+	var result json.RawMessage
+	for attempt := 0; ; attempt++ {
+		t, err := c.session(ctx)
+		if err != nil {
+			return "", err
+		}
+		result, err = t.call(ctx, "tools/call", params)
+		if err == nil {
+			break
+		}
+		// 只在请求确定没有被执行时重试，避免重复执行有副作用的工具
+		if errors.Is(err, errTransportClosed) || errors.Is(err, errSessionExpired) {
+			c.reset(t)
+			if attempt == 0 {
+				continue
+			}
+		} else if reconnectable(err) {
+			c.reset(t)
+		}
+		return "", err
+	}
+
+	var res callResult
+	if err := json.Unmarshal(result, &res); err != nil {
+		return "", fmt.Errorf("invalid tools/call result: %w", err)
+	}
+	parts := make([]string, 0, len(res.Content))
+	for _, content := range res.Content {
+		switch content.Type {
+		case "text":
+			parts = append(parts, content.Text)
+		case "resource":
+			if content.Resource != nil && content.Resource.Text != "" {
+				parts = append(parts, content.Resource.Text)
+			} else if content.Resource != nil {
+				parts = append(parts, fmt.Sprintf("[resource: %s]", content.Resource.URI))
+			}
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s]", content.URI))
+		default:
+			parts = append(parts, fmt.Sprintf("[%s: %s]", content.Type, content.MimeType))
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if text == "" && len(res.StructuredContent) > 0 {
+		text = string(res.StructuredContent)
+	}
+	if res.IsError {
+		return "", errors.New(text)
+	}
+	return text, nil
+}
+
+// reconnectable 判断错误是否由连接断开导致，服务器返回的 JSON-RPC 错误与请求取消不需要重新连接
+func reconnectable(err error) bool {
+	var rpcErr *rpcError
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
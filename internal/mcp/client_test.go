@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"octopus/internal/conf"
+)
+
+// fakeServer 模拟 Streamable HTTP 的 MCP 服务器，tools/list 每页返回两个工具
+type fakeServer struct {
+	tools     []string
+	stream    bool          // 以 SSE 返回响应，响应前先发送 tools/list_changed 通知
+	release   chan struct{} // 不为 nil 时 tools/list 等待其关闭后才返回
+	listCalls atomic.Int32
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+			Cursor    string          `json:"cursor"`
+		} `json:"params"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&req) != nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(req.ID) == 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result any
+	switch req.Method {
+	case "initialize":
+		w.Header().Set(headerSessionID, "session-1")
+		result = map[string]any{"protocolVersion": protocolVersion}
+	case "tools/list":
+		f.listCalls.Add(1)
+		if f.release != nil {
+			<-f.release
+		}
+		start := 0
+		fmt.Sscan(req.Params.Cursor, &start)
+		end := min(start+2, len(f.tools))
+		page := map[string]any{"tools": []map[string]any{}}
+		for _, name := range f.tools[start:end] {
+			page["tools"] = append(page["tools"].([]map[string]any), map[string]any{"name": name, "description": "tool " + name})
+		}
+		if end < len(f.tools) {
+			page["nextCursor"] = fmt.Sprint(end)
+		}
+		result = page
+	case "tools/call":
+		switch req.Params.Name {
+		case "echo":
+			result = map[string]any{"content": []map[string]any{{"type": "text", "text": string(req.Params.Arguments)}}}
+		case "mixed":
+			result = map[string]any{"content": []map[string]any{
+				{"type": "text", "text": "a"},
+				{"type": "resource", "resource": map[string]any{"uri": "file:///b", "text": "b"}},
+				{"type": "resource_link", "uri": "file:///c"},
+				{"type": "image", "mimeType": "image/png"},
+			}}
+		case "structured":
+			result = map[string]any{"content": []map[string]any{}, "structuredContent": map[string]any{"ok": true}}
+		case "fail":
+			result = map[string]any{"content": []map[string]any{{"type": "text", "text": "boom"}}, "isError": true}
+		default:
+			data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": -32602, "message": "unknown tool"}})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	if f.stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", `{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// newTestClient 启动模拟服务器并返回连接它的客户端
+func newTestClient(t *testing.T, f *fakeServer) *Client {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c := newClient(conf.MCPServer{Name: "test", Type: "http", URL: srv.URL})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestListTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		tools    []string
+		stream   bool
+		expected int32 // 两次调用发出的 tools/list 请求数
+	}{
+		{name: "single page", tools: []string{"a"}, expected: 1},
+		{name: "paginated", tools: []string{"a", "b", "c", "d", "e"}, expected: 3},
+		{name: "empty list is cached", tools: nil, expected: 1},
+		{name: "list changed notification", tools: []string{"a"}, stream: true, expected: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeServer{tools: tt.tools, stream: tt.stream}
+			c := newTestClient(t, f)
+			for i := 0; i < 2; i++ {
+				tools, err := c.listTools(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(tools) != len(tt.tools) {
+					t.Fatalf("expected %d tools, got %+v", len(tt.tools), tools)
+				}
+			}
+			if got := f.listCalls.Load(); got != tt.expected {
+				t.Errorf("expected %d tools/list requests, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestListToolsSingleFlight(t *testing.T) {
+	f := &fakeServer{tools: []string{"echo"}, release: make(chan struct{})}
+	c := newTestClient(t, f)
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tools, err := c.listTools(context.Background())
+			if err == nil && len(tools) != 1 {
+				err = fmt.Errorf("unexpected tools %+v", tools)
+			}
+			errs <- err
+		}()
+	}
+	for f.listCalls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 刷新工具列表期间不持有锁，工具调用不会被阻塞
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.callTool(context.Background(), "echo", `{}`)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected tool calls to proceed while the tool list is refreshed")
+	}
+
+	// 取消的请求立即返回，不影响共享的刷新
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.listTools(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled waiter to return context.Canceled, got %v", err)
+	}
+
+	close(f.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := f.listCalls.Load(); got != 1 {
+		t.Fatalf("expected 1 tools/list request, got %d", got)
+	}
+}
+
+func TestCallTool(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      string
+		arguments string
+		stream    bool
+		wantErr   string
+		expected  string
+	}{
+		{name: "text", tool: "echo", arguments: `{"q":1}`, expected: `{"q":1}`},
+		{name: "empty arguments", tool: "echo", arguments: " ", expected: `{}`},
+		{name: "stream response", tool: "echo", arguments: `{}`, stream: true, expected: `{}`},
+		{name: "mixed content", tool: "mixed", expected: "a\nb\n[resource: file:///c]\n[image: image/png]"},
+		{name: "structured content", tool: "structured", expected: `{"ok":true}`},
+		{name: "tool error", tool: "fail", wantErr: "boom"},
+		{name: "rpc error", tool: "missing", wantErr: "mcp error -32602: unknown tool"},
+		{name: "invalid arguments", tool: "echo", arguments: `{`, wantErr: "invalid arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, &fakeServer{stream: tt.stream})
+			got, err := c.callTool(context.Background(), tt.tool, tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"octopus/internal/conf"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "MCP-Protocol-Version"
+	maxEventSize          = 16 * 1024 * 1024
+	maxResponseSize       = 16 * 1024 * 1024
+)
+
+var httpClient = &http.Client{}
+
+// httpTransport Streamable HTTP 传输：每个请求单独 POST，响应可能是 JSON 或 SSE 流
+type httpTransport struct {
+	cfg      conf.MCPServer
+	onNotify func(method string)
+	nextID   atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+	version   string
+}
+
+func newHTTPTransport(cfg conf.MCPServer, onNotify func(string)) *httpTransport {
+	return &httpTransport{cfg: cfg, onNotify: onNotify}
+}
+
+// setProtocolVersion 记录初始化时协商的协议版本，后续请求通过请求头携带
+func (t *httpTransport) setProtocolVersion(version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.version = version
+}
+
+func (t *httpTransport) newRequest(ctx context.Context, method string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range t.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(headerSessionID, t.sessionID)
+	}
+	if t.version != "" {
+		req.Header.Set(headerProtocolVersion, t.version)
+	}
+	t.mu.Unlock()
+	return req, nil
+}
+
+func (t *httpTransport) post(ctx context.Context, msg rpcRequest) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := t.newRequest(ctx, http.MethodPost, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		t.mu.Lock()
+		hadSession := t.sessionID != ""
+		t.mu.Unlock()
+		if hadSession {
+			resp.Body.Close()
+			return nil, errSessionExpired
+		}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("mcp server %s returned status %d: %s", t.cfg.Name, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if id := resp.Header.Get(headerSessionID); id != "" && msg.Method == "initialize" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	resp, err := t.post(ctx, rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var msg *rpcMessage
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		msg, err = t.readStream(resp.Body, id)
+	} else {
+		msg, err = t.readJSON(resp.Body, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if msg.Error != nil {
+		return nil, msg.Error
+	}
+	return msg.Result, nil
+}
+
+// readStream 读取 SSE 流直到收到对应请求的响应，期间的通知交给 onNotify
+func (t *httpTransport) readStream(body io.Reader, id int64) (*rpcMessage, error) {
+	for ev, err := range sse.Read(body, &sse.ReadConfig{MaxEventSize: maxEventSize}) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mcp stream: %w", err)
+		}
+		if ev.Data == "" {
+			continue
+		}
+		if msg := t.match([]byte(ev.Data), id); msg != nil {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("mcp server %s closed the stream without a response", t.cfg.Name)
+}
+
+func (t *httpTransport) readJSON(body io.Reader, id int64) (*rpcMessage, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if msg := t.match(data, id); msg != nil {
+		return msg, nil
+	}
+	return nil, fmt.Errorf("mcp server %s returned no response for request %d", t.cfg.Name, id)
+}
+
+// match 解析单个消息或批量消息，返回与 id 对应的响应
+func (t *httpTransport) match(data []byte, id int64) *rpcMessage {
+	var msgs []rpcMessage
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		if json.Unmarshal(data, &msgs) != nil {
+			return nil
+		}
+	} else {
+		var msg rpcMessage
+		if json.Unmarshal(data, &msg) != nil {
+			return nil
+		}
+		msgs = append(msgs, msg)
+	}
+	for i := range msgs {
+		if got, ok := msgs[i].responseID(); ok && got == id {
+			return &msgs[i]
+		}
+		if len(msgs[i].ID) == 0 && msgs[i].Method != "" {
+			t.onNotify(msgs[i].Method)
+		}
+	}
+	return nil
+}
+
+func (t *httpTransport) notify(ctx context.Context, method string, params any) error {
+	resp, err := t.post(ctx, rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close 通知服务器结束会话，服务器不支持时忽略错误
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := t.newRequest(ctx, http.MethodDelete, nil)
+	if err != nil {
+		return nil
+	}
+	if resp, err := httpClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	return nil
+}
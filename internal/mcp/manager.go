@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sync"
+
+	"octopus/internal/conf"
+	"octopus/internal/servertool"
+	"octopus/internal/transformer/model"
+	"octopus/internal/utils/log"
+)
+
+// MCP 服务器的工具以 mcp__<server>__<tool> 的名称暴露给模型，
+// 复用服务端工具的执行循环：模型调用时由网关转发给 MCP 服务器，结果追加到对话中继续请求
+
+const (
+	toolPrefix    = "mcp__"
+	maxToolName   = 64
+	defaultSchema = `{"type":"object","properties":{}}`
+)
+
+var invalidToolChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+var (
+	clientsMu sync.Mutex
+	clients   = make(map[string]*Client)
+)
+
+// client 返回配置中名称对应的客户端，配置变更后使用新的客户端
+func client(name string) (*Client, error) {
+	var cfg *conf.MCPServer
+	servers := conf.Get().MCP.Servers
+	for i := range servers {
+		if servers[i].Name == name {
+			cfg = &servers[i]
+			break
+		}
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("mcp server %q is not configured", name)
+	}
+
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if c, ok := clients[name]; ok {
+		if reflect.DeepEqual(c.cfg, *cfg) {
+			return c, nil
+		}
+		go c.Close()
+	}
+	c := newClient(*cfg)
+	clients[name] = c
+	return c, nil
+}
+
+// Tools 返回 MCP 服务器提供的工具，首次调用时连接服务器
+func Tools(ctx context.Context, server string) ([]servertool.Tool, error) {
+	c, err := client(server)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := c.listTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tools := make([]servertool.Tool, 0, len(infos))
+	seen := make(map[string]struct{}, len(infos))
+	for _, info := range infos {
+		// 替换字符或截断后与之前的工具重名时改用带哈希的名称
+		exposed := exposedName(server, info.Name, false)
+		if _, ok := seen[exposed]; ok {
+			exposed = exposedName(server, info.Name, true)
+		}
+		if _, ok := seen[exposed]; ok {
+			log.Warnf("mcp server %s: skipping tool %s, its name conflicts with another tool", server, info.Name)
+			continue
+		}
+		seen[exposed] = struct{}{}
+		schema := info.InputSchema
+		if len(schema) == 0 || string(schema) == "null" {
+			schema = json.RawMessage(defaultSchema)
+		}
+		tools = append(tools, &tool{
+			client:      c,
+			server:      server,
+			name:        info.Name,
+			exposed:     exposed,
+			description: info.Description,
+			schema:      schema,
+		})
+	}
+	return tools, nil
+}
+
+// exposedName 生成暴露给模型的工具名称，只保留函数名允许的字符。
+// 超出长度限制或 hashed 为 true 时截断并追加原名称的哈希，不同的工具不会因截断而重名
+func exposedName(server, name string, hashed bool) string {
+	exposed := toolPrefix + server + "__" + invalidToolChars.ReplaceAllString(name, "_")
+	if len(exposed) <= maxToolName && !hashed {
+		return exposed
+	}
+	sum := sha256.Sum256([]byte(server + "\x00" + name))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	if len(exposed) > maxToolName-len(suffix) {
+		exposed = exposed[:maxToolName-len(suffix)]
+	}
+	return exposed + suffix
+}
+
+// Validate 校验分组引用的 MCP 服务器名称
+func Validate(names []string) error {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		found := false
+		for _, s := range conf.Get().MCP.Servers {
+			if s.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown mcp server %q", name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate mcp server %q", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+// Reload 配置热重载后关闭已删除或已修改的服务器连接
+func Reload(cur conf.MCP) {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	for name, c := range clients {
+		keep := false
+		for _, s := range cur.Servers {
+			if s.Name == name && reflect.DeepEqual(s, c.cfg) {
+				keep = true
+				break
+			}
+		}
+		if !keep {
+			delete(clients, name)
+			go c.Close()
+		}
+	}
+}
+
+// Close 关闭所有 MCP 服务器连接
+func Close() error {
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	var errs []error
+	for name, c := range clients {
+		if err := c.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", name, err))
+		}
+		delete(clients, name)
+	}
+	return errors.Join(errs...)
+}
+
+// tool 将 MCP 工具适配为服务端工具
+type tool struct {
+	client      *Client
+	server      string
+	name        string
+	exposed     string
+	description string
+	schema      json.RawMessage
+}
+
+func (t *tool) Name() string { return t.exposed }
+
+func (t *tool) Definition() model.Tool {
+	return model.Tool{
+		Type: "function",
+		Function: model.Function{
+			Name:        t.exposed,
+			Description: t.description,
+			Parameters:  t.schema,
+		},
+	}
+}
+
+func (t *tool) Progress(arguments string) string {
+	return fmt.Sprintf("Calling %s on %s", t.name, t.server)
+}
+
+func (t *tool) Execute(ctx context.Context, arguments string) (string, error) {
+	result, err := t.client.callTool(ctx, t.name, arguments)
+	if err != nil {
+		return "", err
+	}
+	return servertool.LimitResult(result), nil
+}
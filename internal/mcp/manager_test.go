@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"octopus/internal/conf"
+)
+
+func TestExposedName(t *testing.T) {
+	long := strings.Repeat("x", 80)
+	tests := []struct {
+		name     string
+		server   string
+		tool     string
+		hashed   bool
+		expected string
+	}{
+		{name: "plain", server: "docs", tool: "search", expected: "mcp__docs__search"},
+		{name: "invalid characters", server: "docs", tool: "files.read/all", expected: "mcp__docs__files_read_all"},
+		{name: "hashed", server: "docs", tool: "files.read", hashed: true, expected: "mcp__docs__files_read_" + hashOf("docs", "files.read")},
+		{name: "too long", server: "docs", tool: long, expected: ("mcp__docs__" + long)[:55] + "_" + hashOf("docs", long)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exposedName(tt.server, tt.tool, tt.hashed)
+			if got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			if len(got) > maxToolName || invalidToolChars.MatchString(got) {
+				t.Errorf("expected a valid function name, got %v", got)
+			}
+		})
+	}
+}
+
+// hashOf 返回原名称的哈希前缀
+func hashOf(server, tool string) string {
+	sum := sha256.Sum256([]byte(server + "\x00" + tool))
+	return hex.EncodeToString(sum[:4])
+}
+
+func TestTools(t *testing.T) {
+	long := strings.Repeat("x", 80)
+	tests := []struct {
+		name     string
+		tools    []string
+		expected []string
+	}{
+		{name: "distinct", tools: []string{"a", "b"}, expected: []string{"mcp__srv__a", "mcp__srv__b"}},
+		{name: "same after replacing characters", tools: []string{"a.b", "a_b"}, expected: []string{"mcp__srv__a_b", exposedName("srv", "a_b", true)}},
+		{name: "same after truncation", tools: []string{long + "1", long + "2"}, expected: []string{exposedName("srv", long+"1", false), exposedName("srv", long+"2", false)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&fakeServer{tools: tt.tools})
+			defer srv.Close()
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(`{"mcp":{"servers":[{"name":"srv","type":"http","url":"`+srv.URL+`"}]}}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := conf.Load(path); err != nil {
+				t.Fatal(err)
+			}
+			defer Close()
+
+			tools, err := Tools(context.Background(), "srv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tools) != len(tt.expected) {
+				t.Fatalf("expected %v, got %d tools", tt.expected, len(tools))
+			}
+			for i, tool := range tools {
+				if tool.Name() != tt.expected[i] || tool.Definition().Function.Name != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected[i], tool.Name())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"octopus/internal/conf"
+	"octopus/internal/utils/log"
+)
+
+// stdioTransport 启动 MCP 服务器子进程，通过标准输入输出按行交换 JSON-RPC 消息
+type stdioTransport struct {
+	name     string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	onNotify func(method string)
+	nextID   atomic.Int64
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int64]chan *rpcMessage
+	done    chan struct{}
+	err     error
+}
+
+func startStdio(cfg conf.MCPServer, onNotify func(string)) (*stdioTransport, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	cmd.Env = append(os.Environ(), cfg.Env...)
+	cmd.Dir = cfg.Dir
+	cmd.Stderr = &stderrLogger{name: cfg.Name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mcp server %s: %w", cfg.Name, err)
+	}
+
+	t := &stdioTransport{
+		name:     cfg.Name,
+		cmd:      cmd,
+		stdin:    stdin,
+		onNotify: onNotify,
+		pending:  make(map[int64]chan *rpcMessage),
+		done:     make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+	return t, nil
+}
+
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	reader := bufio.NewReaderSize(stdout, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			t.handle(line)
+		}
+		if err != nil {
+			waitErr := t.cmd.Wait()
+			t.fail(fmt.Errorf("%w: mcp server %s exited: %v", errTransportClosed, t.name, waitErr))
+			return
+		}
+	}
+}
+
+func (t *stdioTransport) handle(line []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		log.Debugf("mcp server %s: ignoring invalid message: %s", t.name, line)
+		return
+	}
+	if id, ok := msg.responseID(); ok {
+		t.mu.Lock()
+		ch := t.pending[id]
+		delete(t.pending, id)
+		t.mu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+		return
+	}
+	if len(msg.ID) == 0 {
+		t.onNotify(msg.Method)
+		return
+	}
+	// 服务器发起的请求：只响应 ping，其他能力没有声明
+	reply := map[string]any{"jsonrpc": "2.0", "id": msg.ID}
+	if msg.Method == "ping" {
+		reply["result"] = map[string]any{}
+	} else {
+		reply["error"] = rpcError{Code: -32601, Message: "method not found"}
+	}
+	_ = t.write(reply)
+}
+
+// fail 标记传输断开，唤醒所有等待中的请求
+func (t *stdioTransport) fail(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = err
+	close(t.done)
+}
+
+func (t *stdioTransport) write(msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", errTransportClosed, err)
+	}
+	return nil
+}
+
+func (t *stdioTransport) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	ch := make(chan *rpcMessage, 1)
+	t.mu.Lock()
+	if t.err != nil {
+		t.mu.Unlock()
+		return nil, t.err
+	}
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return nil, msg.Error
+		}
+		return msg.Result, nil
+	case <-t.done:
+		// 请求已经发出，进程退出时不能确定是否已执行
+		return nil, fmt.Errorf("mcp server %s exited during %s", t.name, method)
+	case <-ctx.Done():
+		_ = t.write(rpcRequest{JSONRPC: "2.0", Method: "notifications/cancelled", Params: map[string]any{"requestId": id, "reason": ctx.Err().Error()}})
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, method string, params any) error {
+	return t.write(rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// close 关闭标准输入让服务器自行退出，超时后强制结束进程
+func (t *stdioTransport) close() error {
+	err := t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(3 * time.Second):
+		_ = t.cmd.Process.Kill()
+		<-t.done
+	}
+	return err
+}
+
+// stderrLogger 将服务器的标准错误输出写入调试日志
+type stderrLogger struct {
+	name string
+}
+
+func (l *stderrLogger) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimSpace(p), []byte("\n")) {
+		if len(line) > 0 {
+			log.Debugf("mcp server %s: %s", l.name, line)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type AlertEvent string
+
+const (
+	AlertEventChannelErrorRate   AlertEvent = "channel_error_rate"   // 渠道错误率超过阈值(%)
+	AlertEventChannelKeysFailing AlertEvent = "channel_keys_failing" // 渠道所有启用的 Key 最近一次请求均失败
+	AlertEventAPIKeyBudget       AlertEvent = "apikey_budget"        // API Key 消费达到 MaxCost 的阈值(%)
+	AlertEventPriceSyncFailed    AlertEvent = "price_sync_failed"    // 模型价格同步失败
+	AlertEventModelSyncFailed    AlertEvent = "model_sync_failed"    // 渠道模型同步失败
+	AlertEventNewVersion         AlertEvent = "new_version"          // 有新版本可用
+	AlertEventBackupFailed       AlertEvent = "backup_failed"        // 定时备份失败
+)
+
+type AlertNotifierType string
+
+const (
+	AlertNotifierWebhook  AlertNotifierType = "webhook"
+	AlertNotifierSlack    AlertNotifierType = "slack"
+	AlertNotifierDiscord  AlertNotifierType = "discord"
+	AlertNotifierTelegram AlertNotifierType = "telegram"
+	AlertNotifierEmail    AlertNotifierType = "email"
+)
+
+// AlertNotifier 告警投递目标
+type AlertNotifier struct {
+	ID      int               `json:"id" gorm:"primaryKey"`
+	Name    string            `json:"name" gorm:"not null"`
+	Type    AlertNotifierType `json:"type" gorm:"not null"`
+	Enabled bool              `json:"enabled" gorm:"default:true"`
+	// webhook / slack / discord
+	URL      string `json:"url" gorm:"serializer:encrypted"`
+	Template string `json:"template"` // webhook 请求体模板(text/template)，为空时发送默认 JSON
+	// telegram
+	TelegramBotToken string `json:"telegram_bot_token" gorm:"serializer:encrypted"`
+	TelegramChatID   string `json:"telegram_chat_id"`
+	// email
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password" gorm:"serializer:encrypted"`
+	EmailFrom    string `json:"email_from"`
+	EmailTo      string `json:"email_to"` // 多个收件人用逗号分隔
+}
+
+// AlertRule 告警规则
+type AlertRule struct {
+	ID          int        `json:"id" gorm:"primaryKey"`
+	Name        string     `json:"name" gorm:"not null"`
+	Event       AlertEvent `json:"event" gorm:"not null"`
+	Enabled     bool       `json:"enabled" gorm:"default:true"`
+	Threshold   float64    `json:"threshold"`    // channel_error_rate / apikey_budget 的百分比阈值
+	Window      int        `json:"window"`       // channel_error_rate 统计窗口(分钟)
+	MinRequests int        `json:"min_requests"` // channel_error_rate 窗口内最少请求数，避免少量请求误报
+	Cooldown    int        `json:"cooldown"`     // 同一规则同一目标两次通知的最小间隔(分钟)，0 使用默认值
+	NotifierIDs []int      `json:"notifier_ids" gorm:"serializer:json"`
+}
+
+// Alert 一次待发送的告警（不入库）
+type Alert struct {
+	Event    AlertEvent `json:"event"`
+	RuleID   int        `json:"rule_id"`
+	RuleName string     `json:"rule_name"`
+	Subject  string     `json:"subject"` // 告警对象，如 channel:1 / apikey:2
+	Title    string     `json:"title"`
+	Message  string     `json:"message"`
+	Value    float64    `json:"value,omitempty"` // 触发时的观测值
+	Time     int64      `json:"time"`
+}
+
+func (n *AlertNotifier) Validate() error {
+	if strings.TrimSpace(n.Name) == "" {
+		return fmt.Errorf("notifier name is required")
+	}
+	switch n.Type {
+	case AlertNotifierWebhook, AlertNotifierSlack, AlertNotifierDiscord:
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notifier url must be a valid http(s) url")
+		}
+	case AlertNotifierTelegram:
+		if n.TelegramBotToken == "" || n.TelegramChatID == "" {
+			return fmt.Errorf("telegram bot token and chat id are required")
+		}
+	case AlertNotifierEmail:
+		if n.SMTPHost == "" || n.SMTPPort <= 0 || n.EmailFrom == "" || n.EmailTo == "" {
+			return fmt.Errorf("smtp host, port, from and to are required")
+		}
+	default:
+		return fmt.Errorf("unsupported notifier type: %s", n.Type)
+	}
+	return nil
+}
+
+func (r *AlertRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	switch r.Event {
+	case AlertEventChannelErrorRate:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("error rate threshold must be in (0, 100]")
+		}
+		if r.Window <= 0 || r.Window > 60 {
+			return fmt.Errorf("error rate window must be between 1 and 60 minutes")
+		}
+	case AlertEventAPIKeyBudget:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("budget threshold must be greater than 0")
+		}
+	case AlertEventChannelKeysFailing, AlertEventPriceSyncFailed, AlertEventModelSyncFailed, AlertEventNewVersion, AlertEventBackupFailed:
+	default:
+		return fmt.Errorf("unsupported alert event: %s", r.Event)
+	}
+	if r.Cooldown < 0 {
+		return fmt.Errorf("cooldown must not be negative")
+	}
+	if len(r.NotifierIDs) == 0 {
+		return fmt.Errorf("at least one notifier is required")
+	}
+	return nil
+}
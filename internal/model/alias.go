@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dlclark/regexp2"
+)
+
+type ModelAliasMatchType string
+
+const (
+	ModelAliasMatchExact    ModelAliasMatchType = "exact"    // 完全匹配（忽略大小写）
+	ModelAliasMatchWildcard ModelAliasMatchType = "wildcard" // 通配符匹配，支持 * 与 ?（忽略大小写）
+	ModelAliasMatchRegex    ModelAliasMatchType = "regex"    // 正则匹配（ECMAScript 语法）
+)
+
+// ModelAliasMatchTimeout 单次正则匹配的超时时间，避免回溯过多的规则拖慢请求，超时按未命中处理
+const ModelAliasMatchTimeout = 50 * time.Millisecond
+
+// ModelAlias 将客户端请求的模型名称映射到分组，与分组名称无关
+type ModelAlias struct {
+	ID              int                 `json:"id" gorm:"primaryKey"`
+	Pattern         string              `json:"pattern" gorm:"not null"`
+	MatchType       ModelAliasMatchType `json:"match_type" gorm:"not null"`
+	GroupID         int                 `json:"group_id" gorm:"not null;index"`
+	RewriteResponse bool                `json:"rewrite_response"` // 响应中的模型名称改写为客户端请求的名称
+	Priority        int                 `json:"priority"`         // 通配符与正则规则按优先级从小到大匹配
+	Enabled         bool                `json:"enabled"`
+}
+
+func (a *ModelAlias) Validate() error {
+	if strings.TrimSpace(a.Pattern) == "" {
+		return fmt.Errorf("alias pattern is required")
+	}
+	if a.GroupID == 0 {
+		return fmt.Errorf("alias group is required")
+	}
+	switch a.MatchType {
+	case ModelAliasMatchExact, ModelAliasMatchWildcard, ModelAliasMatchRegex:
+	default:
+		return fmt.Errorf("invalid match type: %s", a.MatchType)
+	}
+	if _, err := a.Compile(); err != nil {
+		return fmt.Errorf("invalid alias pattern: %w", err)
+	}
+	return nil
+}
+
+// Compile 将通配符与正则规则编译为带匹配超时的正则表达式，完全匹配规则返回 nil
+func (a *ModelAlias) Compile() (*regexp2.Regexp, error) {
+	re, err := a.compile()
+	if err != nil || re == nil {
+		return nil, err
+	}
+	re.MatchTimeout = ModelAliasMatchTimeout
+	return re, nil
+}
+
+func (a *ModelAlias) compile() (*regexp2.Regexp, error) {
+	switch a.MatchType {
+	case ModelAliasMatchWildcard:
+		var sb strings.Builder
+		sb.WriteString("^")
+		for _, r := range a.Pattern {
+			switch r {
+			case '*':
+				sb.WriteString(".*")
+			case '?':
+				sb.WriteString(".")
+			default:
+				sb.WriteString(regexp2.Escape(string(r)))
+			}
+		}
+		sb.WriteString("$")
+		return regexp2.Compile(sb.String(), regexp2.ECMAScript|regexp2.IgnoreCase)
+	case ModelAliasMatchRegex:
+		return regexp2.Compile(a.Pattern, regexp2.ECMAScript)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestModelAliasValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		alias   ModelAlias
+		wantErr bool
+	}{
+		{name: "exact", alias: ModelAlias{Pattern: "gpt-4", MatchType: ModelAliasMatchExact, GroupID: 1}},
+		{name: "wildcard", alias: ModelAlias{Pattern: "claude-*", MatchType: ModelAliasMatchWildcard, GroupID: 1}},
+		{name: "regex", alias: ModelAlias{Pattern: `^gpt-4o(-mini)?$`, MatchType: ModelAliasMatchRegex, GroupID: 1}},
+		{name: "empty pattern", alias: ModelAlias{Pattern: " ", MatchType: ModelAliasMatchExact, GroupID: 1}, wantErr: true},
+		{name: "missing group", alias: ModelAlias{Pattern: "gpt-4", MatchType: ModelAliasMatchExact}, wantErr: true},
+		{name: "invalid match type", alias: ModelAlias{Pattern: "gpt-4", MatchType: "prefix", GroupID: 1}, wantErr: true},
+		{name: "invalid regex", alias: ModelAlias{Pattern: "gpt-(4", MatchType: ModelAliasMatchRegex, GroupID: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.alias.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestModelAliasCompile(t *testing.T) {
+	tests := []struct {
+		name  string
+		alias ModelAlias
+		input string
+		want  bool
+	}{
+		{name: "wildcard star", alias: ModelAlias{Pattern: "claude-*", MatchType: ModelAliasMatchWildcard}, input: "Claude-3-Opus", want: true},
+		{name: "wildcard question mark", alias: ModelAlias{Pattern: "gpt-?", MatchType: ModelAliasMatchWildcard}, input: "gpt-4", want: true},
+		{name: "wildcard escapes dots", alias: ModelAlias{Pattern: "gpt-4.1", MatchType: ModelAliasMatchWildcard}, input: "gpt-4x1", want: false},
+		{name: "wildcard anchored", alias: ModelAlias{Pattern: "gpt", MatchType: ModelAliasMatchWildcard}, input: "gpt-4", want: false},
+		{name: "regex", alias: ModelAlias{Pattern: `^gpt-4o(-mini)?$`, MatchType: ModelAliasMatchRegex}, input: "gpt-4o-mini", want: true},
+		{name: "regex case sensitive", alias: ModelAlias{Pattern: `^gpt-4o$`, MatchType: ModelAliasMatchRegex}, input: "GPT-4o", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := tt.alias.Compile()
+			if err != nil {
+				t.Fatalf("failed to compile: %v", err)
+			}
+			got, err := re.MatchString(tt.input)
+			if err != nil {
+				t.Fatalf("failed to match: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestModelAliasCompileTimeout(t *testing.T) {
+	alias := ModelAlias{Pattern: `^(a+)+$`, MatchType: ModelAliasMatchRegex}
+	re, err := alias.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if re.MatchTimeout != ModelAliasMatchTimeout {
+		t.Fatalf("expected match timeout %v, got %v", ModelAliasMatchTimeout, re.MatchTimeout)
+	}
+	start := time.Now()
+	matched, err := re.MatchString(strings.Repeat("a", 64) + "!")
+	if err == nil || matched {
+		t.Fatalf("expected a timeout error, got matched=%v err=%v", matched, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the match to stop near the timeout, took %v", elapsed)
+	}
+}
+
+func TestModelAliasCompileExact(t *testing.T) {
+	alias := ModelAlias{Pattern: "gpt-4", MatchType: ModelAliasMatchExact}
+	re, err := alias.Compile()
+	if err != nil || re != nil {
+		t.Errorf("expected nil regexp for exact alias, got %v, %v", re, err)
+	}
+}
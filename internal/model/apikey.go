@@ -1,11 +1,103 @@
 package model
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// apiKeyPrefixLen 前缀中保留的随机字符数，用于识别与查找
+const apiKeyPrefixLen = 8
+
+// apiKeyMinSecretLen 截取前缀后至少剩余的字符数，不足时改用哈希前缀，避免前缀暴露密钥
+const apiKeyMinSecretLen = 16
+
 type APIKey struct {
 	ID              int     `json:"id" gorm:"primaryKey"`
 	Name            string  `json:"name" gorm:"not null"`
-	APIKey          string  `json:"api_key" gorm:"not null"`
+	APIKey          string  `json:"api_key,omitempty" gorm:"-"` // 明文仅在创建/轮换时返回一次，不入库
+	KeyPrefix       string  `json:"key_prefix" gorm:"index"`
+	KeyHash         string  `json:"key_hash,omitempty"`
+	PrevKeyPrefix   string  `json:"prev_key_prefix,omitempty" gorm:"index"`
+	PrevKeyHash     string  `json:"prev_key_hash,omitempty"`
+	PrevKeyExpireAt int64   `json:"prev_key_expire_at,omitempty"`
 	Enabled         bool    `json:"enabled" gorm:"default:true"`
 	ExpireAt        int64   `json:"expire_at,omitempty"`
 	MaxCost         float64 `json:"max_cost,omitempty"`
 	SupportedModels string  `json:"supported_models,omitempty"`
+	// Guardrail 转发前的敏感信息检查，与分组的策略同时生效
+	Guardrail *GuardrailPolicy `json:"guardrail,omitempty" gorm:"serializer:json"`
+}
+
+type APIKeyRotate struct {
+	ID int `json:"id"`
+	// GracePeriod 旧密钥继续可用的秒数，0 表示立即失效
+	GracePeriod int64 `json:"grace_period"`
+}
+
+// APIKeyPrefix 返回密钥的可见前缀，例如 sk-octopus-AbCd1234。
+// 不符合 <前缀>-<随机串> 格式或过短的密钥返回固定长度的哈希，例如 #0123456789abcdef。
+// 前缀不保证唯一，查找时需要逐个校验哈希
+func APIKeyPrefix(key string) string {
+	idx := strings.LastIndex(key, "-")
+	end := idx + 1 + apiKeyPrefixLen
+	if idx < 0 || len(key)-end < apiKeyMinSecretLen {
+		sum := sha256.Sum256([]byte(key))
+		return "#" + hex.EncodeToString(sum[:8])
+	}
+	return key[:end]
+}
+
+// HashAPIKey 使用随机盐计算密钥哈希，格式: sha256:<salt hex>:<hash hex>
+func HashAPIKey(key string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	sum := sha256.Sum256(append(salt, key...))
+	return "sha256:" + hex.EncodeToString(salt) + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyAPIKeyHash 校验明文密钥与哈希是否匹配
+func VerifyAPIKeyHash(key, hash string) bool {
+	parts := strings.Split(hash, ":")
+	if len(parts) != 3 || parts[0] != "sha256" {
+		return false
+	}
+	salt, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	want, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	sum := sha256.Sum256(append(salt, key...))
+	return subtle.ConstantTimeCompare(sum[:], want) == 1
+}
+
+// HashKey 根据 APIKey 明文填充 KeyPrefix 与 KeyHash
+func (k *APIKey) HashKey() error {
+	hash, err := HashAPIKey(k.APIKey)
+	if err != nil {
+		return err
+	}
+	k.KeyPrefix = APIKeyPrefix(k.APIKey)
+	k.KeyHash = hash
+	return nil
+}
+
+// Verify 校验明文密钥，轮换宽限期内旧密钥同样有效
+func (k *APIKey) Verify(key string) bool {
+	if k.KeyHash != "" && VerifyAPIKeyHash(key, k.KeyHash) {
+		return true
+	}
+	if k.PrevKeyHash != "" && k.PrevKeyExpireAt > time.Now().Unix() {
+		return VerifyAPIKeyHash(key, k.PrevKeyHash)
+	}
+	return false
 }
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIKeyPrefix(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		hashed bool
+	}{
+		{name: "generated key", key: "sk-octopus-AbCd1234" + strings.Repeat("x", 40), want: "sk-octopus-AbCd1234"},
+		{name: "no dash", key: "plainsecretwithoutanydash", hashed: true},
+		{name: "short secret", key: "sk-octopus-AbCd1234xyz", hashed: true},
+		{name: "empty", key: "", hashed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := APIKeyPrefix(tt.key)
+			if !tt.hashed {
+				if got != tt.want {
+					t.Errorf("expected %q, got %q", tt.want, got)
+				}
+				return
+			}
+			if !strings.HasPrefix(got, "#") || len(got) != 17 {
+				t.Errorf("expected a fixed-length hash prefix, got %q", got)
+			}
+			if tt.key != "" && strings.Contains(got, tt.key) {
+				t.Errorf("expected prefix not to contain the key, got %q", got)
+			}
+			if APIKeyPrefix(tt.key) != got {
+				t.Errorf("expected prefix to be deterministic")
+			}
+		})
+	}
+}
+
+func TestAPIKeyVerify(t *testing.T) {
+	current, _ := HashAPIKey("sk-new")
+	prev, _ := HashAPIKey("sk-old")
+	now := time.Now().Unix()
+	tests := []struct {
+		name string
+		key  APIKey
+		in   string
+		want bool
+	}{
+		{name: "current key", key: APIKey{KeyHash: current}, in: "sk-new", want: true},
+		{name: "wrong key", key: APIKey{KeyHash: current}, in: "sk-other", want: false},
+		{name: "previous key in grace period", key: APIKey{KeyHash: current, PrevKeyHash: prev, PrevKeyExpireAt: now + 60}, in: "sk-old", want: true},
+		{name: "previous key expired", key: APIKey{KeyHash: current, PrevKeyHash: prev, PrevKeyExpireAt: now - 1}, in: "sk-old", want: false},
+		{name: "malformed hash", key: APIKey{KeyHash: "sha256:zz:zz"}, in: "sk-new", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Verify(tt.in); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package model
+
+import "octopus/internal/utils/diff"
+
+type AuditAction string
+
+const (
+	AuditActionChannelCreate  AuditAction = "channel.create"
+	AuditActionChannelUpdate  AuditAction = "channel.update"
+	AuditActionChannelEnabled AuditAction = "channel.enabled"
+	AuditActionChannelDelete  AuditAction = "channel.delete"
+	AuditActionGroupCreate    AuditAction = "group.create"
+	AuditActionGroupUpdate    AuditAction = "group.update"
+	AuditActionGroupDelete    AuditAction = "group.delete"
+	AuditActionAPIKeyCreate   AuditAction = "apikey.create"
+	AuditActionAPIKeyUpdate   AuditAction = "apikey.update"
+	AuditActionAPIKeyRotate   AuditAction = "apikey.rotate"
+	AuditActionAPIKeyDelete   AuditAction = "apikey.delete"
+	AuditActionSettingUpdate  AuditAction = "setting.update"
+	AuditActionDBImport       AuditAction = "db.import"
+
+	AuditActionAlertNotifierCreate AuditAction = "alert_notifier.create"
+	AuditActionAlertNotifierUpdate AuditAction = "alert_notifier.update"
+	AuditActionAlertNotifierDelete AuditAction = "alert_notifier.delete"
+	AuditActionAlertRuleCreate     AuditAction = "alert_rule.create"
+	AuditActionAlertRuleUpdate     AuditAction = "alert_rule.update"
+	AuditActionAlertRuleDelete     AuditAction = "alert_rule.delete"
+
+	AuditActionChannelKeyAutoDisable AuditAction = "channel_key.auto_disable"
+	AuditActionChannelKeyAutoEnable  AuditAction = "channel_key.auto_enable"
+
+	AuditActionModelAliasCreate AuditAction = "model_alias.create"
+	AuditActionModelAliasUpdate AuditAction = "model_alias.update"
+	AuditActionModelAliasDelete AuditAction = "model_alias.delete"
+	AuditActionModelSyncApprove AuditAction = "model_sync.approve"
+	AuditActionModelSyncReject  AuditAction = "model_sync.reject"
+)
+
+// AuditActorSystem 没有请求上下文（定时任务、内部调用）时的操作者
+const AuditActorSystem = "system"
+
+type AuditLog struct {
+	ID         int64         `json:"id" gorm:"primaryKey;autoIncrement:false"` // Snowflake ID
+	Time       int64         `json:"time" gorm:"index"`                        // 时间戳（秒）
+	Actor      string        `json:"actor" gorm:"index"`                       // 操作者
+	SourceIP   string        `json:"source_ip"`                                // 来源 IP
+	Action     AuditAction   `json:"action" gorm:"index"`                      // 操作类型
+	TargetType string        `json:"target_type" gorm:"index"`                 // 目标类型，如 channel / group
+	TargetID   string        `json:"target_id" gorm:"index"`                   // 目标 ID
+	Changes    []diff.Change `json:"changes" gorm:"serializer:json"`           // 变更前后字段差异
+}
+
+// AuditLogFilter 审计日志查询条件，空值表示不过滤
+type AuditLogFilter struct {
+	Actor      string
+	Action     AuditAction
+	TargetType string
+	TargetID   string
+	StartTime  *int64
+	EndTime    *int64
+}
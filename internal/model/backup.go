@@ -4,11 +4,18 @@ import "time"
 
 // DBDump is a full-database JSON export format for Octopus.
 // Import uses incremental semantics (insert new rows, and upsert on certain key-based tables).
+// Dumps written by older versions are upgraded by op.DBDumpDecode before they are imported.
 type DBDump struct {
 	Version      int       `json:"version"`
 	ExportedAt   time.Time `json:"exported_at"`
 	IncludeLogs  bool      `json:"include_logs"`
 	IncludeStats bool      `json:"include_stats"`
+	// Encrypted reports whether channel credentials are exported as ciphertext.
+	// Such dumps can only be imported by an instance sharing the same data keys.
+	Encrypted bool `json:"encrypted"`
+	// DataKeys carries the data keys of an encrypted dump, wrapped with the exporter's master key,
+	// so the dump can be restored into a fresh database or after the data key was rotated.
+	DataKeys []DataKey `json:"data_keys,omitempty"`
 
 	Channels    []Channel    `json:"channels,omitempty"`
 	ChannelKeys []ChannelKey `json:"channel_keys,omitempty"`
@@ -18,6 +25,8 @@ type DBDump struct {
 	APIKeys    []APIKey    `json:"api_keys,omitempty"`
 	Settings   []Setting   `json:"settings,omitempty"`
 
+	ModelAliases []ModelAlias `json:"model_aliases,omitempty"`
+
 	StatsTotal   []StatsTotal   `json:"stats_total,omitempty"`
 	StatsDaily   []StatsDaily   `json:"stats_daily,omitempty"`
 	StatsHourly  []StatsHourly  `json:"stats_hourly,omitempty"`
@@ -28,7 +37,53 @@ type DBDump struct {
 	RelayLogs []RelayLog `json:"relay_logs,omitempty"`
 }
 
+// DBImportOptions controls how a dump is imported.
+type DBImportOptions struct {
+	// Replace wipes the tables covered by the dump before importing. It cannot be combined with a selective restore.
+	Replace bool `json:"replace"`
+	// DryRun runs the import in a transaction that is rolled back, so the result only previews the changes.
+	DryRun bool `json:"dry_run"`
+
+	// Channels, Groups and APIKeys select entities of the dump by name or ID for a selective restore.
+	// When any of them is set only the selected entities are imported, together with their channel keys,
+	// model prices and group items. Existing entities with the same name are overwritten and IDs that
+	// collide with other rows are remapped.
+	Channels []string `json:"channels,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	APIKeys  []string `json:"api_keys,omitempty"`
+
+	// MasterKey is the master key the dump's data keys were wrapped with. It is only needed when the
+	// master key was changed (rekey) after the dump was exported; empty means the current master key.
+	MasterKey string `json:"-"`
+}
+
+func (o DBImportOptions) Selective() bool {
+	return len(o.Channels) > 0 || len(o.Groups) > 0 || len(o.APIKeys) > 0
+}
+
+type DBImportTableResult struct {
+	Inserted int64 `json:"inserted"`
+	Updated  int64 `json:"updated"`
+	Skipped  int64 `json:"skipped"`
+}
+
+// DBImportRemap records a row that was imported under a new ID because its original ID was taken.
+type DBImportRemap struct {
+	Table string `json:"table"`
+	Name  string `json:"name"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+}
+
 type DBImportResult struct {
+	// Replaced reports whether the tables covered by the dump were wiped before importing.
+	Replaced bool `json:"replaced,omitempty"`
+	// DryRun reports that nothing was committed.
+	DryRun bool `json:"dry_run,omitempty"`
+
 	// RowsAffected contains the rows affected for each table operation (insert/upsert depending on table).
-	RowsAffected map[string]int64 `json:"rows_affected"`
+	RowsAffected map[string]int64                `json:"rows_affected"`
+	Tables       map[string]*DBImportTableResult `json:"tables"`
+	Remapped     []DBImportRemap                 `json:"remapped,omitempty"`
+	Warnings     []string                        `json:"warnings,omitempty"`
 }
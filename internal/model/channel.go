@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"octopus/internal/transformer/outbound"
@@ -29,7 +30,9 @@ type Channel struct {
 	AutoGroup     AutoGroupType         `json:"auto_group" gorm:"default:0"`
 	CustomHeader  []CustomHeader        `json:"custom_header" gorm:"serializer:json"`
 	ParamOverride *string               `json:"param_override"`
-	ChannelProxy  *string               `json:"channel_proxy"`
+	ChannelProxy  *string               `json:"channel_proxy" gorm:"serializer:encrypted"`
+	PromptCache   *PromptCachePolicy    `json:"prompt_cache,omitempty" gorm:"serializer:json"`
+	Script        string                `json:"script,omitempty"` // Starlark 脚本，在分组脚本之后修改发往该渠道的请求与其响应
 	Stats         *StatsChannel         `json:"stats,omitempty" gorm:"foreignKey:ChannelID"`
 }
 
@@ -43,14 +46,65 @@ type CustomHeader struct {
 	HeaderValue string `json:"header_value"`
 }
 
+// PromptCachePolicy 自动提示词缓存策略：请求没有携带 cache_control 时，
+// 为发往 Anthropic 渠道的请求在工具定义、系统提示词与最近几轮对话上插入缓存断点
+type PromptCachePolicy struct {
+	Enabled bool   `json:"enabled"`
+	Turns   int    `json:"turns,omitempty"` // 缓存最近 N 轮对话，默认 2，受 Anthropic 最多 4 个断点的限制
+	TTL     string `json:"ttl,omitempty"`   // 5m（默认）或 1h
+}
+
+// PromptCacheMaxBreakpoints Anthropic 单个请求最多允许的 cache_control 断点数
+const PromptCacheMaxBreakpoints = 4
+
+func (p *PromptCachePolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Turns < 0 || p.Turns > PromptCacheMaxBreakpoints {
+		return fmt.Errorf("prompt_cache.turns must be between 0 and %d", PromptCacheMaxBreakpoints)
+	}
+	if p.TTL != "" && p.TTL != "5m" && p.TTL != "1h" {
+		return fmt.Errorf("prompt_cache.ttl must be 5m or 1h")
+	}
+	return nil
+}
+
+// Active 策略是否启用，nil 视为未设置
+func (p *PromptCachePolicy) Active() bool {
+	return p != nil && p.Enabled
+}
+
+type ChannelKeyHealth string
+
+const (
+	ChannelKeyHealthValid          ChannelKeyHealth = "valid"
+	ChannelKeyHealthQuotaExhausted ChannelKeyHealth = "quota_exhausted"
+	ChannelKeyHealthRevoked        ChannelKeyHealth = "revoked"
+	ChannelKeyHealthRateLimited    ChannelKeyHealth = "rate_limited"
+	ChannelKeyHealthError          ChannelKeyHealth = "error" // 网络错误或无法识别的响应
+)
+
 type ChannelKey struct {
-	ID               int     `json:"id" gorm:"primaryKey"`
-	ChannelID        int     `json:"channel_id"`
-	Enabled          bool    `json:"enabled" gorm:"default:true"`
-	ChannelKey       string  `json:"channel_key"`
-	StatusCode       int     `json:"status_code"`
-	LastUseTimeStamp int64   `json:"last_use_time_stamp"`
-	TotalCost        float64 `json:"total_cost"`
+	ID               int              `json:"id" gorm:"primaryKey"`
+	ChannelID        int              `json:"channel_id"`
+	Enabled          bool             `json:"enabled" gorm:"default:true"`
+	ChannelKey       string           `json:"channel_key" gorm:"serializer:encrypted"`
+	StatusCode       int              `json:"status_code"`
+	LastUseTimeStamp int64            `json:"last_use_time_stamp"`
+	TotalCost        float64          `json:"total_cost"`
+	HealthStatus     ChannelKeyHealth `json:"health_status,omitempty"`     // 最近一次健康检查结果
+	HealthCheckedAt  int64            `json:"health_checked_at,omitempty"` // 最近一次健康检查时间
+	AutoDisabled     bool             `json:"auto_disabled,omitempty"`     // 是否由健康检查自动禁用，恢复后会自动启用
+	DisabledReason   string           `json:"disabled_reason,omitempty"`   // 自动禁用原因
+}
+
+// ChannelKeyTestResult 渠道 key 连通性测试结果，不会修改 key 的状态
+type ChannelKeyTestResult struct {
+	KeyID   int              `json:"key_id"`
+	Enabled bool             `json:"enabled"`
+	Health  ChannelKeyHealth `json:"health"`
+	Reason  string           `json:"reason,omitempty"`
 }
 
 // ChannelUpdateRequest 渠道更新请求 - 仅包含变更的数据
@@ -68,6 +122,8 @@ type ChannelUpdateRequest struct {
 	CustomHeader  *[]CustomHeader        `json:"custom_header,omitempty"`
 	ChannelProxy  *string                `json:"channel_proxy,omitempty"`
 	ParamOverride *string                `json:"param_override,omitempty"`
+	PromptCache   *PromptCachePolicy     `json:"prompt_cache,omitempty"` // 传入 enabled=false 的空策略表示清除
+	Script        *string                `json:"script,omitempty"`       // 空字符串表示清除
 
 	KeysToAdd    []ChannelKeyAddRequest    `json:"keys_to_add,omitempty"`
 	KeysToUpdate []ChannelKeyUpdateRequest `json:"keys_to_update,omitempty"`
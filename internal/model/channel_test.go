@@ -0,0 +1,44 @@
+package model
+
+import "testing"
+
+func TestPromptCachePolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *PromptCachePolicy
+		wantErr bool
+	}{
+		{name: "nil", policy: nil},
+		{name: "defaults", policy: &PromptCachePolicy{Enabled: true}},
+		{name: "one hour", policy: &PromptCachePolicy{Enabled: true, Turns: 4, TTL: "1h"}},
+		{name: "too many turns", policy: &PromptCachePolicy{Enabled: true, Turns: PromptCacheMaxBreakpoints + 1}, wantErr: true},
+		{name: "negative turns", policy: &PromptCachePolicy{Turns: -1}, wantErr: true},
+		{name: "invalid ttl", policy: &PromptCachePolicy{TTL: "10m"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStatsMetricsCacheHitRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		metrics  StatsMetrics
+		expected float64
+	}{
+		{name: "no input", metrics: StatsMetrics{}, expected: 0},
+		{name: "no cache", metrics: StatsMetrics{InputToken: 100}, expected: 0},
+		{name: "quarter cached", metrics: StatsMetrics{InputToken: 400, CacheReadToken: 100, CacheWriteToken: 200}, expected: 0.25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.metrics.CacheHitRate(); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package model
 
-import "octopus/internal/transformer/outbound"
+import (
+	"fmt"
+	"strconv"
+
+	"octopus/internal/transformer/outbound"
+)
 
 // ChannelTypeInfo 渠道类型信息
 type ChannelTypeInfo struct {
@@ -37,5 +42,30 @@ func GetAllChannelTypes() []ChannelTypeInfo {
 			Name:  "antigravity",
 			Label: "Antigravity",
 		},
+		{
+			Value: int(outbound.OutboundTypeMock),
+			Name:  "mock",
+			Label: "Mock",
+		},
+	}
+}
+
+// ParseChannelType 按类型名称或数值解析渠道类型
+func ParseChannelType(s string) (outbound.OutboundType, error) {
+	for _, t := range GetAllChannelTypes() {
+		if t.Name == s || strconv.Itoa(t.Value) == s {
+			return outbound.OutboundType(t.Value), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid channel type %q", s)
+}
+
+// ChannelTypeName 返回渠道类型名称，未知类型返回数值
+func ChannelTypeName(t outbound.OutboundType) string {
+	for _, info := range GetAllChannelTypes() {
+		if info.Value == int(t) {
+			return info.Name
+		}
 	}
+	return strconv.Itoa(int(t))
 }
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"testing"
+
+	"octopus/internal/transformer/outbound"
+)
+
+func TestParseChannelType(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    outbound.OutboundType
+		wantErr bool
+	}{
+		{name: "by name", input: "anthropic", want: outbound.OutboundTypeAnthropic},
+		{name: "by value", input: "1", want: outbound.OutboundType(1)},
+		{name: "unknown name", input: "claude", wantErr: true},
+		{name: "empty", input: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChannelType(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChannelTypeName(t *testing.T) {
+	for _, info := range GetAllChannelTypes() {
+		t.Run(info.Name, func(t *testing.T) {
+			typ, err := ParseChannelType(ChannelTypeName(outbound.OutboundType(info.Value)))
+			if err != nil || int(typ) != info.Value {
+				t.Errorf("expected round trip to %d, got %d, %v", info.Value, typ, err)
+			}
+		})
+	}
+	if got := ChannelTypeName(outbound.OutboundType(9999)); got != "9999" {
+		t.Errorf("expected %q, got %q", "9999", got)
+	}
+}
@@ -0,0 +1,24 @@
+package model
+
+// CacheVersion 集群模式下各缓存实体的版本号。实例修改配置后递增版本号，
+// 其他实例轮询到版本变化后从数据库刷新对应的缓存
+type CacheVersion struct {
+	Name    string `json:"name" gorm:"primaryKey;size:64"`
+	Version int64  `json:"version"`
+}
+
+// ClusterLease 集群模式下的租约，持有者在过期前续约，用于选举执行定时任务的主节点
+type ClusterLease struct {
+	Name      string `json:"name" gorm:"primaryKey;size:64"`
+	Holder    string `json:"holder" gorm:"size:255"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// CacheStatus 一类内存缓存的状态
+type CacheStatus struct {
+	Name        string `json:"name"`
+	Epoch       int64  `json:"epoch"`       // 从数据库加载的次数，每次重新加载后递增
+	Fingerprint string `json:"fingerprint"` // 最近一次加载时数据库中对应数据的指纹
+	CheckedAt   int64  `json:"checked_at"`  // 最近一次与数据库比对的时间
+	ReloadedAt  int64  `json:"reloaded_at"` // 最近一次加载的时间
+}
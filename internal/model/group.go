@@ -10,12 +10,18 @@ const (
 )
 
 type Group struct {
-	ID                int         `json:"id" gorm:"primaryKey"`
-	Name              string      `json:"name" gorm:"unique;not null"`
-	Mode              GroupMode   `json:"mode" gorm:"not null"`
-	MatchRegex        string      `json:"match_regex"`
-	FirstTokenTimeOut int         `json:"first_token_time_out"` // 单个渠道首个Token响应超时时间(秒)
-	Items             []GroupItem `json:"items,omitempty" gorm:"foreignKey:GroupID"`
+	ID                int                `json:"id" gorm:"primaryKey"`
+	Name              string             `json:"name" gorm:"unique;not null"`
+	Mode              GroupMode          `json:"mode" gorm:"not null"`
+	MatchRegex        string             `json:"match_regex"`
+	FirstTokenTimeOut int                `json:"first_token_time_out"`                          // 单个渠道首个Token响应超时时间(秒)
+	PromptCache       *PromptCachePolicy `json:"prompt_cache,omitempty" gorm:"serializer:json"` // 启用时覆盖渠道的提示词缓存策略
+	ServerTools       []string           `json:"server_tools,omitempty" gorm:"serializer:json"` // 由网关执行的服务端工具，如 web_search、web_fetch
+	MCPServers        []string           `json:"mcp_servers,omitempty" gorm:"serializer:json"`  // 工具由网关执行的 MCP 服务器名称，见配置 mcp.servers
+	Reasoning         *ReasoningPolicy   `json:"reasoning,omitempty" gorm:"serializer:json"`    // 思考预算与思考内容的返回形式
+	Guardrail         *GuardrailPolicy   `json:"guardrail,omitempty" gorm:"serializer:json"`    // 转发前的敏感信息检查，与 API Key 的策略同时生效
+	Script            string             `json:"script,omitempty"`                              // Starlark 脚本，可以修改请求与响应、限定渠道或拒绝请求
+	Items             []GroupItem        `json:"items,omitempty" gorm:"foreignKey:GroupID"`
 }
 
 type GroupItem struct {
@@ -34,6 +40,12 @@ type GroupUpdateRequest struct {
 	Mode              *GroupMode               `json:"mode,omitempty"`                 // 仅在模式变更时发送
 	MatchRegex        *string                  `json:"match_regex,omitempty"`          // 仅在匹配正则变更时发送
 	FirstTokenTimeOut *int                     `json:"first_token_time_out,omitempty"` // 仅在超时变更时发送(秒)
+	PromptCache       *PromptCachePolicy       `json:"prompt_cache,omitempty"`         // 仅在提示词缓存策略变更时发送，enabled=false 的空策略表示清除
+	ServerTools       *[]string                `json:"server_tools,omitempty"`         // 仅在服务端工具变更时发送，空数组表示清除
+	MCPServers        *[]string                `json:"mcp_servers,omitempty"`          // 仅在 MCP 服务器变更时发送，空数组表示清除
+	Reasoning         *ReasoningPolicy         `json:"reasoning,omitempty"`            // 仅在思考策略变更时发送，enabled=false 的空策略表示清除
+	Guardrail         *GuardrailPolicy         `json:"guardrail,omitempty"`            // 仅在内容安全策略变更时发送，enabled=false 的空策略表示清除
+	Script            *string                  `json:"script,omitempty"`               // 仅在脚本变更时发送，空字符串表示清除
 	ItemsToAdd        []GroupItemAddRequest    `json:"items_to_add,omitempty"`         // 新增的 items
 	ItemsToUpdate     []GroupItemUpdateRequest `json:"items_to_update,omitempty"`      // 更新的 items (priority 变更)
 	ItemsToDelete     []int                    `json:"items_to_delete,omitempty"`      // 删除的 item IDs
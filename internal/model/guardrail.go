@@ -0,0 +1,32 @@
+package model
+
+// GuardrailAction 命中检测规则后的处理方式
+type GuardrailAction string
+
+const (
+	GuardrailActionBlock GuardrailAction = "block" // 拒绝请求，响应中命中时截断输出
+	GuardrailActionMask  GuardrailAction = "mask"  // 替换命中的内容后继续转发
+	GuardrailActionLog   GuardrailAction = "log"   // 放行并记录命中的检测器与次数
+)
+
+// GuardrailPolicy 内容安全策略：转发前检查请求中的敏感信息，可选检查返回给客户端的内容。
+// 分组与 API Key 都可以配置，两者同时生效
+type GuardrailPolicy struct {
+	Enabled  bool            `json:"enabled"`
+	Rules    []GuardrailRule `json:"rules,omitempty"`
+	Response bool            `json:"response,omitempty"` // 同时检查响应内容，流式响应使用滑动窗口
+}
+
+// GuardrailRule 一条检测规则
+type GuardrailRule struct {
+	Detector    string          `json:"detector"`              // 内置检测器名称，或 regex、keywords
+	Pattern     string          `json:"pattern,omitempty"`     // detector=regex 时的正则表达式
+	Keywords    []string        `json:"keywords,omitempty"`    // detector=keywords 时的词典，不区分大小写
+	Action      GuardrailAction `json:"action"`                // block、mask 或 log
+	Replacement string          `json:"replacement,omitempty"` // mask 时的替换文本，默认 [DETECTOR]
+}
+
+// Active 策略是否启用，nil 视为未设置
+func (p *GuardrailPolicy) Active() bool {
+	return p != nil && p.Enabled && len(p.Rules) > 0
+}
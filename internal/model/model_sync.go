@@ -0,0 +1,40 @@
+package model
+
+// ModelSyncPending 模型同步待审核变更，每个渠道最多保留一条
+type ModelSyncPending struct {
+	ID          int      `json:"id" gorm:"primaryKey"`
+	ChannelID   int      `json:"channel_id" gorm:"uniqueIndex"`
+	ChannelName string   `json:"channel_name" gorm:"-"`
+	Added       []string `json:"added" gorm:"serializer:json"`
+	Removed     []string `json:"removed" gorm:"serializer:json"`
+	Protected   []string `json:"protected,omitempty" gorm:"serializer:json"` // 受保护规则限制、不会被自动删除的模型
+	Rejected    bool     `json:"rejected"`                                   // 已被拒绝，上游变更内容不变时不再等待审核，也不会自动应用
+	CreatedAt   int64    `json:"created_at"`                                 // 首次发现该变更的时间，用于计算宽限期
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
+// Empty 是否没有任何变更
+func (p *ModelSyncPending) Empty() bool {
+	return len(p.Added) == 0 && len(p.Removed) == 0
+}
+
+// Same 判断两次同步得到的变更是否一致
+func (p *ModelSyncPending) Same(other *ModelSyncPending) bool {
+	return sameStringSet(p.Added, other.Added) && sameStringSet(p.Removed, other.Removed)
+}
+
+func sameStringSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	m := make(map[string]struct{}, len(a))
+	for _, v := range a {
+		m[v] = struct{}{}
+	}
+	for _, v := range b {
+		if _, ok := m[v]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package model
+
+import "fmt"
+
+// ReasoningOutput 思考内容返回给客户端的形式
+type ReasoningOutput string
+
+const (
+	ReasoningOutputPassthrough      ReasoningOutput = "passthrough"       // 按入站格式原样返回
+	ReasoningOutputStrip            ReasoningOutput = "strip"             // 删除思考内容与 <think> 标签
+	ReasoningOutputReasoningContent ReasoningOutput = "reasoning_content" // 把正文开头的 <think> 标签提取为思考内容
+	ReasoningOutputThinkTags        ReasoningOutput = "think_tags"        // 把思考内容放入正文的 <think> 标签
+)
+
+// ReasoningPolicy 分组的思考策略：统一各上游的思考预算与思考内容的返回形式。
+// 启用后思考签名会标记来源上游，后续请求切换到其他上游时不再发送无效的签名
+type ReasoningPolicy struct {
+	Enabled bool                       `json:"enabled"`
+	Output  ReasoningOutput            `json:"output,omitempty"`  // 默认 passthrough
+	Budgets map[string]ReasoningBudget `json:"budgets,omitempty"` // 上游模型名 → 各档位的思考预算，"*" 匹配其余模型
+}
+
+// ReasoningBudget reasoning_effort 各档位对应的思考预算(token)，0 表示使用上游的默认映射
+type ReasoningBudget struct {
+	Low    int64 `json:"low,omitempty"`
+	Medium int64 `json:"medium,omitempty"`
+	High   int64 `json:"high,omitempty"`
+}
+
+func (p *ReasoningPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Output {
+	case "", ReasoningOutputPassthrough, ReasoningOutputStrip, ReasoningOutputReasoningContent, ReasoningOutputThinkTags:
+	default:
+		return fmt.Errorf("reasoning.output must be passthrough, strip, reasoning_content or think_tags")
+	}
+	for name, b := range p.Budgets {
+		if name == "" {
+			return fmt.Errorf("reasoning.budgets: model name is required")
+		}
+		if b.Low < 0 || b.Medium < 0 || b.High < 0 {
+			return fmt.Errorf("reasoning.budgets.%s: budget must not be negative", name)
+		}
+	}
+	return nil
+}
+
+// Active 策略是否启用，nil 视为未设置
+func (p *ReasoningPolicy) Active() bool {
+	return p != nil && p.Enabled
+}
+
+// Budget 返回上游模型在指定档位的思考预算，未配置时返回 0
+func (p *ReasoningPolicy) Budget(modelName, effort string) int64 {
+	b, ok := p.Budgets[modelName]
+	if !ok {
+		b = p.Budgets["*"]
+	}
+	switch effort {
+	case "low":
+		return b.Low
+	case "medium":
+		return b.Medium
+	case "high":
+		return b.High
+	}
+	return 0
+}
@@ -0,0 +1,50 @@
+package model
+
+import "testing"
+
+func TestReasoningPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  *ReasoningPolicy
+		wantErr bool
+	}{
+		{name: "nil", policy: nil},
+		{name: "defaults", policy: &ReasoningPolicy{Enabled: true}},
+		{name: "think tags", policy: &ReasoningPolicy{Enabled: true, Output: ReasoningOutputThinkTags, Budgets: map[string]ReasoningBudget{"*": {Low: 1024}}}},
+		{name: "invalid output", policy: &ReasoningPolicy{Output: "html"}, wantErr: true},
+		{name: "empty model name", policy: &ReasoningPolicy{Budgets: map[string]ReasoningBudget{"": {Low: 1}}}, wantErr: true},
+		{name: "negative budget", policy: &ReasoningPolicy{Budgets: map[string]ReasoningBudget{"m": {High: -1}}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReasoningPolicyBudget(t *testing.T) {
+	policy := &ReasoningPolicy{Enabled: true, Budgets: map[string]ReasoningBudget{
+		"claude": {Low: 1000, Medium: 2000, High: 3000},
+		"*":      {Medium: 500},
+	}}
+	tests := []struct {
+		name     string
+		model    string
+		effort   string
+		expected int64
+	}{
+		{name: "model budget", model: "claude", effort: "high", expected: 3000},
+		{name: "wildcard", model: "gemini", effort: "medium", expected: 500},
+		{name: "wildcard level not set", model: "gemini", effort: "high", expected: 0},
+		{name: "unknown effort", model: "claude", effort: "max", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Budget(tt.model, tt.effort); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
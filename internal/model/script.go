@@ -0,0 +1,37 @@
+package model
+
+import "encoding/json"
+
+// ScriptContext 脚本钩子的第二个参数 ctx，描述当前请求
+type ScriptContext struct {
+	Group    string `json:"group"`
+	Channel  string `json:"channel"` // 分组脚本的 on_request 运行在选择渠道之前，此时为空
+	Model    string `json:"model"`   // 客户端请求的模型名称
+	APIKeyID int    `json:"api_key_id"`
+	Stream   bool   `json:"stream"`
+}
+
+// ScriptTestRequest 在管理接口中试运行脚本的钩子
+type ScriptTestRequest struct {
+	Script  string          `json:"script" binding:"required"`
+	Hook    string          `json:"hook" binding:"required"`  // on_request、on_response 或 on_chunk
+	Input   json.RawMessage `json:"input" binding:"required"` // 内部格式的请求、响应或流式分片
+	Context ScriptContext   `json:"context"`
+}
+
+// ScriptTestResult 试运行的结果
+type ScriptTestResult struct {
+	Output     json.RawMessage `json:"output,omitempty"` // 钩子处理后的内容，拒绝或出错时为空
+	Rejected   *ScriptReject   `json:"rejected,omitempty"`
+	Routes     []string        `json:"routes,omitempty"`
+	Logs       []string        `json:"logs"`
+	Error      string          `json:"error,omitempty"`
+	Steps      uint64          `json:"steps"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+// ScriptReject 脚本调用 reject() 返回给客户端的错误
+type ScriptReject struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
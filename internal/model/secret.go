@@ -0,0 +1,10 @@
+package model
+
+// DataKey 数据加密密钥（DEK），由主密钥（KEK）加密后存储
+// 实际的敏感字段使用 Active 的 DataKey 加密，主密钥轮换时只需重新包装 DataKey
+type DataKey struct {
+	ID         string `json:"id" gorm:"primaryKey;size:32"`
+	WrappedKey string `json:"wrapped_key" gorm:"not null"`
+	Active     bool   `json:"active" gorm:"default:false"`
+	CreatedAt  int64  `json:"created_at"`
+}
@@ -15,7 +15,14 @@ const (
 	SettingKeySyncLLMInterval         SettingKey = "sync_llm_interval"          // LLM 同步间隔(小时)
 	SettingKeyRelayLogKeepPeriod      SettingKey = "relay_log_keep_period"      // 日志保存时间范围(天)
 	SettingKeyRelayLogKeepEnabled     SettingKey = "relay_log_keep_enabled"     // 是否保留历史日志
+	SettingKeyAuditLogKeepPeriod      SettingKey = "audit_log_keep_period"      // 审计日志保存时间范围(天)
+	SettingKeyKeyHealthCheckInterval  SettingKey = "key_health_check_interval"  // 渠道 key 健康检查间隔(分钟), 0 为关闭
+	SettingKeyKeyHealthAutoDisable    SettingKey = "key_health_auto_disable"    // 健康检查是否自动禁用失效或额度耗尽的 key
+	SettingKeyModelSyncReviewEnabled  SettingKey = "model_sync_review_enabled"  // 模型同步变更是否需要审核
+	SettingKeyModelSyncGracePeriod    SettingKey = "model_sync_grace_period"    // 待审核变更自动生效的宽限期(小时), 0 为仅手动审核
+	SettingKeyModelSyncProtectDays    SettingKey = "model_sync_protect_days"    // 已分组且最近 N 天有请求的模型不会被自动删除, 0 为关闭
 	SettingKeyCORSAllowOrigins        SettingKey = "cors_allow_origins"         // 跨域白名单(逗号分隔, 如 "example.com,example2.com"). 为空不允许跨域, "*"允许所有
+	SettingKeyCacheCheckInterval      SettingKey = "cache_check_interval"       // 检查数据库变更并刷新内存缓存的间隔(分钟), 0 为关闭
 )
 
 type Setting struct {
@@ -26,26 +33,34 @@ type Setting struct {
 func DefaultSettings() []Setting {
 	return []Setting{
 		{Key: SettingKeyProxyURL, Value: ""},
-		{Key: SettingKeyStatsSaveInterval, Value: "10"},       // 默认10分钟保存一次统计信息
-		{Key: SettingKeyCORSAllowOrigins, Value: ""},          // CORS 默认不允许跨域，设置为 "*" 才允许所有来源
-		{Key: SettingKeyModelInfoUpdateInterval, Value: "24"}, // 默认24小时更新一次模型信息
-		{Key: SettingKeySyncLLMInterval, Value: "24"},         // 默认24小时同步一次LLM
-		{Key: SettingKeyRelayLogKeepPeriod, Value: "7"},       // 默认日志保存7天
-		{Key: SettingKeyRelayLogKeepEnabled, Value: "true"},   // 默认保留历史日志
+		{Key: SettingKeyStatsSaveInterval, Value: "10"},         // 默认10分钟保存一次统计信息
+		{Key: SettingKeyCORSAllowOrigins, Value: ""},            // CORS 默认不允许跨域，设置为 "*" 才允许所有来源
+		{Key: SettingKeyModelInfoUpdateInterval, Value: "24"},   // 默认24小时更新一次模型信息
+		{Key: SettingKeySyncLLMInterval, Value: "24"},           // 默认24小时同步一次LLM
+		{Key: SettingKeyRelayLogKeepPeriod, Value: "7"},         // 默认日志保存7天
+		{Key: SettingKeyRelayLogKeepEnabled, Value: "true"},     // 默认保留历史日志
+		{Key: SettingKeyAuditLogKeepPeriod, Value: "90"},        // 默认审计日志保存90天
+		{Key: SettingKeyKeyHealthCheckInterval, Value: "60"},    // 默认60分钟检查一次渠道 key
+		{Key: SettingKeyKeyHealthAutoDisable, Value: "true"},    // 默认自动禁用失效或额度耗尽的 key
+		{Key: SettingKeyModelSyncReviewEnabled, Value: "false"}, // 默认直接应用模型同步结果
+		{Key: SettingKeyModelSyncGracePeriod, Value: "24"},      // 默认待审核变更24小时后自动生效
+		{Key: SettingKeyModelSyncProtectDays, Value: "0"},       // 默认不启用删除保护
+		{Key: SettingKeyCacheCheckInterval, Value: "1"},         // 默认每分钟检查一次缓存是否与数据库一致
 	}
 }
 
 func (s *Setting) Validate() error {
 	switch s.Key {
-	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod:
+	case SettingKeyModelInfoUpdateInterval, SettingKeySyncLLMInterval, SettingKeyRelayLogKeepPeriod, SettingKeyAuditLogKeepPeriod, SettingKeyKeyHealthCheckInterval,
+		SettingKeyModelSyncGracePeriod, SettingKeyModelSyncProtectDays, SettingKeyCacheCheckInterval:
 		_, err := strconv.Atoi(s.Value)
 		if err != nil {
 			return fmt.Errorf("model info update interval must be an integer")
 		}
 		return nil
-	case SettingKeyRelayLogKeepEnabled:
+	case SettingKeyRelayLogKeepEnabled, SettingKeyModelSyncReviewEnabled, SettingKeyKeyHealthAutoDisable:
 		if s.Value != "true" && s.Value != "false" {
-			return fmt.Errorf("relay log keep enabled must be true or false")
+			return fmt.Errorf("%s must be true or false", s.Key)
 		}
 		return nil
 	case SettingKeyProxyURL:
@@ -1,13 +1,15 @@
 package model
 
 type StatsMetrics struct {
-	InputToken     int64   `json:"input_token" gorm:"bigint"`
-	OutputToken    int64   `json:"output_token" gorm:"bigint"`
-	InputCost      float64 `json:"input_cost" gorm:"type:real"`
-	OutputCost     float64 `json:"output_cost" gorm:"type:real"`
-	WaitTime       int64   `json:"wait_time" gorm:"bigint"`
-	RequestSuccess int64   `json:"request_success" gorm:"bigint"`
-	RequestFailed  int64   `json:"request_failed" gorm:"bigint"`
+	InputToken      int64   `json:"input_token" gorm:"bigint"`
+	OutputToken     int64   `json:"output_token" gorm:"bigint"`
+	CacheReadToken  int64   `json:"cache_read_token" gorm:"bigint"`  // 输入中命中缓存的 token，包含在 InputToken 中
+	CacheWriteToken int64   `json:"cache_write_token" gorm:"bigint"` // 输入中写入缓存的 token，包含在 InputToken 中
+	InputCost       float64 `json:"input_cost" gorm:"type:real"`
+	OutputCost      float64 `json:"output_cost" gorm:"type:real"`
+	WaitTime        int64   `json:"wait_time" gorm:"bigint"`
+	RequestSuccess  int64   `json:"request_success" gorm:"bigint"`
+	RequestFailed   int64   `json:"request_failed" gorm:"bigint"`
 }
 
 type StatsTotal struct {
@@ -47,9 +49,19 @@ type StatsAPIKey struct {
 func (s *StatsMetrics) Add(delta StatsMetrics) {
 	s.InputToken += delta.InputToken
 	s.OutputToken += delta.OutputToken
+	s.CacheReadToken += delta.CacheReadToken
+	s.CacheWriteToken += delta.CacheWriteToken
 	s.InputCost += delta.InputCost
 	s.OutputCost += delta.OutputCost
 	s.WaitTime += delta.WaitTime
 	s.RequestSuccess += delta.RequestSuccess
 	s.RequestFailed += delta.RequestFailed
 }
+
+// CacheHitRate 输入 token 中命中缓存的比例
+func (s *StatsMetrics) CacheHitRate() float64 {
+	if s.InputToken == 0 {
+		return 0
+	}
+	return float64(s.CacheReadToken) / float64(s.InputToken)
+}
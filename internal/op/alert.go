@@ -0,0 +1,203 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/utils/cache"
+
+	"gorm.io/gorm"
+)
+
+var alertNotifierCache = cache.New[int, model.AlertNotifier](16)
+var alertRuleCache = cache.New[int, model.AlertRule](16)
+
+func AlertNotifierList(ctx context.Context) ([]model.AlertNotifier, error) {
+	notifiers := make([]model.AlertNotifier, 0, alertNotifierCache.Len())
+	for _, n := range alertNotifierCache.GetAll() {
+		notifiers = append(notifiers, n)
+	}
+	return notifiers, nil
+}
+
+func AlertNotifierGet(id int, ctx context.Context) (model.AlertNotifier, error) {
+	n, ok := alertNotifierCache.Get(id)
+	if !ok {
+		return model.AlertNotifier{}, fmt.Errorf("alert notifier not found")
+	}
+	return n, nil
+}
+
+// AlertNotifierMask 返回隐藏地址与凭据后的告警通知，用于返回给前端
+func AlertNotifierMask(n model.AlertNotifier) model.AlertNotifier {
+	n.URL = maskURL(n.URL)
+	n.TelegramBotToken = maskSecret(n.TelegramBotToken)
+	n.SMTPPassword = maskSecret(n.SMTPPassword)
+	return n
+}
+
+// AlertNotifierUnmask 更新时前端原样提交的掩码值替换为已保存的地址与凭据
+func AlertNotifierUnmask(n *model.AlertNotifier) {
+	existing, ok := alertNotifierCache.Get(n.ID)
+	if !ok {
+		return
+	}
+	if existing.URL != "" && n.URL == maskURL(existing.URL) {
+		n.URL = existing.URL
+	}
+	if existing.TelegramBotToken != "" && n.TelegramBotToken == maskSecret(existing.TelegramBotToken) {
+		n.TelegramBotToken = existing.TelegramBotToken
+	}
+	if existing.SMTPPassword != "" && n.SMTPPassword == maskSecret(existing.SMTPPassword) {
+		n.SMTPPassword = existing.SMTPPassword
+	}
+}
+
+// maskURL 保留协议与主机，隐藏路径与查询参数中的令牌
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return maskSecret(s)
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
+
+func AlertNotifierCreate(n *model.AlertNotifier, ctx context.Context) error {
+	if err := db.Conn(ctx).Create(n).Error; err != nil {
+		return fmt.Errorf("failed to create alert notifier: %w", err)
+	}
+	alertNotifierCache.Set(n.ID, *n)
+	auditRecord(ctx, model.AuditActionAlertNotifierCreate, "alert_notifier", n.ID, nil, auditAlertNotifier(n))
+	return nil
+}
+
+func AlertNotifierUpdate(n *model.AlertNotifier, ctx context.Context) error {
+	existing, ok := alertNotifierCache.Get(n.ID)
+	if !ok {
+		return fmt.Errorf("alert notifier not found")
+	}
+	AlertNotifierUnmask(n)
+	if err := db.Conn(ctx).Save(n).Error; err != nil {
+		return fmt.Errorf("failed to update alert notifier: %w", err)
+	}
+	alertNotifierCache.Set(n.ID, *n)
+	auditRecord(ctx, model.AuditActionAlertNotifierUpdate, "alert_notifier", n.ID, auditAlertNotifier(&existing), auditAlertNotifier(n))
+	return nil
+}
+
+func AlertNotifierDelete(id int, ctx context.Context) error {
+	existing, ok := alertNotifierCache.Get(id)
+	if !ok {
+		return fmt.Errorf("alert notifier not found")
+	}
+	if err := db.Conn(ctx).Delete(&model.AlertNotifier{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete alert notifier: %w", err)
+	}
+	alertNotifierCache.Del(id)
+	auditRecord(ctx, model.AuditActionAlertNotifierDelete, "alert_notifier", id, auditAlertNotifier(&existing), nil)
+	return nil
+}
+
+func AlertRuleList(ctx context.Context) ([]model.AlertRule, error) {
+	rules := make([]model.AlertRule, 0, alertRuleCache.Len())
+	for _, r := range alertRuleCache.GetAll() {
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// AlertRuleListByEvent 返回指定事件的已启用规则
+func AlertRuleListByEvent(event model.AlertEvent) []model.AlertRule {
+	rules := make([]model.AlertRule, 0)
+	for _, r := range alertRuleCache.GetAll() {
+		if r.Enabled && r.Event == event {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+func AlertRuleCreate(r *model.AlertRule, ctx context.Context) error {
+	if err := db.Conn(ctx).Create(r).Error; err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	alertRuleCache.Set(r.ID, *r)
+	auditRecord(ctx, model.AuditActionAlertRuleCreate, "alert_rule", r.ID, nil, r)
+	return nil
+}
+
+func AlertRuleUpdate(r *model.AlertRule, ctx context.Context) error {
+	existing, ok := alertRuleCache.Get(r.ID)
+	if !ok {
+		return fmt.Errorf("alert rule not found")
+	}
+	if err := db.Conn(ctx).Save(r).Error; err != nil {
+		return fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	alertRuleCache.Set(r.ID, *r)
+	auditRecord(ctx, model.AuditActionAlertRuleUpdate, "alert_rule", r.ID, &existing, r)
+	return nil
+}
+
+func AlertRuleDelete(id int, ctx context.Context) error {
+	existing, ok := alertRuleCache.Get(id)
+	if !ok {
+		return fmt.Errorf("alert rule not found")
+	}
+	if err := db.Conn(ctx).Delete(&model.AlertRule{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+	alertRuleCache.Del(id)
+	auditRecord(ctx, model.AuditActionAlertRuleDelete, "alert_rule", id, &existing, nil)
+	return nil
+}
+
+// AlertNotifierCredentialReencrypt 使用当前活动的数据密钥重写告警通知的地址与凭据
+func AlertNotifierCredentialReencrypt(tx *gorm.DB) error {
+	var notifiers []model.AlertNotifier
+	if err := tx.Find(&notifiers).Error; err != nil {
+		return fmt.Errorf("failed to load alert notifiers: %w", err)
+	}
+	for _, n := range notifiers {
+		if err := tx.Model(&model.AlertNotifier{}).Where("id = ?", n.ID).
+			Select("url", "telegram_bot_token", "smtp_password").Updates(&n).Error; err != nil {
+			return fmt.Errorf("failed to reencrypt alert notifier %d: %w", n.ID, err)
+		}
+	}
+	return nil
+}
+
+func alertRefreshCache(ctx context.Context) error {
+	notifiers := []model.AlertNotifier{}
+	if err := db.Conn(ctx).Find(&notifiers).Error; err != nil {
+		return err
+	}
+	rules := []model.AlertRule{}
+	if err := db.Conn(ctx).Find(&rules).Error; err != nil {
+		return err
+	}
+	notifierIDs := make(map[int]struct{}, len(notifiers))
+	for _, n := range notifiers {
+		notifierIDs[n.ID] = struct{}{}
+		alertNotifierCache.Set(n.ID, n)
+	}
+	for id := range alertNotifierCache.GetAll() {
+		if _, ok := notifierIDs[id]; !ok {
+			alertNotifierCache.Del(id)
+		}
+	}
+	ruleIDs := make(map[int]struct{}, len(rules))
+	for _, r := range rules {
+		ruleIDs[r.ID] = struct{}{}
+		alertRuleCache.Set(r.ID, r)
+	}
+	for id := range alertRuleCache.GetAll() {
+		if _, ok := ruleIDs[id]; !ok {
+			alertRuleCache.Del(id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package op
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/secret"
+)
+
+func TestCredentialReencryptNotifiers(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	n := &model.AlertNotifier{
+		Name:             "tg",
+		Type:             model.AlertNotifierTelegram,
+		Enabled:          true,
+		URL:              "https://hooks.example.com/services/T000/B000/XXXX",
+		TelegramBotToken: "123456:telegram-token",
+		TelegramChatID:   "42",
+		SMTPPassword:     "smtp-password",
+	}
+	if err := AlertNotifierCreate(n, ctx); err != nil {
+		t.Fatal(err)
+	}
+	var before struct{ TelegramBotToken string }
+	db.GetDB().Raw("SELECT telegram_bot_token FROM alert_notifiers WHERE id = ?", n.ID).Scan(&before)
+
+	if err := secret.RotateDataKey(db.GetDB(), CredentialReencrypt); err != nil {
+		t.Fatalf("failed to rotate data key: %v", err)
+	}
+
+	var raw struct{ TelegramBotToken string }
+	db.GetDB().Raw("SELECT telegram_bot_token FROM alert_notifiers WHERE id = ?", n.ID).Scan(&raw)
+	if !secret.IsEncrypted(raw.TelegramBotToken) || raw.TelegramBotToken == before.TelegramBotToken {
+		t.Fatalf("expected the token to be re-encrypted, got %q", raw.TelegramBotToken)
+	}
+	var got model.AlertNotifier
+	if err := db.GetDB().First(&got, n.ID).Error; err != nil {
+		t.Fatalf("failed to read notifier after rotation: %v", err)
+	}
+	tests := []struct {
+		name      string
+		got, want string
+	}{
+		{name: "url", got: got.URL, want: n.URL},
+		{name: "telegram bot token", got: got.TelegramBotToken, want: n.TelegramBotToken},
+		{name: "smtp password", got: got.SMTPPassword, want: n.SMTPPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, tt.got)
+			}
+		})
+	}
+}
+
+func TestAlertNotifierMask(t *testing.T) {
+	stored := model.AlertNotifier{
+		ID:               9001,
+		URL:              "https://hooks.slack.com/services/T000/B000/XXXX",
+		TelegramBotToken: "123456:telegram-token",
+		SMTPPassword:     "smtp-password",
+	}
+	alertNotifierCache.Set(stored.ID, stored)
+	t.Cleanup(func() { alertNotifierCache.Del(stored.ID) })
+
+	masked := AlertNotifierMask(stored)
+	if masked.URL != "https://hooks.slack.com/****" {
+		t.Errorf("expected masked url, got %q", masked.URL)
+	}
+	if strings.Contains(masked.TelegramBotToken, "telegram") || strings.Contains(masked.SMTPPassword, "password") {
+		t.Errorf("expected masked credentials, got %+v", masked)
+	}
+
+	tests := []struct {
+		name   string
+		update model.AlertNotifier
+		want   model.AlertNotifier
+	}{
+		{name: "mask sent back", update: masked, want: stored},
+		{
+			name:   "new values",
+			update: model.AlertNotifier{ID: stored.ID, URL: "https://example.com/new", TelegramBotToken: "new-token", SMTPPassword: "new-password"},
+			want:   model.AlertNotifier{ID: stored.ID, URL: "https://example.com/new", TelegramBotToken: "new-token", SMTPPassword: "new-password"},
+		},
+		{name: "cleared", update: model.AlertNotifier{ID: stored.ID}, want: model.AlertNotifier{ID: stored.ID}},
+		{name: "unknown notifier", update: model.AlertNotifier{ID: 1, URL: masked.URL}, want: model.AlertNotifier{ID: 1, URL: masked.URL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.update
+			AlertNotifierUnmask(&got)
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
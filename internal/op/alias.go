@@ -0,0 +1,186 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/utils/cache"
+	"octopus/internal/utils/log"
+
+	"github.com/dlclark/regexp2"
+)
+
+var modelAliasCache = cache.New[int, model.ModelAlias](16)
+
+// modelAliasMatcher 缓存编译后的匹配规则，别名变更时整体重建
+type modelAliasMatcher struct {
+	exact    map[string]model.ModelAlias // key 为小写的别名
+	patterns []compiledModelAlias        // 通配符与正则规则，按优先级排序
+}
+
+type compiledModelAlias struct {
+	alias model.ModelAlias
+	re    *regexp2.Regexp
+}
+
+var (
+	modelAliasRules     modelAliasMatcher
+	modelAliasRulesLock sync.RWMutex
+)
+
+func ModelAliasList(ctx context.Context) ([]model.ModelAlias, error) {
+	aliases := make([]model.ModelAlias, 0, modelAliasCache.Len())
+	for _, a := range modelAliasCache.GetAll() {
+		aliases = append(aliases, a)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].ID < aliases[j].ID })
+	return aliases, nil
+}
+
+func ModelAliasCreate(a *model.ModelAlias, ctx context.Context) error {
+	if _, ok := groupCache.Get(a.GroupID); !ok {
+		return fmt.Errorf("group not found")
+	}
+	if err := db.Conn(ctx).Create(a).Error; err != nil {
+		return fmt.Errorf("failed to create model alias: %w", err)
+	}
+	modelAliasCache.Set(a.ID, *a)
+	modelAliasRebuild()
+	auditRecord(ctx, model.AuditActionModelAliasCreate, "model_alias", a.ID, nil, a)
+	return nil
+}
+
+func ModelAliasUpdate(a *model.ModelAlias, ctx context.Context) error {
+	existing, ok := modelAliasCache.Get(a.ID)
+	if !ok {
+		return fmt.Errorf("model alias not found")
+	}
+	if _, ok := groupCache.Get(a.GroupID); !ok {
+		return fmt.Errorf("group not found")
+	}
+	if err := db.Conn(ctx).Save(a).Error; err != nil {
+		return fmt.Errorf("failed to update model alias: %w", err)
+	}
+	modelAliasCache.Set(a.ID, *a)
+	modelAliasRebuild()
+	auditRecord(ctx, model.AuditActionModelAliasUpdate, "model_alias", a.ID, &existing, a)
+	return nil
+}
+
+func ModelAliasDelete(id int, ctx context.Context) error {
+	existing, ok := modelAliasCache.Get(id)
+	if !ok {
+		return fmt.Errorf("model alias not found")
+	}
+	if err := db.Conn(ctx).Delete(&model.ModelAlias{}, id).Error; err != nil {
+		return fmt.Errorf("failed to delete model alias: %w", err)
+	}
+	modelAliasCache.Del(id)
+	modelAliasRebuild()
+	auditRecord(ctx, model.AuditActionModelAliasDelete, "model_alias", id, &existing, nil)
+	return nil
+}
+
+// ModelAliasResolve 按请求的模型名称查找分组：优先使用同名分组，其次依次匹配完全、通配符与正则别名。
+// 命中别名时同时返回该别名
+func ModelAliasResolve(name string, ctx context.Context) (model.Group, *model.ModelAlias, error) {
+	if group, err := GroupGetMap(name, ctx); err == nil {
+		return group, nil, nil
+	}
+
+	modelAliasRulesLock.RLock()
+	rules := modelAliasRules
+	modelAliasRulesLock.RUnlock()
+
+	if a, ok := rules.exact[strings.ToLower(name)]; ok {
+		if group, ok := groupCache.Get(a.GroupID); ok {
+			return group, &a, nil
+		}
+	}
+	for _, r := range rules.patterns {
+		matched, err := r.re.MatchString(name)
+		if err != nil {
+			log.Warnf("match model alias failed (alias=%d pattern=%q model=%q): %v", r.alias.ID, r.alias.Pattern, name, err)
+			continue
+		}
+		if !matched {
+			continue
+		}
+		if group, ok := groupCache.Get(r.alias.GroupID); ok {
+			a := r.alias
+			return group, &a, nil
+		}
+	}
+	return model.Group{}, nil, fmt.Errorf("group not found")
+}
+
+// ModelAliasListNames 返回可直接请求的别名（仅完全匹配规则）
+func ModelAliasListNames(ctx context.Context) []string {
+	modelAliasRulesLock.RLock()
+	defer modelAliasRulesLock.RUnlock()
+	names := make([]string, 0, len(modelAliasRules.exact))
+	for _, a := range modelAliasRules.exact {
+		if _, ok := groupCache.Get(a.GroupID); ok {
+			names = append(names, a.Pattern)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelAliasDelByGroup 删除指向已删除分组的别名
+func modelAliasDelByGroup(groupID int) {
+	for _, a := range modelAliasCache.GetAll() {
+		if a.GroupID == groupID {
+			modelAliasCache.Del(a.ID)
+		}
+	}
+	modelAliasRebuild()
+}
+
+func modelAliasRebuild() {
+	rules := modelAliasMatcher{exact: make(map[string]model.ModelAlias)}
+	for _, a := range modelAliasCache.GetAll() {
+		if !a.Enabled {
+			continue
+		}
+		if a.MatchType == model.ModelAliasMatchExact {
+			rules.exact[strings.ToLower(a.Pattern)] = a
+			continue
+		}
+		re, err := a.Compile()
+		if err != nil || re == nil {
+			log.Warnf("compile model alias failed (alias=%d pattern=%q): %v", a.ID, a.Pattern, err)
+			continue
+		}
+		rules.patterns = append(rules.patterns, compiledModelAlias{alias: a, re: re})
+	}
+	sort.Slice(rules.patterns, func(i, j int) bool {
+		if rules.patterns[i].alias.Priority != rules.patterns[j].alias.Priority {
+			return rules.patterns[i].alias.Priority < rules.patterns[j].alias.Priority
+		}
+		return rules.patterns[i].alias.ID < rules.patterns[j].alias.ID
+	})
+
+	modelAliasRulesLock.Lock()
+	modelAliasRules = rules
+	modelAliasRulesLock.Unlock()
+}
+
+func modelAliasRefreshCache(ctx context.Context) error {
+	aliases := []model.ModelAlias{}
+	if err := db.Conn(ctx).Find(&aliases).Error; err != nil {
+		return err
+	}
+	modelAliasCache.Clear()
+	for _, a := range aliases {
+		modelAliasCache.Set(a.ID, a)
+	}
+	modelAliasRebuild()
+	return nil
+}
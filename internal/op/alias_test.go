@@ -0,0 +1,61 @@
+package op
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestModelAliasResolve(t *testing.T) {
+	groups := []model.Group{{ID: 901, Name: "claude"}, {ID: 902, Name: "gpt"}, {ID: 903, Name: "slow"}}
+	aliases := []model.ModelAlias{
+		{ID: 901, Pattern: "Sonnet", MatchType: model.ModelAliasMatchExact, GroupID: 901, Enabled: true},
+		{ID: 902, Pattern: "claude-*", MatchType: model.ModelAliasMatchWildcard, GroupID: 901, Priority: 2, Enabled: true},
+		{ID: 903, Pattern: "^(a+)+$", MatchType: model.ModelAliasMatchRegex, GroupID: 903, Priority: 0, Enabled: true},
+		{ID: 904, Pattern: "^(gpt|a).*", MatchType: model.ModelAliasMatchRegex, GroupID: 902, Priority: 1, Enabled: true},
+		{ID: 905, Pattern: "disabled-*", MatchType: model.ModelAliasMatchWildcard, GroupID: 901, Enabled: false},
+	}
+	for _, g := range groups {
+		groupCache.Set(g.ID, g)
+	}
+	for _, a := range aliases {
+		modelAliasCache.Set(a.ID, a)
+	}
+	modelAliasRebuild()
+	t.Cleanup(func() {
+		for _, g := range groups {
+			groupCache.Del(g.ID)
+		}
+		for _, a := range aliases {
+			modelAliasCache.Del(a.ID)
+		}
+		modelAliasRebuild()
+	})
+
+	tests := []struct {
+		name      string
+		model     string
+		wantGroup string
+		wantErr   bool
+	}{
+		{name: "exact ignores case", model: "sonnet", wantGroup: "claude"},
+		{name: "wildcard", model: "claude-3-opus", wantGroup: "claude"},
+		{name: "priority order", model: "gpt-4o", wantGroup: "gpt"},
+		{name: "timeout counts as no match", model: strings.Repeat("a", 64) + "!", wantGroup: "gpt"},
+		{name: "disabled alias", model: "disabled-model", wantErr: true},
+		{name: "no match", model: "llama", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			group, _, err := ModelAliasResolve(tt.model, context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && group.Name != tt.wantGroup {
+				t.Errorf("expected %q, got %q", tt.wantGroup, group.Name)
+			}
+		})
+	}
+}
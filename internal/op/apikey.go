@@ -3,6 +3,9 @@ package op
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"octopus/internal/db"
 	"octopus/internal/model"
@@ -10,14 +13,23 @@ import (
 )
 
 var apiKeyCache = cache.New[int, model.APIKey](16)
-var apiKeyIDMap = cache.New[string, int](16)
+
+// apiKeyPrefixMap 前缀到密钥 ID 的映射，前缀可能重复，同一前缀下保留所有候选
+var apiKeyPrefixMap = cache.New[string, []int](16)
+var apiKeyPrefixLock sync.Mutex
+
+// apiKeyCredentialColumns 密钥凭据相关字段，只能通过创建/轮换修改
+var apiKeyCredentialColumns = []string{"key_prefix", "key_hash", "prev_key_prefix", "prev_key_hash", "prev_key_expire_at"}
 
 func APIKeyCreate(key *model.APIKey, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(key).Error; err != nil {
+	if err := key.HashKey(); err != nil {
+		return fmt.Errorf("failed to hash API key: %w", err)
+	}
+	if err := db.Conn(ctx).Create(key).Error; err != nil {
 		return fmt.Errorf("failed to create API key: %w", err)
 	}
-	apiKeyCache.Set(key.ID, *key)
-	apiKeyIDMap.Set(key.APIKey, key.ID)
+	apiKeyCacheSet(*key)
+	auditRecord(ctx, model.AuditActionAPIKeyCreate, "apikey", key.ID, nil, auditAPIKey(key))
 	return nil
 }
 
@@ -26,14 +38,46 @@ func APIKeyUpdate(key *model.APIKey, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("API key not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Omit("api_key").Save(key).Error; err != nil {
+	if err := db.Conn(ctx).Omit(apiKeyCredentialColumns...).Save(key).Error; err != nil {
 		return fmt.Errorf("failed to update API key: %w", err)
 	}
-	key.APIKey = existing.APIKey
+	key.APIKey = ""
+	key.KeyPrefix = existing.KeyPrefix
+	key.KeyHash = existing.KeyHash
+	key.PrevKeyPrefix = existing.PrevKeyPrefix
+	key.PrevKeyHash = existing.PrevKeyHash
+	key.PrevKeyExpireAt = existing.PrevKeyExpireAt
 	apiKeyCache.Set(key.ID, *key)
+	auditRecord(ctx, model.AuditActionAPIKeyUpdate, "apikey", key.ID, auditAPIKey(&existing), auditAPIKey(key))
 	return nil
 }
 
+// APIKeyRotate 为密钥设置新的明文 newKey，旧密钥在 gracePeriod 内仍然有效
+func APIKeyRotate(id int, newKey string, gracePeriod time.Duration, ctx context.Context) (model.APIKey, error) {
+	existing, ok := apiKeyCache.Get(id)
+	if !ok {
+		return model.APIKey{}, fmt.Errorf("API key not found")
+	}
+	rotated := existing
+	rotated.APIKey = newKey
+	if err := rotated.HashKey(); err != nil {
+		return model.APIKey{}, fmt.Errorf("failed to hash API key: %w", err)
+	}
+	rotated.PrevKeyPrefix, rotated.PrevKeyHash, rotated.PrevKeyExpireAt = "", "", 0
+	if gracePeriod > 0 {
+		rotated.PrevKeyPrefix = existing.KeyPrefix
+		rotated.PrevKeyHash = existing.KeyHash
+		rotated.PrevKeyExpireAt = time.Now().Add(gracePeriod).Unix()
+	}
+	if err := db.Conn(ctx).Model(&model.APIKey{ID: id}).Select(apiKeyCredentialColumns).Updates(&rotated).Error; err != nil {
+		return model.APIKey{}, fmt.Errorf("failed to rotate API key: %w", err)
+	}
+	apiKeyCacheDel(existing)
+	apiKeyCacheSet(rotated)
+	auditRecord(ctx, model.AuditActionAPIKeyRotate, "apikey", id, auditAPIKey(&existing), auditAPIKey(&rotated))
+	return rotated, nil
+}
+
 func APIKeyList(ctx context.Context) ([]model.APIKey, error) {
 	keys := make([]model.APIKey, 0, apiKeyCache.Len())
 	for _, apiKey := range apiKeyCache.GetAll() {
@@ -50,41 +94,97 @@ func APIKeyGet(id int, ctx context.Context) (model.APIKey, error) {
 	return apiKey, nil
 }
 
+// APIKeyGetByAPIKey 通过前缀定位候选密钥，再逐个校验哈希
 func APIKeyGetByAPIKey(apiKey string, ctx context.Context) (model.APIKey, error) {
-	id, ok := apiKeyIDMap.Get(apiKey)
-	if !ok {
-		return model.APIKey{}, fmt.Errorf("API key not found")
+	ids, _ := apiKeyPrefixMap.Get(model.APIKeyPrefix(apiKey))
+	for _, id := range ids {
+		key, err := APIKeyGet(id, ctx)
+		if err == nil && key.Verify(apiKey) {
+			return key, nil
+		}
 	}
-	return APIKeyGet(id, ctx)
+	return model.APIKey{}, fmt.Errorf("API key not found")
 }
 
 func APIKeyDelete(id int, ctx context.Context) error {
 	k := model.APIKey{
 		ID: id,
 	}
-	if err := StatsAPIKeyDel(id); err != nil {
+	if err := StatsAPIKeyDel(id, ctx); err != nil {
 		return fmt.Errorf("failed to delete stats API key: %v", err)
 	}
-	result := db.GetDB().WithContext(ctx).Delete(&k)
+	result := db.Conn(ctx).Delete(&k)
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("API key not found")
 	}
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete API key: %w", result.Error)
 	}
+	if existing, ok := apiKeyCache.Get(id); ok {
+		apiKeyCacheDel(existing)
+		auditRecord(ctx, model.AuditActionAPIKeyDelete, "apikey", id, auditAPIKey(&existing), nil)
+	}
 	apiKeyCache.Del(k.ID)
-	apiKeyIDMap.Del(k.APIKey)
 	return nil
 }
 
+func apiKeyCacheSet(key model.APIKey) {
+	key.APIKey = ""
+	apiKeyCache.Set(key.ID, key)
+	apiKeyPrefixLock.Lock()
+	defer apiKeyPrefixLock.Unlock()
+	apiKeyPrefixAdd(key.KeyPrefix, key.ID)
+	if key.PrevKeyPrefix != "" {
+		apiKeyPrefixAdd(key.PrevKeyPrefix, key.ID)
+	}
+}
+
+func apiKeyCacheDel(key model.APIKey) {
+	apiKeyPrefixLock.Lock()
+	defer apiKeyPrefixLock.Unlock()
+	apiKeyPrefixRemove(key.KeyPrefix, key.ID)
+	if key.PrevKeyPrefix != "" {
+		apiKeyPrefixRemove(key.PrevKeyPrefix, key.ID)
+	}
+}
+
+// apiKeyPrefixAdd 与 apiKeyPrefixRemove 需要持有 apiKeyPrefixLock，映射中的切片不原地修改
+func apiKeyPrefixAdd(prefix string, id int) {
+	ids, _ := apiKeyPrefixMap.Get(prefix)
+	if !slices.Contains(ids, id) {
+		apiKeyPrefixMap.Set(prefix, append(slices.Clone(ids), id))
+	}
+}
+
+func apiKeyPrefixRemove(prefix string, id int) {
+	ids, _ := apiKeyPrefixMap.Get(prefix)
+	ids = slices.DeleteFunc(slices.Clone(ids), func(v int) bool { return v == id })
+	if len(ids) == 0 {
+		apiKeyPrefixMap.Del(prefix)
+		return
+	}
+	apiKeyPrefixMap.Set(prefix, ids)
+}
+
 func apiKeyRefreshCache(ctx context.Context) error {
 	apiKeys := []model.APIKey{}
-	if err := db.GetDB().WithContext(ctx).Find(&apiKeys).Error; err != nil {
+	if err := db.Conn(ctx).Find(&apiKeys).Error; err != nil {
 		return err
 	}
+	ids := make(map[int]struct{}, len(apiKeys))
 	for _, apiKey := range apiKeys {
-		apiKeyCache.Set(apiKey.ID, apiKey)
-		apiKeyIDMap.Set(apiKey.APIKey, apiKey.ID)
+		ids[apiKey.ID] = struct{}{}
+		if old, ok := apiKeyCache.Get(apiKey.ID); ok && (old.KeyPrefix != apiKey.KeyPrefix || old.PrevKeyPrefix != apiKey.PrevKeyPrefix) {
+			apiKeyCacheDel(old)
+		}
+		apiKeyCacheSet(apiKey)
+	}
+	// 删除数据库中已经不存在的 key
+	for id, old := range apiKeyCache.GetAll() {
+		if _, ok := ids[id]; !ok {
+			apiKeyCacheDel(old)
+			apiKeyCache.Del(id)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,61 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"octopus/internal/model"
+)
+
+func TestAPIKeyGetByAPIKeySharedPrefix(t *testing.T) {
+	const prefix = "sk-octopus-AbCd1234"
+	keys := []model.APIKey{
+		{ID: 101, APIKey: prefix + "aaaaaaaaaaaaaaaaaaaaaaaa"},
+		{ID: 102, APIKey: prefix + "bbbbbbbbbbbbbbbbbbbbbbbb"},
+	}
+	for i := range keys {
+		if err := keys[i].HashKey(); err != nil {
+			t.Fatal(err)
+		}
+		apiKeyCacheSet(keys[i])
+	}
+	t.Cleanup(func() {
+		for _, k := range keys {
+			apiKeyCacheDel(k)
+			apiKeyCache.Del(k.ID)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		key     string
+		wantID  int
+		wantErr bool
+	}{
+		{name: "first candidate", key: keys[0].APIKey, wantID: 101},
+		{name: "second candidate", key: keys[1].APIKey, wantID: 102},
+		{name: "same prefix wrong secret", key: prefix + "cccccccccccccccccccccccc", wantErr: true},
+		{name: "unknown prefix", key: "sk-octopus-Zzzz9999cccccccccccccccccccccccc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := APIKeyGetByAPIKey(tt.key, context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && got.ID != tt.wantID {
+				t.Errorf("expected %d, got %d", tt.wantID, got.ID)
+			}
+		})
+	}
+
+	// 删除一个候选后另一个仍然可用
+	apiKeyCacheDel(keys[0])
+	apiKeyCache.Del(keys[0].ID)
+	if _, err := APIKeyGetByAPIKey(keys[0].APIKey, context.Background()); err == nil {
+		t.Error("expected deleted key to be rejected")
+	}
+	if got, err := APIKeyGetByAPIKey(keys[1].APIKey, context.Background()); err != nil || got.ID != 102 {
+		t.Errorf("expected 102, got %d, %v", got.ID, err)
+	}
+}
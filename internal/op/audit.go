@@ -0,0 +1,174 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/utils/diff"
+	"octopus/internal/utils/log"
+	"octopus/internal/utils/snowflake"
+)
+
+type auditActorKey struct{}
+
+type auditActor struct {
+	name string
+	ip   string
+}
+
+// WithAuditActor 在 ctx 中记录操作者与来源 IP，供后续 op 写入审计日志
+func WithAuditActor(ctx context.Context, actor, sourceIP string) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, auditActor{name: actor, ip: sourceIP})
+}
+
+// auditRecord 记录一次配置变更，写入失败只打印日志，不影响业务操作
+func auditRecord(ctx context.Context, action model.AuditAction, targetType string, targetID any, before, after any) {
+	changes, err := diff.Fields(before, after)
+	if err != nil {
+		log.Warnf("audit: failed to diff %s %s/%v: %v", action, targetType, targetID, err)
+		return
+	}
+	// update 类操作没有实际变化时不记录
+	if before != nil && after != nil && len(changes) == 0 {
+		return
+	}
+	entry := model.AuditLog{
+		ID:         snowflake.GenerateID(),
+		Time:       time.Now().Unix(),
+		Actor:      model.AuditActorSystem,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   fmt.Sprint(targetID),
+		Changes:    changes,
+	}
+	if actor, ok := ctx.Value(auditActorKey{}).(auditActor); ok {
+		entry.Actor = actor.name
+		entry.SourceIP = actor.ip
+	}
+	if err := db.Conn(context.WithoutCancel(ctx)).Create(&entry).Error; err != nil {
+		log.Warnf("audit: failed to save %s %s/%v: %v", action, targetType, targetID, err)
+	}
+}
+
+// auditChannel 返回用于审计的渠道快照，隐藏密钥与代理认证信息
+func auditChannel(ch *model.Channel) *model.Channel {
+	if ch == nil {
+		return nil
+	}
+	cp := *ch
+	if len(ch.Keys) > 0 {
+		cp.Keys = make([]model.ChannelKey, len(ch.Keys))
+		// 只保留配置字段，运行时统计（状态码、费用等）不参与比较
+		for i, k := range ch.Keys {
+			cp.Keys[i] = model.ChannelKey{
+				ID:         k.ID,
+				ChannelID:  k.ChannelID,
+				Enabled:    k.Enabled,
+				ChannelKey: maskSecret(k.ChannelKey),
+			}
+		}
+	}
+	if ch.ChannelProxy != nil {
+		proxy := *ch.ChannelProxy
+		if u, err := url.Parse(proxy); err == nil && u.User != nil {
+			proxy = u.Redacted()
+		}
+		cp.ChannelProxy = &proxy
+	}
+	return &cp
+}
+
+// auditAPIKey 返回用于审计的 API Key 快照，不记录哈希
+func auditAPIKey(k *model.APIKey) *model.APIKey {
+	if k == nil {
+		return nil
+	}
+	cp := *k
+	cp.APIKey = ""
+	cp.KeyHash = ""
+	cp.PrevKeyHash = ""
+	return &cp
+}
+
+// auditAlertNotifier 返回用于审计的告警通知快照，隐藏地址与凭据
+func auditAlertNotifier(n *model.AlertNotifier) *model.AlertNotifier {
+	if n == nil {
+		return nil
+	}
+	cp := *n
+	cp.URL = maskURL(n.URL)
+	cp.TelegramBotToken = maskSecret(n.TelegramBotToken)
+	cp.SMTPPassword = maskSecret(n.SMTPPassword)
+	return &cp
+}
+
+// auditSetting 返回用于审计的设置快照，代理地址可能包含认证信息
+func auditSetting(key model.SettingKey, value string) model.Setting {
+	if key == model.SettingKeyProxyURL {
+		value = maskURL(value)
+	}
+	return model.Setting{Key: key, Value: value}
+}
+
+// maskSecret 最多保留与 API Key 相同的 4 个字符前缀用于识别，较短的值完全隐藏
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	if len(s) < 16 {
+		return "****"
+	}
+	return s[:4] + "****"
+}
+
+// AuditLogList 分页查询审计日志，按时间倒序
+func AuditLogList(ctx context.Context, filter model.AuditLogFilter, page, pageSize int) ([]model.AuditLog, error) {
+	query := db.Conn(ctx).Model(&model.AuditLog{})
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("time >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("time <= ?", *filter.EndTime)
+	}
+	logs := make([]model.AuditLog, 0, pageSize)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	return logs, nil
+}
+
+// AuditLogCleanupTask 按 audit_log_keep_period 删除过期的审计日志
+func AuditLogCleanupTask(ctx context.Context) error {
+	keepPeriod, err := SettingGetInt(model.SettingKeyAuditLogKeepPeriod)
+	if err != nil {
+		return err
+	}
+	if keepPeriod <= 0 {
+		return nil
+	}
+	cutoffTime := time.Now().Add(-time.Duration(keepPeriod) * 24 * time.Hour).Unix()
+	result := db.Conn(ctx).Where("time < ?", cutoffTime).Delete(&model.AuditLog{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Debugf("audit log cleanup: deleted %d rows", result.RowsAffected)
+	}
+	return nil
+}
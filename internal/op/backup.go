@@ -2,19 +2,24 @@ package op
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"octopus/internal/db"
 	"octopus/internal/model"
+	"octopus/internal/secret"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-const dbDumpVersion = 1
+// dbDumpVersion 当前导出格式版本，格式变化时递增并在 backup_migrate.go 中注册迁移
+const dbDumpVersion = 2
 
-func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DBDump, error) {
-	conn := db.GetDB().WithContext(ctx)
+// DBExportAll 导出全部数据；decrypt 为 false 时渠道凭据保持加密
+func DBExportAll(ctx context.Context, includeLogs, includeStats, decrypt bool) (*model.DBDump, error) {
+	conn := db.Conn(ctx)
 
 	d := &model.DBDump{
 		Version:      dbDumpVersion,
@@ -29,6 +34,15 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 	if err := conn.Find(&d.ChannelKeys).Error; err != nil {
 		return nil, fmt.Errorf("export channel_keys: %w", err)
 	}
+	if !decrypt {
+		if err := encryptDumpCredentials(d); err != nil {
+			return nil, err
+		}
+		// 密文依赖数据密钥，随导出文件一起保存包装后的密钥，否则无法恢复到新库或轮换后的库
+		if err := conn.Find(&d.DataKeys).Error; err != nil {
+			return nil, fmt.Errorf("export data_keys: %w", err)
+		}
+	}
 	if err := conn.Find(&d.Groups).Error; err != nil {
 		return nil, fmt.Errorf("export groups: %w", err)
 	}
@@ -44,6 +58,9 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 	if err := conn.Find(&d.Settings).Error; err != nil {
 		return nil, fmt.Errorf("export settings: %w", err)
 	}
+	if err := conn.Find(&d.ModelAliases).Error; err != nil {
+		return nil, fmt.Errorf("export model_aliases: %w", err)
+	}
 
 	if includeStats {
 		if err := conn.Find(&d.StatsTotal).Error; err != nil {
@@ -76,102 +93,326 @@ func DBExportAll(ctx context.Context, includeLogs, includeStats bool) (*model.DB
 }
 
 func DBImportIncremental(ctx context.Context, dump *model.DBDump) (*model.DBImportResult, error) {
+	return DBImport(ctx, dump, model.DBImportOptions{})
+}
+
+// DBImportReplace 先清空导出文件覆盖的表，再导入全部数据；设置项仍按 key 覆盖，不会删除未导出的设置
+func DBImportReplace(ctx context.Context, dump *model.DBDump) (*model.DBImportResult, error) {
+	return DBImport(ctx, dump, model.DBImportOptions{Replace: true})
+}
+
+// errDryRun 用于回滚预览导入的事务
+var errDryRun = errors.New("dry run")
+
+// DBImport 按选项导入导出文件，dump 需由 DBDumpDecode 解析以完成版本迁移
+func DBImport(ctx context.Context, dump *model.DBDump, opts model.DBImportOptions) (*model.DBImportResult, error) {
 	if dump == nil {
 		return nil, fmt.Errorf("empty dump")
 	}
+	if dump.Version != dbDumpVersion {
+		return nil, fmt.Errorf("unsupported dump version %d, expected %d", dump.Version, dbDumpVersion)
+	}
+	if opts.Replace && opts.Selective() {
+		return nil, fmt.Errorf("replace cannot be combined with a selective restore")
+	}
 
-	if dump.Version != 0 && dump.Version != dbDumpVersion {
-		return nil, fmt.Errorf("unsupported dump version: %d", dump.Version)
+	if err := decryptDumpCredentials(dump, opts.MasterKey); err != nil {
+		return nil, err
+	}
+
+	var sel *dumpSelection
+	if opts.Selective() {
+		var err error
+		if sel, err = selectDump(dump, opts); err != nil {
+			return nil, err
+		}
 	}
 
-	conn := db.GetDB().WithContext(ctx)
-	res := &model.DBImportResult{RowsAffected: map[string]int64{}}
+	conn := db.Conn(ctx)
+	res := &model.DBImportResult{
+		Replaced:     opts.Replace,
+		DryRun:       opts.DryRun,
+		RowsAffected: map[string]int64{},
+		Tables:       map[string]*model.DBImportTableResult{},
+	}
 
 	err := conn.Transaction(func(tx *gorm.DB) error {
-		// base tables
-		if n, err := createDoNothing(tx, dump.Channels); err != nil {
-			return fmt.Errorf("import channels: %w", err)
-		} else {
-			res.RowsAffected["channels"] = n
-		}
-		if n, err := createDoNothing(tx, dump.ChannelKeys); err != nil {
-			return fmt.Errorf("import channel_keys: %w", err)
-		} else {
-			res.RowsAffected["channel_keys"] = n
-		}
-		if n, err := createDoNothing(tx, dump.Groups); err != nil {
-			return fmt.Errorf("import groups: %w", err)
-		} else {
-			res.RowsAffected["groups"] = n
-		}
-		if n, err := createDoNothing(tx, dump.GroupItems); err != nil {
-			return fmt.Errorf("import group_items: %w", err)
-		} else {
-			res.RowsAffected["group_items"] = n
-		}
-		if n, err := createUpsertAll(tx, dump.LLMInfos, []clause.Column{{Name: "name"}}); err != nil {
-			return fmt.Errorf("import llm_infos: %w", err)
-		} else {
-			res.RowsAffected["llm_infos"] = n
-		}
-		if n, err := createDoNothing(tx, dump.APIKeys); err != nil {
-			return fmt.Errorf("import api_keys: %w", err)
-		} else {
-			res.RowsAffected["api_keys"] = n
-		}
-		if n, err := createUpsertSettings(tx, dump.Settings); err != nil {
-			return fmt.Errorf("import settings: %w", err)
-		} else {
-			res.RowsAffected["settings"] = n
-		}
-
-		if dump.IncludeStats {
-			if n, err := createUpsertAll(tx, dump.StatsTotal, []clause.Column{{Name: "id"}}); err != nil {
-				return fmt.Errorf("import stats_total: %w", err)
-			} else {
-				res.RowsAffected["stats_total"] = n
-			}
-			if n, err := createUpsertAll(tx, dump.StatsDaily, []clause.Column{{Name: "date"}}); err != nil {
-				return fmt.Errorf("import stats_daily: %w", err)
-			} else {
-				res.RowsAffected["stats_daily"] = n
+		var err error
+		switch {
+		case sel != nil:
+			err = importSelective(tx, dump, sel, res)
+		default:
+			if opts.Replace {
+				if err := wipeDumpTables(tx, dump); err != nil {
+					return err
+				}
 			}
-			if n, err := createUpsertAll(tx, dump.StatsHourly, []clause.Column{{Name: "hour"}}); err != nil {
-				return fmt.Errorf("import stats_hourly: %w", err)
-			} else {
-				res.RowsAffected["stats_hourly"] = n
-			}
-			if n, err := createUpsertAll(tx, dump.StatsModel, []clause.Column{{Name: "id"}}); err != nil {
-				return fmt.Errorf("import stats_model: %w", err)
-			} else {
-				res.RowsAffected["stats_model"] = n
+			err = importAll(tx, dump, res)
+		}
+		if err != nil {
+			return err
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	for table, r := range res.Tables {
+		res.RowsAffected[table] = r.Inserted + r.Updated
+	}
+	if !opts.DryRun {
+		auditRecord(ctx, model.AuditActionDBImport, "database", "", nil, res)
+	}
+	return res, nil
+}
+
+// importAll 导入全部数据：基础表已存在的行跳过，价格、设置与统计按主键覆盖
+func importAll(tx *gorm.DB, dump *model.DBDump, res *model.DBImportResult) error {
+	// base tables
+	if err := importDoNothing(tx, res, "channels", dump.Channels, func(c model.Channel) (int, bool) { return c.ID, c.Enabled }); err != nil {
+		return err
+	}
+	if err := importDoNothing(tx, res, "channel_keys", dump.ChannelKeys, func(k model.ChannelKey) (int, bool) { return k.ID, k.Enabled }); err != nil {
+		return err
+	}
+	if err := importDoNothing(tx, res, "groups", dump.Groups, nil); err != nil {
+		return err
+	}
+	if err := importDoNothing(tx, res, "group_items", dump.GroupItems, nil); err != nil {
+		return err
+	}
+	if err := importUpsert(tx, res, "llm_infos", dump.LLMInfos, []string{"name", "channel_id"}, func(l model.LLMInfo) []any { return []any{l.Name, l.ChannelID} }); err != nil {
+		return err
+	}
+	if err := importDoNothing(tx, res, "api_keys", dump.APIKeys, func(k model.APIKey) (int, bool) { return k.ID, k.Enabled }); err != nil {
+		return err
+	}
+	if err := importUpsert(tx, res, "settings", dump.Settings, []string{"key"}, func(s model.Setting) []any { return []any{s.Key} }); err != nil {
+		return err
+	}
+	if err := importDoNothing(tx, res, "model_aliases", dump.ModelAliases, nil); err != nil {
+		return err
+	}
+
+	if dump.IncludeStats {
+		if err := importUpsert(tx, res, "stats_total", dump.StatsTotal, []string{"id"}, func(s model.StatsTotal) []any { return []any{s.ID} }); err != nil {
+			return err
+		}
+		if err := importUpsert(tx, res, "stats_daily", dump.StatsDaily, []string{"date"}, func(s model.StatsDaily) []any { return []any{s.Date} }); err != nil {
+			return err
+		}
+		if err := importUpsert(tx, res, "stats_hourly", dump.StatsHourly, []string{"hour"}, func(s model.StatsHourly) []any { return []any{s.Hour} }); err != nil {
+			return err
+		}
+		if err := importUpsert(tx, res, "stats_model", dump.StatsModel, []string{"id"}, func(s model.StatsModel) []any { return []any{s.ID} }); err != nil {
+			return err
+		}
+		if err := importUpsert(tx, res, "stats_channel", dump.StatsChannel, []string{"channel_id"}, func(s model.StatsChannel) []any { return []any{s.ChannelID} }); err != nil {
+			return err
+		}
+		if err := importUpsert(tx, res, "stats_api_key", dump.StatsAPIKey, []string{"api_key_id"}, func(s model.StatsAPIKey) []any { return []any{s.APIKeyID} }); err != nil {
+			return err
+		}
+	}
+
+	if dump.IncludeLogs {
+		if err := importDoNothing(tx, res, "relay_logs", dump.RelayLogs, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableResult(res *model.DBImportResult, table string) *model.DBImportTableResult {
+	r, ok := res.Tables[table]
+	if !ok {
+		r = &model.DBImportTableResult{}
+		res.Tables[table] = r
+	}
+	return r
+}
+
+// importDoNothing 插入新行并跳过冲突的行。gorm 会把带 default:true 的 false 字段写成默认值，
+// 传入 enabled 时在插入后恢复新插入行的禁用状态
+func importDoNothing[T any](tx *gorm.DB, res *model.DBImportResult, table string, rows []T, enabled func(T) (int, bool)) error {
+	r := tableResult(res, table)
+	if len(rows) == 0 {
+		return nil
+	}
+	var disabled []int
+	if enabled != nil {
+		for _, row := range rows {
+			if id, ok := enabled(row); !ok {
+				disabled = append(disabled, id)
 			}
-			if n, err := createUpsertAll(tx, dump.StatsChannel, []clause.Column{{Name: "channel_id"}}); err != nil {
-				return fmt.Errorf("import stats_channel: %w", err)
-			} else {
-				res.RowsAffected["stats_channel"] = n
+		}
+	}
+	var existing []int
+	if len(disabled) > 0 {
+		if err := tx.Model(new(T)).Where("id IN ?", disabled).Pluck("id", &existing).Error; err != nil {
+			return fmt.Errorf("import %s: %w", table, err)
+		}
+	}
+
+	n, err := createDoNothing(tx, rows)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", table, err)
+	}
+	r.Inserted += n
+	r.Skipped += int64(len(rows)) - n
+
+	if len(disabled) > 0 {
+		skip := make(map[int]bool, len(existing))
+		for _, id := range existing {
+			skip[id] = true
+		}
+		ids := make([]int, 0, len(disabled))
+		for _, id := range disabled {
+			if !skip[id] {
+				ids = append(ids, id)
 			}
-			if n, err := createUpsertAll(tx, dump.StatsAPIKey, []clause.Column{{Name: "api_key_id"}}); err != nil {
-				return fmt.Errorf("import stats_api_key: %w", err)
-			} else {
-				res.RowsAffected["stats_api_key"] = n
+		}
+		if len(ids) > 0 {
+			if err := tx.Model(new(T)).Where("id IN ?", ids).Update("enabled", false).Error; err != nil {
+				return fmt.Errorf("import %s: %w", table, err)
 			}
 		}
+	}
+	return nil
+}
 
-		if dump.IncludeLogs {
-			if n, err := createDoNothing(tx, dump.RelayLogs); err != nil {
-				return fmt.Errorf("import relay_logs: %w", err)
-			} else {
-				res.RowsAffected["relay_logs"] = n
-			}
+// importUpsert 按 columns 插入或覆盖，导入前统计已存在的行以区分新增与更新
+func importUpsert[T any](tx *gorm.DB, res *model.DBImportResult, table string, rows []T, columns []string, key func(T) []any) error {
+	r := tableResult(res, table)
+	if len(rows) == 0 {
+		return nil
+	}
+	existing, err := countExisting(tx, rows, columns, key)
+	if err != nil {
+		return fmt.Errorf("import %s: %w", table, err)
+	}
+	conflict := make([]clause.Column, len(columns))
+	for i, c := range columns {
+		conflict[i] = clause.Column{Name: c}
+	}
+	if _, err := createUpsertAll(tx, rows, conflict); err != nil {
+		return fmt.Errorf("import %s: %w", table, err)
+	}
+	r.Updated += existing
+	r.Inserted += int64(len(rows)) - existing
+	return nil
+}
+
+const importBatchSize = 500
+
+// countExisting 统计 rows 中按 columns 已存在于数据库的行数
+func countExisting[T any](tx *gorm.DB, rows []T, columns []string, key func(T) []any) (int64, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = tx.Statement.Quote(c)
+	}
+	cond := fmt.Sprintf("(%s) IN ?", strings.Join(quoted, ", "))
+
+	var total int64
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := min(start+importBatchSize, len(rows))
+		values := make([][]any, 0, end-start)
+		for _, row := range rows[start:end] {
+			values = append(values, key(row))
 		}
+		var n int64
+		if err := tx.Model(new(T)).Where(cond, values).Count(&n).Error; err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
 
+// wipeDumpTables 清空导出文件覆盖的表，依赖渠道的待审核模型同步记录一并清除
+func wipeDumpTables(tx *gorm.DB, dump *model.DBDump) error {
+	tables := []any{
+		&model.GroupItem{}, &model.ModelAlias{}, &model.Group{}, &model.ModelSyncPending{},
+		&model.ChannelKey{}, &model.Channel{}, &model.LLMInfo{}, &model.APIKey{},
+	}
+	if dump.IncludeStats {
+		tables = append(tables, &model.StatsTotal{}, &model.StatsDaily{}, &model.StatsHourly{},
+			&model.StatsModel{}, &model.StatsChannel{}, &model.StatsAPIKey{})
+	}
+	if dump.IncludeLogs {
+		tables = append(tables, &model.RelayLog{})
+	}
+	for _, t := range tables {
+		if err := tx.Where("1 = 1").Delete(t).Error; err != nil {
+			return fmt.Errorf("wipe %T: %w", t, err)
+		}
+	}
+	return nil
+}
+
+func encryptDumpCredentials(d *model.DBDump) error {
+	for i := range d.ChannelKeys {
+		v, err := secret.Encrypt(d.ChannelKeys[i].ChannelKey)
+		if err != nil {
+			return fmt.Errorf("encrypt channel key %d: %w", d.ChannelKeys[i].ID, err)
+		}
+		d.ChannelKeys[i].ChannelKey = v
+	}
+	for i := range d.Channels {
+		if d.Channels[i].ChannelProxy == nil {
+			continue
+		}
+		v, err := secret.Encrypt(*d.Channels[i].ChannelProxy)
+		if err != nil {
+			return fmt.Errorf("encrypt channel proxy %d: %w", d.Channels[i].ID, err)
+		}
+		d.Channels[i].ChannelProxy = &v
+	}
+	d.Encrypted = true
+	return nil
+}
+
+// decryptDumpCredentials 使用导出文件携带的数据密钥解密凭据，masterKey 为导出时的主密钥，为空时使用当前主密钥；
+// 不携带数据密钥的旧导出文件只有与导出方共享数据密钥时才能成功
+func decryptDumpCredentials(d *model.DBDump, masterKey string) error {
+	// 明文导出的凭据可能恰好以密文前缀开头，不能当作密文解密
+	if !d.Encrypted {
 		return nil
-	})
+	}
+	var kek []byte
+	if masterKey != "" {
+		var err error
+		if kek, err = secret.ParseMasterKey(masterKey); err != nil {
+			return err
+		}
+	}
+	keys, err := secret.UnwrapDataKeys(d.DataKeys, kek)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("dump data keys (pass the master key used at export time if it was changed since): %w", err)
 	}
-	return res, nil
+	for i := range d.ChannelKeys {
+		v, err := secret.DecryptWith(keys, d.ChannelKeys[i].ChannelKey)
+		if err != nil {
+			return fmt.Errorf("decrypt channel key %d (export with decrypt=true to move data between instances): %w", d.ChannelKeys[i].ID, err)
+		}
+		d.ChannelKeys[i].ChannelKey = v
+	}
+	for i := range d.Channels {
+		if d.Channels[i].ChannelProxy == nil {
+			continue
+		}
+		v, err := secret.DecryptWith(keys, *d.Channels[i].ChannelProxy)
+		if err != nil {
+			return fmt.Errorf("decrypt channel proxy %d (export with decrypt=true to move data between instances): %w", d.Channels[i].ID, err)
+		}
+		d.Channels[i].ChannelProxy = &v
+	}
+	d.Encrypted = false
+	d.DataKeys = nil
+	return nil
 }
 
 func createDoNothing[T any](tx *gorm.DB, rows []T) (int64, error) {
@@ -192,14 +433,3 @@ func createUpsertAll[T any](tx *gorm.DB, rows []T, columns []clause.Column) (int
 	}).Create(&rows)
 	return result.RowsAffected, result.Error
 }
-
-func createUpsertSettings(tx *gorm.DB, rows []model.Setting) (int64, error) {
-	if len(rows) == 0 {
-		return 0, nil
-	}
-	result := tx.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "key"}},
-		DoUpdates: clause.AssignmentColumns([]string{"value"}),
-	}).Create(&rows)
-	return result.RowsAffected, result.Error
-}
@@ -0,0 +1,129 @@
+package op
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"octopus/internal/model"
+)
+
+// dumpMigration 将导出文件从 From 版本升级到 From+1。迁移直接作用于 JSON 对象，
+// 因此可以读取已经从模型结构体中删除或改名的字段
+type dumpMigration struct {
+	From int
+	Up   func(d map[string]any) error
+}
+
+// dumpMigrations 按 From 升序排列，最后一项的 From+1 必须等于 dbDumpVersion
+var dumpMigrations = []dumpMigration{
+	{From: 0, Up: migrateDumpLegacyChannels},
+	{From: 1, Up: migrateDumpHashAPIKeys},
+}
+
+// DBDumpDecode 解析导出文件并逐级迁移到当前版本
+func DBDumpDecode(data []byte) (*model.DBDump, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid dump: %w", err)
+	}
+
+	version := 0
+	if v, ok := raw["version"]; ok && v != nil {
+		n, err := strconv.Atoi(fmt.Sprint(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid dump version %v", v)
+		}
+		version = n
+	}
+	if version > dbDumpVersion {
+		return nil, fmt.Errorf("dump version %d is newer than the supported version %d, upgrade octopus first", version, dbDumpVersion)
+	}
+	for _, m := range dumpMigrations {
+		if m.From != version {
+			continue
+		}
+		if err := m.Up(raw); err != nil {
+			return nil, fmt.Errorf("migrate dump from version %d: %w", m.From, err)
+		}
+		version = m.From + 1
+	}
+	if version != dbDumpVersion {
+		return nil, fmt.Errorf("no migration path from dump version %d", version)
+	}
+	raw["version"] = version
+
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var dump model.DBDump
+	if err := json.Unmarshal(migrated, &dump); err != nil {
+		return nil, fmt.Errorf("invalid dump: %w", err)
+	}
+	return &dump, nil
+}
+
+// dumpRows 返回导出文件中某张表的行，非对象的元素会被忽略
+func dumpRows(d map[string]any, table string) []map[string]any {
+	list, _ := d[table].([]any)
+	rows := make([]map[string]any, 0, len(list))
+	for _, item := range list {
+		if row, ok := item.(map[string]any); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// 0 -> 1: 早期版本的渠道只有单个 key 与 base_url 字段，
+// 转换为 channel_keys 与 base_urls，与数据库迁移 001 保持一致
+func migrateDumpLegacyChannels(d map[string]any) error {
+	keys, _ := d["channel_keys"].([]any)
+	hasKeys := map[string]bool{}
+	for _, k := range dumpRows(d, "channel_keys") {
+		hasKeys[fmt.Sprint(k["channel_id"])] = true
+	}
+	for _, ch := range dumpRows(d, "channels") {
+		if key, _ := ch["key"].(string); key != "" && !hasKeys[fmt.Sprint(ch["id"])] {
+			keys = append(keys, map[string]any{
+				"channel_id":  ch["id"],
+				"channel_key": key,
+				"enabled":     true,
+			})
+		}
+		delete(ch, "key")
+
+		if baseURL, _ := ch["base_url"].(string); baseURL != "" {
+			if urls, _ := ch["base_urls"].([]any); len(urls) == 0 {
+				ch["base_urls"] = []any{map[string]any{"url": baseURL, "delay": 0}}
+			}
+		}
+		delete(ch, "base_url")
+	}
+	if len(keys) > 0 {
+		d["channel_keys"] = keys
+	}
+	return nil
+}
+
+// 1 -> 2: API Key 只保存哈希，旧版本导出的明文 api_key 在迁移时转换为 key_prefix 与 key_hash
+func migrateDumpHashAPIKeys(d map[string]any) error {
+	for _, k := range dumpRows(d, "api_keys") {
+		plain, _ := k["api_key"].(string)
+		delete(k, "api_key")
+		if hash, _ := k["key_hash"].(string); hash != "" || plain == "" {
+			continue
+		}
+		key := model.APIKey{APIKey: plain}
+		if err := key.HashKey(); err != nil {
+			return err
+		}
+		k["key_prefix"] = key.KeyPrefix
+		k["key_hash"] = key.KeyHash
+	}
+	return nil
+}
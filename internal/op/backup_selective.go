@@ -0,0 +1,234 @@
+package op
+
+import (
+	"fmt"
+	"strconv"
+
+	"octopus/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dumpSelection 选择性恢复中选中的渠道、分组与 API Key
+type dumpSelection struct {
+	channels []model.Channel
+	groups   []model.Group
+	apiKeys  []model.APIKey
+}
+
+// selectDump 按名称或 ID 在导出文件中查找选中的对象，任一选择器没有匹配时返回错误
+func selectDump(dump *model.DBDump, opts model.DBImportOptions) (*dumpSelection, error) {
+	sel := &dumpSelection{}
+	var err error
+	if sel.channels, err = selectRows("channel", dump.Channels, opts.Channels, func(c model.Channel) (int, string) { return c.ID, c.Name }); err != nil {
+		return nil, err
+	}
+	if sel.groups, err = selectRows("group", dump.Groups, opts.Groups, func(g model.Group) (int, string) { return g.ID, g.Name }); err != nil {
+		return nil, err
+	}
+	if sel.apiKeys, err = selectRows("api key", dump.APIKeys, opts.APIKeys, func(k model.APIKey) (int, string) { return k.ID, k.Name }); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+func selectRows[T any](kind string, rows []T, selectors []string, ident func(T) (int, string)) ([]T, error) {
+	var selected []T
+	for _, s := range selectors {
+		found := false
+		for _, row := range rows {
+			id, name := ident(row)
+			if name == s || strconv.Itoa(id) == s {
+				selected = append(selected, row)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s %q not found in dump", kind, s)
+		}
+	}
+	return selected, nil
+}
+
+// importSelective 只导入选中的对象：按名称匹配已有对象并覆盖，新对象的 ID 被占用时重新分配
+func importSelective(tx *gorm.DB, dump *model.DBDump, sel *dumpSelection, res *model.DBImportResult) error {
+	// 导出文件中的渠道 ID -> 当前数据库中的渠道 ID
+	channelIDs := map[int]int{}
+	for _, ch := range sel.channels {
+		id, err := restoreChannel(tx, dump, ch, res)
+		if err != nil {
+			return fmt.Errorf("restore channel %s: %w", ch.Name, err)
+		}
+		channelIDs[ch.ID] = id
+	}
+	for _, g := range sel.groups {
+		if err := restoreGroup(tx, dump, g, channelIDs, res); err != nil {
+			return fmt.Errorf("restore group %s: %w", g.Name, err)
+		}
+	}
+	for _, k := range sel.apiKeys {
+		if err := restoreAPIKey(tx, k, res); err != nil {
+			return fmt.Errorf("restore api key %s: %w", k.Name, err)
+		}
+	}
+	return nil
+}
+
+func restoreChannel(tx *gorm.DB, dump *model.DBDump, ch model.Channel, res *model.DBImportResult) (int, error) {
+	dumpID := ch.ID
+	ch.Keys = nil
+	ch.Stats = nil
+
+	var existing model.Channel
+	if err := tx.Where("name = ?", ch.Name).Limit(1).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	if existing.ID != 0 {
+		ch.ID = existing.ID
+		if err := tx.Omit(clause.Associations).Save(&ch).Error; err != nil {
+			return 0, err
+		}
+		tableResult(res, "channels").Updated++
+		// key 以导出文件为准整体替换
+		if err := tx.Where("channel_id = ?", ch.ID).Delete(&model.ChannelKey{}).Error; err != nil {
+			return 0, err
+		}
+	} else {
+		if err := insertRemapped(tx, res, "channels", ch.Name, &ch, &ch.ID, ch.Enabled); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, k := range dump.ChannelKeys {
+		if k.ChannelID != dumpID {
+			continue
+		}
+		k.ChannelID = ch.ID
+		if err := insertRemapped(tx, res, "channel_keys", "", &k, &k.ID, k.Enabled); err != nil {
+			return 0, err
+		}
+	}
+
+	var prices []model.LLMInfo
+	for _, l := range dump.LLMInfos {
+		if l.ChannelID == dumpID {
+			l.ChannelID = ch.ID
+			prices = append(prices, l)
+		}
+	}
+	if err := importUpsert(tx, res, "llm_infos", prices, []string{"name", "channel_id"}, func(l model.LLMInfo) []any { return []any{l.Name, l.ChannelID} }); err != nil {
+		return 0, err
+	}
+	return ch.ID, nil
+}
+
+func restoreGroup(tx *gorm.DB, dump *model.DBDump, g model.Group, channelIDs map[int]int, res *model.DBImportResult) error {
+	dumpID := g.ID
+	g.Items = nil
+
+	var existing model.Group
+	if err := tx.Where("name = ?", g.Name).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if existing.ID != 0 {
+		g.ID = existing.ID
+		if err := tx.Omit(clause.Associations).Save(&g).Error; err != nil {
+			return err
+		}
+		tableResult(res, "groups").Updated++
+		if err := tx.Where("group_id = ?", g.ID).Delete(&model.GroupItem{}).Error; err != nil {
+			return err
+		}
+	} else {
+		if err := insertRemapped(tx, res, "groups", g.Name, &g, &g.ID, true); err != nil {
+			return err
+		}
+	}
+
+	dumpChannels := make(map[int]string, len(dump.Channels))
+	for _, ch := range dump.Channels {
+		dumpChannels[ch.ID] = ch.Name
+	}
+	for _, item := range dump.GroupItems {
+		if item.GroupID != dumpID {
+			continue
+		}
+		channelID, ok := channelIDs[item.ChannelID]
+		if !ok {
+			// 未选中的渠道按名称匹配当前数据库中的渠道
+			var existing model.Channel
+			if name, inDump := dumpChannels[item.ChannelID]; inDump {
+				if err := tx.Where("name = ?", name).Limit(1).Find(&existing).Error; err != nil {
+					return err
+				}
+			}
+			if existing.ID == 0 {
+				tableResult(res, "group_items").Skipped++
+				res.Warnings = append(res.Warnings, fmt.Sprintf("group %s: item %s skipped, channel %d does not exist", g.Name, item.ModelName, item.ChannelID))
+				continue
+			}
+			channelID = existing.ID
+			channelIDs[item.ChannelID] = channelID
+		}
+		item.GroupID = g.ID
+		item.ChannelID = channelID
+		if err := insertRemapped(tx, res, "group_items", "", &item, &item.ID, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreAPIKey 优先按密钥哈希匹配已有的 API Key，其次按名称
+func restoreAPIKey(tx *gorm.DB, k model.APIKey, res *model.DBImportResult) error {
+	var existing model.APIKey
+	if k.KeyHash != "" {
+		if err := tx.Where("key_hash = ?", k.KeyHash).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+	}
+	if existing.ID == 0 {
+		if err := tx.Where("name = ?", k.Name).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+	}
+	if existing.ID != 0 {
+		k.ID = existing.ID
+		if err := tx.Save(&k).Error; err != nil {
+			return err
+		}
+		tableResult(res, "api_keys").Updated++
+		return nil
+	}
+	return insertRemapped(tx, res, "api_keys", k.Name, &k, &k.ID, k.Enabled)
+}
+
+// insertRemapped 插入一行，原 ID 已被占用时由数据库分配新 ID；name 非空时记录重新分配的 ID。
+// gorm 会把带 default:true 的 false 字段写成默认值，enabled 为 false 时插入后单独更新
+func insertRemapped[T any](tx *gorm.DB, res *model.DBImportResult, table, name string, row *T, id *int, enabled bool) error {
+	from := *id
+	if from != 0 {
+		var n int64
+		if err := tx.Model(new(T)).Where("id = ?", from).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			*id = 0
+		}
+	}
+	if err := tx.Omit(clause.Associations).Create(row).Error; err != nil {
+		return err
+	}
+	if !enabled {
+		if err := tx.Model(new(T)).Where("id = ?", *id).Update("enabled", false).Error; err != nil {
+			return err
+		}
+	}
+	tableResult(res, table).Inserted++
+	if name != "" && from != 0 && *id != from {
+		res.Remapped = append(res.Remapped, model.DBImportRemap{Table: table, Name: name, From: from, To: *id})
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package op
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+)
+
+func TestDBDumpDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+		check   func(t *testing.T, d *model.DBDump)
+	}{
+		{
+			name: "version 0 legacy channel fields",
+			data: `{"channels":[{"id":3,"name":"c","key":"sk-legacy","base_url":"https://example.com"}]}`,
+			check: func(t *testing.T, d *model.DBDump) {
+				if len(d.ChannelKeys) != 1 || d.ChannelKeys[0].ChannelID != 3 || d.ChannelKeys[0].ChannelKey != "sk-legacy" {
+					t.Fatalf("expected legacy key to become a channel key, got %+v", d.ChannelKeys)
+				}
+				if len(d.Channels[0].BaseUrls) != 1 || d.Channels[0].BaseUrls[0].URL != "https://example.com" {
+					t.Fatalf("expected legacy base_url to become base_urls, got %+v", d.Channels[0].BaseUrls)
+				}
+			},
+		},
+		{
+			name: "version 0 keeps existing channel keys",
+			data: `{"channels":[{"id":3,"name":"c","key":"sk-legacy"}],"channel_keys":[{"id":1,"channel_id":3,"channel_key":"sk-new"}]}`,
+			check: func(t *testing.T, d *model.DBDump) {
+				if len(d.ChannelKeys) != 1 || d.ChannelKeys[0].ChannelKey != "sk-new" {
+					t.Fatalf("expected only the existing channel key, got %+v", d.ChannelKeys)
+				}
+			},
+		},
+		{
+			name: "version 1 plaintext api key",
+			data: `{"version":1,"api_keys":[{"id":1,"name":"k","api_key":"sk-octopus-0123456789abcdef"}]}`,
+			check: func(t *testing.T, d *model.DBDump) {
+				k := d.APIKeys[0]
+				if k.APIKey != "" || k.KeyHash == "" || k.KeyPrefix != model.APIKeyPrefix("sk-octopus-0123456789abcdef") {
+					t.Fatalf("expected plaintext key to be hashed, got %+v", k)
+				}
+				if !k.Verify("sk-octopus-0123456789abcdef") {
+					t.Fatal("expected hashed key to verify")
+				}
+			},
+		},
+		{
+			name: "current version",
+			data: `{"version":2,"groups":[{"id":1,"name":"g"}]}`,
+			check: func(t *testing.T, d *model.DBDump) {
+				if d.Version != dbDumpVersion || len(d.Groups) != 1 {
+					t.Fatalf("expected current dump unchanged, got %+v", d)
+				}
+			},
+		},
+		{name: "newer version", data: `{"version":99}`, wantErr: "upgrade octopus"},
+		{name: "invalid version", data: `{"version":"x"}`, wantErr: "invalid dump version"},
+		{name: "invalid json", data: `{`, wantErr: "invalid dump"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := DBDumpDecode([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, d)
+		})
+	}
+}
+
+// selectiveDump 导出文件中的渠道与分组 ID 均为 1，与测试库中已有的行冲突
+func selectiveDump() *model.DBDump {
+	return &model.DBDump{
+		Version:     dbDumpVersion,
+		Channels:    []model.Channel{{ID: 1, Name: "restored", Model: "m", Enabled: true}, {ID: 2, Name: "unselected", Model: "m"}},
+		ChannelKeys: []model.ChannelKey{{ID: 1, ChannelID: 1, Enabled: true, ChannelKey: "sk-restored"}},
+		Groups:      []model.Group{{ID: 1, Name: "g", Mode: model.GroupModeRoundRobin}},
+		GroupItems: []model.GroupItem{
+			{ID: 1, GroupID: 1, ChannelID: 1, ModelName: "m"},
+			{ID: 2, GroupID: 1, ChannelID: 2, ModelName: "m"},
+		},
+		LLMInfos: []model.LLMInfo{{Name: "m", ChannelID: 1}},
+	}
+}
+
+func TestDBImportSelective(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     model.DBImportOptions
+		wantErr  string
+		channels int64
+		remapped int
+	}{
+		{name: "channel and group", opts: model.DBImportOptions{Channels: []string{"restored"}, Groups: []string{"1"}}, channels: 2, remapped: 2},
+		{name: "dry run", opts: model.DBImportOptions{Channels: []string{"restored"}, DryRun: true}, channels: 1, remapped: 1},
+		{name: "unknown selector", opts: model.DBImportOptions{Channels: []string{"missing"}}, wantErr: "not found in dump"},
+		{name: "replace with selection", opts: model.DBImportOptions{Channels: []string{"restored"}, Replace: true}, wantErr: "cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			ctx := context.Background()
+			conn := db.GetDB()
+			if err := conn.Create(&model.Channel{ID: 1, Name: "existing", Model: "m"}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := conn.Create(&model.Group{ID: 1, Name: "existing", Mode: model.GroupModeRoundRobin}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := DBImport(ctx, selectiveDump(), tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Remapped) != tt.remapped {
+				t.Fatalf("expected %d remapped rows, got %+v", tt.remapped, res.Remapped)
+			}
+			var channels int64
+			conn.Model(&model.Channel{}).Count(&channels)
+			if channels != tt.channels {
+				t.Fatalf("expected %d channels, got %d", tt.channels, channels)
+			}
+			if tt.opts.DryRun {
+				return
+			}
+
+			var ch model.Channel
+			if err := conn.Where("name = ?", "restored").First(&ch).Error; err != nil {
+				t.Fatal(err)
+			}
+			if ch.ID == 1 {
+				t.Fatal("expected restored channel to get a new ID")
+			}
+			var keys []model.ChannelKey
+			conn.Where("channel_id = ?", ch.ID).Find(&keys)
+			if len(keys) != 1 || keys[0].ChannelKey != "sk-restored" {
+				t.Fatalf("expected the channel key to follow the remapped channel, got %+v", keys)
+			}
+			var g model.Group
+			if err := conn.Where("name = ?", "g").First(&g).Error; err != nil {
+				t.Fatal(err)
+			}
+			var items []model.GroupItem
+			conn.Where("group_id = ?", g.ID).Find(&items)
+			if len(items) != 1 || items[0].ChannelID != ch.ID {
+				t.Fatalf("expected one item pointing at channel %d, got %+v", ch.ID, items)
+			}
+			if len(res.Warnings) != 1 {
+				t.Fatalf("expected a warning for the unselected channel, got %v", res.Warnings)
+			}
+		})
+	}
+}
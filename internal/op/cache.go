@@ -3,12 +3,74 @@ package op
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"slices"
+	"sync"
 	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/utils/log"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// cacheEntity 一类从数据库加载的内存缓存，models 中任意一张表的数据变化都会重新加载整个缓存
+type cacheEntity struct {
+	name    string
+	models  []any
+	runtime []string // 运行时频繁写入、以内存为准的列，不参与数据指纹
+	refresh func(ctx context.Context) error
+}
+
+var cacheEntities = []cacheEntity{
+	{name: "setting", models: []any{&model.Setting{}}, refresh: settingRefreshCache},
+	{name: "channel", models: []any{&model.Channel{}, &model.ChannelKey{}}, runtime: []string{"status_code", "last_use_time_stamp", "total_cost", "health_checked_at"}, refresh: channelRefreshCache},
+	{name: "group", models: []any{&model.Group{}, &model.GroupItem{}}, refresh: groupRefreshCache},
+	{name: "api_key", models: []any{&model.APIKey{}}, refresh: apiKeyRefreshCache},
+	{name: "llm", models: []any{&model.LLMInfo{}}, refresh: llmRefreshCache},
+	{name: "model_alias", models: []any{&model.ModelAlias{}}, refresh: modelAliasRefreshCache},
+	{name: "alert", models: []any{&model.AlertNotifier{}, &model.AlertRule{}}, refresh: alertRefreshCache},
+	{name: "user", models: []any{&model.User{}}, refresh: userRefreshCache},
+}
+
+var (
+	// cacheReloadLock 串行化缓存的重新加载，避免定时检查、集群同步与手动刷新互相覆盖
+	cacheReloadLock sync.Mutex
+	cacheStatus     = make(map[string]model.CacheStatus)
+	cacheStatusLock sync.RWMutex
 )
 
+const cacheSkipNotifyKey = "octopus:cache_skip_notify"
+
+// cacheWrites 记录本进程修改过的缓存实体，CachePublish 时递增 cache_versions 中的版本号，
+// 运行中的服务（以及集群中的其他实例）据此发现变化并重新加载缓存
+var cacheWrites struct {
+	mu     sync.Mutex
+	conn   *gorm.DB            // 已注册写入回调的连接
+	tables map[string]string   // 数据表 -> 缓存实体
+	dirty  map[string]struct{} // 修改过、尚未递增版本号的实体
+}
+
 func InitCache() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if err := cacheTrackWrites(db.GetDB()); err != nil {
+		return fmt.Errorf("track cache writes: %w", err)
+	}
+	cacheReloadLock.Lock()
+	defer cacheReloadLock.Unlock()
+	// 指纹在加载前计算，加载期间发生的修改会在下一次检查时被发现
+	fingerprints := make(map[string]string, len(cacheEntities))
+	for _, e := range cacheEntities {
+		fp, err := cacheFingerprint(ctx, e)
+		if err != nil {
+			log.Warnf("failed to fingerprint %s cache: %v", e.name, err)
+			continue
+		}
+		fingerprints[e.name] = fp
+	}
 	if err := settingRefreshCache(ctx); err != nil {
 		return fmt.Errorf("setting refresh cache error: %v", err)
 	}
@@ -27,6 +89,273 @@ func InitCache() error {
 	if err := statsRefreshCache(ctx); err != nil {
 		return fmt.Errorf("stats refresh cache error: %v", err)
 	}
+	if err := alertRefreshCache(ctx); err != nil {
+		return fmt.Errorf("alert refresh cache error: %v", err)
+	}
+	if err := modelAliasRefreshCache(ctx); err != nil {
+		return fmt.Errorf("model alias refresh cache error: %v", err)
+	}
+	if err := userRefreshCache(ctx); err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("user refresh cache error: %v", err)
+	}
+	for _, e := range cacheEntities {
+		cacheReloaded(e.name, fingerprints[e.name])
+	}
+	return nil
+}
+
+// CacheStatusList 返回各缓存的加载次数与数据指纹
+func CacheStatusList() []model.CacheStatus {
+	cacheStatusLock.RLock()
+	defer cacheStatusLock.RUnlock()
+	list := make([]model.CacheStatus, 0, len(cacheEntities))
+	for _, e := range cacheEntities {
+		st := cacheStatus[e.name]
+		st.Name = e.name
+		list = append(list, st)
+	}
+	return list
+}
+
+// CacheReload 从数据库重新加载指定的缓存，names 为空时重新加载全部缓存
+func CacheReload(ctx context.Context, names []string) error {
+	entities, err := cacheEntitiesByName(names)
+	if err != nil {
+		return err
+	}
+	cacheReloadLock.Lock()
+	defer cacheReloadLock.Unlock()
+	for _, e := range entities {
+		if err := cacheReload(ctx, e); err != nil {
+			return fmt.Errorf("reload %s cache: %w", e.name, err)
+		}
+	}
+	return nil
+}
+
+// CacheCheck 比较数据库中的数据指纹，重新加载在缓存之外被修改过的缓存（导入、直接修改数据库等），返回重新加载的缓存
+func CacheCheck(ctx context.Context) ([]string, error) {
+	cacheReloadLock.Lock()
+	defer cacheReloadLock.Unlock()
+	var reloaded []string
+	for _, e := range cacheEntities {
+		fp, err := cacheFingerprint(ctx, e)
+		if err != nil {
+			return reloaded, fmt.Errorf("fingerprint %s cache: %w", e.name, err)
+		}
+		cacheStatusLock.Lock()
+		st := cacheStatus[e.name]
+		st.CheckedAt = time.Now().Unix()
+		cacheStatus[e.name] = st
+		cacheStatusLock.Unlock()
+		if fp == st.Fingerprint {
+			continue
+		}
+		if err := e.refresh(ctx); err != nil {
+			return reloaded, fmt.Errorf("reload %s cache: %w", e.name, err)
+		}
+		cacheReloaded(e.name, fp)
+		reloaded = append(reloaded, e.name)
+	}
+	return reloaded, nil
+}
+
+// CacheCheckTask 定时检查缓存与数据库是否一致
+func CacheCheckTask() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	reloaded, err := CacheCheck(ctx)
+	if err != nil {
+		log.Warnf("cache check failed: %v", err)
+	}
+	if len(reloaded) > 0 {
+		log.Infof("cache reloaded after database changes: %v", reloaded)
+	}
+}
+
+func cacheEntitiesByName(names []string) ([]cacheEntity, error) {
+	if len(names) == 0 {
+		return cacheEntities, nil
+	}
+	entities := make([]cacheEntity, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, e := range cacheEntities {
+			if e.name == name {
+				entities = append(entities, e)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown cache %q", name)
+		}
+	}
+	return entities, nil
+}
+
+// cacheReload 重新加载一个缓存，调用方需持有 cacheReloadLock
+func cacheReload(ctx context.Context, e cacheEntity) error {
+	fp, err := cacheFingerprint(ctx, e)
+	if err != nil {
+		return err
+	}
+	if err := e.refresh(ctx); err != nil {
+		return err
+	}
+	cacheReloaded(e.name, fp)
+	return nil
+}
+
+func cacheReloaded(name, fingerprint string) {
+	cacheStatusLock.Lock()
+	defer cacheStatusLock.Unlock()
+	st := cacheStatus[name]
+	st.Epoch++
+	st.Fingerprint = fingerprint
+	st.ReloadedAt = time.Now().Unix()
+	if st.CheckedAt == 0 {
+		st.CheckedAt = st.ReloadedAt
+	}
+	cacheStatus[name] = st
+}
+
+// cacheFingerprint 由实体在 cache_versions 中的版本号与各表按主键顺序的原始列值计算指纹，运行时列不参与计算。
+// 经过本程序的修改（包括导入、apply 等离线命令）会递增版本号，直接执行的 SQL 插入、更新与删除会改变列值。
+// 列值按数据库返回的原样写入哈希，不做反序列化与解密，配置表的数据量下开销很小
+func cacheFingerprint(ctx context.Context, e cacheEntity) (string, error) {
+	conn := db.Conn(ctx)
+	var version model.CacheVersion
+	if err := conn.Where("name = ?", e.name).Limit(1).Find(&version).Error; err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d", version.Version)
+	for _, m := range e.models {
+		stmt := &gorm.Statement{DB: conn}
+		if err := stmt.Parse(m); err != nil {
+			return "", err
+		}
+		columns := make([]string, 0, len(stmt.Schema.DBNames))
+		for _, name := range stmt.Schema.DBNames {
+			if !slices.Contains(e.runtime, name) {
+				columns = append(columns, name)
+			}
+		}
+		order := make([]clause.OrderByColumn, 0, len(stmt.Schema.PrimaryFieldDBNames))
+		for _, col := range stmt.Schema.PrimaryFieldDBNames {
+			order = append(order, clause.OrderByColumn{Column: clause.Column{Name: col}})
+		}
+		rows, err := conn.Model(m).Select(columns).Clauses(clause.OrderBy{Columns: order}).Rows()
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "|%s", stmt.Schema.Table)
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		for rows.Next() {
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return "", err
+			}
+			h.Write([]byte{'\n'})
+			for _, v := range values {
+				fmt.Fprintf(h, "%#v,", v)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%016x", h.Sum64()), nil
+}
+
+// cacheTrackWrites 在连接上注册写入回调，记录被修改的缓存实体；同一连接只注册一次
+func cacheTrackWrites(conn *gorm.DB) error {
+	cacheWrites.mu.Lock()
+	defer cacheWrites.mu.Unlock()
+	if cacheWrites.conn == conn {
+		return nil
+	}
+	tables := make(map[string]string)
+	for _, e := range cacheEntities {
+		for _, m := range e.models {
+			table, err := tableName(conn, m)
+			if err != nil {
+				return err
+			}
+			tables[table] = e.name
+		}
+	}
+
+	notify := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Table == "" {
+			return
+		}
+		if _, skip := tx.Get(cacheSkipNotifyKey); skip {
+			return
+		}
+		cacheWrites.mu.Lock()
+		defer cacheWrites.mu.Unlock()
+		if name, ok := cacheWrites.tables[tx.Statement.Table]; ok {
+			cacheWrites.dirty[name] = struct{}{}
+		}
+	}
+	cb := conn.Callback()
+	if err := cb.Create().After("gorm:create").Register("octopus:cache_notify", notify); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("octopus:cache_notify", notify); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("octopus:cache_notify", notify); err != nil {
+		return err
+	}
+	cacheWrites.conn = conn
+	cacheWrites.tables = tables
+	cacheWrites.dirty = make(map[string]struct{})
+	return nil
+}
+
+// cacheSkipNotify 标记写入不属于配置变更，例如运行时统计字段的落库；返回的连接可以重复使用
+func cacheSkipNotify(tx *gorm.DB) *gorm.DB {
+	return tx.Set(cacheSkipNotifyKey, true).Session(&gorm.Session{})
+}
+
+// CachePublish 递增本进程修改过的缓存实体的版本号。离线命令修改数据库后调用，
+// 运行中的服务会在下一次缓存检查（集群模式下为下一次同步）时重新加载对应缓存
+func CachePublish(ctx context.Context) error {
+	cacheWrites.mu.Lock()
+	dirty := cacheWrites.dirty
+	cacheWrites.dirty = make(map[string]struct{})
+	cacheWrites.mu.Unlock()
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	conn := cacheSkipNotify(db.Conn(ctx))
+	table, err := tableName(conn, &model.CacheVersion{})
+	if err != nil {
+		return err
+	}
+	for name := range dirty {
+		if err := conn.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.Assignments(map[string]any{"version": gorm.Expr(table + ".version + 1")}),
+		}).Create(&model.CacheVersion{Name: name, Version: 1}).Error; err != nil {
+			cacheWrites.mu.Lock()
+			for name := range dirty {
+				cacheWrites.dirty[name] = struct{}{}
+			}
+			cacheWrites.mu.Unlock()
+			return err
+		}
+	}
 	return nil
 }
 
@@ -44,3 +373,12 @@ func SaveCache() error {
 	}
 	return nil
 }
+
+// tableName 返回模型对应的数据表名
+func tableName(tx *gorm.DB, m any) (string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(m); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
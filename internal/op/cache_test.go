@@ -0,0 +1,153 @@
+package op
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+)
+
+func TestCacheCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(t *testing.T, ctx context.Context)
+		expected []string
+	}{
+		{name: "no change", change: func(*testing.T, context.Context) {}, expected: nil},
+		{
+			name: "sql insert",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := cacheSkipNotify(db.GetDB()).Create(&model.Channel{Name: "b", Model: "m"}).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"channel"},
+		},
+		{
+			name: "sql delete",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Exec("DELETE FROM channels").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"channel"},
+		},
+		{
+			name: "sql update",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Exec("UPDATE channels SET model = 'x'").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"channel"},
+		},
+		{
+			name: "sql update of key",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Exec("UPDATE channel_keys SET enabled = false").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"channel"},
+		},
+		{
+			name: "runtime columns",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Exec("UPDATE channel_keys SET status_code = 500, last_use_time_stamp = 1, total_cost = 2, health_checked_at = 3").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: nil,
+		},
+		{
+			name: "version bump",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Create(&model.CacheVersion{Name: "channel", Version: 1}).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"channel"},
+		},
+		{
+			name: "published write",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Model(&model.Group{}).Where("name = ?", "g").Update("match_regex", "x").Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := CachePublish(ctx); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"group"},
+		},
+		{
+			name: "unpublished write",
+			change: func(t *testing.T, ctx context.Context) {
+				if err := db.GetDB().Model(&model.Group{}).Where("name = ?", "g").Update("match_regex", "x").Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expected: []string{"group"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestDB(t)
+			ctx := context.Background()
+			if err := db.GetDB().Create(&model.Channel{Name: "a", Model: "m", Keys: []model.ChannelKey{{ChannelKey: "k"}}}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := db.GetDB().Create(&model.Group{Name: "g", Mode: model.GroupModeRoundRobin}).Error; err != nil {
+				t.Fatal(err)
+			}
+			if err := InitCache(); err != nil {
+				t.Fatal(err)
+			}
+			// 首次加载会写入默认设置，先检查一次使指纹与数据库一致
+			if _, err := CacheCheck(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.change(t, ctx)
+			reloaded, err := CacheCheck(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(reloaded, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, reloaded)
+			}
+			// 重新加载后指纹与数据库一致，再次检查不会重复加载
+			if reloaded, _ := CacheCheck(ctx); len(reloaded) != 0 {
+				t.Fatalf("expected no reload on the second check, got %v", reloaded)
+			}
+		})
+	}
+}
+
+func TestInitCacheKeepsWrites(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	if err := InitCache(); err != nil {
+		t.Fatal(err)
+	}
+	// 导入等写入在重新加载缓存之后仍需递增版本号，通知其他实例
+	if err := db.GetDB().Create(&model.Group{Name: "g", Mode: model.GroupModeRoundRobin}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := InitCache(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CachePublish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var versions []model.CacheVersion
+	if err := db.GetDB().Order("name").Find(&versions).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 首次加载补写的默认设置不会递增版本号
+	if len(versions) != 1 || versions[0].Name != "group" || versions[0].Version != 1 {
+		t.Fatalf("expected only the group version bumped, got %+v", versions)
+	}
+}
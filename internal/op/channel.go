@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"octopus/internal/db"
 	"octopus/internal/model"
+	"octopus/internal/secret"
 	"octopus/internal/utils/cache"
 	"octopus/internal/utils/log"
 	"octopus/internal/utils/xstrings"
+
+	"gorm.io/gorm"
 )
 
 var channelCache = cache.New[int, model.Channel](16)
@@ -17,6 +21,9 @@ var channelKeyCache = cache.New[int, model.ChannelKey](16)
 var channelKeyCacheNeedUpdate = make(map[int]struct{})
 var channelKeyCacheNeedUpdateLock sync.Mutex
 
+// channelKeyCostDelta 尚未写入数据库的 key 费用增量，由 channelKeyCacheNeedUpdateLock 保护
+var channelKeyCostDelta = make(map[int]float64)
+
 func ChannelList(ctx context.Context) ([]model.Channel, error) {
 	channels := make([]model.Channel, 0, channelCache.Len())
 	for _, channel := range channelCache.GetAll() {
@@ -26,7 +33,7 @@ func ChannelList(ctx context.Context) ([]model.Channel, error) {
 }
 
 func ChannelCreate(channel *model.Channel, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(channel).Error; err != nil {
+	if err := db.Conn(ctx).Create(channel).Error; err != nil {
 		return err
 	}
 	channelCache.Set(channel.ID, *channel)
@@ -35,11 +42,13 @@ func ChannelCreate(channel *model.Channel, ctx context.Context) error {
 			channelKeyCache.Set(k.ID, k)
 		}
 	}
+	auditRecord(ctx, model.AuditActionChannelCreate, "channel", channel.ID, nil, auditChannel(channel))
 	return nil
 }
 
 // ChannelKeyUpdate 仅更新 ChannelKey 的内存缓存（不落库），并标记为需要在 SaveCache 时写入数据库。
-func ChannelKeyUpdate(key model.ChannelKey) error {
+// cost 为本次请求的费用，累加到 key 的 TotalCost 上
+func ChannelKeyUpdate(key model.ChannelKey, cost float64) error {
 	if key.ID == 0 || key.ChannelID == 0 {
 		return fmt.Errorf("invalid channel key")
 	}
@@ -47,6 +56,15 @@ func ChannelKeyUpdate(key model.ChannelKey) error {
 	if !ok {
 		return fmt.Errorf("channel not found")
 	}
+	channelKeyCacheNeedUpdateLock.Lock()
+	defer channelKeyCacheNeedUpdateLock.Unlock()
+	// 只合并运行时统计字段，避免请求期间的旧快照覆盖启用状态与健康检查结果
+	if cached, ok := channelKeyCache.Get(key.ID); ok {
+		cached.StatusCode = key.StatusCode
+		cached.LastUseTimeStamp = key.LastUseTimeStamp
+		key = cached
+	}
+	key.TotalCost += cost
 	if len(ch.Keys) > 0 {
 		keys := make([]model.ChannelKey, len(ch.Keys))
 		copy(keys, ch.Keys)
@@ -60,11 +78,106 @@ func ChannelKeyUpdate(key model.ChannelKey) error {
 	}
 	channelCache.Set(key.ChannelID, ch)
 	channelKeyCache.Set(key.ID, key)
-	channelKeyCacheNeedUpdateLock.Lock()
 	channelKeyCacheNeedUpdate[key.ID] = struct{}{}
+	channelKeyCostDelta[key.ID] += cost
+	return nil
+}
+
+// ChannelKeyHealthUpdate 保存 key 的健康检查结果。autoDisable 开启时禁用失效或额度耗尽的 key，自动禁用的 key 恢复后重新启用。
+// 启用状态以更新时的缓存为准，并且只在数据库中的状态与缓存一致时写入，探测期间的手动启用或禁用不会被覆盖
+func ChannelKeyHealthUpdate(ctx context.Context, keyID int, health model.ChannelKeyHealth, reason string, autoDisable bool) error {
+	channelKeyCacheNeedUpdateLock.Lock()
+	old, ok := channelKeyCache.Get(keyID)
+	if !ok {
+		channelKeyCacheNeedUpdateLock.Unlock()
+		return fmt.Errorf("channel key not found")
+	}
+	ch, ok := channelCache.Get(old.ChannelID)
+	if !ok {
+		channelKeyCacheNeedUpdateLock.Unlock()
+		return fmt.Errorf("channel not found")
+	}
+	key := old
+	key.HealthStatus = health
+	key.HealthCheckedAt = time.Now().Unix()
+	key.Enabled, key.AutoDisabled, key.DisabledReason = nextKeyState(old, health, reason, autoDisable)
+	updates := map[string]any{
+		"health_status":     key.HealthStatus,
+		"health_checked_at": key.HealthCheckedAt,
+	}
+	stateChanged := key.Enabled != old.Enabled || key.AutoDisabled != old.AutoDisabled || key.DisabledReason != old.DisabledReason
+	if stateChanged {
+		state := map[string]any{
+			"health_status":     key.HealthStatus,
+			"health_checked_at": key.HealthCheckedAt,
+			"enabled":           key.Enabled,
+			"auto_disabled":     key.AutoDisabled,
+			"disabled_reason":   key.DisabledReason,
+		}
+		result := db.Conn(ctx).Model(&model.ChannelKey{}).
+			Where("id = ? AND enabled = ? AND auto_disabled = ?", keyID, old.Enabled, old.AutoDisabled).
+			Updates(state)
+		if result.Error != nil {
+			channelKeyCacheNeedUpdateLock.Unlock()
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// 数据库中的启用状态已被手动修改或由其他实例更新，只保存探测结果，缓存随之后的刷新同步
+			key.Enabled, key.AutoDisabled, key.DisabledReason = old.Enabled, old.AutoDisabled, old.DisabledReason
+			stateChanged = false
+		}
+	}
+	if !stateChanged {
+		if err := db.Conn(ctx).Model(&model.ChannelKey{}).Where("id = ?", keyID).Updates(updates).Error; err != nil {
+			channelKeyCacheNeedUpdateLock.Unlock()
+			return err
+		}
+	}
+	keys := make([]model.ChannelKey, len(ch.Keys))
+	copy(keys, ch.Keys)
+	for i := range keys {
+		if keys[i].ID == keyID {
+			keys[i] = key
+			break
+		}
+	}
+	ch.Keys = keys
+	channelCache.Set(ch.ID, ch)
+	channelKeyCache.Set(keyID, key)
 	channelKeyCacheNeedUpdateLock.Unlock()
+
+	switch {
+	case old.Enabled && !key.Enabled:
+		auditRecord(ctx, model.AuditActionChannelKeyAutoDisable, "channel_key", keyID,
+			map[string]any{"enabled": true}, map[string]any{"enabled": false, "health_status": health, "reason": reason})
+	case !old.Enabled && key.Enabled:
+		auditRecord(ctx, model.AuditActionChannelKeyAutoEnable, "channel_key", keyID,
+			map[string]any{"enabled": false, "health_status": old.HealthStatus}, map[string]any{"enabled": true, "health_status": health})
+	}
 	return nil
 }
+
+// nextKeyState 根据探测结果计算 key 的启用状态，autoDisable 关闭时不会禁用 key，手动禁用的 key 保持不变
+func nextKeyState(key model.ChannelKey, health model.ChannelKeyHealth, reason string, autoDisable bool) (enabled, autoDisabled bool, disabledReason string) {
+	enabled, autoDisabled, disabledReason = key.Enabled, key.AutoDisabled, key.DisabledReason
+	switch health {
+	case model.ChannelKeyHealthRevoked, model.ChannelKeyHealthQuotaExhausted:
+		if autoDisable && (key.Enabled || key.AutoDisabled) {
+			if key.Enabled {
+				log.Warnf("channel key auto disabled (channel=%d, key=%d): %s %s", key.ChannelID, key.ID, health, reason)
+			}
+			enabled, autoDisabled = false, true
+			disabledReason = string(health) + ": " + reason
+		}
+	case model.ChannelKeyHealthValid:
+		if key.AutoDisabled {
+			enabled, autoDisabled, disabledReason = true, false, ""
+			log.Infof("channel key recovered (channel=%d, key=%d)", key.ChannelID, key.ID)
+		}
+	}
+	return enabled, autoDisabled, disabledReason
+}
+
 func ChannelBaseUrlUpdate(channelID int, baseUrl []model.BaseUrl) error {
 	ch, ok := channelCache.Get(channelID)
 	if !ok {
@@ -82,27 +195,41 @@ func ChannelBaseUrlUpdate(channelID int, baseUrl []model.BaseUrl) error {
 	return nil
 }
 
-// ChannelKeySaveDB 将运行时更新过的 ChannelKey 缓存写入数据库。
+// ChannelKeySaveDB 将运行时更新过的 ChannelKey 字段写入数据库。
+// 费用以增量累加，多个实例共享数据库时不会互相覆盖
 func ChannelKeySaveDB(ctx context.Context) error {
 	channelKeyCacheNeedUpdateLock.Lock()
 	keyIDs := make([]int, 0, len(channelKeyCacheNeedUpdate))
 	for id := range channelKeyCacheNeedUpdate {
 		keyIDs = append(keyIDs, id)
 	}
+	costs := channelKeyCostDelta
 	channelKeyCacheNeedUpdate = make(map[int]struct{})
+	channelKeyCostDelta = make(map[int]float64)
 	channelKeyCacheNeedUpdateLock.Unlock()
 
 	if len(keyIDs) == 0 {
 		return nil
 	}
 
-	dbConn := db.GetDB().WithContext(ctx)
-	for _, id := range keyIDs {
+	dbConn := cacheSkipNotify(db.Conn(ctx))
+	for i, id := range keyIDs {
 		k, ok := channelKeyCache.Get(id)
 		if !ok {
 			continue
 		}
-		if err := dbConn.Save(&k).Error; err != nil {
+		if err := dbConn.Model(&model.ChannelKey{}).Where("id = ?", id).Updates(map[string]any{
+			"status_code":         k.StatusCode,
+			"last_use_time_stamp": k.LastUseTimeStamp,
+			"total_cost":          gorm.Expr("total_cost + ?", costs[id]),
+		}).Error; err != nil {
+			// 未写入的 key 留到下一次写入
+			channelKeyCacheNeedUpdateLock.Lock()
+			for _, id := range keyIDs[i:] {
+				channelKeyCacheNeedUpdate[id] = struct{}{}
+				channelKeyCostDelta[id] += costs[id]
+			}
+			channelKeyCacheNeedUpdateLock.Unlock()
 			return err
 		}
 	}
@@ -110,12 +237,12 @@ func ChannelKeySaveDB(ctx context.Context) error {
 }
 
 func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model.Channel, error) {
-	_, ok := channelCache.Get(req.ID)
+	oldChannel, ok := channelCache.Get(req.ID)
 	if !ok {
 		return nil, fmt.Errorf("channel not found")
 	}
 
-	tx := db.GetDB().WithContext(ctx).Begin()
+	tx := db.Begin(ctx)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -173,6 +300,16 @@ func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model
 		selectFields = append(selectFields, "param_override")
 		updates.ParamOverride = req.ParamOverride
 	}
+	if req.PromptCache != nil {
+		selectFields = append(selectFields, "prompt_cache")
+		if req.PromptCache.Active() {
+			updates.PromptCache = req.PromptCache
+		}
+	}
+	if req.Script != nil {
+		selectFields = append(selectFields, "script")
+		updates.Script = *req.Script
+	}
 
 	// 只有当有字段需要更新时才执行 UPDATE
 	if len(selectFields) > 0 {
@@ -198,11 +335,20 @@ func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model
 				updates["enabled"] = *ku.Enabled
 			}
 			if ku.ChannelKey != nil {
-				updates["channel_key"] = *ku.ChannelKey
+				// map 更新不会经过 gorm serializer，需要手动加密
+				encrypted, err := secret.Encrypt(*ku.ChannelKey)
+				if err != nil {
+					tx.Rollback()
+					return nil, fmt.Errorf("failed to encrypt channel key %d: %w", ku.ID, err)
+				}
+				updates["channel_key"] = encrypted
 			}
 			if len(updates) == 0 {
 				continue
 			}
+			// 手动修改后不再由健康检查自动恢复
+			updates["auto_disabled"] = false
+			updates["disabled_reason"] = ""
 			if err := tx.Model(&model.ChannelKey{}).
 				Where("id = ? AND channel_id = ?", ku.ID, req.ID).
 				Updates(updates).Error; err != nil {
@@ -238,6 +384,7 @@ func ChannelUpdate(req *model.ChannelUpdateRequest, ctx context.Context) (*model
 	}
 
 	channel, _ := channelCache.Get(req.ID)
+	auditRecord(ctx, model.AuditActionChannelUpdate, "channel", req.ID, auditChannel(&oldChannel), auditChannel(&channel))
 	return &channel, nil
 }
 
@@ -246,11 +393,14 @@ func ChannelEnabled(id int, enabled bool, ctx context.Context) error {
 	if !ok {
 		return fmt.Errorf("channel not found")
 	}
-	if err := db.GetDB().WithContext(ctx).Model(&model.Channel{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
+	if err := db.Conn(ctx).Model(&model.Channel{}).Where("id = ?", id).Update("enabled", enabled).Error; err != nil {
 		return err
 	}
+	before := oldChannel.Enabled
 	oldChannel.Enabled = enabled
 	channelCache.Set(id, oldChannel)
+	auditRecord(ctx, model.AuditActionChannelEnabled, "channel", id,
+		map[string]bool{"enabled": before}, map[string]bool{"enabled": enabled})
 	return nil
 }
 
@@ -261,7 +411,7 @@ func ChannelDel(id int, ctx context.Context) error {
 	}
 
 	// 开启事务
-	tx := db.GetDB().WithContext(ctx).Begin()
+	tx := db.Begin(ctx)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -289,6 +439,12 @@ func ChannelDel(id int, ctx context.Context) error {
 		return fmt.Errorf("failed to delete channel keys: %w", err)
 	}
 
+	// 删除待审核的模型同步变更
+	if err := tx.Where("channel_id = ?", id).Delete(&model.ModelSyncPending{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete model sync pending: %w", err)
+	}
+
 	// 删除统计数据
 	if err := tx.Where("channel_id = ?", id).Delete(&model.StatsChannel{}).Error; err != nil {
 		tx.Rollback()
@@ -312,7 +468,8 @@ func ChannelDel(id int, ctx context.Context) error {
 			channelKeyCache.Del(k.ID)
 		}
 	}
-	StatsChannelDel(id)
+	StatsChannelDel(id, ctx)
+	auditRecord(ctx, model.AuditActionChannelDelete, "channel", id, auditChannel(&ch), nil)
 
 	// 刷新受影响的分组缓存
 	for _, groupID := range affectedGroupIDs {
@@ -353,25 +510,55 @@ func ChannelGet(id int, ctx context.Context) (*model.Channel, error) {
 
 func channelRefreshCache(ctx context.Context) error {
 	channels := []model.Channel{}
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Keys").
 		Preload("Stats").
 		Find(&channels).Error; err != nil {
 		log.Warnf("failed to get channels: %v", err)
 		return err
 	}
-	channelKeyCache.Clear()
 	channelKeyCacheNeedUpdateLock.Lock()
-	channelKeyCacheNeedUpdate = make(map[int]struct{})
-	channelKeyCacheNeedUpdateLock.Unlock()
+	defer channelKeyCacheNeedUpdateLock.Unlock()
+	// 尚未写入数据库的运行时字段以缓存为准
+	for i := range channels {
+		for j, k := range channels[i].Keys {
+			if _, pending := channelKeyCacheNeedUpdate[k.ID]; !pending {
+				continue
+			}
+			if cached, ok := channelKeyCache.Get(k.ID); ok {
+				k.StatusCode = cached.StatusCode
+				k.LastUseTimeStamp = cached.LastUseTimeStamp
+			}
+			k.TotalCost += channelKeyCostDelta[k.ID]
+			channels[i].Keys[j] = k
+		}
+	}
+
+	ids := make(map[int]struct{}, len(channels))
+	keyIDs := make(map[int]struct{})
 	for _, channel := range channels {
+		ids[channel.ID] = struct{}{}
 		channelCache.Set(channel.ID, channel)
 		for _, k := range channel.Keys {
 			if k.ID != 0 {
+				keyIDs[k.ID] = struct{}{}
 				channelKeyCache.Set(k.ID, k)
 			}
 		}
 	}
+	// 删除数据库中已经不存在的渠道与 key
+	for id := range channelCache.GetAll() {
+		if _, ok := ids[id]; !ok {
+			channelCache.Del(id)
+		}
+	}
+	for id := range channelKeyCache.GetAll() {
+		if _, ok := keyIDs[id]; !ok {
+			channelKeyCache.Del(id)
+			delete(channelKeyCacheNeedUpdate, id)
+			delete(channelKeyCostDelta, id)
+		}
+	}
 	return nil
 }
 
@@ -384,7 +571,7 @@ func channelRefreshCacheByID(id int, ctx context.Context) error {
 		}
 	}
 	var channel model.Channel
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Keys").
 		Preload("Stats").
 		First(&channel, id).Error; err != nil {
@@ -398,3 +585,39 @@ func channelRefreshCacheByID(id int, ctx context.Context) error {
 	}
 	return nil
 }
+
+// CredentialReencrypt 使用当前活动的数据密钥重写全部加密字段，供数据密钥轮换使用。
+// 轮换后旧数据密钥会被删除，新增使用 serializer:encrypted 的模型需要在这里一并处理
+func CredentialReencrypt(tx *gorm.DB) error {
+	if err := ChannelCredentialReencrypt(tx); err != nil {
+		return err
+	}
+	return AlertNotifierCredentialReencrypt(tx)
+}
+
+// ChannelCredentialReencrypt 使用当前活动的数据密钥重写全部渠道凭据
+func ChannelCredentialReencrypt(tx *gorm.DB) error {
+	var keys []model.ChannelKey
+	if err := tx.Find(&keys).Error; err != nil {
+		return fmt.Errorf("failed to load channel keys: %w", err)
+	}
+	for _, k := range keys {
+		if err := tx.Model(&model.ChannelKey{}).Where("id = ?", k.ID).Select("channel_key").Updates(&k).Error; err != nil {
+			return fmt.Errorf("failed to reencrypt channel key %d: %w", k.ID, err)
+		}
+	}
+
+	var channels []model.Channel
+	if err := tx.Find(&channels).Error; err != nil {
+		return fmt.Errorf("failed to load channels: %w", err)
+	}
+	for _, ch := range channels {
+		if ch.ChannelProxy == nil {
+			continue
+		}
+		if err := tx.Model(&model.Channel{}).Where("id = ?", ch.ID).Select("channel_proxy").Updates(&ch).Error; err != nil {
+			return fmt.Errorf("failed to reencrypt channel proxy %d: %w", ch.ID, err)
+		}
+	}
+	return nil
+}
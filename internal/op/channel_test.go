@@ -0,0 +1,118 @@
+package op
+
+import (
+	"context"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+)
+
+func TestNextKeyState(t *testing.T) {
+	enabled := model.ChannelKey{ID: 1, Enabled: true}
+	autoDisabled := model.ChannelKey{ID: 1, AutoDisabled: true, DisabledReason: "revoked: Unauthorized"}
+	manuallyDisabled := model.ChannelKey{ID: 1}
+	tests := []struct {
+		name             string
+		key              model.ChannelKey
+		health           model.ChannelKeyHealth
+		autoDisable      bool
+		wantEnabled      bool
+		wantAutoDisabled bool
+	}{
+		{name: "revoked without auto disable", key: enabled, health: model.ChannelKeyHealthRevoked, wantEnabled: true},
+		{name: "revoked with auto disable", key: enabled, health: model.ChannelKeyHealthRevoked, autoDisable: true, wantAutoDisabled: true},
+		{name: "quota exhausted with auto disable", key: enabled, health: model.ChannelKeyHealthQuotaExhausted, autoDisable: true, wantAutoDisabled: true},
+		{name: "rate limited keeps key", key: enabled, health: model.ChannelKeyHealthRateLimited, autoDisable: true, wantEnabled: true},
+		{name: "recovered", key: autoDisabled, health: model.ChannelKeyHealthValid, wantEnabled: true},
+		{name: "still failing", key: autoDisabled, health: model.ChannelKeyHealthError, wantAutoDisabled: true},
+		{name: "manually disabled stays disabled", key: manuallyDisabled, health: model.ChannelKeyHealthValid, autoDisable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotEnabled, gotAutoDisabled, _ := nextKeyState(tt.key, tt.health, "reason", tt.autoDisable)
+			if gotEnabled != tt.wantEnabled || gotAutoDisabled != tt.wantAutoDisabled {
+				t.Errorf("expected enabled=%v auto_disabled=%v, got enabled=%v auto_disabled=%v",
+					tt.wantEnabled, tt.wantAutoDisabled, gotEnabled, gotAutoDisabled)
+			}
+		})
+	}
+}
+
+func TestChannelKeyHealthUpdate(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		key    model.ChannelKey
+		health model.ChannelKeyHealth
+		// during 模拟探测期间对 key 的修改
+		during           func(t *testing.T, channelID, keyID int)
+		wantEnabled      bool
+		wantAutoDisabled bool
+	}{
+		{
+			name:             "revoked key is disabled",
+			key:              model.ChannelKey{Enabled: true, ChannelKey: "sk-revoked"},
+			health:           model.ChannelKeyHealthRevoked,
+			wantAutoDisabled: true,
+		},
+		{
+			name:        "auto disabled key recovers",
+			key:         model.ChannelKey{AutoDisabled: true, DisabledReason: "revoked: Unauthorized", ChannelKey: "sk-recovered"},
+			health:      model.ChannelKeyHealthValid,
+			wantEnabled: true,
+		},
+		{
+			name:   "manual disable during probe is kept",
+			key:    model.ChannelKey{AutoDisabled: true, ChannelKey: "sk-manual-disable"},
+			health: model.ChannelKeyHealthValid,
+			during: func(t *testing.T, channelID, keyID int) {
+				enabled := false
+				req := &model.ChannelUpdateRequest{ID: channelID, KeysToUpdate: []model.ChannelKeyUpdateRequest{{ID: keyID, Enabled: &enabled}}}
+				if _, err := ChannelUpdate(req, ctx); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name:   "database change before cache refresh is kept",
+			key:    model.ChannelKey{AutoDisabled: true, ChannelKey: "sk-stale-cache"},
+			health: model.ChannelKeyHealthValid,
+			during: func(t *testing.T, channelID, keyID int) {
+				if err := db.GetDB().Model(&model.ChannelKey{}).Where("id = ?", keyID).
+					Updates(map[string]any{"enabled": false, "auto_disabled": false}).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &model.Channel{Name: tt.name, Enabled: true, Keys: []model.ChannelKey{tt.key}}
+			if err := ChannelCreate(ch, ctx); err != nil {
+				t.Fatal(err)
+			}
+			keyID := ch.Keys[0].ID
+			if tt.during != nil {
+				tt.during(t, ch.ID, keyID)
+			}
+			if err := ChannelKeyHealthUpdate(ctx, keyID, tt.health, "reason", true); err != nil {
+				t.Fatal(err)
+			}
+
+			var stored model.ChannelKey
+			if err := db.GetDB().First(&stored, keyID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.HealthStatus != tt.health {
+				t.Errorf("expected health %q, got %q", tt.health, stored.HealthStatus)
+			}
+			if stored.Enabled != tt.wantEnabled || stored.AutoDisabled != tt.wantAutoDisabled {
+				t.Errorf("expected enabled=%v auto_disabled=%v, got enabled=%v auto_disabled=%v",
+					tt.wantEnabled, tt.wantAutoDisabled, stored.Enabled, stored.AutoDisabled)
+			}
+		})
+	}
+}
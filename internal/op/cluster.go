@@ -0,0 +1,261 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+	"octopus/internal/utils/log"
+	"octopus/internal/utils/snowflake"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 集群模式：多个实例共享同一个 MySQL/PostgreSQL 数据库。
+// 修改配置的实例递增 cache_versions 中对应实体的版本号，其他实例轮询到变化后刷新缓存；
+// 定时任务只在持有 task_leader 租约的实例上执行；雪花 ID 的节点号通过 node:<n> 租约分配，保证各实例不同
+
+const (
+	clusterLeaderLease     = "task_leader"
+	clusterNodeLeasePrefix = "node:"
+	clusterNodeCount       = 1024 // 雪花 ID 节点号的取值范围
+)
+
+var cluster struct {
+	enabled bool
+	nodeID  string
+	lease   time.Duration
+	leader  atomic.Bool
+	// leaseExpires 本实例持有的租约的过期时间，续约失败时在此之前仍视为主节点
+	leaseExpires time.Time
+	// node 本实例持有的雪花 ID 节点号租约，-1 表示尚未分配
+	node int
+
+	syncMu   sync.Mutex
+	versions map[string]int64 // 已经同步到的版本号，由 syncMu 保护
+}
+
+// ClusterEnabled 是否运行在集群模式
+func ClusterEnabled() bool {
+	return cluster.enabled
+}
+
+// ClusterIsLeader 本实例是否为执行定时任务的主节点，非集群模式下始终为 true
+func ClusterIsLeader() bool {
+	return !cluster.enabled || cluster.leader.Load()
+}
+
+// ClusterNodeID 返回本实例的节点标识，非集群模式下为空
+func ClusterNodeID() string {
+	return cluster.nodeID
+}
+
+// ClusterInit 开启集群模式：注册写入回调以发现配置变更，加载当前版本号并尝试获取主节点租约
+func ClusterInit(ctx context.Context, nodeID string, lease time.Duration) error {
+	conn := db.GetDB()
+	if name := conn.Dialector.Name(); name == "sqlite" {
+		return fmt.Errorf("cluster mode requires a mysql or postgres database, got %s", name)
+	}
+	if err := cacheTrackWrites(conn); err != nil {
+		return err
+	}
+	cluster.enabled = true
+	cluster.nodeID = nodeID
+	cluster.lease = lease
+
+	versions, err := clusterLoadVersions(ctx)
+	if err != nil {
+		return err
+	}
+	cluster.versions = versions
+	cluster.node = -1
+	if err := clusterClaimNode(ctx); err != nil {
+		return err
+	}
+	clusterRenewLease(ctx)
+	log.Infof("cluster mode enabled, node: %s (%d), leader: %t", nodeID, cluster.node, cluster.leader.Load())
+	return nil
+}
+
+// ClusterSyncTask 发布本实例的配置变更、续约主节点租约，并刷新其他实例修改过的缓存
+func ClusterSyncTask() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cluster.syncMu.Lock()
+	defer cluster.syncMu.Unlock()
+	if err := CachePublish(ctx); err != nil {
+		log.Warnf("cluster: failed to publish cache changes: %v", err)
+	}
+	clusterRenewNode(ctx)
+	clusterRenewLease(ctx)
+	if err := clusterPoll(ctx); err != nil {
+		log.Warnf("cluster: failed to sync caches: %v", err)
+	}
+}
+
+// ClusterClose 发布尚未同步的配置变更并释放主节点租约，使其他实例可以立即接管定时任务
+func ClusterClose() error {
+	if !cluster.enabled {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cluster.syncMu.Lock()
+	defer cluster.syncMu.Unlock()
+	if err := CachePublish(ctx); err != nil {
+		log.Warnf("cluster: failed to publish cache changes: %v", err)
+	}
+	names := []string{clusterNodeLease(cluster.node)}
+	if cluster.leader.Load() {
+		cluster.leader.Store(false)
+		names = append(names, clusterLeaderLease)
+	}
+	return cacheSkipNotify(db.Conn(ctx)).Model(&model.ClusterLease{}).
+		Where("name IN ? AND holder = ?", names, cluster.nodeID).
+		Update("expires_at", 0).Error
+}
+
+func clusterLoadVersions(ctx context.Context) (map[string]int64, error) {
+	var rows []model.CacheVersion
+	if err := db.Conn(ctx).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	versions := make(map[string]int64, len(rows))
+	for _, r := range rows {
+		versions[r.Name] = r.Version
+	}
+	return versions, nil
+}
+
+// clusterPoll 刷新版本号发生变化的缓存，刷新失败的实体在下一次轮询时重试
+func clusterPoll(ctx context.Context) error {
+	versions, err := clusterLoadVersions(ctx)
+	if err != nil {
+		return err
+	}
+	cacheReloadLock.Lock()
+	defer cacheReloadLock.Unlock()
+	for _, e := range cacheEntities {
+		if versions[e.name] == cluster.versions[e.name] {
+			continue
+		}
+		if err := cacheReload(ctx, e); err != nil {
+			log.Warnf("cluster: failed to refresh %s cache: %v", e.name, err)
+			continue
+		}
+		log.Debugf("cluster: %s cache refreshed to version %d", e.name, versions[e.name])
+		cluster.versions[e.name] = versions[e.name]
+	}
+	return nil
+}
+
+// clusterAcquireLease 获取或续约租约：租约不存在、已过期或由本实例持有时写入本实例，返回本实例是否持有租约
+func clusterAcquireLease(conn *gorm.DB, name string, now, expires time.Time) (bool, error) {
+	if err := conn.Model(&model.ClusterLease{}).
+		Where("name = ? AND (holder = ? OR expires_at < ?)", name, cluster.nodeID, now.Unix()).
+		Updates(map[string]any{"holder": cluster.nodeID, "expires_at": expires.Unix()}).Error; err != nil {
+		return false, err
+	}
+	if err := conn.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.ClusterLease{Name: name, Holder: cluster.nodeID, ExpiresAt: expires.Unix()}).Error; err != nil {
+		return false, err
+	}
+	var lease model.ClusterLease
+	if err := conn.Where("name = ?", name).First(&lease).Error; err != nil {
+		return false, err
+	}
+	return lease.Holder == cluster.nodeID && lease.ExpiresAt >= now.Unix(), nil
+}
+
+func clusterNodeLease(node int) string {
+	return clusterNodeLeasePrefix + strconv.Itoa(node)
+}
+
+// clusterClaimNode 为本实例分配雪花 ID 节点号：从节点标识的哈希值开始依次尝试，
+// 跳过其他实例持有且未过期的节点号，取得第一个租约后设置为本实例的节点号
+func clusterClaimNode(ctx context.Context) error {
+	now := time.Now()
+	conn := cacheSkipNotify(db.Conn(ctx))
+
+	var held []model.ClusterLease
+	if err := conn.Where("name LIKE ? AND holder <> ? AND expires_at >= ?", clusterNodeLeasePrefix+"%", cluster.nodeID, now.Unix()).
+		Find(&held).Error; err != nil {
+		return fmt.Errorf("load node leases: %w", err)
+	}
+	taken := make(map[int]bool, len(held))
+	for _, l := range held {
+		if n, err := strconv.Atoi(strings.TrimPrefix(l.Name, clusterNodeLeasePrefix)); err == nil {
+			taken[n] = true
+		}
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(cluster.nodeID))
+	start := int(h.Sum32() % clusterNodeCount)
+	for i := 0; i < clusterNodeCount; i++ {
+		node := (start + i) % clusterNodeCount
+		if taken[node] {
+			continue
+		}
+		ok, err := clusterAcquireLease(conn, clusterNodeLease(node), now, now.Add(cluster.lease))
+		if err != nil {
+			return fmt.Errorf("claim node %d: %w", node, err)
+		}
+		if ok {
+			cluster.node = node
+			snowflake.SetNode(int64(node))
+			return nil
+		}
+	}
+	return fmt.Errorf("no free snowflake node: all %d node leases are held by other instances", clusterNodeCount)
+}
+
+// clusterRenewNode 续约节点号租约；租约已被其他实例取得时（例如本实例长时间失联）重新分配节点号
+func clusterRenewNode(ctx context.Context) {
+	now := time.Now()
+	ok, err := clusterAcquireLease(cacheSkipNotify(db.Conn(ctx)), clusterNodeLease(cluster.node), now, now.Add(cluster.lease))
+	if err != nil {
+		log.Warnf("cluster: failed to renew node lease %d: %v", cluster.node, err)
+		return
+	}
+	if ok {
+		return
+	}
+	prev := cluster.node
+	if err := clusterClaimNode(ctx); err != nil {
+		log.Errorf("cluster: node lease %d was taken over and no new node could be claimed: %v", prev, err)
+		return
+	}
+	log.Warnf("cluster: node lease %d was taken over by another instance, switched to node %d", prev, cluster.node)
+}
+
+// clusterRenewLease 获取或续约主节点租约
+func clusterRenewLease(ctx context.Context) {
+	now := time.Now()
+	expires := now.Add(cluster.lease)
+
+	leader, err := clusterAcquireLease(cacheSkipNotify(db.Conn(ctx)), clusterLeaderLease, now, expires)
+	if err != nil {
+		log.Warnf("cluster: failed to renew leader lease: %v", err)
+		// 无法访问数据库时租约到期前仍然有效
+		leader = cluster.leader.Load() && now.Before(cluster.leaseExpires)
+	} else if leader {
+		cluster.leaseExpires = expires
+	}
+
+	if was := cluster.leader.Swap(leader); was != leader {
+		if leader {
+			log.Infof("cluster: node %s became the task leader", cluster.nodeID)
+		} else {
+			log.Infof("cluster: node %s is no longer the task leader", cluster.nodeID)
+		}
+	}
+}
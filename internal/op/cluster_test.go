@@ -0,0 +1,110 @@
+package op
+
+import (
+	"context"
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+)
+
+// setupTestCluster 在 sqlite 测试库上初始化集群状态，只用于测试租约逻辑
+func setupTestCluster(t *testing.T, nodeID string) int {
+	t.Helper()
+	setupTestDB(t)
+	cluster.nodeID = nodeID
+	cluster.lease = time.Minute
+	cluster.node = -1
+	t.Cleanup(func() {
+		cluster.nodeID = ""
+		cluster.node = -1
+	})
+	h := fnv.New32a()
+	h.Write([]byte(nodeID))
+	return int(h.Sum32() % clusterNodeCount)
+}
+
+func TestClusterClaimNode(t *testing.T) {
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+	tests := []struct {
+		name   string
+		leases func(start int) []model.ClusterLease
+		offset int // 期望分配的节点号相对哈希起点的偏移
+	}{
+		{name: "free", leases: func(int) []model.ClusterLease { return nil }, offset: 0},
+		{
+			name: "held by another instance",
+			leases: func(start int) []model.ClusterLease {
+				return []model.ClusterLease{
+					{Name: clusterNodeLease(start), Holder: "b", ExpiresAt: future},
+					{Name: clusterNodeLease((start + 1) % clusterNodeCount), Holder: "c", ExpiresAt: future},
+				}
+			},
+			offset: 2,
+		},
+		{
+			name: "expired lease",
+			leases: func(start int) []model.ClusterLease {
+				return []model.ClusterLease{{Name: clusterNodeLease(start), Holder: "b", ExpiresAt: past}}
+			},
+			offset: 0,
+		},
+		{
+			name: "own lease",
+			leases: func(start int) []model.ClusterLease {
+				return []model.ClusterLease{{Name: clusterNodeLease(start), Holder: "a", ExpiresAt: future}}
+			},
+			offset: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := setupTestCluster(t, "a")
+			for _, l := range tt.leases(start) {
+				if err := db.GetDB().Create(&l).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := clusterClaimNode(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			expected := (start + tt.offset) % clusterNodeCount
+			if cluster.node != expected {
+				t.Fatalf("expected %v, got %v", expected, cluster.node)
+			}
+			var lease model.ClusterLease
+			if err := db.GetDB().Where("name = ?", clusterNodeLease(expected)).First(&lease).Error; err != nil {
+				t.Fatal(err)
+			}
+			if lease.Holder != "a" {
+				t.Fatalf("expected lease holder %v, got %v", "a", lease.Holder)
+			}
+		})
+	}
+}
+
+func TestClusterRenewNodeTakenOver(t *testing.T) {
+	start := setupTestCluster(t, "a")
+	ctx := context.Background()
+	if err := clusterClaimNode(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	clusterRenewNode(ctx)
+	if cluster.node != start {
+		t.Fatalf("expected renewal to keep node %v, got %v", start, cluster.node)
+	}
+
+	// 模拟本实例失联期间租约过期并被其他实例取得
+	if err := db.GetDB().Model(&model.ClusterLease{}).Where("name = ?", clusterNodeLease(start)).
+		Updates(map[string]any{"holder": "b", "expires_at": time.Now().Add(time.Hour).Unix()}).Error; err != nil {
+		t.Fatal(err)
+	}
+	clusterRenewNode(ctx)
+	if cluster.node == start || cluster.node < 0 {
+		t.Fatalf("expected a new node after the lease was taken over, got %v", cluster.node)
+	}
+}
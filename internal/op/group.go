@@ -47,11 +47,12 @@ func GroupGetMap(name string, ctx context.Context) (model.Group, error) {
 }
 
 func GroupCreate(group *model.Group, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(group).Error; err != nil {
+	if err := db.Conn(ctx).Create(group).Error; err != nil {
 		return err
 	}
 	groupCache.Set(group.ID, *group)
 	groupMap.Set(group.Name, *group)
+	auditRecord(ctx, model.AuditActionGroupCreate, "group", group.ID, nil, group)
 	return nil
 }
 
@@ -62,7 +63,7 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 	}
 	oldName := oldGroup.Name
 
-	tx := db.GetDB().WithContext(ctx).Begin()
+	tx := db.Begin(ctx)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -88,6 +89,36 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 		selectFields = append(selectFields, "first_token_time_out")
 		updates.FirstTokenTimeOut = *req.FirstTokenTimeOut
 	}
+	if req.PromptCache != nil {
+		selectFields = append(selectFields, "prompt_cache")
+		if req.PromptCache.Active() {
+			updates.PromptCache = req.PromptCache
+		}
+	}
+	if req.ServerTools != nil {
+		selectFields = append(selectFields, "server_tools")
+		updates.ServerTools = *req.ServerTools
+	}
+	if req.MCPServers != nil {
+		selectFields = append(selectFields, "mcp_servers")
+		updates.MCPServers = *req.MCPServers
+	}
+	if req.Reasoning != nil {
+		selectFields = append(selectFields, "reasoning")
+		if req.Reasoning.Active() {
+			updates.Reasoning = req.Reasoning
+		}
+	}
+	if req.Guardrail != nil {
+		selectFields = append(selectFields, "guardrail")
+		if req.Guardrail.Active() {
+			updates.Guardrail = req.Guardrail
+		}
+	}
+	if req.Script != nil {
+		selectFields = append(selectFields, "script")
+		updates.Script = *req.Script
+	}
 
 	if len(selectFields) > 0 {
 		if err := tx.Model(&model.Group{}).Where("id = ?", req.ID).Select(selectFields).Updates(&updates).Error; err != nil {
@@ -159,6 +190,7 @@ func GroupUpdate(req *model.GroupUpdateRequest, ctx context.Context) (*model.Gro
 	if oldName != "" && oldName != group.Name {
 		groupMap.Del(oldName)
 	}
+	auditRecord(ctx, model.AuditActionGroupUpdate, "group", req.ID, &oldGroup, &group)
 	return &group, nil
 }
 
@@ -168,7 +200,7 @@ func GroupDel(id int, ctx context.Context) error {
 		return fmt.Errorf("group not found")
 	}
 
-	tx := db.GetDB().WithContext(ctx).Begin()
+	tx := db.Begin(ctx)
 	defer func() {
 		if r := recover(); r != nil {
 			tx.Rollback()
@@ -180,6 +212,11 @@ func GroupDel(id int, ctx context.Context) error {
 		return fmt.Errorf("failed to delete group items: %w", err)
 	}
 
+	if err := tx.Where("group_id = ?", id).Delete(&model.ModelAlias{}).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to delete model aliases: %w", err)
+	}
+
 	if err := tx.Delete(&model.Group{}, id).Error; err != nil {
 		tx.Rollback()
 		return fmt.Errorf("failed to delete group: %w", err)
@@ -191,6 +228,8 @@ func GroupDel(id int, ctx context.Context) error {
 
 	groupCache.Del(id)
 	groupMap.Del(group.Name)
+	modelAliasDelByGroup(id)
+	auditRecord(ctx, model.AuditActionGroupDelete, "group", id, &group, nil)
 	return nil
 }
 
@@ -199,7 +238,7 @@ func GroupItemAdd(item *model.GroupItem, ctx context.Context) error {
 		return fmt.Errorf("group not found")
 	}
 
-	if err := db.GetDB().WithContext(ctx).Create(item).Error; err != nil {
+	if err := db.Conn(ctx).Create(item).Error; err != nil {
 		return err
 	}
 
@@ -252,7 +291,7 @@ func GroupItemBatchAdd(groupID int, items []model.GroupIDAndLLMName, ctx context
 		nextPriority++
 	}
 
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "group_id"}, {Name: "channel_id"}, {Name: "model_name"}},
 			DoNothing: true,
@@ -265,7 +304,7 @@ func GroupItemBatchAdd(groupID int, items []model.GroupIDAndLLMName, ctx context
 }
 
 func GroupItemUpdate(item *model.GroupItem, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Model(item).
+	if err := db.Conn(ctx).Model(item).
 		Select("ModelName", "Priority", "Weight").
 		Updates(item).Error; err != nil {
 		return err
@@ -276,11 +315,11 @@ func GroupItemUpdate(item *model.GroupItem, ctx context.Context) error {
 
 func GroupItemDel(id int, ctx context.Context) error {
 	var item model.GroupItem
-	if err := db.GetDB().WithContext(ctx).First(&item, id).Error; err != nil {
+	if err := db.Conn(ctx).First(&item, id).Error; err != nil {
 		return fmt.Errorf("group item not found")
 	}
 
-	if err := db.GetDB().WithContext(ctx).Delete(&item).Error; err != nil {
+	if err := db.Conn(ctx).Delete(&item).Error; err != nil {
 		return err
 	}
 
@@ -299,7 +338,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 	}
 
 	var groupIDs []int
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Model(&model.GroupItem{}).
 		Distinct("group_id").
 		Where("(channel_id, model_name) IN ?", conditions).
@@ -311,7 +350,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 		return nil
 	}
 
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Where("(channel_id, model_name) IN ?", conditions).
 		Delete(&model.GroupItem{}).Error; err != nil {
 		return fmt.Errorf("failed to delete group items: %w", err)
@@ -326,7 +365,7 @@ func GroupItemBatchDelByChannelAndModels(keys []model.GroupIDAndLLMName, ctx con
 
 func GroupItemList(groupID int, ctx context.Context) ([]model.GroupItem, error) {
 	var items []model.GroupItem
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Where("group_id = ?", groupID).
 		Order("priority ASC").
 		Find(&items).Error; err != nil {
@@ -337,26 +376,37 @@ func GroupItemList(groupID int, ctx context.Context) ([]model.GroupItem, error)
 
 func groupRefreshCache(ctx context.Context) error {
 	groups := []model.Group{}
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		Find(&groups).Error; err != nil {
 		return err
 	}
 
-	// 清空旧缓存，避免残留旧名称映射
-	groupCache.Clear()
-	groupMap.Clear()
-
+	// 先写入新数据再删除残留的旧 ID 与旧名称映射，刷新期间查询不会落空
+	ids := make(map[int]struct{}, len(groups))
+	names := make(map[string]struct{}, len(groups))
 	for _, group := range groups {
+		ids[group.ID] = struct{}{}
+		names[group.Name] = struct{}{}
 		groupCache.Set(group.ID, group)
 		groupMap.Set(group.Name, group)
 	}
+	for id := range groupCache.GetAll() {
+		if _, ok := ids[id]; !ok {
+			groupCache.Del(id)
+		}
+	}
+	for name := range groupMap.GetAll() {
+		if _, ok := names[name]; !ok {
+			groupMap.Del(name)
+		}
+	}
 	return nil
 }
 
 func groupRefreshCacheByID(id int, ctx context.Context) error {
 	var group model.Group
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		First(&group, id).Error; err != nil {
 		return err
@@ -375,7 +425,7 @@ func groupRefreshCacheByIDs(ids []int, ctx context.Context) error {
 		return nil
 	}
 	var groups []model.Group
-	if err := db.GetDB().WithContext(ctx).
+	if err := db.Conn(ctx).
 		Preload("Items").
 		Where("id IN ?", ids).
 		Find(&groups).Error; err != nil {
@@ -13,7 +13,7 @@ var llmModelCache = cache.New[string, model.LLMPrice](16)
 
 func LLMList(ctx context.Context) ([]model.LLMInfo, error) {
 	models := []model.LLMInfo{}
-	if err := db.GetDB().WithContext(ctx).Find(&models).Error; err != nil {
+	if err := db.Conn(ctx).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	return models, nil
@@ -21,14 +21,14 @@ func LLMList(ctx context.Context) ([]model.LLMInfo, error) {
 
 func LLMListByChannel(ctx context.Context, channelID int) ([]model.LLMInfo, error) {
 	models := []model.LLMInfo{}
-	if err := db.GetDB().WithContext(ctx).Where("channel_id = ?", channelID).Find(&models).Error; err != nil {
+	if err := db.Conn(ctx).Where("channel_id = ?", channelID).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	return models, nil
 }
 
 func LLMUpdate(model model.LLMInfo, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Save(&model).Error; err != nil {
+	if err := db.Conn(ctx).Save(&model).Error; err != nil {
 		return err
 	}
 	cacheKey := fmt.Sprintf("%s:%d", model.Name, model.ChannelID)
@@ -37,7 +37,7 @@ func LLMUpdate(model model.LLMInfo, ctx context.Context) error {
 }
 
 func LLMDelete(modelName string, channelID int, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Where("name = ? AND channel_id = ?", modelName, channelID).Delete(&model.LLMInfo{}).Error; err != nil {
+	if err := db.Conn(ctx).Where("name = ? AND channel_id = ?", modelName, channelID).Delete(&model.LLMInfo{}).Error; err != nil {
 		return err
 	}
 	// 清除该模型的缓存 (格式: "modelName:channelID")
@@ -47,7 +47,7 @@ func LLMDelete(modelName string, channelID int, ctx context.Context) error {
 }
 
 func LLMCreate(m model.LLMInfo, ctx context.Context) error {
-	if err := db.GetDB().WithContext(ctx).Create(&m).Error; err != nil {
+	if err := db.Conn(ctx).Create(&m).Error; err != nil {
 		return err
 	}
 	cacheKey := fmt.Sprintf("%s:%d", m.Name, m.ChannelID)
@@ -76,7 +76,7 @@ func LLMGet(name string, channelID int) (model.LLMPrice, error) {
 // 导出此函数以便外部调用（如定期刷新任务）
 func LLMRefreshCache(ctx context.Context) error {
 	models := []model.LLMInfo{}
-	if err := db.GetDB().WithContext(ctx).Find(&models).Error; err != nil {
+	if err := db.Conn(ctx).Find(&models).Error; err != nil {
 		return err
 	}
 
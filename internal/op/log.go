@@ -95,7 +95,7 @@ func relayLogFlushToDB(ctx context.Context) error {
 	flushedUpto := len(batch)
 	relayLogCacheLock.Unlock()
 
-	result := db.GetDB().WithContext(ctx).Create(&batch)
+	result := db.Conn(ctx).Create(&batch)
 	if result.Error != nil {
 		return result.Error
 	}
@@ -183,7 +183,7 @@ func relayLogCleanup(ctx context.Context) error {
 	}
 
 	cutoffTime := time.Now().Add(-time.Duration(keepPeriod) * 24 * time.Hour).Unix()
-	result := db.GetDB().WithContext(ctx).Where("time < ?", cutoffTime).Delete(&model.RelayLog{})
+	result := db.Conn(ctx).Where("time < ?", cutoffTime).Delete(&model.RelayLog{})
 	if result.Error != nil {
 		return result.Error
 	}
@@ -251,7 +251,7 @@ func RelayLogList(ctx context.Context, startTime, endTime *int, page, pageSize i
 				dbOffset = offset - cacheCount
 			}
 
-			query := db.GetDB().WithContext(ctx)
+			query := db.Conn(ctx)
 			if hasTimeFilter {
 				query = query.Where("time >= ? AND time <= ?", *startTime, *endTime)
 			}
@@ -271,5 +271,28 @@ func RelayLogClear(ctx context.Context) error {
 	relayLogCacheLock.Lock()
 	relayLogCache = make([]model.RelayLog, 0, relayLogMaxSize)
 	relayLogCacheLock.Unlock()
-	return db.GetDB().WithContext(ctx).Where("1 = 1").Delete(&model.RelayLog{}).Error
+	return db.Conn(ctx).Where("1 = 1").Delete(&model.RelayLog{}).Error
+}
+
+// RelayLogUsedModels 返回渠道自 since 以来有请求记录的实际模型名称（包含尚未落库的缓存日志）
+func RelayLogUsedModels(ctx context.Context, channelID int, since int64) (map[string]struct{}, error) {
+	used := make(map[string]struct{})
+	relayLogCacheLock.Lock()
+	for _, l := range relayLogCache {
+		if l.ChannelId == channelID && l.Time >= since {
+			used[l.ActualModelName] = struct{}{}
+		}
+	}
+	relayLogCacheLock.Unlock()
+
+	var names []string
+	if err := db.Conn(ctx).Model(&model.RelayLog{}).
+		Where("channel_id = ? AND time >= ?", channelID, since).
+		Distinct().Pluck("actual_model_name", &names).Error; err != nil {
+		return nil, err
+	}
+	for _, n := range names {
+		used[n] = struct{}{}
+	}
+	return used, nil
 }
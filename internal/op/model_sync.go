@@ -0,0 +1,89 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"octopus/internal/db"
+	"octopus/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ModelSyncPendingList 返回等待审核的变更，已拒绝的变更不在其中
+func ModelSyncPendingList(ctx context.Context) ([]model.ModelSyncPending, error) {
+	var list []model.ModelSyncPending
+	if err := db.Conn(ctx).Where("rejected = ?", false).Order("id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if ch, ok := channelCache.Get(list[i].ChannelID); ok {
+			list[i].ChannelName = ch.Name
+		}
+	}
+	return list, nil
+}
+
+func ModelSyncPendingGet(id int, ctx context.Context) (*model.ModelSyncPending, error) {
+	var p model.ModelSyncPending
+	if err := db.Conn(ctx).First(&p, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("pending change not found")
+		}
+		return nil, err
+	}
+	if ch, ok := channelCache.Get(p.ChannelID); ok {
+		p.ChannelName = ch.Name
+	}
+	return &p, nil
+}
+
+// ModelSyncPendingSave 保存渠道的待审核变更；变更内容与已有记录一致时保留原创建时间与拒绝状态，不重置宽限期，
+// 变更内容不同时从当前时间重新计算宽限期并重新等待审核
+func ModelSyncPendingSave(p *model.ModelSyncPending, ctx context.Context) error {
+	conn := db.Conn(ctx)
+	var old model.ModelSyncPending
+	err := conn.Where("channel_id = ?", p.ChannelID).First(&old).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		p.ID = old.ID
+		if old.Same(p) {
+			p.CreatedAt = old.CreatedAt
+			p.Rejected = old.Rejected
+		} else {
+			p.CreatedAt = time.Now().Unix()
+			p.Rejected = false
+		}
+	}
+	return conn.Save(p).Error
+}
+
+// ModelSyncPendingDelByChannel 删除渠道的待审核变更（上游模型列表恢复一致时调用）
+func ModelSyncPendingDelByChannel(channelID int, ctx context.Context) error {
+	return db.Conn(ctx).Where("channel_id = ?", channelID).Delete(&model.ModelSyncPending{}).Error
+}
+
+// ModelSyncPendingResolve 记录审核结果。通过的变更被删除；拒绝的变更保留为已拒绝，
+// 之后同步得到相同的变更时不会重新等待审核或自动应用，直到上游的变更内容发生变化
+func ModelSyncPendingResolve(p *model.ModelSyncPending, approved bool, ctx context.Context) error {
+	conn := db.Conn(ctx).Model(&model.ModelSyncPending{}).Where("id = ?", p.ID)
+	var err error
+	if approved {
+		err = conn.Delete(&model.ModelSyncPending{}).Error
+	} else {
+		err = conn.Update("rejected", true).Error
+	}
+	if err != nil {
+		return err
+	}
+	action := model.AuditActionModelSyncReject
+	if approved {
+		action = model.AuditActionModelSyncApprove
+	}
+	auditRecord(ctx, action, "channel", p.ChannelID, nil, p)
+	return nil
+}
@@ -0,0 +1,45 @@
+package op
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"octopus/internal/model"
+)
+
+func TestModelSyncPendingSave(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	firstSeen := time.Now().Add(-48 * time.Hour).Unix()
+	if err := ModelSyncPendingSave(&model.ModelSyncPending{ChannelID: 1, Added: []string{"a"}, CreatedAt: firstSeen}, ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		pending   model.ModelSyncPending
+		wantReset bool
+	}{
+		{name: "same diff keeps created_at", pending: model.ModelSyncPending{ChannelID: 1, Added: []string{"a"}}},
+		{name: "changed diff restarts grace period", pending: model.ModelSyncPending{ChannelID: 1, Added: []string{"a", "b"}}, wantReset: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now().Unix()
+			if err := ModelSyncPendingSave(&tt.pending, ctx); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ModelSyncPendingGet(tt.pending.ID, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantReset && got.CreatedAt < before {
+				t.Errorf("expected created_at to be reset to now, got %d", got.CreatedAt)
+			}
+			if !tt.wantReset && got.CreatedAt != firstSeen {
+				t.Errorf("expected %d, got %d", firstSeen, got.CreatedAt)
+			}
+		})
+	}
+}
@@ -31,7 +31,7 @@ func SettingGetString(key model.SettingKey) (string, error) {
 	return setting, nil
 }
 
-func SettingSetString(key model.SettingKey, value string) error {
+func SettingSetString(key model.SettingKey, value string, ctx context.Context) error {
 	valueCache, ok := settingCache.Get(key)
 	if !ok {
 		return fmt.Errorf("setting not found")
@@ -39,7 +39,7 @@ func SettingSetString(key model.SettingKey, value string) error {
 	if valueCache == value {
 		return nil
 	}
-	result := db.GetDB().Model(&model.Setting{Key: key}).Update("Value", value)
+	result := db.Conn(ctx).Model(&model.Setting{Key: key}).Update("Value", value)
 	if result.Error != nil {
 		return fmt.Errorf("failed to set setting: %w", result.Error)
 	}
@@ -47,6 +47,8 @@ func SettingSetString(key model.SettingKey, value string) error {
 		return fmt.Errorf("failed to set setting, key not found")
 	}
 	settingCache.Set(key, value)
+	auditRecord(ctx, model.AuditActionSettingUpdate, "setting", key,
+		auditSetting(key, valueCache), auditSetting(key, value))
 	return nil
 }
 
@@ -90,7 +92,7 @@ func SettingSetInt(key model.SettingKey, value int) error {
 }
 
 func settingRefreshCache(ctx context.Context) error {
-	db := db.GetDB().WithContext(ctx)
+	db := db.Conn(ctx)
 
 	var settings []model.Setting
 	if err := db.Find(&settings).Error; err != nil {
@@ -112,7 +114,8 @@ func settingRefreshCache(ctx context.Context) error {
 	}
 
 	if len(missingSettings) > 0 {
-		if err := db.CreateInBatches(missingSettings, len(missingSettings)).Error; err != nil {
+		// 补写的默认设置不属于配置变更，无需通知其他进程
+		if err := cacheSkipNotify(db).CreateInBatches(missingSettings, len(missingSettings)).Error; err != nil {
 			return fmt.Errorf("failed to create missing settings: %w", err)
 		}
 		settings = append(settings, missingSettings...)
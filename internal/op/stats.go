@@ -26,16 +26,72 @@ var statsHourlyCache [24]model.StatsHourly
 var statsHourlyCacheLock sync.RWMutex
 
 var statsChannelCache = cache.New[int, model.StatsChannel](16)
-var statsChannelCacheNeedUpdate = make(map[int]struct{})
-var statsChannelCacheNeedUpdateLock sync.Mutex
-
 var statsModelCache = cache.New[int, model.StatsModel](16)
-var statsModelCacheNeedUpdate = make(map[int]struct{})
-var statsModelCacheNeedUpdateLock sync.Mutex
-
 var statsAPIKeyCache = cache.New[int, model.StatsAPIKey](16)
-var statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-var statsAPIKeyCacheNeedUpdateLock sync.Mutex
+
+// statsDelta 尚未写入数据库的统计增量。写库时累加到数据库已有的值上而不是覆盖，
+// 多个实例共享同一个数据库时各自的统计不会互相覆盖
+type statsDelta struct {
+	total   model.StatsMetrics
+	daily   map[string]model.StatsMetrics
+	hourly  map[int]model.StatsHourly
+	channel map[int]model.StatsMetrics
+	model   map[int]model.StatsModel
+	apiKey  map[int]model.StatsMetrics
+}
+
+func newStatsDelta() statsDelta {
+	return statsDelta{
+		daily:   make(map[string]model.StatsMetrics),
+		hourly:  make(map[int]model.StatsHourly),
+		channel: make(map[int]model.StatsMetrics),
+		model:   make(map[int]model.StatsModel),
+		apiKey:  make(map[int]model.StatsMetrics),
+	}
+}
+
+// merge 将写库失败的增量合并回当前增量
+func (d *statsDelta) merge(o statsDelta) {
+	d.total.Add(o.total)
+	for date, m := range o.daily {
+		cur := d.daily[date]
+		cur.Add(m)
+		d.daily[date] = cur
+	}
+	for hour, h := range o.hourly {
+		// 同一小时已经进入新的一天时丢弃旧日期的增量
+		cur, ok := d.hourly[hour]
+		switch {
+		case !ok || cur.Date < h.Date:
+			d.hourly[hour] = h
+		case cur.Date == h.Date:
+			cur.StatsMetrics.Add(h.StatsMetrics)
+			d.hourly[hour] = cur
+		}
+	}
+	for id, m := range o.channel {
+		cur := d.channel[id]
+		cur.Add(m)
+		d.channel[id] = cur
+	}
+	for id, m := range o.model {
+		cur, ok := d.model[id]
+		if !ok {
+			cur = model.StatsModel{ID: id, Name: m.Name, ChannelID: m.ChannelID}
+		}
+		cur.StatsMetrics.Add(m.StatsMetrics)
+		d.model[id] = cur
+	}
+	for id, m := range o.apiKey {
+		cur := d.apiKey[id]
+		cur.Add(m)
+		d.apiKey[id] = cur
+	}
+}
+
+// statsPending 保护 statsPendingDelta；更新统计时同时持有该锁修改缓存与增量，保证两者一致
+var statsPending sync.Mutex
+var statsPendingDelta = newStatsDelta()
 
 func StatsSaveDBTask() {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
@@ -51,184 +107,139 @@ func StatsSaveDBTask() {
 	}
 }
 
+// StatsSaveDB 将统计增量累加写入数据库，失败时增量保留到下一次写入。
+// 集群模式下写入后重新加载数据库中的统计，使缓存包含其他实例的数据
 func StatsSaveDB(ctx context.Context) error {
-	statsTotalCacheLock.RLock()
-	totalSnap := statsTotalCache
-	statsTotalCacheLock.RUnlock()
-	if totalSnap.ID == 0 {
-		totalSnap.ID = 1
-	}
-
-	statsDailyCacheLock.RLock()
-	dailySnap := statsDailyCache
-	statsDailyCacheLock.RUnlock()
-
-	statsHourlyCacheLock.RLock()
-	hourlyAll := statsHourlyCache
-	statsHourlyCacheLock.RUnlock()
-
-	statsChannelCacheNeedUpdateLock.Lock()
-	channelIDs := make([]int, 0, len(statsChannelCacheNeedUpdate))
-	for id := range statsChannelCacheNeedUpdate {
-		channelIDs = append(channelIDs, id)
-	}
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
-
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
-	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
-
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
-	for id := range statsAPIKeyCacheNeedUpdate {
-		apiKeyIDs = append(apiKeyIDs, id)
+	statsPending.Lock()
+	delta := statsPendingDelta
+	statsPendingDelta = newStatsDelta()
+	statsPending.Unlock()
+
+	if err := db.Conn(ctx).Transaction(func(tx *gorm.DB) error {
+		return persistStatsDelta(tx, delta)
+	}); err != nil {
+		statsPending.Lock()
+		statsPendingDelta.merge(delta)
+		statsPending.Unlock()
+		return err
+	}
+	if ClusterEnabled() {
+		return statsRefreshCache(ctx)
 	}
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
-
-	return persistStatsSnapshots(ctx, totalSnap, dailySnap, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
+	return nil
 }
 
-func persistStatsSnapshots(
-	ctx context.Context,
-	totalSnap model.StatsTotal,
-	dailySnap model.StatsDaily,
-	hourlyAll [24]model.StatsHourly,
-	channelIDs []int,
-	modelIDs []int,
-	apiKeyIDs []int,
-) error {
-	dbConn := db.GetDB().WithContext(ctx)
-
-	if result := dbConn.Save(&totalSnap); result.Error != nil {
-		return result.Error
+func persistStatsDelta(tx *gorm.DB, delta statsDelta) error {
+	if err := statsAddDB(tx, &model.StatsTotal{ID: 1, StatsMetrics: delta.total}, "id", delta.total); err != nil {
+		return err
 	}
-	if result := dbConn.Save(&dailySnap); result.Error != nil {
-		return result.Error
-	}
-
-	todayDate := time.Now().Format("20060102")
-	hourlyStats := make([]model.StatsHourly, 0, 24)
-	for hour := 0; hour < 24; hour++ {
-		if hourlyAll[hour].Date == todayDate {
-			hourlyStats = append(hourlyStats, hourlyAll[hour])
+	for date, m := range delta.daily {
+		if err := statsAddDB(tx, &model.StatsDaily{Date: date, StatsMetrics: m}, "date", m); err != nil {
+			return err
 		}
 	}
-	if len(hourlyStats) > 0 {
-		if result := dbConn.Clauses(clause.OnConflict{
-			Columns:   []clause.Column{{Name: "hour"}},
-			UpdateAll: true,
-		}).Create(&hourlyStats); result.Error != nil {
-			return result.Error
+	for _, h := range delta.hourly {
+		if err := statsHourlyAddDB(tx, h); err != nil {
+			return err
 		}
 	}
-
-	for _, id := range channelIDs {
-		ch, ok := statsChannelCache.Get(id)
-		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&ch); result.Error != nil {
-			return result.Error
+	for id, m := range delta.channel {
+		if err := statsAddDB(tx, &model.StatsChannel{ChannelID: id, StatsMetrics: m}, "channel_id", m); err != nil {
+			return err
 		}
 	}
-
-	for _, id := range modelIDs {
-		m, ok := statsModelCache.Get(id)
-		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&m); result.Error != nil {
-			return result.Error
+	for _, m := range delta.model {
+		if err := statsAddDB(tx, &m, "id", m.StatsMetrics); err != nil {
+			return err
 		}
 	}
-
-	for _, id := range apiKeyIDs {
-		ak, ok := statsAPIKeyCache.Get(id)
-		if !ok {
-			continue
-		}
-		if result := dbConn.Save(&ak); result.Error != nil {
-			return result.Error
+	for id, m := range delta.apiKey {
+		if err := statsAddDB(tx, &model.StatsAPIKey{APIKeyID: id, StatsMetrics: m}, "api_key_id", m); err != nil {
+			return err
 		}
 	}
-
 	return nil
 }
 
-func statsSaveDBWithDailyOverride(ctx context.Context, dailyOverride model.StatsDaily) error {
-	statsTotalCacheLock.RLock()
-	totalSnap := statsTotalCache
-	statsTotalCacheLock.RUnlock()
-	if totalSnap.ID == 0 {
-		totalSnap.ID = 1
-	}
-
-	statsHourlyCacheLock.RLock()
-	hourlyAll := statsHourlyCache
-	statsHourlyCacheLock.RUnlock()
-
-	statsChannelCacheNeedUpdateLock.Lock()
-	channelIDs := make([]int, 0, len(statsChannelCacheNeedUpdate))
-	for id := range statsChannelCacheNeedUpdate {
-		channelIDs = append(channelIDs, id)
-	}
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
+var statsMetricsColumns = []string{"input_token", "output_token", "cache_read_token", "cache_write_token", "input_cost", "output_cost", "wait_time", "request_success", "request_failed"}
 
-	statsModelCacheNeedUpdateLock.Lock()
-	modelIDs := make([]int, 0, len(statsModelCacheNeedUpdate))
-	for id := range statsModelCacheNeedUpdate {
-		modelIDs = append(modelIDs, id)
-	}
-	statsModelCacheNeedUpdate = make(map[int]struct{})
-	statsModelCacheNeedUpdateLock.Unlock()
+func statsMetricsValues(m model.StatsMetrics) []any {
+	return []any{m.InputToken, m.OutputToken, m.CacheReadToken, m.CacheWriteToken, m.InputCost, m.OutputCost, m.WaitTime, m.RequestSuccess, m.RequestFailed}
+}
 
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	apiKeyIDs := make([]int, 0, len(statsAPIKeyCacheNeedUpdate))
-	for id := range statsAPIKeyCacheNeedUpdate {
-		apiKeyIDs = append(apiKeyIDs, id)
-	}
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
+// statsAddDB 插入一行统计，主键冲突时把增量 m 累加到已有的行上；row 中的统计字段应与 m 相同
+func statsAddDB(tx *gorm.DB, row any, key string, m model.StatsMetrics) error {
+	table, err := tableName(tx, row)
+	if err != nil {
+		return err
+	}
+	values := statsMetricsValues(m)
+	set := make([]clause.Assignment, len(statsMetricsColumns))
+	for i, col := range statsMetricsColumns {
+		set[i] = clause.Assignment{Column: clause.Column{Name: col}, Value: gorm.Expr(table+"."+col+" + ?", values[i])}
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: key}},
+		DoUpdates: set,
+	}).Create(row).Error
+}
 
-	return persistStatsSnapshots(ctx, totalSnap, dailyOverride, hourlyAll, channelIDs, modelIDs, apiKeyIDs)
+// statsHourlyAddDB 按小时累加统计；数据库中的行属于更早的日期时直接覆盖
+func statsHourlyAddDB(tx *gorm.DB, h model.StatsHourly) error {
+	table, err := tableName(tx, &h)
+	if err != nil {
+		return err
+	}
+	values := statsMetricsValues(h.StatsMetrics)
+	set := make([]clause.Assignment, 0, len(statsMetricsColumns)+1)
+	for i, col := range statsMetricsColumns {
+		set = append(set, clause.Assignment{
+			Column: clause.Column{Name: col},
+			Value:  gorm.Expr("CASE WHEN "+table+".date = ? THEN "+table+"."+col+" + ? ELSE ? END", h.Date, values[i], values[i]),
+		})
+	}
+	// MySQL 按顺序执行赋值，date 必须最后更新
+	set = append(set, clause.Assignment{Column: clause.Column{Name: "date"}, Value: h.Date})
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hour"}},
+		DoUpdates: set,
+	}).Create(&h).Error
 }
 
 func StatsDailyUpdate(ctx context.Context, metrics model.StatsMetrics) error {
 	today := time.Now().Format("20060102")
 
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	statsDailyCacheLock.Lock()
-	if statsDailyCache.Date == today {
-		statsDailyCache.StatsMetrics.Add(metrics)
-		statsDailyCacheLock.Unlock()
-		return nil
+	if statsDailyCache.Date != today {
+		statsDailyCache = model.StatsDaily{Date: today}
 	}
-
-	prevDaily := statsDailyCache
-	statsDailyCache = model.StatsDaily{Date: today}
 	statsDailyCache.StatsMetrics.Add(metrics)
 	statsDailyCacheLock.Unlock()
 
-	return statsSaveDBWithDailyOverride(ctx, prevDaily)
+	daily := statsPendingDelta.daily[today]
+	daily.Add(metrics)
+	statsPendingDelta.daily[today] = daily
+	return nil
 }
 
 func StatsTotalUpdate(metrics model.StatsMetrics) error {
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	statsTotalCacheLock.Lock()
-	defer statsTotalCacheLock.Unlock()
 	if statsTotalCache.ID == 0 {
 		statsTotalCache.ID = 1
 	}
 	statsTotalCache.StatsMetrics.Add(metrics)
+	statsTotalCacheLock.Unlock()
+	statsPendingDelta.total.Add(metrics)
 	return nil
 }
 
 func StatsChannelUpdate(channelID int, metrics model.StatsMetrics) error {
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	channelCache, ok := statsChannelCache.Get(channelID)
 	if !ok {
 		channelCache = model.StatsChannel{
@@ -237,9 +248,9 @@ func StatsChannelUpdate(channelID int, metrics model.StatsMetrics) error {
 	}
 	channelCache.StatsMetrics.Add(metrics)
 	statsChannelCache.Set(channelID, channelCache)
-	statsChannelCacheNeedUpdateLock.Lock()
-	statsChannelCacheNeedUpdate[channelID] = struct{}{}
-	statsChannelCacheNeedUpdateLock.Unlock()
+	delta := statsPendingDelta.channel[channelID]
+	delta.Add(metrics)
+	statsPendingDelta.channel[channelID] = delta
 	return nil
 }
 
@@ -248,36 +259,52 @@ func StatsHourlyUpdate(metrics model.StatsMetrics) error {
 	nowHour := now.Hour()
 	todayDate := time.Now().Format("20060102")
 
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	statsHourlyCacheLock.Lock()
-	defer statsHourlyCacheLock.Unlock()
-
 	if statsHourlyCache[nowHour].Date != todayDate {
 		statsHourlyCache[nowHour] = model.StatsHourly{
 			Hour: nowHour,
 			Date: todayDate,
 		}
 	}
-
 	statsHourlyCache[nowHour].StatsMetrics.Add(metrics)
+	statsHourlyCacheLock.Unlock()
+
+	delta, ok := statsPendingDelta.hourly[nowHour]
+	if !ok || delta.Date != todayDate {
+		delta = model.StatsHourly{Hour: nowHour, Date: todayDate}
+	}
+	delta.StatsMetrics.Add(metrics)
+	statsPendingDelta.hourly[nowHour] = delta
 	return nil
 }
 
 func StatsModelUpdate(stats model.StatsModel) error {
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	modelCache, ok := statsModelCache.Get(stats.ID)
 	if !ok {
 		modelCache = model.StatsModel{
-			ID: stats.ID,
+			ID:        stats.ID,
+			Name:      stats.Name,
+			ChannelID: stats.ChannelID,
 		}
 	}
 	modelCache.StatsMetrics.Add(stats.StatsMetrics)
 	statsModelCache.Set(stats.ID, modelCache)
-	statsModelCacheNeedUpdateLock.Lock()
-	statsModelCacheNeedUpdate[stats.ID] = struct{}{}
-	statsModelCacheNeedUpdateLock.Unlock()
+	delta, ok := statsPendingDelta.model[stats.ID]
+	if !ok {
+		delta = model.StatsModel{ID: stats.ID, Name: stats.Name, ChannelID: stats.ChannelID}
+	}
+	delta.StatsMetrics.Add(stats.StatsMetrics)
+	statsPendingDelta.model[stats.ID] = delta
 	return nil
 }
 
 func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
+	statsPending.Lock()
+	defer statsPending.Unlock()
 	apiKeyCache, ok := statsAPIKeyCache.Get(apiKeyID)
 	if !ok {
 		apiKeyCache = model.StatsAPIKey{
@@ -286,32 +313,32 @@ func StatsAPIKeyUpdate(apiKeyID int, metrics model.StatsMetrics) error {
 	}
 	apiKeyCache.StatsMetrics.Add(metrics)
 	statsAPIKeyCache.Set(apiKeyID, apiKeyCache)
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	statsAPIKeyCacheNeedUpdate[apiKeyID] = struct{}{}
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
+	delta := statsPendingDelta.apiKey[apiKeyID]
+	delta.Add(metrics)
+	statsPendingDelta.apiKey[apiKeyID] = delta
 	return nil
 }
 
-func StatsChannelDel(id int) error {
+func StatsChannelDel(id int, ctx context.Context) error {
+	statsPending.Lock()
+	delete(statsPendingDelta.channel, id)
+	statsPending.Unlock()
 	if _, ok := statsChannelCache.Get(id); !ok {
 		return nil
 	}
 	statsChannelCache.Del(id)
-	statsChannelCacheNeedUpdateLock.Lock()
-	delete(statsChannelCacheNeedUpdate, id)
-	statsChannelCacheNeedUpdateLock.Unlock()
-	return db.GetDB().Delete(&model.StatsChannel{}, id).Error
+	return db.Conn(ctx).Delete(&model.StatsChannel{}, id).Error
 }
 
-func StatsAPIKeyDel(id int) error {
+func StatsAPIKeyDel(id int, ctx context.Context) error {
+	statsPending.Lock()
+	delete(statsPendingDelta.apiKey, id)
+	statsPending.Unlock()
 	if _, ok := statsAPIKeyCache.Get(id); !ok {
 		return nil
 	}
 	statsAPIKeyCache.Del(id)
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	delete(statsAPIKeyCacheNeedUpdate, id)
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
-	return db.GetDB().Delete(&model.StatsAPIKey{}, id).Error
+	return db.Conn(ctx).Delete(&model.StatsAPIKey{}, id).Error
 }
 
 func StatsTotalGet() model.StatsTotal {
@@ -332,10 +359,10 @@ func StatsChannelGet(id int) model.StatsChannel {
 		tmp := model.StatsChannel{
 			ChannelID: id,
 		}
+		statsPending.Lock()
 		statsChannelCache.Set(id, tmp)
-		statsChannelCacheNeedUpdateLock.Lock()
-		statsChannelCacheNeedUpdate[id] = struct{}{}
-		statsChannelCacheNeedUpdateLock.Unlock()
+		statsPendingDelta.channel[id] = statsPendingDelta.channel[id]
+		statsPending.Unlock()
 		return tmp
 	}
 	return stats
@@ -347,10 +374,10 @@ func StatsAPIKeyGet(id int) model.StatsAPIKey {
 		tmp := model.StatsAPIKey{
 			APIKeyID: id,
 		}
+		statsPending.Lock()
 		statsAPIKeyCache.Set(id, tmp)
-		statsAPIKeyCacheNeedUpdateLock.Lock()
-		statsAPIKeyCacheNeedUpdate[id] = struct{}{}
-		statsAPIKeyCacheNeedUpdateLock.Unlock()
+		statsPendingDelta.apiKey[id] = statsPendingDelta.apiKey[id]
+		statsPending.Unlock()
 		return tmp
 	}
 	return stats
@@ -390,7 +417,7 @@ func StatsHourlyGet() []model.StatsHourly {
 
 func StatsGetDaily(ctx context.Context) ([]model.StatsDaily, error) {
 	var statsDaily []model.StatsDaily
-	result := db.GetDB().WithContext(ctx).Find(&statsDaily)
+	result := db.Conn(ctx).Find(&statsDaily)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -398,7 +425,7 @@ func StatsGetDaily(ctx context.Context) ([]model.StatsDaily, error) {
 }
 
 func statsRefreshCache(ctx context.Context) error {
-	dbConn := db.GetDB().WithContext(ctx)
+	dbConn := db.Conn(ctx)
 	today := time.Now().Format("20060102")
 
 	var loadedDaily model.StatsDaily
@@ -433,34 +460,55 @@ func statsRefreshCache(ctx context.Context) error {
 		return fmt.Errorf("failed to get hourly stats: %v", result.Error)
 	}
 
+	var loadedAPIKeys []model.StatsAPIKey
+	result = dbConn.Find(&loadedAPIKeys)
+	if result.Error != nil {
+		return fmt.Errorf("failed to get api key stats: %v", result.Error)
+	}
+
+	// 缓存 = 数据库中的值 + 尚未写入数据库的增量
+	statsPending.Lock()
+	defer statsPending.Unlock()
+	delta := statsPendingDelta
+
+	loadedDaily.StatsMetrics.Add(delta.daily[loadedDaily.Date])
 	statsDailyCacheLock.Lock()
 	statsDailyCache = loadedDaily
 	statsDailyCacheLock.Unlock()
 
+	loadedTotal.StatsMetrics.Add(delta.total)
 	statsTotalCacheLock.Lock()
 	statsTotalCache = loadedTotal
 	statsTotalCacheLock.Unlock()
 
-	statsChannelCache.Clear()
-	statsChannelCacheNeedUpdateLock.Lock()
-	statsChannelCacheNeedUpdate = make(map[int]struct{})
-	statsChannelCacheNeedUpdateLock.Unlock()
+	channels := make(map[int]model.StatsChannel, len(loadedChannels))
 	for _, v := range loadedChannels {
-		statsChannelCache.Set(v.ChannelID, v)
+		channels[v.ChannelID] = v
 	}
-
-	var loadedAPIKeys []model.StatsAPIKey
-	result = dbConn.Find(&loadedAPIKeys)
-	if result.Error != nil {
-		return fmt.Errorf("failed to get api key stats: %v", result.Error)
+	for id, m := range delta.channel {
+		v := channels[id]
+		v.ChannelID = id
+		v.StatsMetrics.Add(m)
+		channels[id] = v
+	}
+	statsChannelCache.Clear()
+	for id, v := range channels {
+		statsChannelCache.Set(id, v)
 	}
 
-	statsAPIKeyCache.Clear()
-	statsAPIKeyCacheNeedUpdateLock.Lock()
-	statsAPIKeyCacheNeedUpdate = make(map[int]struct{})
-	statsAPIKeyCacheNeedUpdateLock.Unlock()
+	apiKeys := make(map[int]model.StatsAPIKey, len(loadedAPIKeys))
 	for _, v := range loadedAPIKeys {
-		statsAPIKeyCache.Set(v.APIKeyID, v)
+		apiKeys[v.APIKeyID] = v
+	}
+	for id, m := range delta.apiKey {
+		v := apiKeys[id]
+		v.APIKeyID = id
+		v.StatsMetrics.Add(m)
+		apiKeys[id] = v
+	}
+	statsAPIKeyCache.Clear()
+	for id, v := range apiKeys {
+		statsAPIKeyCache.Set(id, v)
 	}
 
 	statsHourlyCacheLock.Lock()
@@ -470,6 +518,13 @@ func statsRefreshCache(ctx context.Context) error {
 			statsHourlyCache[v.Hour] = v
 		}
 	}
+	for hour, h := range delta.hourly {
+		if statsHourlyCache[hour].Date == h.Date {
+			statsHourlyCache[hour].StatsMetrics.Add(h.StatsMetrics)
+		} else {
+			statsHourlyCache[hour] = h
+		}
+	}
 	statsHourlyCacheLock.Unlock()
 
 	return nil
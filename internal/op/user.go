@@ -1,6 +1,7 @@
 package op
 
 import (
+	"context"
 	"fmt"
 
 	"octopus/internal/db"
@@ -67,3 +68,12 @@ func UserVerify(username, password string) error {
 func UserGet() model.User {
 	return userCache
 }
+
+func userRefreshCache(ctx context.Context) error {
+	var user model.User
+	if err := db.Conn(ctx).First(&user).Error; err != nil {
+		return err
+	}
+	userCache = user
+	return nil
+}
@@ -0,0 +1,70 @@
+package relay
+
+import (
+	"context"
+	"sync"
+)
+
+// drainRetryAfter 停机期间拒绝新请求时返回的 Retry-After 秒数
+const drainRetryAfter = 5
+
+var drain = struct {
+	sync.Mutex
+	draining bool
+	active   int
+	idle     chan struct{}
+}{}
+
+// abortCtx 在 Abort 时取消，进行中的中继请求的上下文由它派生
+var abortCtx, abortAll = context.WithCancel(context.Background())
+
+// acquire 登记一个进行中的中继请求，停机排空阶段返回 false
+func acquire() bool {
+	drain.Lock()
+	defer drain.Unlock()
+	if drain.draining {
+		return false
+	}
+	drain.active++
+	return true
+}
+
+func release() {
+	drain.Lock()
+	defer drain.Unlock()
+	drain.active--
+	if drain.active == 0 && drain.idle != nil {
+		close(drain.idle)
+		drain.idle = nil
+	}
+}
+
+// Drain 停止接收新的中继请求并等待进行中的请求完成，
+// 返回 ctx 结束时仍未完成的请求数
+func Drain(ctx context.Context) int {
+	drain.Lock()
+	drain.draining = true
+	if drain.active == 0 {
+		drain.Unlock()
+		return 0
+	}
+	if drain.idle == nil {
+		drain.idle = make(chan struct{})
+	}
+	idle := drain.idle
+	drain.Unlock()
+
+	select {
+	case <-idle:
+		return 0
+	case <-ctx.Done():
+		drain.Lock()
+		defer drain.Unlock()
+		return drain.active
+	}
+}
+
+// Abort 中断所有进行中的中继请求，已经收到的用量仍会被记录
+func Abort() {
+	abortAll()
+}
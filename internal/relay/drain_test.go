@@ -0,0 +1,72 @@
+package relay
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// resetDrain 恢复排空状态，测试结束后重新接收请求
+func resetDrain(t *testing.T) {
+	t.Helper()
+	reset := func() {
+		drain.Lock()
+		drain.draining = false
+		drain.active = 0
+		drain.idle = nil
+		drain.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name     string
+		active   int           // 排空开始前进行中的请求数
+		finish   int           // 排空开始后完成的请求数
+		timeout  time.Duration // Drain 的等待时间
+		expected int           // Drain 返回的剩余请求数
+	}{
+		{name: "idle", timeout: time.Second, expected: 0},
+		{name: "requests finish", active: 2, finish: 2, timeout: 5 * time.Second, expected: 0},
+		{name: "timeout", active: 3, finish: 1, timeout: 100 * time.Millisecond, expected: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetDrain(t)
+			for i := 0; i < tt.active; i++ {
+				if !acquire() {
+					t.Fatal("expected acquire to succeed before draining")
+				}
+			}
+
+			done := make(chan int)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+				defer cancel()
+				done <- Drain(ctx)
+			}()
+			// 等待 Drain 进入排空状态
+			for {
+				drain.Lock()
+				draining := drain.draining
+				drain.Unlock()
+				if draining {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if acquire() {
+				t.Fatal("expected new requests to be rejected while draining")
+			}
+			for i := 0; i < tt.finish; i++ {
+				release()
+			}
+
+			if got := <-done; got != tt.expected {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
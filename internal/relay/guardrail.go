@@ -0,0 +1,67 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+
+	"octopus/internal/guardrail"
+	dbmodel "octopus/internal/model"
+	"octopus/internal/server/resp"
+	"octopus/internal/transformer/model"
+	"octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+)
+
+// newGuardrail 合并分组与 API Key 的内容安全策略，均未启用时返回 nil
+func newGuardrail(c *gin.Context, group *dbmodel.Group) *guardrail.Checker {
+	value, _ := c.Get("api_key_guardrail")
+	policy, _ := value.(*dbmodel.GuardrailPolicy)
+	return guardrail.New(group.Guardrail, policy)
+}
+
+// checkRequest 在转发前检查请求，返回替换命中内容后的请求；命中 block 规则时返回错误并记录日志。
+// 日志只记录替换后的请求，不保存命中的内容
+func checkRequest(c *gin.Context, guard *guardrail.Checker, req *model.InternalLLMRequest, metrics *RelayMetrics) (*model.InternalLLMRequest, bool) {
+	if guard == nil {
+		return req, true
+	}
+	checked, report := guard.Request(req)
+	if report.Empty() {
+		return req, true
+	}
+	log.Warnf("guardrail: request for model %s hit %s", req.Model, report)
+	metrics.SetInternalRequest(checked)
+	if report.Blocked != "" {
+		err := fmt.Errorf("request blocked by guardrail: %s detected", report.Blocked)
+		metrics.Save(c.Request.Context(), false, err)
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return checked, true
+}
+
+// guardResponse 检查非流式响应
+func (rc *relayContext) guardResponse(resp *model.InternalLLMResponse) {
+	if report := rc.guard.Response(resp); !report.Empty() {
+		log.Warnf("guardrail: response from channel %s hit %s", rc.channel.Name, report)
+	}
+}
+
+// guardStream 检查流式分片，flush 为 true 时输出暂存的全部文本。
+// 返回 false 表示分片中的内容全部被暂存或拦截，不需要发送
+func (rc *relayContext) guardStream(chunk *model.InternalLLMResponse, flush bool) bool {
+	if rc.guardState == nil {
+		return true
+	}
+	hadChoices := len(chunk.Choices) > 0
+	var report guardrail.Report
+	if flush {
+		report = rc.guardState.Flush(chunk)
+	} else {
+		report = rc.guardState.Chunk(chunk)
+	}
+	if !report.Empty() {
+		log.Warnf("guardrail: response from channel %s hit %s", rc.channel.Name, report)
+	}
+	return !hadChoices || len(chunk.Choices) > 0 || chunk.Usage != nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"octopus/internal/alert"
 	"octopus/internal/model"
 	"octopus/internal/op"
 	"octopus/internal/price"
@@ -74,6 +75,10 @@ func (m *RelayMetrics) SetInternalResponse(resp *transformerModel.InternalLLMRes
 	usage := resp.Usage
 	m.Stats.InputToken = usage.PromptTokens
 	m.Stats.OutputToken = usage.CompletionTokens
+	if usage.PromptTokensDetails != nil {
+		m.Stats.CacheReadToken = usage.PromptTokensDetails.CachedTokens
+	}
+	m.Stats.CacheWriteToken = usage.CacheCreationInputTokens
 
 	// 计算费用 - 优先使用渠道特定价格；若未配置且输入或输出为 0 则回退到默认价格
 	modelPrice, err := op.LLMGet(m.ActualModel, m.ChannelID)
@@ -133,6 +138,8 @@ func (m *RelayMetrics) SetInternalResponse(resp *transformerModel.InternalLLMRes
 // err: 失败时的错误信息，成功时为 nil
 func (m *RelayMetrics) Save(ctx context.Context, success bool, err error) {
 	duration := time.Since(m.StartTime)
+	// 客户端断开或停机中断请求后仍需保存日志
+	ctx = context.WithoutCancel(ctx)
 
 	// 保存统计信息
 	m.saveStats(success, duration)
@@ -155,6 +162,7 @@ func (m *RelayMetrics) saveStats(success bool, duration time.Duration) {
 	op.StatsHourlyUpdate(m.Stats)
 	op.StatsDailyUpdate(context.Background(), m.Stats)
 	op.StatsAPIKeyUpdate(m.APIKeyID, m.Stats)
+	alert.ObserveRelay(m.ChannelID, success)
 
 	log.Infof("channel: %d, model: %s, success: %t, wait time: %d, input token: %d, output token: %d, input cost: %f, output cost: %f total cost: %f, cache_read: %d, cache_write: %d, normal: %d",
 		m.ChannelID, m.ActualModel, success, m.Stats.WaitTime,
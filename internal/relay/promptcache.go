@@ -0,0 +1,124 @@
+package relay
+
+import (
+	"slices"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound"
+)
+
+// defaultPromptCacheTurns 未设置轮数时缓存最近两轮：上一轮结束处用于读取上次请求写入的缓存，末尾用于写入本次的缓存
+const defaultPromptCacheTurns = 2
+
+// promptCachePolicy 返回对渠道生效的提示词缓存策略，分组启用的策略优先于渠道策略
+func promptCachePolicy(group *dbmodel.Group, channel *dbmodel.Channel) *dbmodel.PromptCachePolicy {
+	if channel.Type != outbound.OutboundTypeAnthropic {
+		return nil
+	}
+	if group != nil && group.PromptCache.Active() {
+		return group.PromptCache
+	}
+	if channel.PromptCache.Active() {
+		return channel.PromptCache
+	}
+	return nil
+}
+
+// injectPromptCache 按策略在工具定义、系统提示词与最近几轮对话的末尾插入 cache_control 断点，
+// 总数不超过 Anthropic 的 4 个限制。请求已经携带 cache_control 时保持原样。
+// 返回修改后的副本，原请求在切换到其他渠道时保持不变
+func injectPromptCache(req *model.InternalLLMRequest, policy *dbmodel.PromptCachePolicy) *model.InternalLLMRequest {
+	if policy == nil || !req.IsChatRequest() || hasCacheControl(req) {
+		return req
+	}
+	cc := &model.CacheControl{Type: "ephemeral"}
+	if policy.TTL == "1h" {
+		cc.TTL = "1h"
+	}
+
+	cp := *req
+	cp.Messages = slices.Clone(req.Messages)
+	budget := dbmodel.PromptCacheMaxBreakpoints
+
+	// 工具定义位于提示词最前面，标记最后一个工具即可缓存全部工具
+	if n := len(cp.Tools); n > 0 {
+		cp.Tools = slices.Clone(req.Tools)
+		cp.Tools[n-1].CacheControl = cc
+		budget--
+	}
+	for i := len(cp.Messages) - 1; i >= 0; i-- {
+		if cp.Messages[i].Role == "system" {
+			cp.Messages[i].CacheControl = cc
+			budget--
+			break
+		}
+	}
+
+	turns := policy.Turns
+	if turns == 0 {
+		turns = defaultPromptCacheTurns
+	}
+	for _, i := range turnBreakpoints(cp.Messages, min(turns, budget)) {
+		markMessage(&cp.Messages[i], cc)
+	}
+	return &cp
+}
+
+// turnBreakpoints 返回最近 n 轮对话各自最后一条消息的下标：整个对话的末尾，以及之前每个用户轮次开始前的一条消息
+func turnBreakpoints(messages []model.Message, n int) []int {
+	var result []int
+	for i := len(messages) - 1; i >= 0 && len(result) < n; i-- {
+		if messages[i].Role == "system" {
+			continue
+		}
+		// 第一条非 system 消息即对话末尾；messages[i+1] 是用户发起的新一轮时，messages[i] 即上一轮的结尾
+		if len(result) == 0 || messages[i+1].Role == "user" {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// markMessage 将断点放在消息最后一个会被转换为内容块的位置
+func markMessage(msg *model.Message, cc *model.CacheControl) {
+	if n := len(msg.ToolCalls); msg.Role == "assistant" && n > 0 {
+		msg.ToolCalls = slices.Clone(msg.ToolCalls)
+		msg.ToolCalls[n-1].CacheControl = cc
+		return
+	}
+	if msg.Content.Content == nil && msg.Role != "tool" {
+		for j := len(msg.Content.MultipleContent) - 1; j >= 0; j-- {
+			if t := msg.Content.MultipleContent[j].Type; t == "text" || t == "image_url" {
+				msg.Content.MultipleContent = slices.Clone(msg.Content.MultipleContent)
+				msg.Content.MultipleContent[j].CacheControl = cc
+				return
+			}
+		}
+	}
+	msg.CacheControl = cc
+}
+
+func hasCacheControl(req *model.InternalLLMRequest) bool {
+	for _, tool := range req.Tools {
+		if tool.CacheControl != nil {
+			return true
+		}
+	}
+	for _, msg := range req.Messages {
+		if msg.CacheControl != nil {
+			return true
+		}
+		for _, part := range msg.Content.MultipleContent {
+			if part.CacheControl != nil {
+				return true
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			if call.CacheControl != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
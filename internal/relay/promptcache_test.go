@@ -0,0 +1,188 @@
+package relay
+
+import (
+	"testing"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound"
+)
+
+func TestPromptCachePolicy(t *testing.T) {
+	channelPolicy := &dbmodel.PromptCachePolicy{Enabled: true, Turns: 1}
+	groupPolicy := &dbmodel.PromptCachePolicy{Enabled: true, Turns: 3}
+	tests := []struct {
+		name     string
+		group    *dbmodel.Group
+		channel  *dbmodel.Channel
+		expected *dbmodel.PromptCachePolicy
+	}{
+		{name: "not anthropic", channel: &dbmodel.Channel{Type: outbound.OutboundTypeOpenAIChat, PromptCache: channelPolicy}, expected: nil},
+		{name: "channel policy", channel: &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic, PromptCache: channelPolicy}, expected: channelPolicy},
+		{name: "group overrides channel", group: &dbmodel.Group{PromptCache: groupPolicy}, channel: &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic, PromptCache: channelPolicy}, expected: groupPolicy},
+		{name: "disabled group policy", group: &dbmodel.Group{PromptCache: &dbmodel.PromptCachePolicy{Turns: 3}}, channel: &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic, PromptCache: channelPolicy}, expected: channelPolicy},
+		{name: "no policy", group: &dbmodel.Group{}, channel: &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic}, expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := promptCachePolicy(tt.group, tt.channel); got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// conversation 两轮对话：system、user、assistant(调用工具)、tool、assistant、user
+func conversation() *model.InternalLLMRequest {
+	return &model.InternalLLMRequest{
+		Model: "claude",
+		Tools: []model.Tool{{Type: "function", Function: model.Function{Name: "a"}}, {Type: "function", Function: model.Function{Name: "b"}}},
+		Messages: []model.Message{
+			{Role: "system", Content: model.MessageContent{Content: strPtr("system")}},
+			{Role: "user", Content: model.MessageContent{MultipleContent: []model.MessageContentPart{{Type: "text", Text: strPtr("first")}}}},
+			{Role: "assistant", ToolCalls: []model.ToolCall{{ID: "call_1", Type: "function"}}},
+			{Role: "tool", ToolCallID: strPtr("call_1"), Content: model.MessageContent{Content: strPtr("result")}},
+			{Role: "assistant", Content: model.MessageContent{Content: strPtr("answer")}},
+			{Role: "user", Content: model.MessageContent{Content: strPtr("second")}},
+		},
+	}
+}
+
+// breakpoints 返回请求中各处断点的位置描述
+func breakpoints(req *model.InternalLLMRequest) []string {
+	var result []string
+	for _, tool := range req.Tools {
+		if tool.CacheControl != nil {
+			result = append(result, "tool:"+tool.Function.Name+":"+tool.CacheControl.TTL)
+		}
+	}
+	for _, msg := range req.Messages {
+		if msg.CacheControl != nil {
+			result = append(result, "message:"+msg.Role+":"+msg.CacheControl.TTL)
+		}
+		for _, part := range msg.Content.MultipleContent {
+			if part.CacheControl != nil {
+				result = append(result, "part:"+msg.Role+":"+part.CacheControl.TTL)
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			if call.CacheControl != nil {
+				result = append(result, "tool_call:"+call.ID+":"+call.CacheControl.TTL)
+			}
+		}
+	}
+	return result
+}
+
+func TestInjectPromptCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  func() *model.InternalLLMRequest
+		policy   *dbmodel.PromptCachePolicy
+		expected []string
+	}{
+		{name: "no policy", request: conversation, expected: nil},
+		{
+			name:     "default turns",
+			request:  conversation,
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true},
+			expected: []string{"tool:b:", "message:system:", "message:assistant:", "message:user:"},
+		},
+		{
+			name:     "budget limits turns",
+			request:  conversation,
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true, Turns: 4, TTL: "1h"},
+			expected: []string{"tool:b:1h", "message:system:1h", "message:assistant:1h", "message:user:1h"},
+		},
+		{
+			name: "breakpoint on tool call",
+			request: func() *model.InternalLLMRequest {
+				req := conversation()
+				req.Tools = nil
+				req.Messages = req.Messages[:3]
+				return req
+			},
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true, Turns: 1},
+			expected: []string{"message:system:", "tool_call:call_1:"},
+		},
+		{
+			name: "breakpoint on content part",
+			request: func() *model.InternalLLMRequest {
+				req := conversation()
+				req.Tools = nil
+				req.Messages = req.Messages[:2]
+				return req
+			},
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true, Turns: 1},
+			expected: []string{"message:system:", "part:user:"},
+		},
+		{
+			name: "existing cache control",
+			request: func() *model.InternalLLMRequest {
+				req := conversation()
+				req.Messages[5].CacheControl = &model.CacheControl{Type: "ephemeral"}
+				return req
+			},
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true},
+			expected: []string{"message:user:"},
+		},
+		{
+			name: "not a chat request",
+			request: func() *model.InternalLLMRequest {
+				return &model.InternalLLMRequest{Model: "m", EmbeddingInput: &model.EmbeddingInput{Single: strPtr("x")}}
+			},
+			policy:   &dbmodel.PromptCachePolicy{Enabled: true},
+			expected: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.request()
+			got := injectPromptCache(original, tt.policy)
+			points := breakpoints(got)
+			if len(points) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, points)
+			}
+			for i := range points {
+				if points[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, points)
+				}
+			}
+			// 原请求在切换渠道时仍会使用，不能被修改
+			if got != original && len(breakpoints(original)) != 0 {
+				t.Fatalf("expected the original request to stay unmarked, got %v", breakpoints(original))
+			}
+		})
+	}
+}
+
+func TestTurnBreakpoints(t *testing.T) {
+	messages := conversation().Messages
+	tests := []struct {
+		name     string
+		n        int
+		expected []int
+	}{
+		{name: "none", n: 0, expected: nil},
+		{name: "end of conversation", n: 1, expected: []int{5}},
+		{name: "previous turn", n: 2, expected: []int{5, 4}},
+		{name: "more than available", n: 4, expected: []int{5, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := turnBreakpoints(messages, tt.n)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
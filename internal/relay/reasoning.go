@@ -0,0 +1,337 @@
+package relay
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+	// signaturePrefix 启用思考策略时返回给客户端的签名格式为 octopus:<来源>:<原始签名>，
+	// 客户端带回后只发给同一来源的上游
+	signaturePrefix = "octopus:"
+)
+
+// signatureSource 返回渠道签名的来源，同一来源的上游能够验证彼此的签名
+func signatureSource(channelType outbound.OutboundType) string {
+	switch channelType {
+	case outbound.OutboundTypeAnthropic:
+		return "anthropic"
+	case outbound.OutboundTypeOpenAIResponse:
+		return "openai"
+	default:
+		return strconv.Itoa(int(channelType))
+	}
+}
+
+// applyReasoningPolicy 按分组的思考策略调整发往渠道的请求，返回修改后的副本：
+// 按上游模型设置思考预算；带来源的签名只发回原来的上游，发往其他上游时移除；
+// think_tags 模式下客户端带回的 <think> 标签还原为思考内容
+func applyReasoningPolicy(req *model.InternalLLMRequest, policy *dbmodel.ReasoningPolicy, channel *dbmodel.Channel) *model.InternalLLMRequest {
+	if !policy.Active() || !req.IsChatRequest() {
+		return req
+	}
+	cp := *req
+	if req.ReasoningEffort != "" && req.ReasoningBudget == nil {
+		if budget := policy.Budget(req.Model, req.ReasoningEffort); budget > 0 {
+			cp.ReasoningBudget = &budget
+		}
+	}
+
+	cp.Messages = slices.Clone(req.Messages)
+	source := signatureSource(channel.Type)
+	for i := range cp.Messages {
+		msg := &cp.Messages[i]
+		if msg.Role != "assistant" {
+			continue
+		}
+		if policy.Output == dbmodel.ReasoningOutputThinkTags && msg.GetReasoningContent() == "" && msg.Content.Content != nil {
+			if reasoning, content, ok := splitThinkTags(*msg.Content.Content); ok {
+				msg.ReasoningContent = &reasoning
+				msg.Content.Content = &content
+			}
+		}
+		if msg.ReasoningSignature != nil {
+			msg.ReasoningSignature = untagSignature(*msg.ReasoningSignature, source)
+		}
+	}
+	return &cp
+}
+
+// untagSignature 返回可以发给 source 的签名：来源相同时还原原始签名，来源不同时返回 nil，不带来源的签名原样返回
+func untagSignature(signature, source string) *string {
+	rest, ok := strings.CutPrefix(signature, signaturePrefix)
+	if !ok {
+		return &signature
+	}
+	from, raw, ok := strings.Cut(rest, ":")
+	if !ok || from != source {
+		return nil
+	}
+	return &raw
+}
+
+// reasoningOutput 按分组的思考策略转换返回给客户端的思考内容，并为签名标记来源
+type reasoningOutput struct {
+	mode   dbmodel.ReasoningOutput
+	source string
+	split  map[int]*thinkSplitter // 流式：各 choice 正文中 <think> 标签的解析状态
+	opened map[int]bool           // 流式 think_tags：各 choice 是否有未闭合的 <think>
+}
+
+// newReasoningOutput 分组启用了思考策略时返回转换状态，否则返回 nil
+func newReasoningOutput(policy *dbmodel.ReasoningPolicy, channel *dbmodel.Channel) *reasoningOutput {
+	if !policy.Active() {
+		return nil
+	}
+	mode := policy.Output
+	if mode == "" {
+		mode = dbmodel.ReasoningOutputPassthrough
+	}
+	return &reasoningOutput{
+		mode:   mode,
+		source: signatureSource(channel.Type),
+		split:  make(map[int]*thinkSplitter),
+		opened: make(map[int]bool),
+	}
+}
+
+// reset 服务端工具的每一轮是独立的上游响应，重新解析 <think> 标签
+func (o *reasoningOutput) reset() {
+	if o == nil {
+		return
+	}
+	clear(o.split)
+	clear(o.opened)
+}
+
+// response 转换非流式响应
+func (o *reasoningOutput) response(resp *model.InternalLLMResponse) {
+	if o == nil {
+		return
+	}
+	for _, choice := range resp.Choices {
+		msg := choice.Message
+		if msg == nil {
+			continue
+		}
+		reasoning := msg.GetReasoningContent()
+		if o.mode == dbmodel.ReasoningOutputStrip || o.mode == dbmodel.ReasoningOutputReasoningContent {
+			if msg.Content.Content != nil {
+				if r, content, ok := splitThinkTags(*msg.Content.Content); ok {
+					reasoning += r
+					msg.Content.Content = &content
+				}
+			}
+		}
+		switch o.mode {
+		case dbmodel.ReasoningOutputStrip:
+			clearReasoning(msg)
+		case dbmodel.ReasoningOutputReasoningContent:
+			if reasoning != "" {
+				msg.ReasoningContent, msg.Reasoning = &reasoning, nil
+			}
+		case dbmodel.ReasoningOutputThinkTags:
+			if reasoning != "" {
+				prependText(msg, thinkOpenTag+"\n"+reasoning+"\n"+thinkCloseTag+"\n\n")
+			}
+			clearReasoning(msg)
+		}
+		o.tagSignature(msg)
+	}
+}
+
+// stream 转换流式分片
+func (o *reasoningOutput) stream(chunk *model.InternalLLMResponse) {
+	if o == nil {
+		return
+	}
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if choice.Delta == nil {
+			// 结束分片可能需要输出暂存的正文或闭合 <think> 标签
+			if choice.FinishReason == nil || o.mode == dbmodel.ReasoningOutputPassthrough {
+				continue
+			}
+			choice.Delta = &model.Message{}
+		}
+		delta := choice.Delta
+		switch o.mode {
+		case dbmodel.ReasoningOutputStrip, dbmodel.ReasoningOutputReasoningContent:
+			reasoning, content := o.splitDelta(choice)
+			if o.mode == dbmodel.ReasoningOutputStrip {
+				clearReasoning(delta)
+			} else if reasoning = delta.GetReasoningContent() + reasoning; reasoning != "" {
+				delta.ReasoningContent, delta.Reasoning = &reasoning, nil
+			}
+			setDeltaText(delta, content)
+		case dbmodel.ReasoningOutputThinkTags:
+			var sb strings.Builder
+			if reasoning := delta.GetReasoningContent(); reasoning != "" {
+				if !o.opened[choice.Index] {
+					sb.WriteString(thinkOpenTag + "\n")
+					o.opened[choice.Index] = true
+				}
+				sb.WriteString(reasoning)
+			}
+			content := ""
+			if delta.Content.Content != nil {
+				content = *delta.Content.Content
+			}
+			if o.opened[choice.Index] && (content != "" || len(delta.ToolCalls) > 0 || choice.FinishReason != nil) {
+				sb.WriteString("\n" + thinkCloseTag + "\n\n")
+				o.opened[choice.Index] = false
+			}
+			sb.WriteString(content)
+			clearReasoning(delta)
+			setDeltaText(delta, sb.String())
+		}
+		o.tagSignature(delta)
+	}
+}
+
+// splitDelta 从分片正文中分离 <think> 标签内的思考内容，结束时输出暂存的部分
+func (o *reasoningOutput) splitDelta(choice *model.Choice) (reasoning, content string) {
+	s := o.split[choice.Index]
+	if s == nil {
+		s = &thinkSplitter{}
+		o.split[choice.Index] = s
+	}
+	if choice.Delta.Content.Content != nil {
+		reasoning, content = s.feed(*choice.Delta.Content.Content)
+	} else if choice.FinishReason == nil {
+		return "", ""
+	}
+	if choice.FinishReason != nil {
+		r, c := s.flush()
+		reasoning, content = reasoning+r, content+c
+		delete(o.split, choice.Index)
+	}
+	return reasoning, content
+}
+
+func (o *reasoningOutput) tagSignature(msg *model.Message) {
+	if msg.ReasoningSignature == nil || *msg.ReasoningSignature == "" || strings.HasPrefix(*msg.ReasoningSignature, signaturePrefix) {
+		return
+	}
+	tagged := signaturePrefix + o.source + ":" + *msg.ReasoningSignature
+	msg.ReasoningSignature = &tagged
+}
+
+func clearReasoning(msg *model.Message) {
+	msg.ReasoningContent = nil
+	msg.Reasoning = nil
+	msg.ReasoningSignature = nil
+}
+
+// setDeltaText 设置分片的正文，原本没有正文且结果为空时保持不设置
+func setDeltaText(delta *model.Message, text string) {
+	if text == "" && delta.Content.Content == nil {
+		return
+	}
+	if text == "" {
+		delta.Content.Content = nil
+		return
+	}
+	delta.Content.Content = &text
+}
+
+// prependText 在消息正文前插入文本
+func prependText(msg *model.Message, text string) {
+	if len(msg.Content.MultipleContent) > 0 {
+		part := model.MessageContentPart{Type: "text", Text: &text}
+		msg.Content.MultipleContent = append([]model.MessageContentPart{part}, msg.Content.MultipleContent...)
+		return
+	}
+	if msg.Content.Content != nil {
+		text += *msg.Content.Content
+	}
+	msg.Content.Content = &text
+}
+
+// splitThinkTags 分离正文开头 <think> 标签内的思考内容，没有标签时返回 false
+func splitThinkTags(text string) (reasoning, content string, ok bool) {
+	s := &thinkSplitter{}
+	r1, c1 := s.feed(text)
+	r2, c2 := s.flush()
+	return r1 + r2, c1 + c2, s.matched
+}
+
+const (
+	thinkStart = iota
+	thinkInside
+	thinkDone
+)
+
+// thinkSplitter 逐段解析正文开头的 <think>...</think>，标签可能被拆分到多个分片中。
+// 只识别位于正文开头（允许前导空白）的标签，正文中间出现的标签视为普通文本
+type thinkSplitter struct {
+	state    int
+	buf      string
+	matched  bool
+	trimNext bool // 闭合标签后的换行不属于正文
+}
+
+func (s *thinkSplitter) feed(text string) (reasoning, content string) {
+	switch s.state {
+	case thinkStart:
+		s.buf += text
+		trimmed := strings.TrimLeft(s.buf, " \t\r\n")
+		if trimmed == "" || strings.HasPrefix(thinkOpenTag, trimmed) {
+			return "", ""
+		}
+		if !strings.HasPrefix(trimmed, thinkOpenTag) {
+			s.state = thinkDone
+			content, s.buf = s.buf, ""
+			return "", content
+		}
+		s.state, s.matched, s.buf = thinkInside, true, ""
+		return s.feed(strings.TrimLeft(trimmed[len(thinkOpenTag):], "\r\n"))
+	case thinkInside:
+		s.buf += text
+		if i := strings.Index(s.buf, thinkCloseTag); i >= 0 {
+			reasoning, content = s.buf[:i], s.buf[i+len(thinkCloseTag):]
+			s.state, s.buf, s.trimNext = thinkDone, "", true
+			return strings.TrimRight(reasoning, "\r\n"), s.trimContent(content)
+		}
+		// 末尾可能是闭合标签的前半部分，暂存到下一个分片
+		keep := 0
+		for n := min(len(s.buf), len(thinkCloseTag)-1); n > 0; n-- {
+			if strings.HasSuffix(s.buf, thinkCloseTag[:n]) {
+				keep = n
+				break
+			}
+		}
+		reasoning, s.buf = s.buf[:len(s.buf)-keep], s.buf[len(s.buf)-keep:]
+		return reasoning, ""
+	default:
+		return "", s.trimContent(text)
+	}
+}
+
+func (s *thinkSplitter) trimContent(text string) string {
+	if !s.trimNext {
+		return text
+	}
+	text = strings.TrimLeft(text, "\r\n")
+	if text != "" {
+		s.trimNext = false
+	}
+	return text
+}
+
+// flush 输出暂存的内容：未出现标签时为正文，标签未闭合时为思考内容
+func (s *thinkSplitter) flush() (reasoning, content string) {
+	buf := s.buf
+	s.buf = ""
+	if s.state == thinkInside {
+		return buf, ""
+	}
+	return "", buf
+}
@@ -0,0 +1,293 @@
+package relay
+
+import (
+	"testing"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound"
+)
+
+func TestSplitThinkTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		reasoning string
+		content   string
+		ok        bool
+	}{
+		{name: "leading tag", text: "<think>\nplan\n</think>\n\nanswer", reasoning: "plan", content: "answer", ok: true},
+		{name: "leading whitespace", text: "\n <think>plan</think>answer", reasoning: "plan", content: "answer", ok: true},
+		{name: "unclosed tag", text: "<think>plan", reasoning: "plan", ok: true},
+		{name: "no tag", text: "answer", content: "answer"},
+		{name: "tag in the middle", text: "answer <think>x</think>", content: "answer <think>x</think>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasoning, content, ok := splitThinkTags(tt.text)
+			if reasoning != tt.reasoning || content != tt.content || ok != tt.ok {
+				t.Errorf("expected %q %q %v, got %q %q %v", tt.reasoning, tt.content, tt.ok, reasoning, content, ok)
+			}
+		})
+	}
+}
+
+func TestThinkSplitterChunks(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []string
+		reasoning string
+		content   string
+	}{
+		{name: "tags split across chunks", chunks: []string{"<thi", "nk>pl", "an</th", "ink>\n", "\nanswer"}, reasoning: "plan", content: "answer"},
+		{name: "partial close tag is plain text", chunks: []string{"<think>a</t", "b</think>c"}, reasoning: "a</tb", content: "c"},
+		{name: "not a tag", chunks: []string{"<th", "is is text"}, content: "<this is text"},
+		{name: "unclosed at end", chunks: []string{"<think>", "pla", "n</"}, reasoning: "plan</"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &thinkSplitter{}
+			var reasoning, content string
+			for _, chunk := range tt.chunks {
+				r, c := s.feed(chunk)
+				reasoning, content = reasoning+r, content+c
+			}
+			r, c := s.flush()
+			reasoning, content = reasoning+r, content+c
+			if reasoning != tt.reasoning || content != tt.content {
+				t.Errorf("expected %q %q, got %q %q", tt.reasoning, tt.content, reasoning, content)
+			}
+		})
+	}
+}
+
+func TestUntagSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		signature string
+		expected  *string
+	}{
+		{name: "untagged", signature: "raw", expected: strPtr("raw")},
+		{name: "same source", signature: "octopus:anthropic:abc", expected: strPtr("abc")},
+		{name: "signature with colons", signature: "octopus:anthropic:a:b", expected: strPtr("a:b")},
+		{name: "other source", signature: "octopus:openai:abc", expected: nil},
+		{name: "malformed", signature: "octopus:anthropic", expected: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := untagSignature(tt.signature, "anthropic")
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplyReasoningPolicy(t *testing.T) {
+	anthropic := &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic}
+	budgets := map[string]dbmodel.ReasoningBudget{"claude": {High: 3000}}
+	request := func() *model.InternalLLMRequest {
+		return &model.InternalLLMRequest{
+			Model:           "claude",
+			ReasoningEffort: "high",
+			Messages: []model.Message{
+				{Role: "user", Content: model.MessageContent{Content: strPtr("q1")}},
+				{Role: "assistant", Content: model.MessageContent{Content: strPtr("a1")}, ReasoningContent: strPtr("r1"), ReasoningSignature: strPtr("octopus:anthropic:sig1")},
+				{Role: "assistant", Content: model.MessageContent{Content: strPtr("a2")}, ReasoningContent: strPtr("r2"), ReasoningSignature: strPtr("octopus:openai:sig2")},
+				{Role: "assistant", Content: model.MessageContent{Content: strPtr("<think>r3</think>a3")}},
+			},
+		}
+	}
+	tests := []struct {
+		name       string
+		policy     *dbmodel.ReasoningPolicy
+		budget     *int64
+		signatures []string // 各 assistant 消息发往上游的签名，空字符串表示移除
+		contents   []string
+	}{
+		{
+			name:       "inactive",
+			policy:     &dbmodel.ReasoningPolicy{Budgets: budgets},
+			signatures: []string{"octopus:anthropic:sig1", "octopus:openai:sig2", ""},
+			contents:   []string{"a1", "a2", "<think>r3</think>a3"},
+		},
+		{
+			name:       "budget and signatures",
+			policy:     &dbmodel.ReasoningPolicy{Enabled: true, Budgets: budgets},
+			budget:     int64Ptr(3000),
+			signatures: []string{"sig1", "", ""},
+			contents:   []string{"a1", "a2", "<think>r3</think>a3"},
+		},
+		{
+			name:       "think tags restored",
+			policy:     &dbmodel.ReasoningPolicy{Enabled: true, Output: dbmodel.ReasoningOutputThinkTags},
+			signatures: []string{"sig1", "", ""},
+			contents:   []string{"a1", "a2", "a3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := request()
+			got := applyReasoningPolicy(original, tt.policy, anthropic)
+			if (got.ReasoningBudget == nil) != (tt.budget == nil) || (got.ReasoningBudget != nil && *got.ReasoningBudget != *tt.budget) {
+				t.Errorf("expected budget %v, got %v", tt.budget, got.ReasoningBudget)
+			}
+			for i, msg := range got.Messages[1:] {
+				signature := ""
+				if msg.ReasoningSignature != nil {
+					signature = *msg.ReasoningSignature
+				}
+				if signature != tt.signatures[i] || *msg.Content.Content != tt.contents[i] {
+					t.Errorf("message %d: expected %q %q, got %q %q", i+1, tt.signatures[i], tt.contents[i], signature, *msg.Content.Content)
+				}
+			}
+			// 原请求在切换渠道时仍会使用，不能被修改
+			if *original.Messages[1].ReasoningSignature != "octopus:anthropic:sig1" || original.ReasoningBudget != nil {
+				t.Fatalf("expected the original request to stay unchanged, got %+v", original)
+			}
+		})
+	}
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// reasoningMessage 返回 reasoning 与 content 组成的消息，空字符串表示不设置
+func reasoningMessage(reasoning, content, signature string) *model.Message {
+	msg := &model.Message{Role: "assistant"}
+	if reasoning != "" {
+		msg.ReasoningContent = &reasoning
+	}
+	if content != "" {
+		msg.Content.Content = &content
+	}
+	if signature != "" {
+		msg.ReasoningSignature = &signature
+	}
+	return msg
+}
+
+// messageText 返回消息的思考内容、正文与签名
+func messageText(msg *model.Message) (reasoning, content, signature string) {
+	reasoning = msg.GetReasoningContent()
+	if msg.Content.Content != nil {
+		content = *msg.Content.Content
+	}
+	for _, part := range msg.Content.MultipleContent {
+		if part.Text != nil {
+			content += *part.Text
+		}
+	}
+	if msg.ReasoningSignature != nil {
+		signature = *msg.ReasoningSignature
+	}
+	return reasoning, content, signature
+}
+
+func TestReasoningOutputResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    dbmodel.ReasoningOutput
+		message   *model.Message
+		reasoning string
+		content   string
+		signature string
+	}{
+		{name: "passthrough", message: reasoningMessage("r", "<think>t</think>a", "sig"), reasoning: "r", content: "<think>t</think>a", signature: "octopus:anthropic:sig"},
+		{name: "strip", output: dbmodel.ReasoningOutputStrip, message: reasoningMessage("r", "<think>t</think>a", "sig"), content: "a"},
+		{name: "reasoning content from tags", output: dbmodel.ReasoningOutputReasoningContent, message: reasoningMessage("r", "<think>t</think>a", "sig"), reasoning: "rt", content: "a", signature: "octopus:anthropic:sig"},
+		{name: "think tags", output: dbmodel.ReasoningOutputThinkTags, message: reasoningMessage("r", "a", "sig"), content: "<think>\nr\n</think>\n\na"},
+		{
+			name:    "think tags with content parts",
+			output:  dbmodel.ReasoningOutputThinkTags,
+			message: &model.Message{ReasoningContent: strPtr("r"), Content: model.MessageContent{MultipleContent: []model.MessageContentPart{{Type: "text", Text: strPtr("a")}}}},
+			content: "<think>\nr\n</think>\n\na",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newReasoningOutput(&dbmodel.ReasoningPolicy{Enabled: true, Output: tt.output}, &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic})
+			o.response(&model.InternalLLMResponse{Choices: []model.Choice{{Message: tt.message}}})
+			reasoning, content, signature := messageText(tt.message)
+			if reasoning != tt.reasoning || content != tt.content || signature != tt.signature {
+				t.Errorf("expected %q %q %q, got %q %q %q", tt.reasoning, tt.content, tt.signature, reasoning, content, signature)
+			}
+		})
+	}
+}
+
+func TestReasoningOutputStream(t *testing.T) {
+	type delta struct {
+		reasoning, content string
+		finish             bool
+	}
+	tests := []struct {
+		name      string
+		output    dbmodel.ReasoningOutput
+		deltas    []delta
+		reasoning string
+		content   string
+	}{
+		{
+			name:      "passthrough",
+			deltas:    []delta{{reasoning: "r"}, {content: "<think>x</think>"}, {finish: true}},
+			reasoning: "r",
+			content:   "<think>x</think>",
+		},
+		{
+			name:    "strip",
+			output:  dbmodel.ReasoningOutputStrip,
+			deltas:  []delta{{reasoning: "r"}, {content: "<think>"}, {content: "pl"}, {content: "an</think>\n"}, {content: "ans"}, {content: "wer"}, {finish: true}},
+			content: "answer",
+		},
+		{
+			name:      "reasoning content",
+			output:    dbmodel.ReasoningOutputReasoningContent,
+			deltas:    []delta{{content: "<think>"}, {content: "pl"}, {content: "an</think>\n"}, {content: "answer"}, {finish: true}},
+			reasoning: "plan",
+			content:   "answer",
+		},
+		{
+			name:      "reasoning content flushed at finish",
+			output:    dbmodel.ReasoningOutputReasoningContent,
+			deltas:    []delta{{content: "<think>pl"}, {content: "an</th"}, {finish: true}},
+			reasoning: "plan</th",
+		},
+		{
+			name:    "think tags",
+			output:  dbmodel.ReasoningOutputThinkTags,
+			deltas:  []delta{{reasoning: "a"}, {reasoning: "b"}, {content: "x"}, {content: "y"}, {finish: true}},
+			content: "<think>\nab\n</think>\n\nxy",
+		},
+		{
+			name:    "think tags closed at finish",
+			output:  dbmodel.ReasoningOutputThinkTags,
+			deltas:  []delta{{reasoning: "a"}, {finish: true}},
+			content: "<think>\na\n</think>\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newReasoningOutput(&dbmodel.ReasoningPolicy{Enabled: true, Output: tt.output}, &dbmodel.Channel{Type: outbound.OutboundTypeAnthropic})
+			var reasoning, content string
+			for _, d := range tt.deltas {
+				choice := model.Choice{}
+				if d.finish {
+					choice.FinishReason = strPtr("stop")
+				} else {
+					choice.Delta = reasoningMessage(d.reasoning, d.content, "")
+				}
+				chunk := &model.InternalLLMResponse{Choices: []model.Choice{choice}}
+				o.stream(chunk)
+				if msg := chunk.Choices[0].Delta; msg != nil {
+					r, c, _ := messageText(msg)
+					reasoning, content = reasoning+r, content+c
+				}
+			}
+			if reasoning != tt.reasoning || content != tt.content {
+				t.Errorf("expected %q %q, got %q %q", tt.reasoning, tt.content, reasoning, content)
+			}
+		})
+	}
+}
@@ -2,20 +2,26 @@ package relay
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"octopus/internal/conf"
 	"octopus/internal/helper"
+	dbmodel "octopus/internal/model"
 	"octopus/internal/op"
 	"octopus/internal/relay/balancer"
+	"octopus/internal/script"
 	"octopus/internal/server/resp"
 	"octopus/internal/transformer/inbound"
 	"octopus/internal/transformer/model"
 	"octopus/internal/transformer/outbound"
+	"octopus/internal/transformer/outbound/gemini"
 	"octopus/internal/utils/log"
 	"github.com/gin-gonic/gin"
 	"github.com/tmaxmax/go-sse"
@@ -23,6 +29,19 @@ import (
 
 // Handler 处理入站请求并转发到上游服务
 func Handler(inboundType inbound.InboundType, c *gin.Context) {
+	// 停机排空阶段不再接收新请求
+	if !acquire() {
+		c.Header("Retry-After", strconv.Itoa(drainRetryAfter))
+		resp.Error(c, http.StatusServiceUnavailable, "server is shutting down")
+		return
+	}
+	defer release()
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stop := context.AfterFunc(abortCtx, cancel)
+	defer stop()
+	c.Request = c.Request.WithContext(ctx)
+
 	// 解析请求
 	internalRequest, inAdapter, err := parseRequest(inboundType, c)
 	if err != nil {
@@ -42,12 +61,33 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 	metrics := NewRelayMetrics(internalRequest.Model)
 	metrics.SetInternalRequest(internalRequest)
 	metrics.SetAPIKeyID(apiKeyID)
-	// 获取通道分组
-	group, err := op.GroupGetMap(internalRequest.Model, c.Request.Context())
+	// 获取通道分组（支持模型别名）
+	group, alias, err := op.ModelAliasResolve(internalRequest.Model, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusNotFound, "model not found")
 		return
 	}
+	responseModel := ""
+	if alias != nil && alias.RewriteResponse {
+		responseModel = internalRequest.Model
+	}
+	// 内容安全检查，分组与 API Key 的策略同时生效
+	guard := newGuardrail(c, &group)
+	internalRequest, ok := checkRequest(c, guard, internalRequest, metrics)
+	if !ok {
+		return
+	}
+	// 分组脚本可以修改请求、限定渠道或拒绝请求
+	scriptCtx := dbmodel.ScriptContext{
+		Group:    group.Name,
+		Model:    internalRequest.Model,
+		APIKeyID: apiKeyID,
+		Stream:   internalRequest.Stream != nil && *internalRequest.Stream,
+	}
+	internalRequest, groupScript, ok := runGroupScript(c, &group, internalRequest, scriptCtx, metrics)
+	if !ok {
+		return
+	}
 
 	const maxRounds = 3
 	var lastErr error
@@ -110,29 +150,63 @@ func Handler(inboundType inbound.InboundType, c *gin.Context) {
 				continue
 			}
 
+			// 渠道脚本只影响本次尝试的请求
+			scripts := &scriptHooks{group: groupScript, ctx: scriptCtx}
+			scripts.ctx.Channel = channel.Name
+			attemptRequest := internalRequest
+			if scripts.channel, err = loadScript(channel.Script); err == nil {
+				attemptRequest, err = scripts.runChannelScript(c.Request.Context(), channel, internalRequest)
+			}
+			if err != nil {
+				var rejection *script.Rejection
+				if errors.As(err, &rejection) {
+					metrics.Save(c.Request.Context(), false, rejection)
+					resp.Error(c, rejection.Status, rejection.Message)
+					return
+				}
+				log.Warnf("script of channel %s failed: %v", channel.Name, err)
+				lastErr = fmt.Errorf("channel %s script failed: %w", channel.Name, err)
+				item = b.Next(group.Items, item)
+				continue
+			}
+
 			rc := &relayContext{
 				c:                    c,
 				inAdapter:            inAdapter,
 				outAdapter:           outAdapter,
-				internalRequest:      internalRequest,
+				internalRequest:      injectPromptCache(applyReasoningPolicy(attemptRequest, group.Reasoning, channel), promptCachePolicy(&group, channel)),
 				channel:              channel,
 				metrics:              metrics,
 				usedKey:              channel.GetChannelKey(),
 				firstTokenTimeOutSec: group.FirstTokenTimeOut,
+				responseModel:        responseModel,
+				reasoning:            newReasoningOutput(group.Reasoning, channel),
+				guard:                guard,
+				guardState:           guard.NewStream(),
+				scripts:              scripts,
 			}
+			rc.serverTools = newServerToolRun(c.Request.Context(), &group, rc.internalRequest)
 
 			if statusCode, err := rc.forward(); err == nil {
 				rc.collectResponse(c.Request.Context())
 				rc.usedKey.StatusCode = statusCode
 				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
-				rc.usedKey.TotalCost += metrics.Stats.InputCost + metrics.Stats.OutputCost
-				op.ChannelKeyUpdate(rc.usedKey)
+				op.ChannelKeyUpdate(rc.usedKey, metrics.Stats.InputCost+metrics.Stats.OutputCost)
 				metrics.Save(c.Request.Context(), true, nil)
 				return
+			} else if rejection := rc.scripts.rejected(); rejection != nil {
+				// 脚本拒绝了响应，不再尝试其他渠道
+				if c.Writer.Written() {
+					rc.collectResponse(c.Request.Context())
+				} else {
+					resp.Error(c, rejection.Status, rejection.Message)
+				}
+				metrics.Save(c.Request.Context(), false, rejection)
+				return
 			} else {
 				rc.usedKey.StatusCode = statusCode
 				rc.usedKey.LastUseTimeStamp = time.Now().Unix()
-				op.ChannelKeyUpdate(rc.usedKey)
+				op.ChannelKeyUpdate(rc.usedKey, 0)
 				if c.Writer.Written() {
 					// Streaming responses may have already started; retrying would corrupt the client stream.
 					rc.collectResponse(c.Request.Context())
@@ -176,10 +250,31 @@ func parseRequest(inboundType inbound.InboundType, c *gin.Context) (*model.Inter
 	return internalRequest, inAdapter, nil
 }
 
-// forward 转发请求到上游服务
+// forward 转发请求到上游服务，启用服务端工具时由网关执行工具循环
 func (rc *relayContext) forward() (int, error) {
+	if rc.serverTools != nil {
+		return rc.forwardServerTools()
+	}
+	return rc.forwardOnce()
+}
+
+// forwardOnce 发送一次上游请求并处理响应
+func (rc *relayContext) forwardOnce() (int, error) {
 	ctx := rc.c.Request.Context()
 
+	httpClient, err := helper.ChannelHttpClient(rc.channel)
+	if err != nil {
+		log.Warnf("failed to get http client: %v", err)
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	if rc.channel.Type == outbound.OutboundTypeGemini {
+		// 换取 access token 与创建上下文缓存的请求同样经过渠道的代理与录制
+		ctx = gemini.WithHTTPClient(ctx, httpClient)
+		if !conf.Get().Gemini.ContextCache {
+			ctx = gemini.WithoutContextCache(ctx)
+		}
+	}
+
 	// 构建出站请求
 	outboundRequest, err := rc.outAdapter.TransformRequest(
 		ctx,
@@ -196,7 +291,7 @@ func (rc *relayContext) forward() (int, error) {
 	rc.copyHeaders(outboundRequest)
 
 	// 发送请求
-	response, err := rc.sendRequest(outboundRequest)
+	response, err := rc.sendRequest(httpClient, outboundRequest)
 	if err != nil {
 		return 0, fmt.Errorf("failed to send request: %w", err)
 	}
@@ -208,7 +303,7 @@ func (rc *relayContext) forward() (int, error) {
 		if err != nil {
 			return 0, fmt.Errorf("failed to read response body: %w", err)
 		}
-		return 0, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))
+		return response.StatusCode, fmt.Errorf("upstream error: %d: %s", response.StatusCode, string(body))
 	}
 
 	// 处理响应
@@ -242,13 +337,7 @@ func (rc *relayContext) copyHeaders(outboundRequest *http.Request) {
 }
 
 // sendRequest 发送 HTTP 请求
-func (rc *relayContext) sendRequest(req *http.Request) (*http.Response, error) {
-	httpClient, err := helper.ChannelHttpClient(rc.channel)
-	if err != nil {
-		log.Warnf("failed to get http client: %v", err)
-		return nil, err
-	}
-
+func (rc *relayContext) sendRequest(httpClient *http.Client, req *http.Request) (*http.Response, error) {
 	response, err := httpClient.Do(req)
 	if err != nil {
 		log.Warnf("failed to send request: %v", err)
@@ -318,6 +407,10 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 		// 检查客户端是否断开
 		select {
 		case <-ctx.Done():
+			if abortCtx.Err() != nil {
+				log.Warnf("stream aborted by server shutdown")
+				return fmt.Errorf("stream aborted by server shutdown")
+			}
 			log.Infof("client disconnected, stopping stream")
 			return nil
 		case <-firstTokenC:
@@ -337,12 +430,17 @@ func (rc *relayContext) handleStreamResponse(ctx context.Context, response *http
 
 			// 转换流式数据
 			data, err := rc.transformStreamData(ctx, r.data)
+			if rejection := rc.scripts.rejected(); rejection != nil {
+				return rejection
+			}
 			if err != nil || len(data) == 0 {
 				continue
 			}
 			// 记录首个 Token 时间
 			if firstToken {
-				rc.metrics.SetFirstTokenTime(time.Now())
+				if rc.metrics.FirstTokenTime.IsZero() {
+					rc.metrics.SetFirstTokenTime(time.Now())
+				}
 				firstToken = false
 				// Disable the first-token timer once we have meaningful output.
 				if firstTokenTimer != nil {
@@ -374,6 +472,21 @@ func (rc *relayContext) transformStreamData(ctx context.Context, data string) ([
 	if internalStream == nil {
 		return nil, nil
 	}
+	if rc.responseModel != "" {
+		internalStream.Model = rc.responseModel
+	}
+	if rc.serverTools != nil {
+		if internalStream = rc.serverTools.intercept(internalStream); internalStream == nil {
+			return nil, nil
+		}
+	}
+	if internalStream, err = rc.scripts.response(ctx, script.HookChunk, internalStream); err != nil {
+		return nil, err
+	}
+	rc.reasoning.stream(internalStream)
+	if !rc.guardStream(internalStream, false) {
+		return nil, nil
+	}
 
 	// 内部格式 → 入站格式
 	inStream, err := rc.inAdapter.TransformStream(ctx, internalStream)
@@ -393,6 +506,19 @@ func (rc *relayContext) handleResponse(ctx context.Context, response *http.Respo
 		log.Warnf("failed to transform response: %v", err)
 		return fmt.Errorf("failed to transform outbound response: %w", err)
 	}
+	if rc.responseModel != "" {
+		internalResponse.Model = rc.responseModel
+	}
+	if rc.serverTools != nil {
+		// 是否返回给客户端由工具循环决定
+		rc.serverTools.capture(internalResponse)
+		return nil
+	}
+	if internalResponse, err = rc.scripts.response(ctx, script.HookResponse, internalResponse); err != nil {
+		return err
+	}
+	rc.reasoning.response(internalResponse)
+	rc.guardResponse(internalResponse)
 
 	// 内部格式 → 入站格式
 	inResponse, err := rc.inAdapter.TransformResponse(ctx, internalResponse)
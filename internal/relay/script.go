@@ -0,0 +1,148 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/script"
+	"octopus/internal/server/resp"
+	"octopus/internal/transformer/model"
+	"octopus/internal/utils/log"
+	"github.com/gin-gonic/gin"
+)
+
+// scriptHooks 一次转发中生效的分组与渠道脚本。请求先经过分组脚本再经过渠道脚本，响应的顺序相反。
+// 分组脚本的 Session 在整个请求中复用，渠道脚本的 Session 在每次尝试时创建
+type scriptHooks struct {
+	group   *script.Session
+	channel *script.Session
+	ctx     dbmodel.ScriptContext
+
+	// rejection: 响应钩子调用 reject() 时记录，由 Handler 返回给客户端，不再尝试其他渠道
+	rejection *script.Rejection
+}
+
+// loadScript 编译分组或渠道的脚本并返回本次请求使用的 Session，未配置时返回 nil
+func loadScript(src string) (*script.Session, error) {
+	if src == "" {
+		return nil, nil
+	}
+	prog, err := script.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return prog.NewSession(), nil
+}
+
+// runGroupScript 运行分组脚本的 on_request，返回修改后的请求与之后响应钩子复用的 Session，
+// route() 指定的渠道直接作用于 group。脚本拒绝或出错时直接响应客户端并记录日志
+func runGroupScript(c *gin.Context, group *dbmodel.Group, req *model.InternalLLMRequest, sctx dbmodel.ScriptContext, metrics *RelayMetrics) (*model.InternalLLMRequest, *script.Session, bool) {
+	session, err := loadScript(group.Script)
+	if err == nil && session.Has(script.HookRequest) {
+		var result *script.Result
+		var modified *model.InternalLLMRequest
+		modified, result, err = session.Request(c.Request.Context(), req, sctx)
+		logScript("group "+group.Name, script.HookRequest, result)
+		if err == nil {
+			req = modified
+			metrics.SetInternalRequest(req)
+			err = routeGroup(c.Request.Context(), group, result.Routes)
+		}
+	}
+	if err == nil {
+		return req, session, true
+	}
+
+	var rejection *script.Rejection
+	if errors.As(err, &rejection) {
+		metrics.Save(c.Request.Context(), false, rejection)
+		resp.Error(c, rejection.Status, rejection.Message)
+		return nil, nil, false
+	}
+	log.Warnf("script of group %s failed: %v", group.Name, err)
+	err = fmt.Errorf("group script failed: %w", err)
+	metrics.Save(c.Request.Context(), false, err)
+	resp.Error(c, http.StatusInternalServerError, err.Error())
+	return nil, nil, false
+}
+
+// routeGroup 只保留分组中属于 route() 指定渠道的成员，group 为当前请求的副本
+func routeGroup(ctx context.Context, group *dbmodel.Group, channels []string) error {
+	if len(channels) == 0 {
+		return nil
+	}
+	var items []dbmodel.GroupItem
+	for _, item := range group.Items {
+		channel, err := op.ChannelGet(item.ChannelID, ctx)
+		if err == nil && slices.Contains(channels, channel.Name) {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("route(%v) matches no channel in group %s", channels, group.Name)
+	}
+	group.Items = items
+	return nil
+}
+
+// runChannelScript 运行渠道脚本的 on_request，返回本次尝试使用的请求
+func (s *scriptHooks) runChannelScript(ctx context.Context, channel *dbmodel.Channel, req *model.InternalLLMRequest) (*model.InternalLLMRequest, error) {
+	if !s.channel.Has(script.HookRequest) {
+		return req, nil
+	}
+	modified, result, err := s.channel.Request(ctx, req, s.ctx)
+	logScript("channel "+channel.Name, script.HookRequest, result)
+	return modified, err
+}
+
+// response 依次运行渠道与分组脚本的 on_response 或 on_chunk。脚本出错时记录日志并使用原响应，
+// 脚本拒绝时返回错误
+func (s *scriptHooks) response(ctx context.Context, hook string, res *model.InternalLLMResponse) (*model.InternalLLMResponse, error) {
+	if s == nil || res.Object == "[DONE]" {
+		return res, nil
+	}
+	for _, hooked := range []struct {
+		name    string
+		session *script.Session
+	}{{"channel " + s.ctx.Channel, s.channel}, {"group " + s.ctx.Group, s.group}} {
+		if !hooked.session.Has(hook) {
+			continue
+		}
+		modified, result, err := hooked.session.Response(ctx, hook, res, s.ctx)
+		logScript(hooked.name, hook, result)
+		var rejection *script.Rejection
+		switch {
+		case errors.As(err, &rejection):
+			log.Warnf("script of %s rejected the response: %s", hooked.name, rejection.Message)
+			s.rejection = rejection
+			return nil, rejection
+		case err != nil:
+			log.Warnf("script of %s failed in %s: %v", hooked.name, hook, err)
+		default:
+			res = modified
+		}
+	}
+	return res, nil
+}
+
+func (s *scriptHooks) rejected() *script.Rejection {
+	if s == nil {
+		return nil
+	}
+	return s.rejection
+}
+
+// logScript 把脚本中 print() 的输出写入日志
+func logScript(name, hook string, result *script.Result) {
+	if result == nil {
+		return
+	}
+	for _, line := range result.Logs {
+		log.Infof("script of %s (%s): %s", name, hook, line)
+	}
+}
@@ -0,0 +1,507 @@
+package relay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"octopus/internal/conf"
+	"octopus/internal/mcp"
+	dbmodel "octopus/internal/model"
+	"octopus/internal/script"
+	"octopus/internal/servertool"
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound"
+	"octopus/internal/utils/log"
+)
+
+// defaultServerToolIterations 未配置 server_tools.max_iterations 时单个请求最多执行的工具轮数
+const defaultServerToolIterations = 5
+
+// serverToolRun 保存服务端工具循环的状态。
+// 每一轮请求上游后，若模型只调用了服务端工具，网关执行这些工具并把调用与结果追加到对话中继续下一轮；
+// 否则把本轮暂存的结尾（其余工具调用、结束原因与用量）交给客户端。
+// 流式请求中文本与思考内容实时转发，工具进度以思考内容的形式推送给客户端
+type serverToolRun struct {
+	tools   map[string]servertool.Tool
+	request *model.InternalLLMRequest // 累积了工具调用与结果的请求
+	stream  bool
+	usage   *model.Usage // 之前各轮用量之和
+	id      string       // 第一轮的响应 ID，后续各轮沿用，客户端看到的是同一个响应
+	model   string
+	started bool // 已向客户端转发过分片
+
+	// 当前轮的状态
+	held       []*model.InternalLLMResponse // 流式：暂存的工具调用、结束原因与用量
+	response   *model.InternalLLMResponse   // 非流式：本轮的响应
+	roundUsage *model.Usage
+	content    strings.Builder
+	reasoning  strings.Builder
+	signature  string
+	calls      []model.ToolCall
+	callSlots  map[int]int // 流式工具调用的 index → calls 下标
+}
+
+// newServerToolRun 分组启用了服务端工具或 MCP 服务器且为对话请求时返回循环状态，否则返回 nil。
+// 客户端声明的同名工具（包括 Responses 与 Anthropic 的内置 web_search）由网关的实现替代
+func newServerToolRun(ctx context.Context, group *dbmodel.Group, req *model.InternalLLMRequest) *serverToolRun {
+	if len(group.ServerTools) == 0 && len(group.MCPServers) == 0 || !req.IsChatRequest() {
+		return nil
+	}
+	tools := make(map[string]servertool.Tool, len(group.ServerTools))
+	definitions := make([]model.Tool, 0, len(group.ServerTools))
+	for _, name := range group.ServerTools {
+		tool, ok := servertool.Get(name)
+		if !ok {
+			log.Warnf("group %s: unknown server tool %s", group.Name, name)
+			continue
+		}
+		tools[name] = tool
+		definitions = append(definitions, tool.Definition())
+	}
+	// MCP 服务器不可用时跳过其工具，不影响请求本身
+	for _, server := range group.MCPServers {
+		mcpTools, err := mcp.Tools(ctx, server)
+		if err != nil {
+			log.Warnf("group %s: failed to list tools of mcp server %s: %v", group.Name, server, err)
+			continue
+		}
+		for _, tool := range mcpTools {
+			if _, ok := tools[tool.Name()]; ok {
+				continue
+			}
+			tools[tool.Name()] = tool
+			definitions = append(definitions, tool.Definition())
+		}
+	}
+	if len(tools) == 0 {
+		return nil
+	}
+
+	cp := *req
+	cp.Messages = slices.Clone(req.Messages)
+	cp.Tools = make([]model.Tool, 0, len(req.Tools)+len(definitions))
+	for _, tool := range req.Tools {
+		if _, ok := tools[tool.Function.Name]; ok {
+			continue
+		}
+		cp.Tools = append(cp.Tools, tool)
+	}
+	cp.Tools = append(cp.Tools, definitions...)
+	return &serverToolRun{
+		tools:   tools,
+		request: &cp,
+		stream:  req.Stream != nil && *req.Stream,
+	}
+}
+
+// forwardServerTools 循环请求上游并执行服务端工具，直到模型给出最终回答
+func (rc *relayContext) forwardServerTools() (int, error) {
+	run := rc.serverTools
+	ctx := rc.c.Request.Context()
+	maxIterations := conf.Get().ServerTools.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultServerToolIterations
+	}
+
+	for iteration := 0; ; iteration++ {
+		run.reset()
+		rc.reasoning.reset()
+		rc.internalRequest = run.request
+		statusCode, err := rc.forwardOnce()
+		if err != nil {
+			return statusCode, err
+		}
+
+		calls, ok := run.serverCalls()
+		if !ok {
+			return statusCode, rc.finishServerTools()
+		}
+		if iteration >= maxIterations {
+			log.Warnf("server tool iterations exceeded (%d), returning current answer", maxIterations)
+			return statusCode, rc.finishServerTools()
+		}
+
+		calls, index := run.appendToolCalls(calls)
+		for _, call := range calls {
+			tool := run.tools[call.Function.Name]
+			rc.emitProgress(ctx, tool.Progress(call.Function.Arguments)+"\n")
+			result, err := tool.Execute(ctx, call.Function.Arguments)
+			if err != nil {
+				log.Warnf("server tool %s failed: %v", call.Function.Name, err)
+				rc.emitProgress(ctx, fmt.Sprintf("%s failed: %v\n", call.Function.Name, err))
+				result = "Error: " + err.Error()
+			}
+			run.appendToolResult(index, call, result)
+		}
+		if ctx.Err() != nil {
+			return statusCode, nil
+		}
+
+		// 每一轮使用新的出站适配器，避免上一轮的流式状态影响后续转换；后续轮次不再适用首字超时
+		rc.outAdapter = outbound.Get(rc.channel.Type)
+		rc.firstTokenTimeOutSec = 0
+	}
+}
+
+// finishServerTools 把最后一轮的结果交给客户端，移除其中的服务端工具调用并汇总各轮用量
+func (rc *relayContext) finishServerTools() error {
+	run := rc.serverTools
+	ctx := rc.c.Request.Context()
+
+	if !run.stream {
+		resp := run.response
+		if resp == nil {
+			return fmt.Errorf("no response from upstream")
+		}
+		for i := range resp.Choices {
+			run.stripServerCalls(resp.Choices[i].Message, &resp.Choices[i])
+		}
+		resp.Usage = addUsage(run.usage, resp.Usage)
+		resp, err := rc.scripts.response(ctx, script.HookResponse, resp)
+		if err != nil {
+			return err
+		}
+		rc.reasoning.response(resp)
+		rc.guardResponse(resp)
+		inResponse, err := rc.inAdapter.TransformResponse(ctx, resp)
+		if err != nil {
+			log.Warnf("failed to transform response: %v", err)
+			return fmt.Errorf("failed to transform inbound response: %w", err)
+		}
+		rc.c.Data(http.StatusOK, "application/json", inResponse)
+		return nil
+	}
+
+	// 只在最后一个携带用量的分片上返回各轮用量之和，之前仅携带用量的分片不再发送
+	last := -1
+	for i, chunk := range run.held {
+		for j := range chunk.Choices {
+			run.stripServerCalls(chunk.Choices[j].Delta, &chunk.Choices[j])
+		}
+		if chunk.Usage != nil {
+			last = i
+		}
+	}
+	if last < 0 && run.usage != nil {
+		for i := len(run.held) - 1; i >= 0; i-- {
+			if run.held[i].Object != "[DONE]" {
+				last = i
+				break
+			}
+		}
+	}
+	for i, chunk := range run.held {
+		if i == last {
+			chunk.Usage = addUsage(run.usage, chunk.Usage)
+		} else if chunk.Usage != nil {
+			chunk.Usage = nil
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+		}
+		rc.writeStream(ctx, chunk)
+	}
+	if rejection := rc.scripts.rejected(); rejection != nil {
+		return rejection
+	}
+	return nil
+}
+
+// intercept 处理流式分片：文本与思考内容立即转发，工具调用、结束原因与用量暂存到本轮结束。
+// 返回需要立即转发给客户端的分片
+func (run *serverToolRun) intercept(chunk *model.InternalLLMResponse) *model.InternalLLMResponse {
+	if run.id == "" && chunk.ID != "" {
+		run.id, run.model = chunk.ID, chunk.Model
+	}
+	if chunk.Object == "[DONE]" {
+		run.held = append(run.held, chunk)
+		return nil
+	}
+	if run.id != "" {
+		chunk.ID = run.id
+	}
+	if chunk.Usage != nil {
+		run.roundUsage = chunk.Usage
+	}
+
+	forward := *chunk
+	forward.Usage = nil
+	forward.Choices = nil
+	tail := *chunk
+	tail.Choices = nil
+	hold := chunk.Usage != nil
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != nil || (choice.Delta != nil && len(choice.Delta.ToolCalls) > 0) {
+			hold = true
+			tailChoice := model.Choice{Index: choice.Index, Delta: &model.Message{}, FinishReason: choice.FinishReason}
+			if choice.Delta != nil {
+				tailChoice.Delta.ToolCalls = choice.Delta.ToolCalls
+				run.collectToolCalls(choice.Delta.ToolCalls)
+			}
+			tail.Choices = append(tail.Choices, tailChoice)
+		}
+		if choice.Delta == nil {
+			continue
+		}
+		delta := *choice.Delta
+		delta.ToolCalls = nil
+		run.collectMessage(&delta)
+		// 只有角色的分片只需要发送一次
+		if isEmptyDelta(&delta) && (delta.Role == "" || run.started) {
+			continue
+		}
+		forward.Choices = append(forward.Choices, model.Choice{Index: choice.Index, Delta: &delta, Logprobs: choice.Logprobs})
+	}
+	if hold {
+		run.held = append(run.held, &tail)
+	}
+	if len(forward.Choices) == 0 {
+		return nil
+	}
+	run.started = true
+	return &forward
+}
+
+// capture 记录非流式的响应
+func (run *serverToolRun) capture(resp *model.InternalLLMResponse) {
+	run.response = resp
+	run.roundUsage = resp.Usage
+	if run.id == "" {
+		run.id, run.model = resp.ID, resp.Model
+	} else {
+		resp.ID = run.id
+	}
+	if len(resp.Choices) > 0 && resp.Choices[0].Message != nil {
+		msg := resp.Choices[0].Message
+		run.collectMessage(msg)
+		run.calls = append(run.calls, msg.ToolCalls...)
+	}
+}
+
+func (run *serverToolRun) collectMessage(msg *model.Message) {
+	if msg.Content.Content != nil {
+		run.content.WriteString(*msg.Content.Content)
+	}
+	for _, part := range msg.Content.MultipleContent {
+		if part.Type == "text" && part.Text != nil {
+			run.content.WriteString(*part.Text)
+		}
+	}
+	run.reasoning.WriteString(msg.GetReasoningContent())
+	if msg.ReasoningSignature != nil {
+		run.signature += *msg.ReasoningSignature
+	}
+}
+
+// collectToolCalls 按 index 聚合工具调用，流式分片中的参数需要拼接
+func (run *serverToolRun) collectToolCalls(calls []model.ToolCall) {
+	for _, call := range calls {
+		slot, ok := run.callSlots[call.Index]
+		if ok && call.ID != "" && run.calls[slot].ID != "" && call.ID != run.calls[slot].ID {
+			ok = false
+		}
+		if !ok {
+			run.callSlots[call.Index] = len(run.calls)
+			run.calls = append(run.calls, call)
+			continue
+		}
+		existing := &run.calls[slot]
+		if call.ID != "" {
+			existing.ID = call.ID
+		}
+		if call.Function.Name != "" {
+			existing.Function.Name = call.Function.Name
+		}
+		existing.Function.Arguments += call.Function.Arguments
+	}
+}
+
+// serverCalls 本轮只调用了服务端工具时返回这些调用
+func (run *serverToolRun) serverCalls() ([]model.ToolCall, bool) {
+	if len(run.calls) == 0 {
+		return nil, false
+	}
+	for _, call := range run.calls {
+		if _, ok := run.tools[call.Function.Name]; !ok {
+			return nil, false
+		}
+	}
+	return run.calls, true
+}
+
+// appendToolCalls 将本轮的助手消息追加到对话中并累计本轮用量，返回补全了 ID 的工具调用与消息下标
+func (run *serverToolRun) appendToolCalls(calls []model.ToolCall) ([]model.ToolCall, int) {
+	index := len(run.request.Messages)
+	msg := model.Message{Role: "assistant", ToolCalls: make([]model.ToolCall, len(calls))}
+	for i, call := range calls {
+		if call.ID == "" {
+			call.ID = fmt.Sprintf("call_%s_%d_%d", call.Function.Name, index, i)
+		}
+		if call.Function.Arguments == "" {
+			call.Function.Arguments = "{}"
+		}
+		call.Type = "function"
+		call.Index = i
+		msg.ToolCalls[i] = call
+	}
+	if content := run.content.String(); content != "" {
+		msg.Content = model.MessageContent{Content: &content}
+	}
+	if reasoning := run.reasoning.String(); reasoning != "" {
+		msg.ReasoningContent = &reasoning
+		if run.signature != "" {
+			signature := run.signature
+			msg.ReasoningSignature = &signature
+		}
+	}
+	run.request.Messages = append(run.request.Messages, msg)
+	run.usage = addUsage(run.usage, run.roundUsage)
+	return msg.ToolCalls, index
+}
+
+// appendToolResult 追加工具结果。结果消息的 MessageIndex 指向助手消息的下标，
+// 转换为 Anthropic 格式时同一轮的结果合并为一条 user 消息；该下标不小于入站请求中的任何 MessageIndex
+func (run *serverToolRun) appendToolResult(index int, call model.ToolCall, result string) {
+	name := call.Function.Name
+	run.request.Messages = append(run.request.Messages, model.Message{
+		Role:         "tool",
+		Content:      model.MessageContent{Content: &result},
+		ToolCallID:   &call.ID,
+		ToolCallName: &name,
+		MessageIndex: &index,
+	})
+}
+
+// stripServerCalls 移除最终结果中的服务端工具调用，全部移除时结束原因改为 stop
+func (run *serverToolRun) stripServerCalls(msg *model.Message, choice *model.Choice) {
+	if msg == nil || len(msg.ToolCalls) == 0 {
+		if choice.FinishReason != nil && *choice.FinishReason == "tool_calls" && !run.hasClientCalls() {
+			stop := "stop"
+			choice.FinishReason = &stop
+		}
+		return
+	}
+	msg.ToolCalls = slices.DeleteFunc(slices.Clone(msg.ToolCalls), func(call model.ToolCall) bool {
+		_, ok := run.tools[call.Function.Name]
+		return ok
+	})
+	if len(msg.ToolCalls) == 0 {
+		msg.ToolCalls = nil
+		if !run.hasClientCalls() && choice.FinishReason != nil {
+			stop := "stop"
+			choice.FinishReason = &stop
+		}
+	}
+}
+
+func (run *serverToolRun) hasClientCalls() bool {
+	for _, call := range run.calls {
+		if _, ok := run.tools[call.Function.Name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (run *serverToolRun) reset() {
+	run.held = nil
+	run.response = nil
+	run.roundUsage = nil
+	run.content.Reset()
+	run.reasoning.Reset()
+	run.signature = ""
+	run.calls = nil
+	run.callSlots = make(map[int]int)
+}
+
+// emitProgress 以思考内容的形式向流式客户端推送工具进度
+func (rc *relayContext) emitProgress(ctx context.Context, text string) {
+	run := rc.serverTools
+	if !run.stream {
+		return
+	}
+	chunk := &model.InternalLLMResponse{
+		ID:      run.id,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   run.model,
+		Choices: []model.Choice{{
+			Index: 0,
+			Delta: &model.Message{Role: "assistant", ReasoningContent: &text},
+		}},
+	}
+	rc.reasoning.stream(chunk)
+	// 进度文本是完整的，连同内容安全检查暂存的文本一起输出
+	if rc.guardStream(chunk, true) {
+		rc.sendStream(ctx, chunk)
+	}
+}
+
+func (rc *relayContext) writeStream(ctx context.Context, chunk *model.InternalLLMResponse) {
+	if rc.scripts.rejected() != nil {
+		return
+	}
+	chunk, err := rc.scripts.response(ctx, script.HookChunk, chunk)
+	if err != nil {
+		return
+	}
+	rc.reasoning.stream(chunk)
+	if rc.guardStream(chunk, false) {
+		rc.sendStream(ctx, chunk)
+	}
+}
+
+func (rc *relayContext) sendStream(ctx context.Context, chunk *model.InternalLLMResponse) {
+	data, err := rc.inAdapter.TransformStream(ctx, chunk)
+	if err != nil {
+		log.Warnf("failed to transform stream: %v", err)
+		return
+	}
+	if len(data) == 0 {
+		return
+	}
+	rc.c.Writer.Write(data)
+	rc.c.Writer.Flush()
+}
+
+func isEmptyDelta(msg *model.Message) bool {
+	return msg.Content.Content == nil && len(msg.Content.MultipleContent) == 0 &&
+		msg.Refusal == "" && msg.GetReasoningContent() == "" && msg.ReasoningSignature == nil && len(msg.Images) == 0
+}
+
+// addUsage 累加两轮的用量，返回新的对象
+func addUsage(a, b *model.Usage) *model.Usage {
+	if a == nil && b == nil {
+		return nil
+	}
+	result := &model.Usage{}
+	for _, u := range []*model.Usage{a, b} {
+		if u == nil {
+			continue
+		}
+		result.PromptTokens += u.PromptTokens
+		result.CompletionTokens += u.CompletionTokens
+		result.TotalTokens += u.TotalTokens
+		result.CacheCreationInputTokens += u.CacheCreationInputTokens
+		result.AnthropicUsage = result.AnthropicUsage || u.AnthropicUsage
+		if u.PromptTokensDetails != nil {
+			if result.PromptTokensDetails == nil {
+				result.PromptTokensDetails = &model.PromptTokensDetails{}
+			}
+			result.PromptTokensDetails.CachedTokens += u.PromptTokensDetails.CachedTokens
+			result.PromptTokensDetails.AudioTokens += u.PromptTokensDetails.AudioTokens
+		}
+		if u.CompletionTokensDetails != nil {
+			if result.CompletionTokensDetails == nil {
+				result.CompletionTokensDetails = &model.CompletionTokensDetails{}
+			}
+			result.CompletionTokensDetails.ReasoningTokens += u.CompletionTokensDetails.ReasoningTokens
+			result.CompletionTokensDetails.AudioTokens += u.CompletionTokensDetails.AudioTokens
+		}
+	}
+	return result
+}
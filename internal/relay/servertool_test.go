@@ -0,0 +1,216 @@
+package relay
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/servertool"
+	"octopus/internal/transformer/model"
+)
+
+func TestNewServerToolRun(t *testing.T) {
+	chat := func() *model.InternalLLMRequest {
+		return &model.InternalLLMRequest{
+			Model:    "m",
+			Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("hi")}}},
+			Tools: []model.Tool{
+				{Type: "function", Function: model.Function{Name: "lookup"}},
+				{Type: "function", Function: model.Function{Name: "web_search"}},
+			},
+		}
+	}
+	tests := []struct {
+		name     string
+		group    *dbmodel.Group
+		request  func() *model.InternalLLMRequest
+		expected []string // 发往上游的工具名称，nil 表示不启用
+	}{
+		{name: "no server tools", group: &dbmodel.Group{}, request: chat, expected: nil},
+		{
+			name:  "not a chat request",
+			group: &dbmodel.Group{ServerTools: []string{"web_search"}},
+			request: func() *model.InternalLLMRequest {
+				return &model.InternalLLMRequest{Model: "m", EmbeddingInput: &model.EmbeddingInput{Single: strPtr("x")}}
+			},
+			expected: nil,
+		},
+		{name: "unknown tools only", group: &dbmodel.Group{ServerTools: []string{"shell"}}, request: chat, expected: nil},
+		{name: "client tool replaced", group: &dbmodel.Group{ServerTools: []string{"web_search", "web_fetch"}}, request: chat, expected: []string{"lookup", "web_search", "web_fetch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.request()
+			run := newServerToolRun(context.Background(), tt.group, req)
+			if (run == nil) != (tt.expected == nil) {
+				t.Fatalf("expected enabled %v, got %v", tt.expected != nil, run != nil)
+			}
+			if run == nil {
+				return
+			}
+			var names []string
+			for _, tool := range run.request.Tools {
+				names = append(names, tool.Function.Name)
+			}
+			if len(names) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, names)
+			}
+			for i := range names {
+				if names[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, names)
+				}
+			}
+			if len(req.Tools) != 2 {
+				t.Fatalf("expected the original request to stay unchanged, got %+v", req.Tools)
+			}
+		})
+	}
+}
+
+// serverTools 按名称返回已注册的服务端工具
+func serverTools(names ...string) map[string]servertool.Tool {
+	tools := make(map[string]servertool.Tool, len(names))
+	for _, name := range names {
+		tools[name], _ = servertool.Get(name)
+	}
+	return tools
+}
+
+// toolCallChunk 返回包含一个工具调用分片的流式响应
+func toolCallChunk(index int, id, name, arguments string) *model.InternalLLMResponse {
+	return &model.InternalLLMResponse{ID: "chunk", Choices: []model.Choice{{Delta: &model.Message{ToolCalls: []model.ToolCall{{
+		Index: index, ID: id, Type: "function", Function: model.FunctionCall{Name: name, Arguments: arguments},
+	}}}}}}
+}
+
+func TestServerToolRunIntercept(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunks    []*model.InternalLLMResponse
+		forwarded int
+		content   string
+		calls     []model.ToolCall
+		server    bool
+	}{
+		{
+			name: "text is forwarded",
+			chunks: []*model.InternalLLMResponse{
+				{ID: "resp_1", Choices: []model.Choice{{Delta: &model.Message{Role: "assistant"}}}},
+				{ID: "resp_1", Choices: []model.Choice{{Delta: &model.Message{Content: model.MessageContent{Content: strPtr("hello")}}}}},
+				{ID: "resp_1", Choices: []model.Choice{{FinishReason: strPtr("stop")}}, Usage: &model.Usage{TotalTokens: 3}},
+			},
+			forwarded: 2,
+			content:   "hello",
+		},
+		{
+			name: "server tool call is held",
+			chunks: []*model.InternalLLMResponse{
+				toolCallChunk(0, "call_1", "web_search", `{"query":`),
+				toolCallChunk(0, "", "", `"go"}`),
+				{Choices: []model.Choice{{FinishReason: strPtr("tool_calls")}}},
+			},
+			calls:  []model.ToolCall{{Index: 0, ID: "call_1", Type: "function", Function: model.FunctionCall{Name: "web_search", Arguments: `{"query":"go"}`}}},
+			server: true,
+		},
+		{
+			name: "client tool call",
+			chunks: []*model.InternalLLMResponse{
+				toolCallChunk(0, "call_1", "web_search", `{}`),
+				toolCallChunk(1, "call_2", "lookup", `{}`),
+			},
+			calls: []model.ToolCall{
+				{Index: 0, ID: "call_1", Type: "function", Function: model.FunctionCall{Name: "web_search", Arguments: `{}`}},
+				{Index: 1, ID: "call_2", Type: "function", Function: model.FunctionCall{Name: "lookup", Arguments: `{}`}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &serverToolRun{tools: serverTools("web_search"), stream: true}
+			run.reset()
+			forwarded := 0
+			for _, chunk := range tt.chunks {
+				if out := run.intercept(chunk); out != nil {
+					forwarded++
+					for _, choice := range out.Choices {
+						if len(choice.Delta.ToolCalls) > 0 || choice.FinishReason != nil {
+							t.Fatalf("expected tool calls and finish reasons to be held, got %+v", choice)
+						}
+					}
+				}
+			}
+			if forwarded != tt.forwarded || run.content.String() != tt.content {
+				t.Errorf("expected %d forwarded chunks with %q, got %d with %q", tt.forwarded, tt.content, forwarded, run.content.String())
+			}
+			if len(run.calls) != len(tt.calls) {
+				t.Fatalf("expected %+v, got %+v", tt.calls, run.calls)
+			}
+			for i := range run.calls {
+				if run.calls[i] != tt.calls[i] {
+					t.Fatalf("expected %+v, got %+v", tt.calls, run.calls)
+				}
+			}
+			if _, ok := run.serverCalls(); ok != tt.server {
+				t.Errorf("expected server calls %v, got %v", tt.server, ok)
+			}
+		})
+	}
+}
+
+func TestStripServerCalls(t *testing.T) {
+	search := model.ToolCall{ID: "call_1", Function: model.FunctionCall{Name: "web_search"}}
+	lookup := model.ToolCall{ID: "call_2", Function: model.FunctionCall{Name: "lookup"}}
+	tests := []struct {
+		name      string
+		message   *model.Message
+		calls     []model.ToolCall // 本轮收集的工具调用
+		finish    string
+		remaining int
+		expected  string
+	}{
+		{name: "only server calls", message: &model.Message{ToolCalls: []model.ToolCall{search}}, calls: []model.ToolCall{search}, finish: "tool_calls", expected: "stop"},
+		{name: "client call kept", message: &model.Message{ToolCalls: []model.ToolCall{search, lookup}}, calls: []model.ToolCall{search, lookup}, finish: "tool_calls", remaining: 1, expected: "tool_calls"},
+		{name: "stream finish without calls", message: nil, calls: []model.ToolCall{search}, finish: "tool_calls", expected: "stop"},
+		{name: "stream finish with client call", message: nil, calls: []model.ToolCall{lookup}, finish: "tool_calls", expected: "tool_calls"},
+		{name: "normal stop", message: &model.Message{}, finish: "stop", expected: "stop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := &serverToolRun{tools: serverTools("web_search"), calls: tt.calls}
+			choice := &model.Choice{FinishReason: strPtr(tt.finish)}
+			run.stripServerCalls(tt.message, choice)
+			if *choice.FinishReason != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, *choice.FinishReason)
+			}
+			if tt.message != nil && len(tt.message.ToolCalls) != tt.remaining {
+				t.Errorf("expected %d tool calls, got %+v", tt.remaining, tt.message.ToolCalls)
+			}
+		})
+	}
+}
+
+func TestAddUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     *model.Usage
+		expected *model.Usage
+	}{
+		{name: "both nil", expected: nil},
+		{name: "first round", b: &model.Usage{PromptTokens: 3, TotalTokens: 3}, expected: &model.Usage{PromptTokens: 3, TotalTokens: 3}},
+		{
+			name:     "sum",
+			a:        &model.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4},
+			b:        &model.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7, CacheCreationInputTokens: 2, AnthropicUsage: true},
+			expected: &model.Usage{PromptTokens: 8, CompletionTokens: 3, TotalTokens: 11, CacheCreationInputTokens: 2, AnthropicUsage: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := addUsage(tt.a, tt.b)
+			if (got == nil) != (tt.expected == nil) || (got != nil && !reflect.DeepEqual(got, tt.expected)) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"octopus/internal/conf"
+	"octopus/internal/guardrail"
 	dbmodel "octopus/internal/model"
 	"octopus/internal/transformer/model"
 	"github.com/gin-gonic/gin"
@@ -56,4 +57,20 @@ type relayContext struct {
 	// firstTokenTimeOutSec: streaming-only "time to first token" timeout for the selected group/channel.
 	// When >0 and stream doesn't produce any transformed output within this duration, we abort and retry next channel.
 	firstTokenTimeOutSec int
+
+	// responseModel: 命中改写响应的模型别名时，返回给客户端的模型名称
+	responseModel string
+
+	// reasoning: 分组启用思考策略时转换返回给客户端的思考内容
+	reasoning *reasoningOutput
+
+	// guard: 分组或 API Key 启用内容安全策略时检查响应内容，guardState 为流式响应的滑动窗口
+	guard      *guardrail.Checker
+	guardState *guardrail.Stream
+
+	// serverTools: 分组启用服务端工具时的工具循环状态
+	serverTools *serverToolRun
+
+	// scripts: 分组与渠道脚本，修改或拒绝响应
+	scripts *scriptHooks
 }
@@ -0,0 +1,100 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+)
+
+// 钩子以 JSON 形式接收内部格式的请求与响应，内部格式中不序列化的辅助字段（原始请求、缓存标记等）
+// 在钩子返回后从原对象恢复：未被修改的消息与工具整体沿用原对象
+
+// Request 调用 on_request，返回修改后的新请求，原请求不变。脚本未定义该钩子时返回原请求
+func (s *Session) Request(ctx context.Context, req *model.InternalLLMRequest, sctx dbmodel.ScriptContext) (*model.InternalLLMRequest, *Result, error) {
+	if !s.Has(HookRequest) {
+		return req, &Result{}, nil
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, &Result{}, err
+	}
+	output, result, err := s.call(ctx, HookRequest, input, sctx)
+	if err != nil {
+		return nil, result, err
+	}
+	var out model.InternalLLMRequest
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, result, fmt.Errorf("%s returned an invalid request: %w", HookRequest, err)
+	}
+	if err := out.Validate(); err != nil {
+		return nil, result, fmt.Errorf("%s returned an invalid request: %w", HookRequest, err)
+	}
+	restoreRequest(&out, req)
+	return &out, result, nil
+}
+
+// Response 调用 on_response 或 on_chunk，返回修改后的新响应。脚本未定义该钩子时返回原响应
+func (s *Session) Response(ctx context.Context, hook string, resp *model.InternalLLMResponse, sctx dbmodel.ScriptContext) (*model.InternalLLMResponse, *Result, error) {
+	if !s.Has(hook) {
+		return resp, &Result{}, nil
+	}
+	input, err := json.Marshal(resp)
+	if err != nil {
+		return nil, &Result{}, err
+	}
+	output, result, err := s.call(ctx, hook, input, sctx)
+	if err != nil {
+		return nil, result, err
+	}
+	var out model.InternalLLMResponse
+	if err := json.Unmarshal(output, &out); err != nil {
+		return nil, result, fmt.Errorf("%s returned an invalid response: %w", hook, err)
+	}
+	if out.Usage != nil && resp.Usage != nil {
+		out.Usage.PromptModalityTokenDetails = resp.Usage.PromptModalityTokenDetails
+		out.Usage.CompletionModalityTokenDetails = resp.Usage.CompletionModalityTokenDetails
+		out.Usage.AnthropicUsage = resp.Usage.AnthropicUsage
+		out.Usage.CacheCreationInputTokens = resp.Usage.CacheCreationInputTokens
+	}
+	if out.Error != nil && resp.Error != nil {
+		out.Error.StatusCode = resp.Error.StatusCode
+	}
+	return &out, result, nil
+}
+
+func restoreRequest(out, orig *model.InternalLLMRequest) {
+	out.ReasoningBudget = orig.ReasoningBudget
+	out.RawRequest = orig.RawRequest
+	out.RawAPIFormat = orig.RawAPIFormat
+	out.TransformerMetadata = orig.TransformerMetadata
+	out.Include = orig.Include
+	out.Query = orig.Query
+	restoreUnchanged(out.Messages, orig.Messages)
+	restoreUnchanged(out.Tools, orig.Tools)
+}
+
+// restoreUnchanged 把序列化结果与原对象相同的元素替换为原对象，保留其中不序列化的字段
+func restoreUnchanged[T any](out, orig []T) {
+	if len(out) == 0 || len(orig) == 0 {
+		return
+	}
+	unchanged := make(map[string][]int, len(orig))
+	for i := range orig {
+		if data, err := json.Marshal(orig[i]); err == nil {
+			unchanged[string(data)] = append(unchanged[string(data)], i)
+		}
+	}
+	for i := range out {
+		data, err := json.Marshal(out[i])
+		if err != nil {
+			continue
+		}
+		if indexes := unchanged[string(data)]; len(indexes) > 0 {
+			out[i] = orig[indexes[0]]
+			unchanged[string(data)] = indexes[1:]
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	dbmodel "octopus/internal/model"
+	"octopus/internal/transformer/model"
+)
+
+func strPtr(s string) *string { return &s }
+
+func newSession(t *testing.T, src string) *Session {
+	t.Helper()
+	loadConfig(t, `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 1048576}}`)
+	prog, err := Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prog.NewSession()
+}
+
+func TestRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		sctx       dbmodel.ScriptContext
+		model      string
+		routes     []string
+		wantErr    string
+		wantReject *Rejection
+	}{
+		{
+			name:  "modify model",
+			src:   "def on_request(req, ctx):\n    req[\"model\"] = ctx[\"group\"] + \"-mini\"\n",
+			sctx:  dbmodel.ScriptContext{Group: "gpt-4o"},
+			model: "gpt-4o-mini",
+		},
+		{
+			name:  "return new dict",
+			src:   "def on_request(req, ctx):\n    return dict(req, model=\"other\")\n",
+			model: "other",
+		},
+		{
+			name:   "route",
+			src:    "def on_request(req, ctx):\n    route(\"a\", \"b\")\n",
+			model:  "gpt-4o",
+			routes: []string{"a", "b"},
+		},
+		{
+			name:       "reject",
+			src:        "def on_request(req, ctx):\n    reject(\"blocked\", status=403)\n",
+			wantReject: &Rejection{Status: 403, Message: "blocked"},
+		},
+		{
+			name:    "reject invalid status",
+			src:     "def on_request(req, ctx):\n    reject(\"blocked\", status=200)\n",
+			wantErr: "status must be between 400 and 599",
+		},
+		{
+			name:    "invalid request",
+			src:     "def on_request(req, ctx):\n    req.pop(\"messages\")\n",
+			wantErr: "on_request returned an invalid request",
+		},
+		{
+			name:  "no hook",
+			src:   "def on_response(resp, ctx):\n    pass\n",
+			model: "gpt-4o",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newSession(t, tt.src)
+			req := &model.InternalLLMRequest{
+				Model:      "gpt-4o",
+				Messages:   []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("hi")}, CacheControl: &model.CacheControl{Type: "ephemeral"}}},
+				RawRequest: []byte(`{"raw":true}`),
+			}
+			out, result, err := session.Request(context.Background(), req, tt.sctx)
+			if tt.wantReject != nil {
+				var rejection *Rejection
+				if !errors.As(err, &rejection) {
+					t.Fatalf("expected rejection, got %v", err)
+				}
+				if *rejection != *tt.wantReject {
+					t.Fatalf("expected %+v, got %+v", tt.wantReject, rejection)
+				}
+				return
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Model != tt.model {
+				t.Errorf("expected model %q, got %q", tt.model, out.Model)
+			}
+			if strings.Join(result.Routes, ",") != strings.Join(tt.routes, ",") {
+				t.Errorf("expected routes %v, got %v", tt.routes, result.Routes)
+			}
+			if req.Model != "gpt-4o" {
+				t.Errorf("expected original request unchanged, got model %q", req.Model)
+			}
+			// 不序列化的字段从原请求恢复
+			if string(out.RawRequest) != `{"raw":true}` {
+				t.Errorf("expected raw request restored, got %s", out.RawRequest)
+			}
+			if out.Messages[0].CacheControl == nil {
+				t.Error("expected cache control of the unchanged message restored")
+			}
+		})
+	}
+}
+
+func TestResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		hook     string
+		id       string
+		expected string
+		wantErr  string
+	}{
+		{
+			name:     "on_response",
+			src:      "def on_response(resp, ctx):\n    resp[\"id\"] = \"resp-\" + resp[\"id\"]\n",
+			hook:     HookResponse,
+			id:       "1",
+			expected: "resp-1",
+		},
+		{
+			name:     "on_chunk",
+			src:      "def on_chunk(chunk, ctx):\n    if ctx[\"stream\"]:\n        chunk[\"id\"] = \"chunk-\" + chunk[\"id\"]\n",
+			hook:     HookChunk,
+			id:       "2",
+			expected: "chunk-2",
+		},
+		{
+			name:     "hook not defined",
+			src:      "def on_request(req, ctx):\n    pass\n",
+			hook:     HookChunk,
+			id:       "3",
+			expected: "3",
+		},
+		{
+			name:    "output too large",
+			src:     "def on_response(resp, ctx):\n    resp[\"id\"] = \"x\" * 2000000\n",
+			hook:    HookResponse,
+			id:      "4",
+			wantErr: "more than script.max_output_bytes",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newSession(t, tt.src)
+			resp := &model.InternalLLMResponse{
+				ID:    tt.id,
+				Usage: &model.Usage{PromptTokens: 10, AnthropicUsage: true, CacheCreationInputTokens: 5},
+			}
+			out, _, err := session.Response(context.Background(), tt.hook, resp, dbmodel.ScriptContext{Stream: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.ID != tt.expected {
+				t.Errorf("expected id %q, got %q", tt.expected, out.ID)
+			}
+			// 不序列化的用量字段从原响应恢复
+			if out.Usage == nil || out.Usage.PromptTokens != 10 || !out.Usage.AnthropicUsage || out.Usage.CacheCreationInputTokens != 5 {
+				t.Errorf("expected usage restored, got %+v", out.Usage)
+			}
+		})
+	}
+}
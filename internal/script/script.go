@@ -0,0 +1,323 @@
+package script
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"octopus/internal/conf"
+	dbmodel "octopus/internal/model"
+
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// 分组与渠道可以配置 Starlark 脚本，在转发过程中修改请求与响应、限定渠道或拒绝请求。
+// 脚本运行在沙箱中，不能访问文件与网络；顶层代码每个请求执行一次，执行后全局变量被冻结，调用之间不共享可变状态。
+// 单次调用受执行步数、超时时间与输出大小限制
+
+const (
+	HookRequest  = "on_request"
+	HookResponse = "on_response"
+	HookChunk    = "on_chunk"
+)
+
+var hooks = []string{HookRequest, HookResponse, HookChunk}
+
+// 配置未加载时使用的执行限制，与配置的默认值相同
+const (
+	defaultMaxSteps       = 1000000
+	defaultTimeoutMs      = 100
+	defaultMaxOutputBytes = 8 << 20
+)
+
+// programCacheSize 编译结果缓存的数量上限。试运行接口可以提交任意脚本，缓存按最近使用淘汰
+const programCacheSize = 256
+
+var fileOptions = &syntax.FileOptions{Set: true, While: true, TopLevelControl: true}
+
+// predeclared 脚本可以使用的内置函数与模块
+var predeclared = starlark.StringDict{
+	"reject": starlark.NewBuiltin("reject", reject),
+	"route":  starlark.NewBuiltin("route", route),
+	"json":   json.Module,
+}
+
+func init() {
+	predeclared.Freeze()
+}
+
+// Rejection 脚本调用 reject() 拒绝请求
+type Rejection struct {
+	Status  int
+	Message string
+}
+
+func (r *Rejection) Error() string {
+	return fmt.Sprintf("rejected by script: %s", r.Message)
+}
+
+// Program 编译后的脚本
+type Program struct {
+	prog  *starlark.Program
+	hooks map[string]bool
+}
+
+// programCache 按源码缓存最近使用的编译结果
+type programCache struct {
+	mu      sync.Mutex
+	order   *list.List // 最近使用的在前，元素为 *cachedProgram
+	entries map[string]*list.Element
+}
+
+type cachedProgram struct {
+	src  string
+	prog *Program
+}
+
+var programs = &programCache{order: list.New(), entries: make(map[string]*list.Element)}
+
+func (c *programCache) get(src string) (*Program, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[src]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*cachedProgram).prog, true
+}
+
+func (c *programCache) add(src string, p *Program) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[src]; ok {
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[src] = c.order.PushFront(&cachedProgram{src: src, prog: p})
+	for c.order.Len() > programCacheSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedProgram).src)
+	}
+}
+
+// Compile 编译脚本，脚本需要至少定义一个钩子函数。最近使用的脚本只编译一次
+func Compile(src string) (*Program, error) {
+	if p, ok := programs.get(src); ok {
+		return p, nil
+	}
+	f, prog, err := starlark.SourceProgramOptions(fileOptions, "script.star", src, predeclared.Has)
+	if err != nil {
+		return nil, fmt.Errorf("invalid script: %w", err)
+	}
+	p := &Program{prog: prog, hooks: make(map[string]bool)}
+	for _, stmt := range f.Stmts {
+		switch stmt := stmt.(type) {
+		case *syntax.DefStmt:
+			p.hooks[stmt.Name.Name] = true
+		case *syntax.LoadStmt:
+			return nil, fmt.Errorf("invalid script: load is not supported")
+		}
+	}
+	defined := false
+	for _, hook := range hooks {
+		defined = defined || p.hooks[hook]
+	}
+	if !defined {
+		return nil, fmt.Errorf("invalid script: define at least one of %v", hooks)
+	}
+	programs.add(src, p)
+	return p, nil
+}
+
+// Validate 校验分组或渠道配置的脚本，空脚本表示不使用
+func Validate(src string) error {
+	if src == "" {
+		return nil
+	}
+	_, err := Compile(src)
+	return err
+}
+
+// Has 脚本是否定义了钩子函数
+func (p *Program) Has(hook string) bool {
+	return p != nil && p.hooks[hook]
+}
+
+// Session 一次请求中使用的脚本。顶层代码在第一次调用时执行，全局变量随后冻结并由之后的调用复用，
+// 流式响应的每个分片不再重新执行顶层代码；执行限制取创建时的配置。Session 不能并发使用
+type Session struct {
+	prog    *Program
+	limits  conf.Script
+	globals starlark.StringDict
+}
+
+// NewSession 返回脚本在一次请求中的执行状态，p 为 nil 时返回 nil
+func (p *Program) NewSession() *Session {
+	if p == nil {
+		return nil
+	}
+	limits := conf.Get().Script
+	if limits.MaxSteps <= 0 {
+		limits.MaxSteps = defaultMaxSteps
+	}
+	if limits.TimeoutMs <= 0 {
+		limits.TimeoutMs = defaultTimeoutMs
+	}
+	if limits.MaxOutputBytes <= 0 {
+		limits.MaxOutputBytes = defaultMaxOutputBytes
+	}
+	return &Session{prog: p, limits: limits}
+}
+
+// Has 脚本是否定义了钩子函数
+func (s *Session) Has(hook string) bool {
+	return s != nil && s.prog.Has(hook)
+}
+
+// Result 一次钩子调用的附加结果
+type Result struct {
+	Routes []string // route() 限定的渠道名称
+	Logs   []string // print() 的输出
+	Steps  uint64
+}
+
+const stateKey = "octopus.state"
+
+type callState struct {
+	rejection      *Rejection
+	routes         []string
+	printTruncated bool
+}
+
+// call 以 JSON 调用钩子，返回钩子处理后的 JSON。钩子返回 None 时使用原地修改后的参数。
+// 返回值与 print() 的输出不能超过 max_output_bytes，单次操作构造的大对象无法在执行中限制，只在返回时检查
+func (s *Session) call(ctx context.Context, hook string, input []byte, sctx dbmodel.ScriptContext) ([]byte, *Result, error) {
+	result := &Result{}
+	state := &callState{}
+	printed := int64(0)
+	thread := &starlark.Thread{
+		Name: hook,
+		Print: func(_ *starlark.Thread, msg string) {
+			if printed += int64(len(msg)); printed > s.limits.MaxOutputBytes {
+				state.printTruncated = true
+				return
+			}
+			result.Logs = append(result.Logs, msg)
+		},
+	}
+	thread.SetLocal(stateKey, state)
+	thread.SetMaxExecutionSteps(uint64(s.limits.MaxSteps))
+	timer := time.AfterFunc(time.Duration(s.limits.TimeoutMs)*time.Millisecond, func() { thread.Cancel("timeout") })
+	defer timer.Stop()
+	stop := context.AfterFunc(ctx, func() { thread.Cancel("request canceled") })
+	defer stop()
+	defer func() {
+		result.Steps = thread.ExecutionSteps()
+		if state.printTruncated {
+			result.Logs = append(result.Logs, "...(print output truncated)")
+		}
+	}()
+
+	if s.globals == nil {
+		globals, err := s.prog.prog.Init(thread, predeclared)
+		if err != nil {
+			return nil, result, s.prog.wrap(err, state)
+		}
+		globals.Freeze()
+		s.globals = globals
+	}
+	fn, ok := s.globals[hook].(starlark.Callable)
+	if !ok {
+		return input, result, nil
+	}
+	arg, err := starlark.Call(thread, json.Module.Members["decode"], starlark.Tuple{starlark.String(input)}, nil)
+	if err != nil {
+		return nil, result, err
+	}
+	ret, err := starlark.Call(thread, fn, starlark.Tuple{arg, contextValue(sctx)}, nil)
+	if err != nil {
+		return nil, result, s.prog.wrap(err, state)
+	}
+	result.Routes = state.routes
+	if ret == starlark.None {
+		ret = arg
+	}
+	if _, ok := ret.(*starlark.Dict); !ok {
+		return nil, result, fmt.Errorf("%s must return a dict or None, got %s", hook, ret.Type())
+	}
+	out, err := starlark.Call(thread, json.Module.Members["encode"], starlark.Tuple{ret}, nil)
+	if err != nil {
+		return nil, result, s.prog.wrap(err, state)
+	}
+	encoded := string(out.(starlark.String))
+	if int64(len(encoded)) > s.limits.MaxOutputBytes {
+		return nil, result, fmt.Errorf("%s returned %d bytes, more than script.max_output_bytes (%d)", hook, len(encoded), s.limits.MaxOutputBytes)
+	}
+	return []byte(encoded), result, nil
+}
+
+// wrap 区分 reject() 与脚本错误，脚本错误附带调用栈
+func (p *Program) wrap(err error, state *callState) error {
+	if state.rejection != nil {
+		return state.rejection
+	}
+	var evalErr *starlark.EvalError
+	if errors.As(err, &evalErr) {
+		return errors.New(evalErr.Backtrace())
+	}
+	return err
+}
+
+func contextValue(sctx dbmodel.ScriptContext) starlark.Value {
+	d := starlark.NewDict(5)
+	d.SetKey(starlark.String("group"), starlark.String(sctx.Group))
+	d.SetKey(starlark.String("channel"), starlark.String(sctx.Channel))
+	d.SetKey(starlark.String("model"), starlark.String(sctx.Model))
+	d.SetKey(starlark.String("api_key_id"), starlark.MakeInt(sctx.APIKeyID))
+	d.SetKey(starlark.String("stream"), starlark.Bool(sctx.Stream))
+	d.Freeze()
+	return d
+}
+
+// reject(message, status=400) 拒绝请求，message 返回给客户端
+func reject(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var message string
+	status := http.StatusBadRequest
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "message", &message, "status?", &status); err != nil {
+		return nil, err
+	}
+	if status < 400 || status > 599 {
+		return nil, fmt.Errorf("%s: status must be between 400 and 599", b.Name())
+	}
+	state := thread.Local(stateKey).(*callState)
+	state.rejection = &Rejection{Status: status, Message: message}
+	return nil, state.rejection
+}
+
+// route(*channels) 只使用分组中属于这些渠道的成员，仅在分组脚本的 on_request 中生效
+func route(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(kwargs) > 0 {
+		return nil, fmt.Errorf("%s: unexpected keyword arguments", b.Name())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("%s: at least one channel name is required", b.Name())
+	}
+	state := thread.Local(stateKey).(*callState)
+	state.routes = state.routes[:0]
+	for _, arg := range args {
+		name, ok := starlark.AsString(arg)
+		if !ok {
+			return nil, fmt.Errorf("%s: channel name must be a string, got %s", b.Name(), arg.Type())
+		}
+		state.routes = append(state.routes, name)
+	}
+	return starlark.None, nil
+}
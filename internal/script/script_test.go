@@ -0,0 +1,256 @@
+package script
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"octopus/internal/conf"
+	dbmodel "octopus/internal/model"
+)
+
+// loadConfig 写入配置文件并加载为当前配置
+func loadConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		hooks   []string
+		wantErr string
+	}{
+		{name: "request hook", src: "def on_request(req, ctx):\n    return req\n", hooks: []string{HookRequest}},
+		{name: "response hooks", src: "def on_response(resp, ctx):\n    pass\n\ndef on_chunk(chunk, ctx):\n    pass\n", hooks: []string{HookResponse, HookChunk}},
+		{name: "no hooks", src: "def helper():\n    pass\n", wantErr: "define at least one of"},
+		{name: "load", src: "load(\"x.star\", \"y\")\ndef on_request(req, ctx):\n    pass\n", wantErr: "load is not supported"},
+		{name: "syntax error", src: "def on_request(req, ctx)\n    pass\n", wantErr: "invalid script"},
+		{name: "undefined name", src: "def on_request(req, ctx):\n    return missing\n", wantErr: "undefined: missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Compile(tt.src)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, hook := range hooks {
+				expected := false
+				for _, h := range tt.hooks {
+					expected = expected || h == hook
+				}
+				if p.Has(hook) != expected {
+					t.Errorf("expected Has(%s) %v, got %v", hook, expected, p.Has(hook))
+				}
+			}
+		})
+	}
+}
+
+func TestCompileCache(t *testing.T) {
+	src := func(i int) string {
+		return fmt.Sprintf("def on_request(req, ctx):\n    return req # %d\n", i)
+	}
+	first, err := Compile(src(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Compile(src(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != again {
+		t.Fatal("expected the cached program for the same source")
+	}
+
+	// 超出上限后淘汰最久未使用的脚本
+	for i := 1; i <= programCacheSize; i++ {
+		if _, err := Compile(src(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if programs.order.Len() > programCacheSize {
+		t.Fatalf("expected at most %d cached programs, got %d", programCacheSize, programs.order.Len())
+	}
+	if _, ok := programs.get(src(0)); ok {
+		t.Fatal("expected the least recently used program to be evicted")
+	}
+	if _, ok := programs.get(src(programCacheSize)); !ok {
+		t.Fatal("expected the most recently used program to be cached")
+	}
+}
+
+func TestNewSession(t *testing.T) {
+	var p *Program
+	if p.NewSession() != nil {
+		t.Fatal("expected nil session for nil program")
+	}
+	var s *Session
+	if s.Has(HookRequest) {
+		t.Fatal("expected nil session to have no hooks")
+	}
+
+	loadConfig(t, `{"script": {"max_steps": 500, "timeout_ms": 20, "max_output_bytes": 1024}}`)
+	prog, err := Compile("def on_request(req, ctx):\n    pass\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := conf.Script{MaxSteps: 500, TimeoutMs: 20, MaxOutputBytes: 1024}
+	if got := prog.NewSession().limits; got != expected {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestSessionLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		src      string
+		input    string
+		wantErr  string
+		wantLogs []string
+	}{
+		{
+			name:    "max steps",
+			config:  `{"script": {"max_steps": 1000, "timeout_ms": 1000, "max_output_bytes": 1024}}`,
+			src:     "def on_chunk(chunk, ctx):\n    n = 0\n    while True:\n        n += 1\n",
+			input:   `{}`,
+			wantErr: "too many steps",
+		},
+		{
+			name:    "timeout",
+			config:  `{"script": {"max_steps": 100000000000, "timeout_ms": 10, "max_output_bytes": 1024}}`,
+			src:     "def on_chunk(chunk, ctx):\n    n = 0\n    while True:\n        n += 1\n",
+			input:   `{}`,
+			wantErr: "timeout",
+		},
+		{
+			name:    "output too large",
+			config:  `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 64}}`,
+			src:     "def on_chunk(chunk, ctx):\n    chunk[\"id\"] = \"x\" * 100\n",
+			input:   `{}`,
+			wantErr: "more than script.max_output_bytes (64)",
+		},
+		{
+			name:     "print truncated",
+			config:   `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 10}}`,
+			src:      "def on_chunk(chunk, ctx):\n    print(\"12345\")\n    print(\"67890\")\n    print(\"abc\")\n",
+			input:    `{}`,
+			wantLogs: []string{"12345", "67890", "...(print output truncated)"},
+		},
+		{
+			name:    "top-level error",
+			config:  `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 1024}}`,
+			src:     "x = 1 // 0\n\ndef on_chunk(chunk, ctx):\n    pass\n",
+			input:   `{}`,
+			wantErr: "division by zero",
+		},
+		{
+			name:    "invalid return",
+			config:  `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 1024}}`,
+			src:     "def on_chunk(chunk, ctx):\n    return [chunk]\n",
+			input:   `{}`,
+			wantErr: "on_chunk must return a dict or None, got list",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadConfig(t, tt.config)
+			prog, err := Compile(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, result, err := prog.NewSession().call(context.Background(), HookChunk, []byte(tt.input), dbmodel.ScriptContext{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(result.Logs, "|") != strings.Join(tt.wantLogs, "|") {
+				t.Fatalf("expected logs %q, got %q", tt.wantLogs, result.Logs)
+			}
+		})
+	}
+}
+
+func TestSessionGlobals(t *testing.T) {
+	loadConfig(t, `{"script": {"max_steps": 1000000, "timeout_ms": 1000, "max_output_bytes": 1024}}`)
+	tests := []struct {
+		name    string
+		src     string
+		calls   int
+		output  string
+		wantErr string
+		logs    []string
+	}{
+		{
+			name:   "top-level runs once",
+			src:    "print(\"init\")\n\ndef on_chunk(chunk, ctx):\n    print(\"chunk\")\n",
+			calls:  3,
+			output: `{"id":"a"}`,
+			logs:   []string{"init", "chunk", "chunk", "chunk"},
+		},
+		{
+			name:   "read globals",
+			src:    "PREFIX = \"p-\"\n\ndef on_chunk(chunk, ctx):\n    chunk[\"id\"] = PREFIX + chunk[\"id\"]\n",
+			calls:  2,
+			output: `{"id":"p-a"}`,
+		},
+		{
+			name:    "frozen globals",
+			src:     "seen = []\n\ndef on_chunk(chunk, ctx):\n    seen.append(chunk[\"id\"])\n",
+			calls:   1,
+			wantErr: "frozen",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prog, err := Compile(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			session := prog.NewSession()
+			var logs []string
+			for i := 0; i < tt.calls; i++ {
+				out, result, err := session.call(context.Background(), HookChunk, []byte(`{"id":"a"}`), dbmodel.ScriptContext{})
+				if tt.wantErr != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				// 每次调用都从原输入开始，全局变量不会累积修改
+				if string(out) != tt.output {
+					t.Fatalf("expected %s, got %s", tt.output, out)
+				}
+				logs = append(logs, result.Logs...)
+			}
+			if strings.Join(logs, "|") != strings.Join(tt.logs, "|") {
+				t.Fatalf("expected logs %q, got %q", tt.logs, logs)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package secret
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"octopus/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LoadDataKeys 从数据库加载并解包全部数据密钥，不存在时创建一个新的活动密钥
+func LoadDataKeys(db *gorm.DB) error {
+	keyringLock.RLock()
+	kek := masterKey
+	keyringLock.RUnlock()
+	if kek == nil {
+		return ErrNotInitialized
+	}
+
+	var rows []model.DataKey
+	if err := db.Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to load data keys: %w", err)
+	}
+
+	keys := make(map[string][]byte, len(rows))
+	active := ""
+	for _, row := range rows {
+		dek, err := unwrapKey(kek, row.WrappedKey)
+		if err != nil {
+			return fmt.Errorf("failed to unwrap data key %q (wrong master key?): %w", row.ID, err)
+		}
+		keys[row.ID] = dek
+		if row.Active {
+			active = row.ID
+		}
+	}
+
+	if active == "" {
+		row, dek, err := newDataKey(kek)
+		if err != nil {
+			return err
+		}
+		if err := db.Create(&row).Error; err != nil {
+			return fmt.Errorf("failed to create data key: %w", err)
+		}
+		keys[row.ID] = dek
+		active = row.ID
+	}
+
+	keyringLock.Lock()
+	dataKeys = keys
+	activeKeyID = active
+	keyringLock.Unlock()
+	return nil
+}
+
+// Rekey 使用新的主密钥重新包装全部数据密钥，密文字段本身无需改动
+func Rekey(db *gorm.DB, newMasterKey []byte) error {
+	if len(newMasterKey) != keySize {
+		return fmt.Errorf("invalid master key length")
+	}
+	keyringLock.RLock()
+	keys := make(map[string][]byte, len(dataKeys))
+	for id, dek := range dataKeys {
+		keys[id] = dek
+	}
+	keyringLock.RUnlock()
+	if len(keys) == 0 {
+		return ErrNotInitialized
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for id, dek := range keys {
+			wrapped, err := wrapKey(newMasterKey, dek)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&model.DataKey{}).Where("id = ?", id).Update("wrapped_key", wrapped).Error; err != nil {
+				return fmt.Errorf("failed to rewrap data key %q: %w", id, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keyringLock.Lock()
+	masterKey = newMasterKey
+	keyringLock.Unlock()
+	return nil
+}
+
+// RotateDataKey 生成新的活动数据密钥，并通过 reencrypt 使用新密钥重写全部加密字段
+// reencrypt 在同一事务中执行，完成后旧数据密钥会被删除
+func RotateDataKey(db *gorm.DB, reencrypt func(tx *gorm.DB) error) error {
+	keyringLock.RLock()
+	kek := masterKey
+	prevActive := activeKeyID
+	keyringLock.RUnlock()
+	if kek == nil || prevActive == "" {
+		return ErrNotInitialized
+	}
+
+	row, dek, err := newDataKey(kek)
+	if err != nil {
+		return err
+	}
+
+	// 新密钥需要先加入密钥环，reencrypt 写回时才会使用它
+	keyringLock.Lock()
+	dataKeys[row.ID] = dek
+	activeKeyID = row.ID
+	keyringLock.Unlock()
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.DataKey{}).Where("1 = 1").Update("active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		if err := reencrypt(tx); err != nil {
+			return err
+		}
+		return tx.Where("id <> ?", row.ID).Delete(&model.DataKey{}).Error
+	})
+	if err != nil {
+		keyringLock.Lock()
+		delete(dataKeys, row.ID)
+		activeKeyID = prevActive
+		keyringLock.Unlock()
+		return fmt.Errorf("failed to rotate data key: %w", err)
+	}
+
+	keyringLock.Lock()
+	dataKeys = map[string][]byte{row.ID: dek}
+	keyringLock.Unlock()
+	return nil
+}
+
+// UnwrapDataKeys 解包导出文件中携带的数据密钥，kek 为 nil 时使用当前主密钥
+// 导出后执行过 rekey 的实例需要传入导出时的主密钥
+func UnwrapDataKeys(rows []model.DataKey, kek []byte) (map[string][]byte, error) {
+	if kek == nil {
+		keyringLock.RLock()
+		kek = masterKey
+		keyringLock.RUnlock()
+		if kek == nil {
+			return nil, ErrNotInitialized
+		}
+	}
+	keys := make(map[string][]byte, len(rows))
+	for _, row := range rows {
+		dek, err := unwrapKey(kek, row.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key %q (wrong master key?): %w", row.ID, err)
+		}
+		keys[row.ID] = dek
+	}
+	return keys, nil
+}
+
+func newDataKey(kek []byte) (model.DataKey, []byte, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return model.DataKey{}, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return model.DataKey{}, nil, fmt.Errorf("failed to generate data key id: %w", err)
+	}
+	wrapped, err := wrapKey(kek, dek)
+	if err != nil {
+		return model.DataKey{}, nil, err
+	}
+	return model.DataKey{
+		ID:         hex.EncodeToString(idBytes),
+		WrappedKey: wrapped,
+		Active:     true,
+		CreatedAt:  time.Now().Unix(),
+	}, dek, nil
+}
+
+func wrapKey(kek, dek []byte) (string, error) {
+	sealed, err := seal(kek, dek)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func unwrapKey(kek []byte, wrapped string) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return open(kek, sealed)
+}
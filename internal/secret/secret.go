@@ -0,0 +1,215 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"octopus/internal/utils/log"
+)
+
+// 密文格式: enc:v1:<data key id>:<base64(nonce|ciphertext)>
+const (
+	cipherPrefix = "enc:v1:"
+	keySize      = 32
+)
+
+var (
+	masterKey   []byte
+	dataKeys    = make(map[string][]byte)
+	activeKeyID string
+	keyringLock sync.RWMutex
+)
+
+var ErrNotInitialized = errors.New("secret keyring not initialized")
+
+// Init 加载主密钥（KEK）
+// 优先使用 key（配置或环境变量），否则读取 keyFile；keyFile 不存在时自动生成
+func Init(key, keyFile string) error {
+	var (
+		kek []byte
+		err error
+	)
+	if strings.TrimSpace(key) != "" {
+		kek, err = ParseMasterKey(key)
+	} else {
+		kek, err = loadOrCreateKeyFile(keyFile)
+	}
+	if err != nil {
+		return err
+	}
+	keyringLock.Lock()
+	masterKey = kek
+	keyringLock.Unlock()
+	return nil
+}
+
+// ParseMasterKey 解析主密钥，支持 32 字节的 base64 / hex 编码；其他字符串视为口令并做 SHA-256 派生
+func ParseMasterKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("master key is empty")
+	}
+	if b, err := base64.StdEncoding.DecodeString(raw); err == nil && len(b) == keySize {
+		return b, nil
+	}
+	if b, err := hex.DecodeString(raw); err == nil && len(b) == keySize {
+		return b, nil
+	}
+	sum := sha256.Sum256([]byte(raw))
+	return sum[:], nil
+}
+
+// ReadMasterKeyFile 读取主密钥文件
+func ReadMasterKeyFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master key file: %w", err)
+	}
+	return ParseMasterKey(string(content))
+}
+
+// GenerateMasterKey 生成一个新的 base64 编码主密钥
+func GenerateMasterKey() (string, error) {
+	b := make([]byte, keySize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+func loadOrCreateKeyFile(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("neither master key nor master key file is configured")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return ReadMasterKeyFile(path)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to stat master key file: %w", err)
+	}
+
+	key, err := GenerateMasterKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate master key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create master key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write master key file: %w", err)
+	}
+	log.Warnf("master key file not found, generated a new one at %s, please back it up", path)
+	return ParseMasterKey(key)
+}
+
+// Ready 返回密钥环是否已可用于加解密
+func Ready() bool {
+	keyringLock.RLock()
+	defer keyringLock.RUnlock()
+	return masterKey != nil && activeKeyID != ""
+}
+
+// IsEncrypted 判断值是否为本模块生成、且能用当前密钥环解密的密文。
+// 明文本身可能以 enc:v1: 开头，只看前缀无法与密文区分，因此以能否通过认证为准
+func IsEncrypted(value string) bool {
+	if !strings.HasPrefix(value, cipherPrefix) {
+		return false
+	}
+	_, err := Decrypt(value)
+	return err == nil
+}
+
+// Encrypt 使用当前活动的数据密钥加密，空字符串原样返回
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	keyringLock.RLock()
+	id := activeKeyID
+	dek := dataKeys[id]
+	keyringLock.RUnlock()
+	if dek == nil {
+		return "", ErrNotInitialized
+	}
+	sealed, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return cipherPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密密文；非密文（历史明文数据）原样返回
+func Decrypt(value string) (string, error) {
+	return DecryptWith(nil, value)
+}
+
+// DecryptWith 优先使用 keys 中的数据密钥解密，找不到时回退到当前密钥环
+// keys 通常由 UnwrapDataKeys 从导出文件中解包得到
+func DecryptWith(keys map[string][]byte, value string) (string, error) {
+	if !strings.HasPrefix(value, cipherPrefix) {
+		return value, nil
+	}
+	id, payload, ok := strings.Cut(strings.TrimPrefix(value, cipherPrefix), ":")
+	if !ok {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	dek := keys[id]
+	if dek == nil {
+		keyringLock.RLock()
+		dek = dataKeys[id]
+		keyringLock.RUnlock()
+	}
+	if dek == nil {
+		return "", fmt.Errorf("unknown data key %q", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	plain, err := open(dek, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value with data key %q: %w", id, err)
+	}
+	return string(plain), nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
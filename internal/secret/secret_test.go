@@ -0,0 +1,297 @@
+package secret
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"octopus/internal/model"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.DataKey{}); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// initTestKeyring 使用口令初始化主密钥并加载数据密钥
+func initTestKeyring(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if err := Init("test passphrase", ""); err != nil {
+		t.Fatalf("failed to init: %v", err)
+	}
+	if err := LoadDataKeys(db); err != nil {
+		t.Fatalf("failed to load data keys: %v", err)
+	}
+}
+
+func TestParseMasterKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{0xab}, keySize)
+	tests := []struct {
+		name    string
+		input   string
+		want    []byte
+		wantErr bool
+	}{
+		{name: "base64", input: "q6urq6urq6urq6urq6urq6urq6urq6urq6urq6urq6s=", want: raw},
+		{name: "hex", input: strings.Repeat("ab", keySize), want: raw},
+		{name: "surrounding whitespace", input: "  " + strings.Repeat("ab", keySize) + "\n", want: raw},
+		{name: "passphrase", input: "correct horse battery staple"},
+		{name: "empty", input: "  ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMasterKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != keySize {
+				t.Fatalf("expected %d bytes, got %d", keySize, len(got))
+			}
+			if tt.want != nil && !bytes.Equal(got, tt.want) {
+				t.Errorf("expected %x, got %x", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	initTestKeyring(t, newTestDB(t))
+
+	tests := []struct {
+		name  string
+		plain string
+	}{
+		{name: "api key", plain: "sk-test-1234567890"},
+		{name: "unicode", plain: "密钥 🔑"},
+		{name: "empty", plain: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := Encrypt(tt.plain)
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+			if tt.plain != "" && (!IsEncrypted(enc) || strings.Contains(enc, tt.plain)) {
+				t.Fatalf("expected ciphertext, got %q", enc)
+			}
+			dec, err := Decrypt(enc)
+			if err != nil {
+				t.Fatalf("failed to decrypt: %v", err)
+			}
+			if dec != tt.plain {
+				t.Errorf("expected %q, got %q", tt.plain, dec)
+			}
+		})
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	initTestKeyring(t, newTestDB(t))
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "legacy plaintext", input: "sk-plain", want: "sk-plain"},
+		{name: "missing key id", input: cipherPrefix + "abc", wantErr: true},
+		{name: "unknown key id", input: cipherPrefix + "0000:AAAA", wantErr: true},
+		{name: "bad base64", input: cipherPrefix + activeKeyID + ":!!!", wantErr: true},
+		{name: "tampered", input: cipherPrefix + activeKeyID + ":AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decrypt(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRekey(t *testing.T) {
+	db := newTestDB(t)
+	initTestKeyring(t, db)
+	enc, err := Encrypt("sk-before-rekey")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	newKey, _ := ParseMasterKey("new passphrase")
+	if err := Rekey(db, newKey); err != nil {
+		t.Fatalf("failed to rekey: %v", err)
+	}
+
+	// 旧主密钥不能再解包数据密钥，新主密钥可以，密文无需改动
+	if err := Init("test passphrase", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDataKeys(db); err == nil {
+		t.Fatal("expected the old master key to be rejected")
+	}
+	if err := Init("new passphrase", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDataKeys(db); err != nil {
+		t.Fatalf("failed to load data keys with the new master key: %v", err)
+	}
+	if dec, err := Decrypt(enc); err != nil || dec != "sk-before-rekey" {
+		t.Fatalf("expected the old ciphertext to decrypt, got %q, %v", dec, err)
+	}
+}
+
+func TestRotateDataKeyRollback(t *testing.T) {
+	db := newTestDB(t)
+	initTestKeyring(t, db)
+	enc, _ := Encrypt("sk-keep")
+	prev := activeKeyID
+
+	err := RotateDataKey(db, func(tx *gorm.DB) error { return gorm.ErrInvalidData })
+	if err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+	if activeKeyID != prev {
+		t.Errorf("expected active key %s to be restored, got %s", prev, activeKeyID)
+	}
+	var count int64
+	db.Model(&model.DataKey{}).Count(&count)
+	if count != 1 {
+		t.Errorf("expected 1 data key after rollback, got %d", count)
+	}
+	if dec, err := Decrypt(enc); err != nil || dec != "sk-keep" {
+		t.Errorf("expected ciphertext to stay readable, got %q, %v", dec, err)
+	}
+}
+
+func TestDecryptWithExportedDataKeys(t *testing.T) {
+	db := newTestDB(t)
+	initTestKeyring(t, db)
+	enc, _ := Encrypt("sk-exported")
+	var rows []model.DataKey
+	db.Find(&rows)
+
+	// 轮换后旧数据密钥被删除，只能通过导出的包装密钥解密
+	if err := RotateDataKey(db, func(tx *gorm.DB) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(enc); err == nil {
+		t.Fatal("expected the rotated-out data key to be unknown")
+	}
+
+	oldKey, _ := ParseMasterKey("test passphrase")
+	otherKey, _ := ParseMasterKey("other passphrase")
+	tests := []struct {
+		name    string
+		kek     []byte
+		wantErr bool
+	}{
+		{name: "current master key", kek: nil},
+		{name: "explicit master key", kek: oldKey},
+		{name: "wrong master key", kek: otherKey, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := UnwrapDataKeys(rows, tt.kek)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if dec, err := DecryptWith(keys, enc); err != nil || dec != "sk-exported" {
+				t.Fatalf("expected %q, got %q, %v", "sk-exported", dec, err)
+			}
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	initTestKeyring(t, newTestDB(t))
+	enc, _ := Encrypt("sk-real")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{name: "ciphertext", input: enc, expected: true},
+		{name: "plaintext", input: "sk-plain", expected: false},
+		{name: "plaintext with prefix", input: cipherPrefix + "not-a-ciphertext", expected: false},
+		{name: "unauthenticated", input: cipherPrefix + activeKeyID + ":AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEncrypted(tt.input); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestEncryptedSerializer(t *testing.T) {
+	type record struct {
+		ID    int
+		Value string  `gorm:"serializer:encrypted"`
+		Ptr   *string `gorm:"serializer:encrypted"`
+	}
+	db := newTestDB(t)
+	initTestKeyring(t, db)
+	if err := db.AutoMigrate(&record{}); err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := Encrypt("sk-real")
+
+	tests := []struct {
+		name  string
+		value string
+		// expected 读回的明文
+		expected string
+	}{
+		{name: "plaintext", value: "sk-plain", expected: "sk-plain"},
+		{name: "plaintext with prefix", value: cipherPrefix + "not-a-ciphertext", expected: cipherPrefix + "not-a-ciphertext"},
+		{name: "unauthenticated", value: cipherPrefix + activeKeyID + ":AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA", expected: cipherPrefix + activeKeyID + ":AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+		{name: "ciphertext kept", value: enc, expected: "sk-real"},
+		{name: "empty", value: "", expected: ""},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := tt.value
+			if err := db.Create(&record{ID: i + 1, Value: tt.value, Ptr: &value}).Error; err != nil {
+				t.Fatalf("failed to create: %v", err)
+			}
+			var raw struct {
+				Value string
+				Ptr   string
+			}
+			db.Raw("SELECT value, ptr FROM records WHERE id = ?", i+1).Scan(&raw)
+			if tt.value != "" && (!IsEncrypted(raw.Value) || !IsEncrypted(raw.Ptr)) {
+				t.Fatalf("expected stored ciphertext, got %q, %q", raw.Value, raw.Ptr)
+			}
+			var got record
+			if err := db.First(&got, i+1).Error; err != nil {
+				t.Fatalf("failed to read back: %v", err)
+			}
+			if got.Value != tt.expected || got.Ptr == nil || *got.Ptr != tt.expected {
+				t.Errorf("expected %q, got %q, %v", tt.expected, got.Value, got.Ptr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// EncryptedSerializer 透明加解密 string / *string 字段
+// 写入时只有能通过认证的密文原样保存，其余值（包括以 enc:v1: 开头的明文）都会加密，读取时不会被误认为密文
+// 用法: `gorm:"serializer:encrypted"`
+type EncryptedSerializer struct{}
+
+// Scan implements serializer interface
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+	if dbValue != nil {
+		var raw string
+		switch v := dbValue.(type) {
+		case []byte:
+			raw = string(v)
+		case string:
+			raw = v
+		default:
+			return fmt.Errorf("unsupported encrypted column value type %T", dbValue)
+		}
+		plain, err := Decrypt(raw)
+		if err != nil {
+			return err
+		}
+		if field.FieldType.Kind() == reflect.Ptr {
+			fieldValue.Elem().Set(reflect.ValueOf(&plain))
+		} else {
+			fieldValue.Elem().SetString(plain)
+		}
+	}
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value implements serializer interface
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plain string
+	switch v := fieldValue.(type) {
+	case string:
+		plain = v
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plain = *v
+	default:
+		return nil, fmt.Errorf("unsupported encrypted field type %T", fieldValue)
+	}
+	if IsEncrypted(plain) {
+		return plain, nil
+	}
+	return Encrypt(plain)
+}
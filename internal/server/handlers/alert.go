@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"octopus/internal/alert"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/alert").
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/notifier/list", http.MethodGet).
+				Handle(listAlertNotifier),
+		).
+		AddRoute(
+			router.NewRoute("/notifier/create", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(createAlertNotifier),
+		).
+		AddRoute(
+			router.NewRoute("/notifier/update", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(updateAlertNotifier),
+		).
+		AddRoute(
+			router.NewRoute("/notifier/delete/:id", http.MethodDelete).
+				Handle(deleteAlertNotifier),
+		).
+		AddRoute(
+			router.NewRoute("/notifier/test/:id", http.MethodPost).
+				Handle(testAlertNotifier),
+		).
+		AddRoute(
+			router.NewRoute("/rule/list", http.MethodGet).
+				Handle(listAlertRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/create", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(createAlertRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/update", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(updateAlertRule),
+		).
+		AddRoute(
+			router.NewRoute("/rule/delete/:id", http.MethodDelete).
+				Handle(deleteAlertRule),
+		)
+}
+
+func listAlertNotifier(c *gin.Context) {
+	notifiers, err := op.AlertNotifierList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i := range notifiers {
+		notifiers[i] = op.AlertNotifierMask(notifiers[i])
+	}
+	resp.Success(c, notifiers)
+}
+
+func createAlertNotifier(c *gin.Context) {
+	var req model.AlertNotifier
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.AlertNotifierCreate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, op.AlertNotifierMask(req))
+}
+
+func updateAlertNotifier(c *gin.Context) {
+	var req model.AlertNotifier
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	op.AlertNotifierUnmask(&req)
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.AlertNotifierUpdate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, op.AlertNotifierMask(req))
+}
+
+func deleteAlertNotifier(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.AlertNotifierDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func testAlertNotifier(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	notifier, err := op.AlertNotifierGet(id, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	err = alert.Send(c.Request.Context(), notifier, model.Alert{
+		Subject: "test",
+		Title:   "Octopus test alert",
+		Message: "This is a test message from Octopus.",
+		Time:    time.Now().Unix(),
+	})
+	if err != nil {
+		resp.Error(c, http.StatusBadGateway, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
+
+func listAlertRule(c *gin.Context) {
+	rules, err := op.AlertRuleList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, rules)
+}
+
+func createAlertRule(c *gin.Context) {
+	var req model.AlertRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.AlertRuleCreate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func updateAlertRule(c *gin.Context) {
+	var req model.AlertRule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.AlertRuleUpdate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func deleteAlertRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.AlertRuleDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/model-alias").
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/list", http.MethodGet).
+				Handle(listModelAlias),
+		).
+		AddRoute(
+			router.NewRoute("/create", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(createModelAlias),
+		).
+		AddRoute(
+			router.NewRoute("/update", http.MethodPost).
+				Use(middleware.RequireJSON()).
+				Handle(updateModelAlias),
+		).
+		AddRoute(
+			router.NewRoute("/delete/:id", http.MethodDelete).
+				Handle(deleteModelAlias),
+		)
+}
+
+func listModelAlias(c *gin.Context) {
+	aliases, err := op.ModelAliasList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, aliases)
+}
+
+func createModelAlias(c *gin.Context) {
+	var req model.ModelAlias
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.ModelAliasCreate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func updateModelAlias(c *gin.Context) {
+	var req model.ModelAlias
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if err := req.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.ModelAliasUpdate(&req, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, req)
+}
+
+func deleteModelAlias(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	if err := op.ModelAliasDelete(id, c.Request.Context()); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
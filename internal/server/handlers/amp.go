@@ -17,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"octopus/internal/conf"
@@ -48,6 +49,9 @@ var (
 	ampProxy     *httputil.ReverseProxy
 	ampProxyOnce sync.Once
 	ampProxyMu   sync.RWMutex
+	// ampRoutesRegistered 启动时是否注册了 management 路由，未注册时热重载无法启用代理
+	ampRoutesRegistered  bool
+	ampRestrictLocalhost atomic.Bool
 )
 
 func init() {
@@ -95,7 +99,7 @@ func init() {
 // RegisterAmpManagementRoutes 注册 amp management 路由到给定的 engine
 // 这个函数应该在 server.Start() 中调用
 func RegisterAmpManagementRoutes(engine *gin.Engine) {
-	cfg := conf.AppConfig.AmpCode
+	cfg := conf.Get().AmpCode
 	if !cfg.Enabled {
 		log.Infof("amp integration disabled")
 		return
@@ -108,10 +112,12 @@ func RegisterAmpManagementRoutes(engine *gin.Engine) {
 
 	// 初始化 proxy
 	initAmpProxy(cfg.UpstreamURL)
+	ampRoutesRegistered = true
+	ampRestrictLocalhost.Store(cfg.RestrictManagementToLocalhost)
 
 	// Management 路由组
 	api := engine.Group("/api")
-	api.Use(ampManagementMiddleware(&cfg))
+	api.Use(ampManagementMiddleware())
 
 	// 代理 handler
 	proxyHandler := func(c *gin.Context) {
@@ -142,23 +148,46 @@ func RegisterAmpManagementRoutes(engine *gin.Engine) {
 	api.Any("/tab/*path", proxyHandler)
 
 	// Root-level routes
-	engine.Any("/threads", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/threads/*path", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/auth", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/auth/*path", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/docs", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/docs/*path", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/settings", ampManagementMiddleware(&cfg), proxyHandler)
-	engine.Any("/settings/*path", ampManagementMiddleware(&cfg), proxyHandler)
+	engine.Any("/threads", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/threads/*path", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/auth", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/auth/*path", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/docs", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/docs/*path", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/settings", ampManagementMiddleware(), proxyHandler)
+	engine.Any("/settings/*path", ampManagementMiddleware(), proxyHandler)
 
 	log.Infof("amp management routes registered, upstream: %s", cfg.UpstreamURL)
 }
 
+// ReloadAmp 配置热重载时替换 amp 反向代理，进行中的代理请求不受影响
+func ReloadAmp(old, cur conf.AmpCode) {
+	if !ampRoutesRegistered {
+		if cur.UpstreamURL != old.UpstreamURL {
+			log.Warnf("amp management routes are not registered, restart to apply the new upstream URL")
+		}
+		return
+	}
+	ampRestrictLocalhost.Store(cur.RestrictManagementToLocalhost)
+	if cur.UpstreamURL == old.UpstreamURL && cur.UpstreamAPIKey == old.UpstreamAPIKey {
+		return
+	}
+	proxy, err := createAmpReverseProxy(cur.UpstreamURL)
+	if err != nil {
+		log.Errorf("failed to recreate amp proxy: %v", err)
+		return
+	}
+	ampProxyMu.Lock()
+	ampProxy = proxy
+	ampProxyMu.Unlock()
+	log.Infof("amp reverse proxy reloaded for: %s", cur.UpstreamURL)
+}
+
 // ampManagementMiddleware 返回 management 路由的中间件
-func ampManagementMiddleware(cfg *conf.AmpCode) gin.HandlerFunc {
+func ampManagementMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// localhost 限制检查
-		if cfg.RestrictManagementToLocalhost {
+		if ampRestrictLocalhost.Load() {
 			remoteAddr := c.Request.RemoteAddr
 			host, _, err := net.SplitHostPort(remoteAddr)
 			if err != nil {
@@ -247,7 +276,7 @@ func createAmpReverseProxy(upstreamURL string) (*httputil.ReverseProxy, error) {
 	originalDirector := proxy.Director
 
 	// 获取 secret source
-	secretSource := NewMultiSourceSecret(conf.AppConfig.AmpCode.UpstreamAPIKey)
+	secretSource := NewMultiSourceSecret(conf.Get().AmpCode.UpstreamAPIKey)
 
 	proxy.Director = func(req *http.Request) {
 		originalDirector(req)
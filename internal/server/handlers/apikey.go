@@ -4,7 +4,9 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"octopus/internal/guardrail"
 	"octopus/internal/model"
 	"octopus/internal/op"
 	"octopus/internal/server/auth"
@@ -31,6 +33,10 @@ func init() {
 			router.NewRoute("/update", http.MethodPost).
 				Handle(updateAPIKey),
 		).
+		AddRoute(
+			router.NewRoute("/rotate", http.MethodPost).
+				Handle(rotateAPIKey),
+		).
 		AddRoute(
 			router.NewRoute("/delete/:id", http.MethodDelete).
 				Handle(deleteAPIKey),
@@ -53,12 +59,16 @@ func createAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if err := guardrail.Validate(req.Guardrail); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	req.APIKey = auth.GenerateAPIKey()
 	if err := op.APIKeyCreate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	resp.Success(c, req)
+	resp.Success(c, hideAPIKeyHash(req))
 }
 
 func listAPIKey(c *gin.Context) {
@@ -67,7 +77,27 @@ func listAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	resp.Success(c, apiKeys)
+	resp.Success(c, lo.Map(apiKeys, func(k model.APIKey, _ int) model.APIKey {
+		return hideAPIKeyHash(k)
+	}))
+}
+
+func rotateAPIKey(c *gin.Context) {
+	var req model.APIKeyRotate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
+		return
+	}
+	if req.GracePeriod < 0 {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	apiKey, err := op.APIKeyRotate(req.ID, auth.GenerateAPIKey(), time.Duration(req.GracePeriod)*time.Second, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, hideAPIKeyHash(apiKey))
 }
 
 func updateAPIKey(c *gin.Context) {
@@ -76,11 +106,15 @@ func updateAPIKey(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if err := guardrail.Validate(req.Guardrail); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.APIKeyUpdate(&req, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	resp.Success(c, req)
+	resp.Success(c, hideAPIKeyHash(req))
 }
 
 func deleteAPIKey(c *gin.Context) {
@@ -110,6 +144,7 @@ func getStatsAPIKeyById(c *gin.Context) {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
+	models = lo.Uniq(append(models, op.ModelAliasListNames(c.Request.Context())...))
 	var modelsString string
 	if info.SupportedModels == "" {
 		modelsString = strings.Join(models, ", ")
@@ -125,10 +160,17 @@ func getStatsAPIKeyById(c *gin.Context) {
 	info.SupportedModels = modelsString
 	resp.Success(c, map[string]any{
 		"stats": stats,
-		"info":  info,
+		"info":  hideAPIKeyHash(info),
 	})
 }
 
 func loginAPIKey(c *gin.Context) {
 	resp.Success(c, nil)
 }
+
+// hideAPIKeyHash 返回给面板前去掉哈希字段
+func hideAPIKeyHash(k model.APIKey) model.APIKey {
+	k.KeyHash = ""
+	k.PrevKeyHash = ""
+	return k
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/audit").
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/list", http.MethodGet).
+				Handle(listAuditLog),
+		)
+}
+
+func listAuditLog(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filter := model.AuditLogFilter{
+		Actor:      c.Query("actor"),
+		Action:     model.AuditAction(c.Query("action")),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+	}
+	if s := c.Query("start_time"); s != "" {
+		st, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.StartTime = &st
+	}
+	if s := c.Query("end_time"); s != "" {
+		et, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		filter.EndTime = &et
+	}
+
+	logs, err := op.AuditLogList(c.Request.Context(), filter, page, pageSize)
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, logs)
+}
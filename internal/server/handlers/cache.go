@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"octopus/internal/op"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/cache").
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/status", http.MethodGet).
+				Handle(getCacheStatus),
+		).
+		AddRoute(
+			router.NewRoute("/check", http.MethodPost).
+				Handle(checkCache),
+		).
+		AddRoute(
+			router.NewRoute("/reload", http.MethodPost).
+				Handle(reloadCache),
+		)
+}
+
+func getCacheStatus(c *gin.Context) {
+	resp.Success(c, op.CacheStatusList())
+}
+
+func checkCache(c *gin.Context) {
+	reloaded, err := op.CacheCheck(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reloaded == nil {
+		reloaded = []string{}
+	}
+	resp.Success(c, gin.H{"reloaded": reloaded, "status": op.CacheStatusList()})
+}
+
+func reloadCache(c *gin.Context) {
+	var req struct {
+		Names []string `json:"names"`
+	}
+	// 请求体可以为空，表示重新加载全部缓存
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := op.CacheReload(c.Request.Context(), req.Names); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Success(c, op.CacheStatusList())
+}
@@ -10,6 +10,7 @@ import (
 	"octopus/internal/helper"
 	"octopus/internal/model"
 	"octopus/internal/op"
+	"octopus/internal/script"
 	"octopus/internal/server/middleware"
 	"octopus/internal/server/resp"
 	"octopus/internal/server/router"
@@ -54,6 +55,10 @@ func init() {
 		AddRoute(
 			router.NewRoute("/last-sync-time", http.MethodGet).
 				Handle(getLastSyncTime),
+		).
+		AddRoute(
+			router.NewRoute("/test/:id", http.MethodPost).
+				Handle(testChannel),
 		)
 }
 
@@ -76,6 +81,14 @@ func createChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if err := channel.PromptCache.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := script.Validate(channel.Script); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.ChannelCreate(&channel, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -100,6 +113,16 @@ func updateChannel(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidJSON)
 		return
 	}
+	if err := req.PromptCache.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Script != nil {
+		if err := script.Validate(*req.Script); err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 	channel, err := op.ChannelUpdate(&req, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -135,6 +158,20 @@ func enableChannel(c *gin.Context) {
 	resp.Success(c, nil)
 }
 
+func testChannel(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	channel, err := op.ChannelGet(id, c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	resp.Success(c, helper.ChannelTest(c.Request.Context(), *channel))
+}
+
 func deleteChannel(c *gin.Context) {
 	id := c.Param("id")
 	idNum, err := strconv.Atoi(id)
@@ -163,6 +200,16 @@ func fetchModel(c *gin.Context) {
 }
 
 func syncChannel(c *gin.Context) {
+	// dry_run=true 时只返回将要产生的模型变更
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		plans, err := task.SyncModelsDryRun(c.Request.Context())
+		if err != nil {
+			resp.Error(c, http.StatusInternalServerError, err.Error())
+			return
+		}
+		resp.Success(c, plans)
+		return
+	}
 	task.SyncModelsTask()
 	resp.Success(c, nil)
 }
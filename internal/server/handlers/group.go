@@ -4,11 +4,15 @@ import (
 	"net/http"
 	"strconv"
 
+	"octopus/internal/guardrail"
+	"octopus/internal/mcp"
 	"octopus/internal/model"
 	"octopus/internal/op"
 	"octopus/internal/server/middleware"
 	"octopus/internal/server/resp"
+	"octopus/internal/script"
 	"octopus/internal/server/router"
+	"octopus/internal/servertool"
 
 	"github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
@@ -62,6 +66,30 @@ func createGroup(c *gin.Context) {
 			return
 		}
 	}
+	if err := group.PromptCache.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := group.Reasoning.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := guardrail.Validate(group.Guardrail); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := script.Validate(group.Script); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := servertool.Validate(group.ServerTools); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := mcp.Validate(group.MCPServers); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err := op.GroupCreate(&group, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -82,6 +110,36 @@ func updateGroup(c *gin.Context) {
 			return
 		}
 	}
+	if err := req.PromptCache.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := req.Reasoning.Validate(); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := guardrail.Validate(req.Guardrail); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Script != nil {
+		if err := script.Validate(*req.Script); err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.ServerTools != nil {
+		if err := servertool.Validate(*req.ServerTools); err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if req.MCPServers != nil {
+		if err := mcp.Validate(*req.MCPServers); err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 	group, err := op.GroupUpdate(&req, c.Request.Context())
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"octopus/internal/helper"
+	"octopus/internal/op"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/model-sync").
+		Use(middleware.Auth()).
+		AddRoute(
+			router.NewRoute("/pending/list", http.MethodGet).
+				Handle(listModelSyncPending),
+		).
+		AddRoute(
+			router.NewRoute("/pending/approve/:id", http.MethodPost).
+				Handle(approveModelSyncPending),
+		).
+		AddRoute(
+			router.NewRoute("/pending/reject/:id", http.MethodPost).
+				Handle(rejectModelSyncPending),
+		)
+}
+
+func listModelSyncPending(c *gin.Context) {
+	list, err := op.ModelSyncPendingList(c.Request.Context())
+	if err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, list)
+}
+
+// approveModelSyncPending 手动审核通过时会同时删除受保护的模型
+func approveModelSyncPending(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	ctx := c.Request.Context()
+	p, err := op.ModelSyncPendingGet(id, ctx)
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := helper.ModelSyncApply(ctx, p, true); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := op.ModelSyncPendingResolve(p, true, ctx); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if ch, err := op.ChannelGet(p.ChannelID, ctx); err == nil {
+		helper.ChannelAutoGroup(ch, ctx)
+	}
+	resp.Success(c, nil)
+}
+
+func rejectModelSyncPending(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, resp.ErrInvalidParam)
+		return
+	}
+	ctx := c.Request.Context()
+	p, err := op.ModelSyncPendingGet(id, ctx)
+	if err != nil {
+		resp.Error(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err := op.ModelSyncPendingResolve(p, false, ctx); err != nil {
+		resp.Error(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	resp.Success(c, nil)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/script"
+	"octopus/internal/server/middleware"
+	"octopus/internal/server/resp"
+	"octopus/internal/server/router"
+	transformer "octopus/internal/transformer/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	router.NewGroupRouter("/api/v1/script").
+		Use(middleware.Auth()).
+		Use(middleware.RequireJSON()).
+		AddRoute(
+			router.NewRoute("/test", http.MethodPost).
+				Handle(testScript),
+		)
+}
+
+// testScript 使用给定的输入试运行脚本的钩子，执行限制与转发时相同。
+// 脚本出错或拒绝时仍返回 200，结果中包含错误与 print() 的输出
+func testScript(c *gin.Context) {
+	var req model.ScriptTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	prog, err := script.Compile(req.Script)
+	if err != nil {
+		resp.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !prog.Has(req.Hook) {
+		resp.Error(c, http.StatusBadRequest, fmt.Sprintf("script does not define %s", req.Hook))
+		return
+	}
+
+	session := prog.NewSession()
+	var output any
+	var result *script.Result
+	start := time.Now()
+	switch req.Hook {
+	case script.HookRequest:
+		var input transformer.InternalLLMRequest
+		if err := json.Unmarshal(req.Input, &input); err != nil {
+			resp.Error(c, http.StatusBadRequest, "invalid input: "+err.Error())
+			return
+		}
+		output, result, err = session.Request(c.Request.Context(), &input, req.Context)
+	default:
+		var input transformer.InternalLLMResponse
+		if err := json.Unmarshal(req.Input, &input); err != nil {
+			resp.Error(c, http.StatusBadRequest, "invalid input: "+err.Error())
+			return
+		}
+		output, result, err = session.Response(c.Request.Context(), req.Hook, &input, req.Context)
+	}
+
+	res := model.ScriptTestResult{
+		Routes:     result.Routes,
+		Logs:       result.Logs,
+		Steps:      result.Steps,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if res.Logs == nil {
+		res.Logs = []string{}
+	}
+	var rejection *script.Rejection
+	switch {
+	case errors.As(err, &rejection):
+		res.Rejected = &model.ScriptReject{Status: rejection.Status, Message: rejection.Message}
+	case err != nil:
+		res.Error = err.Error()
+	default:
+		res.Output, _ = json.Marshal(output)
+	}
+	resp.Success(c, res)
+}
@@ -58,7 +58,7 @@ func setSetting(c *gin.Context) {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := op.SettingSetString(setting.Key, setting.Value); err != nil {
+	if err := op.SettingSetString(setting.Key, setting.Value, c.Request.Context()); err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -77,6 +77,13 @@ func setSetting(c *gin.Context) {
 			return
 		}
 		task.Update(string(setting.Key), time.Duration(hours)*time.Hour)
+	case model.SettingKeyKeyHealthCheckInterval, model.SettingKeyCacheCheckInterval:
+		minutes, err := strconv.Atoi(setting.Value)
+		if err != nil {
+			resp.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		task.Update(string(setting.Key), time.Duration(minutes)*time.Minute)
 	}
 	resp.Success(c, setting)
 }
@@ -84,8 +91,9 @@ func setSetting(c *gin.Context) {
 func exportDB(c *gin.Context) {
 	includeLogs, _ := strconv.ParseBool(c.DefaultQuery("include_logs", "false"))
 	includeStats, _ := strconv.ParseBool(c.DefaultQuery("include_stats", "false"))
+	decrypt, _ := strconv.ParseBool(c.DefaultQuery("decrypt", "false"))
 
-	dump, err := op.DBExportAll(c.Request.Context(), includeLogs, includeStats)
+	dump, err := op.DBExportAll(c.Request.Context(), includeLogs, includeStats, decrypt)
 	if err != nil {
 		resp.Error(c, http.StatusInternalServerError, err.Error())
 		return
@@ -96,9 +104,22 @@ func exportDB(c *gin.Context) {
 	c.JSON(http.StatusOK, dump)
 }
 
+// importDB 导入导出文件，查询参数：
+// replace=true 先清空导出文件覆盖的表；dry_run=true 只预览不提交；
+// channel、group、api_key 可重复，指定时只恢复选中的对象
 func importDB(c *gin.Context) {
 	var dump model.DBDump
 
+	replace, _ := strconv.ParseBool(c.DefaultQuery("replace", "false"))
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	opts := model.DBImportOptions{
+		Replace:  replace,
+		DryRun:   dryRun,
+		Channels: c.QueryArray("channel"),
+		Groups:   c.QueryArray("group"),
+		APIKeys:  c.QueryArray("api_key"),
+	}
+
 	contentType := c.GetHeader("Content-Type")
 	if strings.Contains(contentType, "multipart/form-data") {
 		fh, err := c.FormFile("file")
@@ -133,49 +154,38 @@ func importDB(c *gin.Context) {
 		}
 	}
 
-	result, err := op.DBImportIncremental(c.Request.Context(), &dump)
+	result, err := op.DBImport(c.Request.Context(), &dump, opts)
 	if err != nil {
 		resp.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	_ = op.InitCache()
+	if !result.DryRun {
+		_ = op.InitCache()
+	}
 
 	resp.Success(c, result)
 }
 
+// decodeDBDump 解析导出文件并迁移到当前版本，兼容包裹在 {code, message, data} 响应中的导出文件
 func decodeDBDump(body []byte, dump *model.DBDump) error {
 	if dump == nil {
 		return json.Unmarshal(body, &struct{}{})
 	}
 
-	if err := json.Unmarshal(body, dump); err != nil {
-		return err
+	var wrapper struct {
+		Code    *int            `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
 	}
-
-	if dump.Version == 0 &&
-		len(dump.Channels) == 0 &&
-		len(dump.Groups) == 0 &&
-		len(dump.GroupItems) == 0 &&
-		len(dump.Settings) == 0 &&
-		len(dump.APIKeys) == 0 &&
-		len(dump.LLMInfos) == 0 &&
-		len(dump.RelayLogs) == 0 &&
-		len(dump.StatsDaily) == 0 &&
-		len(dump.StatsHourly) == 0 &&
-		len(dump.StatsTotal) == 0 &&
-		len(dump.StatsChannel) == 0 &&
-		len(dump.StatsModel) == 0 &&
-		len(dump.StatsAPIKey) == 0 {
-		var wrapper struct {
-			Code    int             `json:"code"`
-			Message string          `json:"message"`
-			Data    json.RawMessage `json:"data"`
-		}
-		if err := json.Unmarshal(body, &wrapper); err == nil && len(wrapper.Data) > 0 {
-			return json.Unmarshal(wrapper.Data, dump)
-		}
+	if err := json.Unmarshal(body, &wrapper); err == nil && wrapper.Code != nil && len(wrapper.Data) > 0 {
+		body = wrapper.Data
 	}
 
+	decoded, err := op.DBDumpDecode(body)
+	if err != nil {
+		return err
+	}
+	*dump = *decoded
 	return nil
 }
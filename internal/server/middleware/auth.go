@@ -26,6 +26,8 @@ func Auth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// 面板只有一个管理员账户，审计日志以其用户名作为操作者
+		c.Request = c.Request.WithContext(op.WithAuditActor(c.Request.Context(), op.UserGet().Username, c.ClientIP()))
 		c.Next()
 	}
 }
@@ -79,6 +81,7 @@ func APIKeyAuth() gin.HandlerFunc {
 		c.Set("request_type", requestType)
 		c.Set("supported_models", apiKeyObj.SupportedModels)
 		c.Set("api_key_id", apiKeyObj.ID)
+		c.Set("api_key_guardrail", apiKeyObj.Guardrail)
 		c.Next()
 	}
 }
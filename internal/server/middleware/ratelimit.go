@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"octopus/internal/server/resp"
@@ -14,7 +15,8 @@ import (
 	"golang.org/x/time/rate"
 )
 
-var globalLimiter *rateFastSlowLimiter
+// globalLimiter 配置热重载时整体替换，进行中的请求继续使用获取许可时的限制器
+var globalLimiter atomic.Pointer[rateFastSlowLimiter]
 
 var errQueueFull = errors.New("concurrency queue full")
 
@@ -120,7 +122,7 @@ func InitRateLimit(
 ) {
 	if fastMax <= 0 && slowMax <= 0 {
 		if maxConcurrent <= 0 && ratePerSecond <= 0 {
-			globalLimiter = nil
+			globalLimiter.Store(nil)
 			log.Infof("concurrency/rate limit disabled")
 			return
 		}
@@ -139,50 +141,52 @@ func InitRateLimit(
 		rateLimiter = rate.NewLimiter(rate.Limit(ratePerSecond), rateBurst)
 	}
 
-	globalLimiter = &rateFastSlowLimiter{
+	limiter := &rateFastSlowLimiter{
 		rateLimiter:  rateLimiter,
 		fast:         newQueueLimiter(fastMax, maxQueue),
 		slow:         newQueueLimiter(slowMax, maxQueue),
 		migrateAfter: time.Duration(migrateAfterSeconds) * time.Second,
 		waitTimeout:  time.Duration(maxQueueWaitSeconds) * time.Second,
 	}
+	globalLimiter.Store(limiter)
 
 	log.Infof("limit enabled: rate %d/s burst %d, fast %d, slow %d, migrate %s, queue %d, max wait %s",
-		ratePerSecond, rateBurst, fastMax, slowMax, globalLimiter.migrateAfter, maxQueue, globalLimiter.waitTimeout)
+		ratePerSecond, rateBurst, fastMax, slowMax, limiter.migrateAfter, maxQueue, limiter.waitTimeout)
 }
 
 // RateLimit 返回并发限制中间件
 func RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 如果未启用限制，直接放行
-		if globalLimiter == nil {
+		limiter := globalLimiter.Load()
+		if limiter == nil {
 			c.Next()
 			return
 		}
 
 		waitCtx := c.Request.Context()
 		var cancel context.CancelFunc
-		if globalLimiter.waitTimeout > 0 {
-			waitCtx, cancel = context.WithTimeout(waitCtx, globalLimiter.waitTimeout)
+		if limiter.waitTimeout > 0 {
+			waitCtx, cancel = context.WithTimeout(waitCtx, limiter.waitTimeout)
 			defer cancel()
 		}
 
 		// 1) 速率限制
-		if globalLimiter.rateLimiter != nil {
-			if err := globalLimiter.rateLimiter.Wait(waitCtx); err != nil {
+		if limiter.rateLimiter != nil {
+			if err := limiter.rateLimiter.Wait(waitCtx); err != nil {
 				handleLimiterError(c, err)
 				return
 			}
 		}
 
 		// 2) 快池并发
-		if globalLimiter.fast == nil && globalLimiter.slow == nil {
+		if limiter.fast == nil && limiter.slow == nil {
 			c.Next()
 			return
 		}
 
-		fast := globalLimiter.fast
-		slow := globalLimiter.slow
+		fast := limiter.fast
+		slow := limiter.slow
 
 		if fast == nil && slow != nil {
 			if err := slow.acquire(waitCtx); err != nil {
@@ -208,9 +212,9 @@ func RateLimit() gin.HandlerFunc {
 		doneCh := make(chan struct{})
 		migrateCtx, migrateCancel := context.WithCancel(c.Request.Context())
 
-		if slow != nil && globalLimiter.migrateAfter > 0 {
+		if slow != nil && limiter.migrateAfter > 0 {
 			go func() {
-				timer := time.NewTimer(globalLimiter.migrateAfter)
+				timer := time.NewTimer(limiter.migrateAfter)
 				defer timer.Stop()
 				select {
 				case <-timer.C:
@@ -1,10 +1,13 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"octopus/internal/conf"
+	"octopus/internal/relay"
 	"octopus/internal/server/handlers"
 	"octopus/internal/server/middleware"
 	"octopus/internal/server/resp"
@@ -40,7 +43,7 @@ func Start() error {
 	// 注册 amp management 路由（需要直接访问 engine）
 	handlers.RegisterAmpManagementRoutes(r)
 
-	httpSrv.Addr = fmt.Sprintf("%s:%d", conf.AppConfig.Server.Host, conf.AppConfig.Server.Port)
+	httpSrv.Addr = fmt.Sprintf("%s:%d", conf.Get().Server.Host, conf.Get().Server.Port)
 	httpSrv.Handler = r
 	go func() {
 		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -50,6 +53,28 @@ func Start() error {
 	return nil
 }
 
+// Close 优雅停机：先拒绝新的中继请求并等待进行中的请求完成，
+// 超过 server.drain_timeout_seconds 后中断剩余请求，最后关闭 HTTP 服务
 func Close() error {
-	return httpSrv.Close()
+	timeout := time.Duration(conf.Get().Server.DrainTimeoutSeconds) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	log.Infof("draining in-flight relay requests (timeout %s)", timeout)
+	if n := relay.Drain(ctx); n > 0 {
+		log.Warnf("drain timeout reached, aborting %d in-flight relay requests", n)
+		relay.Abort()
+		// 等待被中断的请求记录用量
+		abortCtx, abortCancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer abortCancel()
+		if n := relay.Drain(abortCtx); n > 0 {
+			log.Warnf("%d relay requests did not finish after abort", n)
+		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		return httpSrv.Close()
+	}
+	return nil
 }
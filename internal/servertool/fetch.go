@@ -0,0 +1,240 @@
+package servertool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"octopus/internal/conf"
+	"octopus/internal/transformer/model"
+)
+
+// webFetch 抓取网页并转换为纯文本
+type webFetch struct{}
+
+type webFetchArgs struct {
+	URL string `json:"url"`
+}
+
+// fetchClient 不使用环境代理，连接建立前校验目标地址，避免模型通过重定向或域名解析访问内网服务
+var fetchClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: guardAddress,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("too many redirects")
+		}
+		return checkScheme(req.URL)
+	},
+}
+
+// guardAddress 拒绝连接私有、回环、链路本地等地址，server_tools.allow_private 为 true 时不限制
+func guardAddress(network, address string, _ syscall.RawConn) error {
+	if conf.Get().ServerTools.AllowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	addr := addrPort.Addr().Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() || cgnatPrefix.Contains(addr) {
+		return fmt.Errorf("access to private address %s is not allowed", addr)
+	}
+	return nil
+}
+
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+func (webFetch) Name() string { return "web_fetch" }
+
+func (webFetch) Definition() model.Tool {
+	return model.Tool{
+		Type: "function",
+		Function: model.Function{
+			Name:        "web_fetch",
+			Description: "Fetch a web page by URL and return its readable text content.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"The http or https URL to fetch"}},"required":["url"]}`),
+		},
+	}
+}
+
+func (webFetch) Progress(arguments string) string {
+	var args webFetchArgs
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return fmt.Sprintf("Fetching %s", args.URL)
+}
+
+func (webFetch) Execute(ctx context.Context, arguments string) (string, error) {
+	var args webFetchArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	target, err := url.Parse(strings.TrimSpace(args.URL))
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if err := checkScheme(target); err != nil {
+		return "", err
+	}
+
+	cfg := conf.Get().ServerTools
+	if cfg.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.FetchTimeout)*time.Second)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Octopus/1.0)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("fetch failed: %s", resp.Status)
+	}
+
+	maxBytes := cfg.FetchMaxBytes
+	if maxBytes <= 0 {
+		maxBytes = 2 << 20
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	body, err := charset.NewReader(io.LimitReader(resp.Body, maxBytes), contentType)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	switch {
+	case mediaType == "" || mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		text = htmlToText(body)
+	case strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml"):
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", err
+		}
+		text = string(data)
+	default:
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "The page has no readable text content.", nil
+	}
+	return LimitResult(fmt.Sprintf("URL: %s\n\n%s", resp.Request.URL, text)), nil
+}
+
+// skippedElements 转换为文本时忽略的元素
+var skippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "iframe": true, "head": true, "nav": true, "footer": true,
+}
+
+// blockElements 前后换行的元素
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "table": true, "ul": true, "ol": true, "header": true, "main": true,
+}
+
+// htmlToText 提取网页标题与正文文本
+func htmlToText(r io.Reader) string {
+	tokenizer := html.NewTokenizer(r)
+	var sb strings.Builder
+	var title string
+	skip := 0
+	inTitle := false
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			result := collapseLines(sb.String())
+			if title = strings.TrimSpace(title); title != "" {
+				return "Title: " + title + "\n\n" + result
+			}
+			return result
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = true
+			}
+			if skippedElements[tag] && tokenType == html.StartTagToken {
+				skip++
+			}
+			if blockElements[tag] {
+				sb.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if tag == "title" {
+				inTitle = false
+			}
+			if skippedElements[tag] && skip > 0 {
+				skip--
+			}
+			if blockElements[tag] {
+				sb.WriteString("\n")
+			}
+		case html.TextToken:
+			if inTitle {
+				title += string(tokenizer.Text())
+				continue
+			}
+			if skip == 0 {
+				sb.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+// collapseLines 合并连续空白与空行
+func collapseLines(s string) string {
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		result = append(result, line)
+	}
+	return strings.Join(result, "\n")
+}
@@ -0,0 +1,169 @@
+package servertool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGuardAddress(t *testing.T) {
+	tests := []struct {
+		name         string
+		address      string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{name: "public ipv4", address: "93.184.216.34:443"},
+		{name: "public ipv6", address: "[2606:2800:220:1::]:443"},
+		{name: "loopback", address: "127.0.0.1:80", wantErr: true},
+		{name: "ipv6 loopback", address: "[::1]:80", wantErr: true},
+		{name: "private", address: "10.0.0.1:80", wantErr: true},
+		{name: "link local metadata", address: "169.254.169.254:80", wantErr: true},
+		{name: "cgnat", address: "100.64.1.1:80", wantErr: true},
+		{name: "unspecified", address: "0.0.0.0:80", wantErr: true},
+		{name: "ipv4 mapped private", address: "[::ffff:192.168.1.1]:80", wantErr: true},
+		{name: "private allowed", address: "127.0.0.1:80", allowPrivate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.allowPrivate {
+				loadConfig(t, `{"server_tools":{"allow_private":true}}`)
+			} else {
+				loadConfig(t, `{}`)
+			}
+			err := guardAddress("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckScheme(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "http://example.com"},
+		{url: "https://example.com/a?b=c"},
+		{url: "file:///etc/passwd", wantErr: true},
+		{url: "gopher://example.com", wantErr: true},
+		{url: "example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := checkScheme(u); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHtmlToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "title and paragraphs",
+			html:     `<html><head><title> Page </title></head><body><p>first   line</p><p>second</p></body></html>`,
+			expected: "Title: Page\n\nfirst line\nsecond",
+		},
+		{
+			name:     "skipped elements",
+			html:     `<body><nav>menu</nav><script>var a = 1;</script><div>content<br>more</div><footer>foot</footer></body>`,
+			expected: "content\nmore",
+		},
+		{
+			name:     "nested skipped elements",
+			html:     `<div>a<noscript><style>x</style>hidden</noscript>b</div>`,
+			expected: "ab",
+		},
+		{
+			name:     "plain text without tags",
+			html:     "just text\n\n\n  with   spaces ",
+			expected: "just text\nwith spaces",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(strings.NewReader(tt.html)); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWebFetchExecute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, `<html><head><title>Hello</title></head><body><p>world</p></body></html>`)
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "plain body")
+		case "/redirect":
+			http.Redirect(w, r, "/text", http.StatusFound)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte{0x89, 'P', 'N', 'G'})
+		case "/empty":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<script>only()</script>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name         string
+		arguments    string
+		wantErr      string
+		expected     string
+		allowPrivate bool
+	}{
+		{name: "html", arguments: `{"url":"` + srv.URL + `/page"}`, allowPrivate: true, expected: "URL: " + srv.URL + "/page\n\nTitle: Hello\n\nworld"},
+		{name: "follows redirect", arguments: `{"url":"` + srv.URL + `/redirect"}`, allowPrivate: true, expected: "URL: " + srv.URL + "/text\n\nplain body"},
+		{name: "empty page", arguments: `{"url":"` + srv.URL + `/empty"}`, allowPrivate: true, expected: "The page has no readable text content."},
+		{name: "unsupported content type", arguments: `{"url":"` + srv.URL + `/image"}`, allowPrivate: true, wantErr: "unsupported content type"},
+		{name: "not found", arguments: `{"url":"` + srv.URL + `/missing"}`, allowPrivate: true, wantErr: "fetch failed: 404"},
+		{name: "private address blocked", arguments: `{"url":"` + srv.URL + `/page"}`, wantErr: "is not allowed"},
+		{name: "unsupported scheme", arguments: `{"url":"file:///etc/passwd"}`, wantErr: "unsupported url scheme"},
+		{name: "invalid arguments", arguments: `{`, wantErr: "invalid arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.allowPrivate {
+				loadConfig(t, `{"server_tools":{"allow_private":true}}`)
+			} else {
+				loadConfig(t, `{}`)
+			}
+			// 地址在建立连接时校验，复用的空闲连接不会再次校验
+			fetchClient.CloseIdleConnections()
+			got, err := webFetch{}.Execute(context.Background(), tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
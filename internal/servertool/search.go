@@ -0,0 +1,146 @@
+package servertool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"octopus/internal/conf"
+	"octopus/internal/transformer/model"
+)
+
+// webSearch 通过 SearXNG 兼容的搜索服务搜索网页
+type webSearch struct{}
+
+type webSearchArgs struct {
+	Query string `json:"query"`
+}
+
+type searxngResponse struct {
+	Results []struct {
+		Title         string `json:"title"`
+		URL           string `json:"url"`
+		Content       string `json:"content"`
+		PublishedDate string `json:"publishedDate"`
+	} `json:"results"`
+}
+
+var searchClient = &http.Client{}
+
+func (webSearch) Name() string { return "web_search" }
+
+func (webSearch) Definition() model.Tool {
+	return model.Tool{
+		Type: "function",
+		Function: model.Function{
+			Name:        "web_search",
+			Description: "Search the web for up-to-date information. Returns the titles, URLs and snippets of the top results. Use web_fetch to read a result in full.",
+			Parameters:  json.RawMessage(`{"type":"object","properties":{"query":{"type":"string","description":"The search query"}},"required":["query"]}`),
+		},
+	}
+}
+
+func (webSearch) Progress(arguments string) string {
+	var args webSearchArgs
+	_ = json.Unmarshal([]byte(arguments), &args)
+	return fmt.Sprintf("Searching the web for %q", args.Query)
+}
+
+func (webSearch) Execute(ctx context.Context, arguments string) (string, error) {
+	var args webSearchArgs
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return "", fmt.Errorf("invalid arguments: %w", err)
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return "", errors.New("query is required")
+	}
+	cfg := conf.Get().ServerTools
+	if cfg.SearchURL == "" {
+		return "", errors.New("web search is not configured (server_tools.search_url)")
+	}
+
+	searchUrl, err := url.Parse(strings.TrimSuffix(cfg.SearchURL, "/"))
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(searchUrl.Path, "/search") {
+		searchUrl.Path += "/search"
+	}
+	query := searchUrl.Query()
+	query.Set("q", args.Query)
+	query.Set("format", "json")
+	searchUrl.RawQuery = query.Encode()
+
+	if cfg.FetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.FetchTimeout)*time.Second)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, searchUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := searchClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("search failed: %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+
+	var result searxngResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("invalid search response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return "No results found.", nil
+	}
+
+	limit := cfg.SearchResults
+	if limit <= 0 {
+		limit = 5
+	}
+	var sb strings.Builder
+	for i, r := range result.Results {
+		if i >= limit {
+			break
+		}
+		fmt.Fprintf(&sb, "[%d] %s\nURL: %s\n", i+1, r.Title, r.URL)
+		if r.PublishedDate != "" {
+			fmt.Fprintf(&sb, "Published: %s\n", r.PublishedDate)
+		}
+		if r.Content != "" {
+			fmt.Fprintf(&sb, "%s\n", strings.TrimSpace(r.Content))
+		}
+		sb.WriteString("\n")
+	}
+	return LimitResult(strings.TrimSpace(sb.String())), nil
+}
+
+// LimitResult 按 max_result_chars 截断工具结果
+func LimitResult(s string) string {
+	if n := conf.Get().ServerTools.MaxResultChars; n > 0 {
+		return truncate(s, n)
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "...(truncated)"
+}
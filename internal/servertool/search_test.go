@@ -0,0 +1,94 @@
+package servertool
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebSearchExecute(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		switch r.URL.Query().Get("q") {
+		case "none":
+			io.WriteString(w, `{"results":[]}`)
+		case "broken":
+			http.Error(w, "backend unavailable", http.StatusBadGateway)
+		default:
+			io.WriteString(w, `{"results":[
+				{"title":"Go","url":"https://go.dev","content":" The Go language ","publishedDate":"2024-01-01"},
+				{"title":"Tour","url":"https://go.dev/tour"},
+				{"title":"Blog","url":"https://go.dev/blog"}
+			]}`)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		config    string
+		arguments string
+		wantErr   string
+		expected  string
+		query     string
+	}{
+		{
+			name:      "limited results",
+			config:    `{"server_tools":{"search_url":"` + srv.URL + `","search_results":2}}`,
+			arguments: `{"query":"golang"}`,
+			expected:  "[1] Go\nURL: https://go.dev\nPublished: 2024-01-01\nThe Go language\n\n[2] Tour\nURL: https://go.dev/tour",
+			query:     "format=json&q=golang",
+		},
+		{
+			name:      "search path kept",
+			config:    `{"server_tools":{"search_url":"` + srv.URL + `/search/","search_results":1}}`,
+			arguments: `{"query":"golang"}`,
+			expected:  "[1] Go\nURL: https://go.dev\nPublished: 2024-01-01\nThe Go language",
+		},
+		{
+			name:      "no results",
+			config:    `{"server_tools":{"search_url":"` + srv.URL + `"}}`,
+			arguments: `{"query":"none"}`,
+			expected:  "No results found.",
+		},
+		{
+			name:      "truncated result",
+			config:    `{"server_tools":{"search_url":"` + srv.URL + `","max_result_chars":6}}`,
+			arguments: `{"query":"golang"}`,
+			expected:  "[1] Go...(truncated)",
+		},
+		{
+			name:      "upstream error",
+			config:    `{"server_tools":{"search_url":"` + srv.URL + `"}}`,
+			arguments: `{"query":"broken"}`,
+			wantErr:   "search failed: 502: backend unavailable",
+		},
+		{name: "not configured", config: `{}`, arguments: `{"query":"golang"}`, wantErr: "not configured"},
+		{name: "empty query", config: `{}`, arguments: `{"query":"  "}`, wantErr: "query is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadConfig(t, tt.config)
+			got, err := webSearch{}.Execute(context.Background(), tt.arguments)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if tt.query != "" && query != tt.query {
+				t.Errorf("expected query %v, got %v", tt.query, query)
+			}
+		})
+	}
+}
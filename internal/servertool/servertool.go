@@ -0,0 +1,77 @@
+package servertool
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"octopus/internal/transformer/model"
+)
+
+// 服务端工具由网关自行执行：分组启用后，工具定义会加入发往上游的请求，
+// 模型调用这些工具时网关执行并把结果追加到对话中继续请求，直到模型给出最终回答
+
+// Tool 服务端工具
+type Tool interface {
+	// Name 工具名称，与模型调用时的函数名一致
+	Name() string
+	// Definition 发送给模型的函数定义
+	Definition() model.Tool
+	// Progress 返回执行前展示给客户端的进度描述
+	Progress(arguments string) string
+	// Execute 执行工具调用，arguments 为模型生成的 JSON 参数，返回交给模型的结果文本
+	Execute(ctx context.Context, arguments string) (string, error)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Tool)
+)
+
+// Register 注册服务端工具，同名工具会被替换
+func Register(tool Tool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[tool.Name()] = tool
+}
+
+// Get 按名称获取服务端工具
+func Get(name string) (Tool, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	tool, ok := registry[name]
+	return tool, ok
+}
+
+// Names 返回已注册的工具名称
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate 校验分组配置的工具名称
+func Validate(names []string) error {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if _, ok := Get(name); !ok {
+			return fmt.Errorf("unknown server tool %q, available: %v", name, Names())
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("duplicate server tool %q", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
+func init() {
+	Register(webSearch{})
+	Register(webFetch{})
+}
@@ -0,0 +1,72 @@
+package servertool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"octopus/internal/conf"
+)
+
+// loadConfig 写入配置文件并加载为当前配置
+func loadConfig(t *testing.T, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := conf.Load(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		wantErr string
+	}{
+		{name: "empty", names: nil},
+		{name: "builtin tools", names: []string{"web_search", "web_fetch"}},
+		{name: "unknown", names: []string{"web_search", "shell"}, wantErr: `unknown server tool "shell"`},
+		{name: "duplicate", names: []string{"web_fetch", "web_fetch"}, wantErr: `duplicate server tool "web_fetch"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestLimitResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      int
+		input    string
+		expected string
+	}{
+		{name: "unlimited", max: 0, input: "abcdef", expected: "abcdef"},
+		{name: "under limit", max: 10, input: "abcdef", expected: "abcdef"},
+		{name: "over limit", max: 3, input: "abcdef", expected: "abc...(truncated)"},
+		{name: "counts runes", max: 2, input: "你好世界", expected: "你好...(truncated)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadConfig(t, fmt.Sprintf(`{"server_tools":{"max_result_chars":%d}}`, tt.max))
+			if got := LimitResult(tt.input); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"octopus/internal/alert"
+	"octopus/internal/conf"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/update"
+	"octopus/internal/utils/log"
+)
+
+// AlertCheckTask 评估错误率、Key 失败、预算等状态类告警
+func AlertCheckTask() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	alert.Check(ctx)
+}
+
+// VersionCheckTask 检查是否有新版本，仅在配置了 new_version 规则时请求
+func VersionCheckTask() {
+	if conf.Version == "dev" || len(op.AlertRuleListByEvent(model.AlertEventNewVersion)) == 0 {
+		return
+	}
+	latest, err := update.GetLatestInfo()
+	if err != nil {
+		log.Warnf("failed to check latest version: %v", err)
+		return
+	}
+	if latest.TagName == "" || strings.TrimPrefix(latest.TagName, "v") == strings.TrimPrefix(conf.Version, "v") {
+		return
+	}
+	alert.Trigger(model.AlertEventNewVersion, "version:"+latest.TagName,
+		fmt.Sprintf("New version %s is available", latest.TagName),
+		fmt.Sprintf("Current version %s, latest version %s published at %s.", conf.Version, latest.TagName, latest.PublishedAt))
+}
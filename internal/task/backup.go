@@ -0,0 +1,25 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"octopus/internal/alert"
+	"octopus/internal/backup"
+	"octopus/internal/conf"
+	"octopus/internal/model"
+	"octopus/internal/utils/log"
+)
+
+// BackupTask 按配置生成备份并轮转旧备份，失败时触发 backup_failed 告警
+func BackupTask() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	res, err := backup.Run(ctx, conf.Get().Backup)
+	if err != nil {
+		log.Warnf("scheduled backup failed: %v", err)
+		alert.Trigger(model.AlertEventBackupFailed, "backup", "Scheduled backup failed", err.Error())
+		return
+	}
+	log.Infof("backup %s written to %s (%d bytes, %d expired removed)", res.Object.Name, res.Storage, res.Object.Size, len(res.Deleted))
+}
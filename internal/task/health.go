@@ -0,0 +1,74 @@
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"octopus/internal/helper"
+	"octopus/internal/model"
+	"octopus/internal/op"
+	"octopus/internal/transformer/outbound"
+	"octopus/internal/utils/log"
+)
+
+// 同时探测的 key 数量上限
+const keyHealthCheckConcurrency = 4
+
+// ChannelKeyHealthTask 探测所有渠道 key 并记录结果。开启 key_health_auto_disable 时自动禁用已失效的 key，
+// 自动禁用的 key 恢复后重新启用
+func ChannelKeyHealthTask() {
+	log.Debugf("channel key health task started")
+	startTime := time.Now()
+	defer func() {
+		log.Debugf("channel key health task finished, update time: %s", time.Since(startTime))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		log.Errorf("failed to list channels: %v", err)
+		return
+	}
+
+	autoDisable, err := op.SettingGetBool(model.SettingKeyKeyHealthAutoDisable)
+	if err != nil {
+		log.Warnf("failed to get key health auto disable setting: %v", err)
+	}
+
+	sem := make(chan struct{}, keyHealthCheckConcurrency)
+	var wg sync.WaitGroup
+	for _, channel := range channels {
+		// antigravity 使用 OAuth 凭据，无法通过模型列表探测
+		if !channel.Enabled || channel.Type == outbound.OutboundTypeAntigravity {
+			continue
+		}
+		for _, key := range channel.Keys {
+			// 手动禁用的 key 不参与检查
+			if key.ChannelKey == "" || (!key.Enabled && !key.AutoDisabled) {
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(channel model.Channel, key model.ChannelKey) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				checkChannelKey(ctx, channel, key, autoDisable)
+			}(channel, key)
+		}
+	}
+	wg.Wait()
+}
+
+func checkChannelKey(ctx context.Context, channel model.Channel, key model.ChannelKey, autoDisable bool) {
+	probeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	health, reason := helper.ProbeChannelKey(probeCtx, channel, key)
+
+	// 探测可能持续较长时间，启用状态由 op 根据更新时的最新状态计算
+	if err := op.ChannelKeyHealthUpdate(ctx, key.ID, health, reason, autoDisable); err != nil {
+		log.Warnf("failed to update channel key health (channel=%d, key=%d): %v", channel.ID, key.ID, err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"octopus/internal/alert"
+	"octopus/internal/conf"
 	"octopus/internal/model"
 	"octopus/internal/op"
 	"octopus/internal/price"
@@ -11,12 +13,17 @@ import (
 )
 
 const (
-	TaskPriceUpdate  = "price_update"
-	TaskStatsSave    = "stats_save"
-	TaskRelayLogSave = "relay_log_save"
-	TaskSyncLLM      = "sync_llm"
-	TaskCleanLLM     = "clean_llm"
-	TaskBaseUrlDelay = "base_url_delay"
+	TaskPriceUpdate   = "price_update"
+	TaskStatsSave     = "stats_save"
+	TaskRelayLogSave  = "relay_log_save"
+	TaskSyncLLM       = "sync_llm"
+	TaskCleanLLM      = "clean_llm"
+	TaskBaseUrlDelay  = "base_url_delay"
+	TaskAuditLogClean = "audit_log_clean"
+	TaskAlertCheck    = "alert_check"
+	TaskVersionCheck  = "version_check"
+	TaskBackup        = "backup"
+	TaskClusterSync   = "cluster_sync"
 )
 
 func Init() {
@@ -26,13 +33,38 @@ func Init() {
 		return
 	}
 	priceUpdateInterval := time.Duration(priceUpdateIntervalHours) * time.Hour
+	// 集群模式下同步配置变更并选举主节点，以下 RegisterLeader 注册的任务只在主节点执行；
+	// 统计、日志落库与延迟检测等针对本实例内存状态的任务在每个实例上执行
+	if op.ClusterEnabled() {
+		Register(TaskClusterSync, time.Duration(conf.Get().Cluster.SyncIntervalSeconds)*time.Second, false, op.ClusterSyncTask)
+	}
+
 	// 注册价格更新任务
-	Register(string(model.SettingKeyModelInfoUpdateInterval), priceUpdateInterval, true, func() {
+	RegisterLeader(string(model.SettingKeyModelInfoUpdateInterval), priceUpdateInterval, true, func() {
 		if err := price.UpdateLLMPrice(context.Background()); err != nil {
 			log.Warnf("failed to update price info: %v", err)
+			alert.Trigger(model.AlertEventPriceSyncFailed, "price", "Model price sync failed", err.Error())
 		}
 	})
 
+	// 注册告警检查任务，冷却状态保存在内存中，只在主节点评估以免每个实例各发一次；
+	// 错误率按主节点自身转发的请求计算
+	RegisterLeader(TaskAlertCheck, 1*time.Minute, false, AlertCheckTask)
+	RegisterLeader(TaskVersionCheck, 6*time.Hour, true, VersionCheckTask)
+
+	// 注册定时备份任务
+	if conf.Get().Backup.Enabled {
+		RegisterLeader(TaskBackup, time.Duration(conf.Get().Backup.IntervalHours)*time.Hour, false, BackupTask)
+	}
+
+	// 注册渠道 key 健康检查任务
+	keyHealthCheckMinutes, err := op.SettingGetInt(model.SettingKeyKeyHealthCheckInterval)
+	if err != nil {
+		log.Warnf("failed to get key health check interval: %v", err)
+		return
+	}
+	RegisterLeader(string(model.SettingKeyKeyHealthCheckInterval), time.Duration(keyHealthCheckMinutes)*time.Minute, false, ChannelKeyHealthTask)
+
 	// 注册基础URL延迟任务
 	Register(TaskBaseUrlDelay, 1*time.Hour, true, ChannelBaseUrlDelayTask)
 
@@ -43,7 +75,7 @@ func Init() {
 		return
 	}
 	syncLLMInterval := time.Duration(syncLLMIntervalHours) * time.Hour
-	Register(string(model.SettingKeySyncLLMInterval), syncLLMInterval, true, SyncModelsTask)
+	RegisterLeader(string(model.SettingKeySyncLLMInterval), syncLLMInterval, true, SyncModelsTask)
 
 	// 注册统计保存任务
 	statsSaveIntervalMinutes, err := op.SettingGetInt(model.SettingKeyStatsSaveInterval)
@@ -53,6 +85,19 @@ func Init() {
 	}
 	statsSaveInterval := time.Duration(statsSaveIntervalMinutes) * time.Minute
 	Register(TaskStatsSave, statsSaveInterval, false, op.StatsSaveDBTask)
+	// 注册审计日志清理任务
+	RegisterLeader(TaskAuditLogClean, 24*time.Hour, true, func() {
+		if err := op.AuditLogCleanupTask(context.Background()); err != nil {
+			log.Warnf("audit log cleanup task failed: %v", err)
+		}
+	})
+	// 注册缓存一致性检查任务，每个实例各自检查自己的内存缓存
+	cacheCheckMinutes, err := op.SettingGetInt(model.SettingKeyCacheCheckInterval)
+	if err != nil {
+		log.Warnf("failed to get cache check interval: %v", err)
+		return
+	}
+	Register(string(model.SettingKeyCacheCheckInterval), time.Duration(cacheCheckMinutes)*time.Minute, false, op.CacheCheckTask)
 	// 注册中继日志保存任务
 	Register(TaskRelayLogSave, 10*time.Minute, false, func() {
 		if err := op.RelayLogSaveDBTask(context.Background()); err != nil {
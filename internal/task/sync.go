@@ -2,13 +2,14 @@ package task
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
+	"octopus/internal/alert"
 	"octopus/internal/helper"
 	"octopus/internal/model"
 	"octopus/internal/op"
-	"octopus/internal/utils/diff"
 	"octopus/internal/utils/log"
 	"octopus/internal/utils/xstrings"
 )
@@ -29,6 +30,9 @@ func SyncModelsTask() {
 		log.Errorf("failed to list channels: %v", err)
 		return
 	}
+	reviewEnabled, _ := op.SettingGetBool(model.SettingKeyModelSyncReviewEnabled)
+	gracePeriodHours, _ := op.SettingGetInt(model.SettingKeyModelSyncGracePeriod)
+	protectDays, _ := op.SettingGetInt(model.SettingKeyModelSyncProtectDays)
 	totalNewModels := make([]string, 0, 128)
 	seenTotalNewModels := make(map[string]struct{}, 128)
 	for _, channel := range channels {
@@ -38,9 +42,10 @@ func SyncModelsTask() {
 		fetchModels, err := helper.FetchModels(ctx, channel)
 		if err != nil {
 			log.Warnf("failed to fetch models for channel %s: %v", channel.Name, err)
+			alert.Trigger(model.AlertEventModelSyncFailed, fmt.Sprintf("channel:%d", channel.ID),
+				fmt.Sprintf("Model sync failed for channel %s", channel.Name), err.Error())
 			continue
 		}
-		oldModels := xstrings.SplitTrimCompact(",", channel.Model)
 		newModels := xstrings.TrimCompact(fetchModels)
 		for _, m := range newModels {
 			m = strings.TrimSpace(m)
@@ -54,35 +59,45 @@ func SyncModelsTask() {
 			seenTotalNewModels[m] = struct{}{}
 			totalNewModels = append(totalNewModels, m)
 		}
-		deletedModels, addedModels := diff.Diff(oldModels, newModels)
-		if len(deletedModels) > 0 || len(addedModels) > 0 {
-			fetchModelStr := strings.Join(newModels, ",")
-			if _, err := op.ChannelUpdate(&model.ChannelUpdateRequest{
-				ID:    channel.ID,
-				Model: &fetchModelStr,
-			}, ctx); err != nil {
-				log.Errorf("failed to update channel %s: %v", channel.Name, err)
-				continue
-			}
+		plan, err := helper.ModelSyncPlan(ctx, &channel, newModels, protectDays)
+		if err != nil {
+			log.Errorf("failed to plan model sync for channel %s: %v", channel.Name, err)
+			continue
 		}
-		// 批量删除消失的模型对应的 GroupItem
-		if len(deletedModels) > 0 {
-			log.Infof("deleted channel %s models: %v", channel.Name, deletedModels)
-			keys := make([]model.GroupIDAndLLMName, len(deletedModels))
-			for i, m := range deletedModels {
-				keys[i] = model.GroupIDAndLLMName{ChannelID: channel.ID, ModelName: m}
+		if reviewEnabled && !plan.Empty() {
+			// 审核模式下只记录变更，等待审核或宽限期结束后再应用
+			if err := op.ModelSyncPendingSave(plan, ctx); err != nil {
+				log.Errorf("failed to save model sync pending for channel %s: %v", channel.Name, err)
+			} else if plan.Rejected {
+				log.Debugf("channel %s model changes were rejected, skipping: added %v, removed %v", channel.Name, plan.Added, plan.Removed)
+			} else {
+				log.Infof("channel %s model changes pending review: added %v, removed %v", channel.Name, plan.Added, plan.Removed)
+			}
+		} else {
+			if err := op.ModelSyncPendingDelByChannel(channel.ID, ctx); err != nil {
+				log.Warnf("failed to delete model sync pending for channel %s: %v", channel.Name, err)
 			}
-			if err := op.GroupItemBatchDelByChannelAndModels(keys, ctx); err != nil {
-				log.Errorf("failed to batch delete group items for channel %s: %v", channel.Name, err)
+			if len(plan.Protected) > 0 {
+				log.Infof("channel %s keeps protected models: %v", channel.Name, plan.Protected)
+			}
+			if err := helper.ModelSyncApply(ctx, plan, false); err != nil {
+				log.Errorf("failed to apply model sync for channel %s: %v", channel.Name, err)
+				continue
 			}
 		}
 
 		// 自动分组
 		if len(newModels) > 0 {
-			helper.ChannelAutoGroup(&channel, ctx)
+			if ch, err := op.ChannelGet(channel.ID, ctx); err == nil {
+				helper.ChannelAutoGroup(ch, ctx)
+			}
 		}
 	}
 
+	if reviewEnabled && gracePeriodHours > 0 {
+		applyExpiredModelSyncPending(ctx, time.Duration(gracePeriodHours)*time.Hour)
+	}
+
 	// TODO: 在新架构中，模型绑定到渠道，全局模型同步逻辑需要重新设计
 	// 暂时注释掉全局模型价格同步逻辑
 	/*
@@ -111,6 +126,60 @@ func SyncModelsTask() {
 	lastSyncModelsTime = time.Now()
 }
 
+// SyncModelsDryRun 拉取所有自动同步渠道的模型列表，只返回将要产生的变更，不做任何修改
+func SyncModelsDryRun(ctx context.Context) ([]model.ModelSyncPending, error) {
+	channels, err := op.ChannelList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	protectDays, _ := op.SettingGetInt(model.SettingKeyModelSyncProtectDays)
+	plans := make([]model.ModelSyncPending, 0)
+	for _, channel := range channels {
+		if !channel.AutoSync {
+			continue
+		}
+		fetchModels, err := helper.FetchModels(ctx, channel)
+		if err != nil {
+			log.Warnf("failed to fetch models for channel %s: %v", channel.Name, err)
+			continue
+		}
+		plan, err := helper.ModelSyncPlan(ctx, &channel, xstrings.TrimCompact(fetchModels), protectDays)
+		if err != nil {
+			return nil, err
+		}
+		if !plan.Empty() {
+			plans = append(plans, *plan)
+		}
+	}
+	return plans, nil
+}
+
+// applyExpiredModelSyncPending 应用超过宽限期的待审核变更，受保护的模型不会被删除
+func applyExpiredModelSyncPending(ctx context.Context, gracePeriod time.Duration) {
+	pendings, err := op.ModelSyncPendingList(ctx)
+	if err != nil {
+		log.Errorf("failed to list model sync pending: %v", err)
+		return
+	}
+	deadline := time.Now().Add(-gracePeriod).Unix()
+	for i := range pendings {
+		p := &pendings[i]
+		if p.Rejected || p.CreatedAt > deadline {
+			continue
+		}
+		if err := helper.ModelSyncApply(ctx, p, false); err != nil {
+			log.Errorf("failed to apply model sync pending for channel %d: %v", p.ChannelID, err)
+			continue
+		}
+		if err := op.ModelSyncPendingResolve(p, true, ctx); err != nil {
+			log.Errorf("failed to resolve model sync pending for channel %d: %v", p.ChannelID, err)
+		}
+		if ch, err := op.ChannelGet(p.ChannelID, ctx); err == nil {
+			helper.ChannelAutoGroup(ch, ctx)
+		}
+	}
+}
+
 func GetLastSyncModelsTime() time.Time {
 	return lastSyncModelsTime
 }
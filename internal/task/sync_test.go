@@ -0,0 +1,70 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"octopus/internal/model"
+	"octopus/internal/op"
+)
+
+func TestApplyExpiredModelSyncPendingGracePeriod(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+	expired := time.Now().Add(-48 * time.Hour).Unix()
+
+	tests := []struct {
+		name        string
+		first, next []string
+		reject      bool // 第二次同步前拒绝第一次的变更
+		wantApplied bool
+		wantListed  bool
+	}{
+		{name: "unchanged diff past grace period", first: []string{"b"}, next: []string{"b"}, wantApplied: true},
+		{name: "changed diff restarts grace period", first: []string{"b"}, next: []string{"b", "c"}, wantListed: true},
+		{name: "rejected diff is not applied", first: []string{"b"}, next: []string{"b"}, reject: true},
+		{name: "changed diff after rejection waits for review", first: []string{"b"}, next: []string{"c"}, reject: true, wantListed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := &model.Channel{Name: tt.name, Model: "a", BaseUrls: []model.BaseUrl{{URL: "https://example.com"}}}
+			if err := op.ChannelCreate(channel, ctx); err != nil {
+				t.Fatal(err)
+			}
+			first := &model.ModelSyncPending{ChannelID: channel.ID, Added: tt.first, CreatedAt: expired}
+			if err := op.ModelSyncPendingSave(first, ctx); err != nil {
+				t.Fatal(err)
+			}
+			if tt.reject {
+				if err := op.ModelSyncPendingResolve(first, false, ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := op.ModelSyncPendingSave(&model.ModelSyncPending{ChannelID: channel.ID, Added: tt.next}, ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			applyExpiredModelSyncPending(ctx, 24*time.Hour)
+
+			got, err := op.ChannelGet(channel.ID, ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if applied := got.Model != "a"; applied != tt.wantApplied {
+				t.Errorf("expected applied %v, got model %q", tt.wantApplied, got.Model)
+			}
+			list, err := op.ModelSyncPendingList(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			listed := false
+			for _, p := range list {
+				listed = listed || p.ChannelID == channel.ID
+			}
+			if listed != tt.wantListed {
+				t.Errorf("expected listed %v, got %v", tt.wantListed, listed)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"sync"
 	"time"
 
+	"octopus/internal/op"
 	"octopus/internal/utils/log"
 )
 
@@ -12,6 +13,7 @@ type taskEntry struct {
 	interval   time.Duration
 	fn         func()
 	runOnStart bool
+	leaderOnly bool
 	ticker     *time.Ticker
 	stopCh     chan struct{}
 	updateCh   chan time.Duration
@@ -50,6 +52,16 @@ func Register(name string, interval time.Duration, runOnStart bool, fn func()) {
 	log.Debugf("task %s registered with interval %v, runOnStart: %v", name, interval, runOnStart)
 }
 
+// RegisterLeader 注册一个只在集群主节点上执行的定时任务，非集群模式下与 Register 相同
+func RegisterLeader(name string, interval time.Duration, runOnStart bool, fn func()) {
+	Register(name, interval, runOnStart, fn)
+	tasksMu.Lock()
+	defer tasksMu.Unlock()
+	if entry, exists := tasks[name]; exists {
+		entry.leaderOnly = true
+	}
+}
+
 // Update 更新任务的执行间隔
 // 当 interval 为 0 时，删除任务
 func Update(name string, interval time.Duration) {
@@ -102,9 +114,17 @@ func runTask(entry *taskEntry) {
 		return
 	}
 
+	run := func() {
+		if entry.leaderOnly && !op.ClusterIsLeader() {
+			log.Debugf("task %s skipped: not the cluster leader", entry.name)
+			return
+		}
+		entry.fn()
+	}
+
 	// 根据配置决定是否在启动时立即执行
 	if entry.runOnStart {
-		go entry.fn()
+		go run()
 	}
 
 	entry.ticker = time.NewTicker(entry.interval)
@@ -113,7 +133,7 @@ func runTask(entry *taskEntry) {
 	for {
 		select {
 		case <-entry.ticker.C:
-			go entry.fn()
+			go run()
 		case newInterval := <-entry.updateCh:
 			entry.ticker.Stop()
 			entry.interval = newInterval
@@ -0,0 +1,21 @@
+package task
+
+import (
+	"path/filepath"
+	"testing"
+
+	"octopus/internal/db"
+	"octopus/internal/secret"
+)
+
+// setupTestDB 在临时目录中初始化 sqlite 数据库与密钥，测试结束后关闭
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	if err := secret.Init("test passphrase", ""); err != nil {
+		t.Fatalf("failed to init secret: %v", err)
+	}
+	if err := db.InitDB("sqlite", filepath.Join(t.TempDir(), "test.db"), false); err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"octopus/internal/transformer/model"
 	"octopus/internal/utils/tokenizer"
@@ -251,6 +252,11 @@ func (i *MessagesInbound) TransformRequest(ctx context.Context, body []byte) (*m
 	if len(anthropicReq.Tools) > 0 {
 		tools := make([]model.Tool, 0, len(anthropicReq.Tools))
 		for _, tool := range anthropicReq.Tools {
+			// 服务端工具（如 web_search_20250305）没有 input_schema，按类型记录，由网关的服务端工具实现或丢弃
+			if serverType := serverToolType(tool.Type); serverType != "" {
+				tools = append(tools, model.Tool{Type: serverType, Function: model.Function{Name: tool.Name}})
+				continue
+			}
 			llmTool := model.Tool{
 				Type: "function",
 				Function: model.Function{
@@ -509,6 +515,23 @@ func (i *MessagesInbound) TransformStream(ctx context.Context, stream *model.Int
 				i.contentIndex++
 			}
 
+			// If the text content has started before the thinking content (e.g. progress of server tools), we need to stop it
+			if i.hasTextContentStarted {
+				i.hasTextContentStarted = false
+
+				stopEvent := StreamEvent{
+					Type:  "content_block_stop",
+					Index: &i.contentIndex,
+				}
+				data, err := json.Marshal(stopEvent)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal content_block_stop event: %w", err)
+				}
+				events = append(events, formatSSEEvent("content_block_stop", data))
+
+				i.contentIndex++
+			}
+
 			// Generate content_block_start if this is the first thinking content
 			if !i.hasThinkingContentStarted {
 				i.hasThinkingContentStarted = true
@@ -993,3 +1016,15 @@ func mergeToolCall(toolCalls []model.ToolCall, delta model.ToolCall) []model.Too
 func formatSSEEvent(eventType string, data []byte) []byte {
 	return []byte(fmt.Sprintf("event:%s\ndata:%s\n\n", eventType, string(data)))
 }
+
+// serverToolType 将 Anthropic 服务端工具的版本化类型映射为内部类型，客户端工具返回空
+func serverToolType(toolType string) string {
+	switch {
+	case strings.HasPrefix(toolType, "web_search_"):
+		return "web_search"
+	case strings.HasPrefix(toolType, "web_fetch_"):
+		return "web_fetch"
+	default:
+		return ""
+	}
+}
@@ -151,8 +151,8 @@ type ToolChoice struct {
 // Tool represents a tool definition for Anthropic API.
 type Tool struct {
 	// Ensure the omitempty, otherwise it will be sent empty string to the API, will cause some providers ignore the tool.
-	// For now, we only support function (client tool or custom tool in anthropic) tool, so we can just omit the type.
-	// Type         string          `json:"type,omitempty"`
+	// Only server tools (e.g. web_search_20250305) carry a type, client tools omit it.
+	Type         string          `json:"type,omitempty"`
 	Name         string          `json:"name"`
 	Description  string          `json:"description"`
 	InputSchema  json.RawMessage `json:"input_schema"`
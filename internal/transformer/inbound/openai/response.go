@@ -36,6 +36,7 @@ type ResponseInbound struct {
 	// Content accumulation
 	accumulatedText      strings.Builder
 	accumulatedReasoning strings.Builder
+	reasoningSignature   string // 作为 reasoning 项的 encrypted_content 返回，客户端在后续请求中带回
 
 	// Tool call tracking
 	toolCalls           map[int]*model.ToolCall
@@ -147,6 +148,9 @@ func (i *ResponseInbound) TransformStream(ctx context.Context, stream *model.Int
 		if choice.Delta != nil && choice.Delta.ReasoningContent != nil && *choice.Delta.ReasoningContent != "" {
 			events = append(events, i.handleReasoningContent(choice.Delta.ReasoningContent)...)
 		}
+		if choice.Delta != nil && choice.Delta.ReasoningSignature != nil && i.hasReasoningItemStarted {
+			i.reasoningSignature += *choice.Delta.ReasoningSignature
+		}
 
 		// Handle text content delta
 		if choice.Delta != nil && choice.Delta.Content.Content != nil && *choice.Delta.Content.Content != "" {
@@ -438,6 +442,10 @@ func (i *ResponseInbound) closeReasoningItem() [][]byte {
 			Text: fullReasoning,
 		}},
 	}
+	if i.reasoningSignature != "" {
+		item.EncryptedContent = lo.ToPtr(i.reasoningSignature)
+		i.reasoningSignature = ""
+	}
 
 	events = append(events, i.enqueueEvent(&ResponsesStreamEvent{
 		Type:        "response.output_item.done",
@@ -876,9 +884,11 @@ type ResponsesTextOptions struct {
 }
 
 type ResponsesTextFormat struct {
-	Type   string          `json:"type,omitempty"`
-	Name   string          `json:"name,omitempty"`
-	Schema json.RawMessage `json:"schema,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
 }
 
 type ResponsesReasoning struct {
@@ -1005,6 +1015,14 @@ func convertToInternalRequest(req *ResponsesRequest) (*model.InternalLLMRequest,
 		chatReq.ResponseFormat = &model.ResponseFormat{
 			Type: req.Text.Format.Type,
 		}
+		if req.Text.Format.Type == "json_schema" {
+			chatReq.ResponseFormat.JSONSchema, _ = json.Marshal(model.ResponseFormatJSONSchema{
+				Name:        req.Text.Format.Name,
+				Description: req.Text.Format.Description,
+				Schema:      req.Text.Format.Schema,
+				Strict:      req.Text.Format.Strict,
+			})
+		}
 	}
 
 	return chatReq, nil
@@ -1048,14 +1066,36 @@ func convertInputToMessages(input *ResponsesInput) ([]model.Message, error) {
 
 	// Array of items
 	messages := make([]model.Message, 0, len(input.Items))
+	// reasoning 项与随后的回复属于同一条 assistant 消息，思考内容与签名需要跟随该消息发给上游
+	var reasoning *model.Message
 	for _, item := range input.Items {
 		msg, err := convertItemToMessage(&item)
 		if err != nil {
 			return nil, err
 		}
-		if msg != nil {
-			messages = append(messages, *msg)
+		if msg == nil {
+			continue
+		}
+		if item.Type == "reasoning" {
+			if reasoning != nil {
+				messages = append(messages, *reasoning)
+			}
+			reasoning = msg
+			continue
+		}
+		if reasoning != nil {
+			if msg.Role == "assistant" && msg.ReasoningContent == nil {
+				msg.ReasoningContent = reasoning.ReasoningContent
+				msg.ReasoningSignature = reasoning.ReasoningSignature
+			} else {
+				messages = append(messages, *reasoning)
+			}
+			reasoning = nil
 		}
+		messages = append(messages, *msg)
+	}
+	if reasoning != nil {
+		messages = append(messages, *reasoning)
 	}
 
 	return messages, nil
@@ -1223,6 +1263,12 @@ func convertToolsToInternal(tools []ResponsesTool) ([]model.Tool, error) {
 				},
 			})
 
+		case "web_search", "web_search_preview":
+			result = append(result, model.Tool{
+				Type:     "web_search",
+				Function: model.Function{Name: "web_search"},
+			})
+
 		case "image_generation":
 			result = append(result, model.Tool{
 				Type: "image_generation",
@@ -1278,6 +1324,7 @@ func convertToResponsesAPIResponse(resp *model.InternalLLMResponse) *ResponsesRe
 						Text: *message.ReasoningContent,
 					},
 				},
+				EncryptedContent: message.ReasoningSignature,
 			})
 		}
 
@@ -0,0 +1,68 @@
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertInputReasoning(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string // 各消息的 角色:思考内容:签名
+	}{
+		{
+			name: "merged into the reply",
+			input: `[{"role":"user","content":"q"},
+				{"type":"reasoning","summary":[{"type":"summary_text","text":"r"}],"encrypted_content":"sig"},
+				{"type":"message","role":"assistant","content":[{"type":"output_text","text":"a"}]}]`,
+			expected: []string{"user::", "assistant:r:sig"},
+		},
+		{
+			name: "merged into a function call",
+			input: `[{"type":"reasoning","summary":[{"type":"summary_text","text":"r"}],"encrypted_content":"sig"},
+				{"type":"function_call","call_id":"c1","name":"f","arguments":"{}"},
+				{"type":"function_call_output","call_id":"c1","output":"ok"}]`,
+			expected: []string{"assistant:r:sig", "tool::"},
+		},
+		{
+			name: "followed by a user message",
+			input: `[{"type":"reasoning","summary":[{"type":"summary_text","text":"r"}]},
+				{"role":"user","content":"q"}]`,
+			expected: []string{"assistant:r:", "user::"},
+		},
+		{
+			name:     "trailing reasoning",
+			input:    `[{"role":"user","content":"q"},{"type":"reasoning","summary":[],"encrypted_content":"sig"}]`,
+			expected: []string{"user::", "assistant::sig"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var input ResponsesInput
+			if err := json.Unmarshal([]byte(tt.input), &input); err != nil {
+				t.Fatal(err)
+			}
+			messages, err := convertInputToMessages(&input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(messages))
+			for _, msg := range messages {
+				signature := ""
+				if msg.ReasoningSignature != nil {
+					signature = *msg.ReasoningSignature
+				}
+				got = append(got, msg.Role+":"+msg.GetReasoningContent()+":"+signature)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
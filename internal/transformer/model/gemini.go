@@ -9,6 +9,20 @@ type GeminiGenerateContentRequest struct {
 	ToolConfig        *GeminiToolConfig       `json:"toolConfig,omitempty"`
 	GenerationConfig  *GeminiGenerationConfig `json:"generationConfig,omitempty"`
 	SafetySettings    []*GeminiSafetySetting  `json:"safetySettings,omitempty"`
+	CachedContent     string                  `json:"cachedContent,omitempty"`
+}
+
+// GeminiCachedContent is the request/response body of the cachedContents API.
+// See https://ai.google.dev/api/caching
+type GeminiCachedContent struct {
+	Name              string            `json:"name,omitempty"`
+	Model             string            `json:"model,omitempty"`
+	Contents          []*GeminiContent  `json:"contents,omitempty"`
+	SystemInstruction *GeminiContent    `json:"systemInstruction,omitempty"`
+	Tools             []*GeminiTool     `json:"tools,omitempty"`
+	ToolConfig        *GeminiToolConfig `json:"toolConfig,omitempty"`
+	TTL               string            `json:"ttl,omitempty"`
+	ExpireTime        string            `json:"expireTime,omitempty"`
 }
 
 // GeminiToolConfig configures tool/function calling behavior.
@@ -95,15 +109,16 @@ type GeminiCodeExecution struct{}
 
 // GeminiGenerationConfig controls generation parameters
 type GeminiGenerationConfig struct {
-	Temperature        *float64      `json:"temperature,omitempty"`
-	TopP               *float64      `json:"topP,omitempty"`
-	TopK               *int          `json:"topK,omitempty"`
-	CandidateCount     int           `json:"candidateCount,omitempty"`
-	MaxOutputTokens    int           `json:"maxOutputTokens,omitempty"`
-	StopSequences      []string      `json:"stopSequences,omitempty"`
-	ResponseMimeType   string        `json:"responseMimeType,omitempty"`
-	ResponseSchema     *GeminiSchema `json:"responseSchema,omitempty"`
-	ResponseModalities []string      `json:"responseModalities,omitempty"`
+	Temperature        *float64       `json:"temperature,omitempty"`
+	TopP               *float64       `json:"topP,omitempty"`
+	TopK               *int           `json:"topK,omitempty"`
+	CandidateCount     int            `json:"candidateCount,omitempty"`
+	MaxOutputTokens    int            `json:"maxOutputTokens,omitempty"`
+	StopSequences      []string       `json:"stopSequences,omitempty"`
+	ResponseMimeType   string         `json:"responseMimeType,omitempty"`
+	ResponseSchema     *GeminiSchema  `json:"responseSchema,omitempty"`
+	ResponseJsonSchema map[string]any `json:"responseJsonSchema,omitempty"` // standard JSON Schema, alternative to ResponseSchema
+	ResponseModalities []string       `json:"responseModalities,omitempty"`
 
 	// ThinkingConfig is the thinking features configuration
 	ThinkingConfig *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
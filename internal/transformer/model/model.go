@@ -287,6 +287,18 @@ func (r *InternalLLMRequest) ClearHelpFields() {
 	r.Include = nil
 }
 
+// ThinkingBudget 返回思考预算：请求指定的 ReasoningBudget 优先，否则按 ReasoningEffort 从 efforts 中查找，
+// 未知的档位返回 fallback。各上游的默认映射不同，由出站各自提供
+func (r *InternalLLMRequest) ThinkingBudget(efforts map[string]int64, fallback int64) int64 {
+	if r.ReasoningBudget != nil {
+		return *r.ReasoningBudget
+	}
+	if budget, ok := efforts[strings.ToLower(r.ReasoningEffort)]; ok {
+		return budget
+	}
+	return fallback
+}
+
 func (r *InternalLLMRequest) IsImageGenerationRequest() bool {
 	return len(r.Modalities) > 0 && slices.Contains(r.Modalities, "image")
 }
@@ -497,10 +509,30 @@ type File struct {
 type ResponseFormat struct {
 	// Any of "json_schema", "json_object", "text".
 	Type string `json:"type"`
-	// TODO: Schema
+	// JSONSchema is the raw "json_schema" object, see ResponseFormatJSONSchema.
 	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
 }
 
+// ResponseFormatJSONSchema is the OpenAI "json_schema" response format object.
+type ResponseFormatJSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+// GetJSONSchema parses the json_schema object, returns nil if it is absent or invalid.
+func (f *ResponseFormat) GetJSONSchema() *ResponseFormatJSONSchema {
+	if f == nil || len(f.JSONSchema) == 0 {
+		return nil
+	}
+	var schema ResponseFormatJSONSchema
+	if err := json.Unmarshal(f.JSONSchema, &schema); err != nil {
+		return nil
+	}
+	return &schema
+}
+
 // Response is the unified response model.
 // To reduce the work of converting the response, we use the OpenAI response format.
 // And other llm provider should convert the response to this format.
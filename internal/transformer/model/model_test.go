@@ -0,0 +1,25 @@
+package model
+
+import "testing"
+
+func TestThinkingBudget(t *testing.T) {
+	efforts := map[string]int64{"low": 1024, "high": 32768}
+	budget := int64(5000)
+	tests := []struct {
+		name     string
+		request  InternalLLMRequest
+		expected int64
+	}{
+		{name: "explicit budget wins", request: InternalLLMRequest{ReasoningEffort: "low", ReasoningBudget: &budget}, expected: 5000},
+		{name: "effort", request: InternalLLMRequest{ReasoningEffort: "high"}, expected: 32768},
+		{name: "effort is case insensitive", request: InternalLLMRequest{ReasoningEffort: "LOW"}, expected: 1024},
+		{name: "unknown effort", request: InternalLLMRequest{ReasoningEffort: "minimal"}, expected: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.ThinkingBudget(efforts, -1); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
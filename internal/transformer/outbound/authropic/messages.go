@@ -25,6 +25,13 @@ type MessageOutbound struct {
 	toolIndex   int
 	toolCalls   map[int]*model.ToolCall
 	initialized bool
+
+	// Structured output emulation, see structured.go
+	structured       *structuredOutput
+	structuredActive bool // the current stream content block is the structured output tool
+	structuredCalled bool
+	structuredInput  strings.Builder // buffered tool input of a wrapped schema, sent when the block stops
+	otherToolCalled  bool
 }
 
 func (o *MessageOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
@@ -34,6 +41,11 @@ func (o *MessageOutbound) TransformRequest(ctx context.Context, request *model.I
 
 	// Convert to Anthropic request format
 	anthropicReq := convertToAnthropicRequest(request)
+	structured, err := applyStructuredOutput(request, anthropicReq)
+	if err != nil {
+		return nil, err
+	}
+	o.structured = structured
 
 	body, err := json.Marshal(anthropicReq)
 	if err != nil {
@@ -107,7 +119,11 @@ func (o *MessageOutbound) TransformResponse(ctx context.Context, response *http.
 	}
 
 	// Convert to internal response
-	return convertToLLMResponse(&anthropicResp), nil
+	result := convertToLLMResponse(&anthropicResp)
+	if o.structured != nil {
+		o.structured.unwrap(result)
+	}
+	return result, nil
 }
 
 func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
@@ -169,6 +185,13 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 		if streamEvent.ContentBlock != nil {
 			switch streamEvent.ContentBlock.Type {
 			case "tool_use":
+				if o.structured != nil && lo.FromPtr(streamEvent.ContentBlock.Name) == structuredOutputToolName {
+					// The tool input is streamed as message content
+					o.structuredActive = true
+					o.structuredCalled = true
+					return nil, nil
+				}
+				o.otherToolCalled = true
 				o.toolIndex++
 				toolCall := model.ToolCall{
 					Index: o.toolIndex,
@@ -215,7 +238,15 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 					}
 				}
 			case "input_json_delta":
-				if streamEvent.Delta.PartialJSON != nil && o.toolIndex >= 0 {
+				if streamEvent.Delta.PartialJSON != nil && o.structuredActive && o.structured.wrapped {
+					// The value can only be taken out of the complete input
+					o.structuredInput.WriteString(*streamEvent.Delta.PartialJSON)
+					return nil, nil
+				} else if streamEvent.Delta.PartialJSON != nil && o.structuredActive {
+					choice.Delta.Content = model.MessageContent{
+						Content: streamEvent.Delta.PartialJSON,
+					}
+				} else if streamEvent.Delta.PartialJSON != nil && o.toolIndex >= 0 {
 					choice.Delta.ToolCalls = []model.ToolCall{
 						{
 							Index: o.toolIndex,
@@ -260,6 +291,9 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 
 		if streamEvent.Delta != nil && streamEvent.Delta.StopReason != nil {
 			finishReason := convertStopReason(streamEvent.Delta.StopReason)
+			if o.structuredCalled && !o.otherToolCalled && lo.FromPtr(finishReason) == "tool_calls" {
+				finishReason = lo.ToPtr("stop")
+			}
 			resp.Choices = []model.Choice{
 				{
 					Index:        0,
@@ -274,7 +308,25 @@ func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte)
 			resp.Usage = o.streamUsage
 		}
 
-	case "content_block_stop", "ping":
+	case "content_block_stop":
+		wrapped := o.structuredActive && o.structured.wrapped
+		o.structuredActive = false
+		if !wrapped {
+			return nil, nil
+		}
+		content := o.structured.content(o.structuredInput.String())
+		o.structuredInput.Reset()
+		resp.Choices = []model.Choice{
+			{
+				Index: 0,
+				Delta: &model.Message{
+					Role:    "assistant",
+					Content: model.MessageContent{Content: &content},
+				},
+			},
+		}
+
+	case "ping":
 		return nil, nil
 
 	default:
@@ -313,10 +365,10 @@ func convertToAnthropicRequest(req *model.InternalLLMRequest) *anthropicModel.Me
 	}
 
 	// Convert thinking/reasoning
-	if req.ReasoningEffort != "" {
+	if req.ReasoningEffort != "" || req.ReasoningBudget != nil {
 		result.Thinking = &anthropicModel.Thinking{
 			Type:         "enabled",
-			BudgetTokens: getThinkingBudget(req.ReasoningEffort, req.ReasoningBudget),
+			BudgetTokens: req.ThinkingBudget(thinkingBudgets, 8192),
 		}
 	}
 
@@ -349,7 +401,8 @@ func convertSystemPrompt(req *model.InternalLLMRequest) *anthropicModel.SystemPr
 	wasArrayFormat := req.TransformerMetadata != nil && req.TransformerMetadata["anthropic_system_array_format"] == "true"
 
 	if len(systemMessages) == 1 {
-		if wasArrayFormat {
+		// cache_control 只能出现在数组格式中
+		if wasArrayFormat || systemMessages[0].CacheControl != nil {
 			return &anthropicModel.SystemPrompt{
 				MultiplePrompts: []anthropicModel.SystemPromptPart{{
 					Type:         "text",
@@ -520,7 +573,7 @@ func convertAssistantWithToolCalls(msg model.Message) []anthropicModel.MessagePa
 	var blocks []anthropicModel.MessageContentBlock
 
 	// Add thinking block if present
-	if msg.ReasoningContent != nil && *msg.ReasoningContent != "" {
+	if hasThinkingContent(msg) {
 		blocks = append(blocks, anthropicModel.MessageContentBlock{
 			Type:      "thinking",
 			Thinking:  msg.ReasoningContent,
@@ -591,14 +644,17 @@ func buildMessageContent(msg model.Message) anthropicModel.MessageContent {
 	return anthropicModel.MessageContent{}
 }
 
+// hasThinkingContent 只有带签名的思考内容才能发回 Anthropic，
+// 来自其他上游或签名已被移除的思考内容会被上游拒绝，直接省略
 func hasThinkingContent(msg model.Message) bool {
-	return msg.ReasoningContent != nil && *msg.ReasoningContent != ""
+	return msg.ReasoningContent != nil && *msg.ReasoningContent != "" &&
+		msg.ReasoningSignature != nil && *msg.ReasoningSignature != ""
 }
 
 func buildMultipleContentWithThinking(msg model.Message) anthropicModel.MessageContent {
 	var blocks []anthropicModel.MessageContentBlock
 
-	if msg.ReasoningContent != nil && *msg.ReasoningContent != "" {
+	if hasThinkingContent(msg) {
 		blocks = append(blocks, anthropicModel.MessageContentBlock{
 			Type:      "thinking",
 			Thinking:  msg.ReasoningContent,
@@ -729,21 +785,11 @@ func convertCacheControl(cc *model.CacheControl) *anthropicModel.CacheControl {
 	}
 }
 
-func getThinkingBudget(effort string, budget *int64) int64 {
-	if budget != nil {
-		return *budget
-	}
-
-	switch effort {
-	case "low":
-		return 1024
-	case "medium":
-		return 8192
-	case "high":
-		return 32768
-	default:
-		return 8192
-	}
+// thinkingBudgets reasoning_effort 各档位默认的思考预算，未知档位使用 8192
+var thinkingBudgets = map[string]int64{
+	"low":    1024,
+	"medium": 8192,
+	"high":   32768,
 }
 
 // Response conversion functions
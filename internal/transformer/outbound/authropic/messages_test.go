@@ -0,0 +1,55 @@
+package authropic
+
+import (
+	"testing"
+
+	"octopus/internal/transformer/model"
+)
+
+func TestConvertThinking(t *testing.T) {
+	budget := int64(5000)
+	tests := []struct {
+		name      string
+		request   model.InternalLLMRequest
+		budget    int64 // 0 表示不启用思考
+		signature *string
+		thinking  bool // 历史消息中的思考内容是否发给上游
+	}{
+		{name: "no reasoning", request: model.InternalLLMRequest{}},
+		{name: "effort", request: model.InternalLLMRequest{ReasoningEffort: "high"}, budget: 32768},
+		{name: "unknown effort", request: model.InternalLLMRequest{ReasoningEffort: "minimal"}, budget: 8192},
+		{name: "budget without effort", request: model.InternalLLMRequest{ReasoningBudget: &budget}, budget: 5000},
+		{name: "signed thinking", request: model.InternalLLMRequest{ReasoningEffort: "low"}, budget: 1024, signature: strPtr("sig"), thinking: true},
+		{name: "unsigned thinking is omitted", request: model.InternalLLMRequest{ReasoningEffort: "low"}, budget: 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Model = "claude"
+			tt.request.Messages = []model.Message{
+				{Role: "user", Content: model.MessageContent{Content: strPtr("q")}},
+				{Role: "assistant", Content: model.MessageContent{Content: strPtr("a")}, ReasoningContent: strPtr("r"), ReasoningSignature: tt.signature},
+				{Role: "user", Content: model.MessageContent{Content: strPtr("q2")}},
+			}
+			result := convertToAnthropicRequest(&tt.request)
+			if tt.budget == 0 {
+				if result.Thinking != nil {
+					t.Fatalf("expected no thinking, got %+v", result.Thinking)
+				}
+			} else if result.Thinking == nil || result.Thinking.BudgetTokens != tt.budget {
+				t.Fatalf("expected budget %d, got %+v", tt.budget, result.Thinking)
+			}
+
+			thinking := false
+			for _, msg := range result.Messages {
+				for _, block := range msg.Content.MultipleContent {
+					if block.Type == "thinking" {
+						thinking = true
+					}
+				}
+			}
+			if thinking != tt.thinking {
+				t.Errorf("expected thinking block %v, got %v", tt.thinking, thinking)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package authropic
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/samber/lo"
+
+	anthropicModel "octopus/internal/transformer/inbound/anthropic"
+	"octopus/internal/transformer/model"
+)
+
+// Anthropic 没有原生的结构化输出，response_format 为 json_schema 或 json_object 时，
+// 添加一个以 schema 为输入参数的工具并要求模型调用，再把工具的输入参数还原为消息内容返回给客户端。
+// 工具的输入必须是对象，根不是对象的 schema 包装为 {"value": <schema>}，返回时再取出 value
+
+const structuredOutputToolName = "structured_output"
+
+const structuredOutputToolDescription = "Respond with the final answer by calling this tool. The input must conform to the schema."
+
+// structuredValueKey 包装非对象 schema 时使用的属性名
+const structuredValueKey = "value"
+
+// structuredOutput 请求启用的结构化输出模拟
+type structuredOutput struct {
+	wrapped bool // schema 被包装在 value 属性中
+}
+
+// applyStructuredOutput 为请求添加结构化输出工具，未启用模拟时返回 nil。
+// 模拟需要强制调用工具，启用思考或客户端指定了其他 tool_choice 时无法满足，返回错误
+func applyStructuredOutput(req *model.InternalLLMRequest, result *anthropicModel.MessageRequest) (*structuredOutput, error) {
+	if req.ResponseFormat == nil {
+		return nil, nil
+	}
+
+	description := structuredOutputToolDescription
+	var schema json.RawMessage
+	switch req.ResponseFormat.Type {
+	case "json_schema":
+		if s := req.ResponseFormat.GetJSONSchema(); s != nil {
+			schema = s.Schema
+			if s.Description != "" {
+				description += "\n\n" + s.Description
+			}
+		}
+	case "json_object":
+	default:
+		return nil, nil
+	}
+
+	if result.Thinking != nil {
+		return nil, fmt.Errorf("response_format %s cannot be combined with extended thinking on anthropic channels", req.ResponseFormat.Type)
+	}
+	if choice := req.ToolChoice; choice != nil {
+		if choice.NamedToolChoice != nil || (choice.ToolChoice != nil && *choice.ToolChoice == "none") {
+			return nil, fmt.Errorf("response_format %s cannot be combined with this tool_choice on anthropic channels", req.ResponseFormat.Type)
+		}
+	}
+
+	so := &structuredOutput{}
+	switch {
+	case len(schema) == 0:
+		// json_object 或没有 schema 时接受任意对象
+		schema = json.RawMessage(`{"type":"object"}`)
+	case !isObjectSchema(schema):
+		wrapped, err := wrapSchema(schema)
+		if err != nil {
+			return nil, err
+		}
+		schema = wrapped
+		so.wrapped = true
+	}
+
+	result.Tools = append(result.Tools, anthropicModel.Tool{
+		Name:        structuredOutputToolName,
+		Description: description,
+		InputSchema: schema,
+	})
+
+	// 请求中还有其他工具时要求调用任意工具，以免影响正常的工具调用
+	if len(result.Tools) > 1 {
+		result.ToolChoice = &anthropicModel.ToolChoice{Type: "any"}
+	} else {
+		result.ToolChoice = &anthropicModel.ToolChoice{Type: "tool", Name: lo.ToPtr(structuredOutputToolName)}
+	}
+	return so, nil
+}
+
+// wrapSchema 将 schema 包装为 {"value": <schema>}，$defs 与 definitions 移到根上，使 #/$defs/... 引用仍然有效
+func wrapSchema(schema json.RawMessage) (json.RawMessage, error) {
+	wrapper := map[string]any{
+		"type":     "object",
+		"required": []string{structuredValueKey},
+	}
+	var value any = schema
+	// 布尔 schema（true/false）没有 $defs，原样包装
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(schema, &obj); err == nil {
+		for _, key := range []string{"$defs", "definitions"} {
+			if defs, ok := obj[key]; ok {
+				wrapper[key] = defs
+				delete(obj, key)
+			}
+		}
+		value = obj
+	}
+	wrapper["properties"] = map[string]any{structuredValueKey: value}
+	data, err := json.Marshal(wrapper)
+	if err != nil {
+		return nil, fmt.Errorf("invalid json_schema: %w", err)
+	}
+	return data, nil
+}
+
+func isObjectSchema(schema json.RawMessage) bool {
+	if len(schema) == 0 {
+		return false
+	}
+	var s struct {
+		Type any `json:"type"`
+	}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return false
+	}
+	return s.Type == "object"
+}
+
+// content 将工具的输入参数还原为客户端期望的 JSON，包装过的 schema 取出 value
+func (so *structuredOutput) content(arguments string) string {
+	if !so.wrapped {
+		return arguments
+	}
+	var input map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+		return arguments
+	}
+	value, ok := input[structuredValueKey]
+	if !ok {
+		return arguments
+	}
+	return string(value)
+}
+
+// unwrap 将结构化输出工具的调用还原为消息内容
+func (so *structuredOutput) unwrap(resp *model.InternalLLMResponse) {
+	for i := range resp.Choices {
+		msg := resp.Choices[i].Message
+		if msg == nil {
+			continue
+		}
+		for j, call := range msg.ToolCalls {
+			if call.Function.Name != structuredOutputToolName {
+				continue
+			}
+			content := so.content(call.Function.Arguments)
+			msg.Content = model.MessageContent{Content: &content}
+			msg.ToolCalls = append(msg.ToolCalls[:j], msg.ToolCalls[j+1:]...)
+			if len(msg.ToolCalls) == 0 {
+				msg.ToolCalls = nil
+				resp.Choices[i].FinishReason = lo.ToPtr("stop")
+			}
+			break
+		}
+	}
+}
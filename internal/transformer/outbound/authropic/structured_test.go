@@ -0,0 +1,218 @@
+package authropic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"octopus/internal/transformer/model"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+// schemaFormat 返回 json_schema 类型的 response_format
+func schemaFormat(schema string) *model.ResponseFormat {
+	return &model.ResponseFormat{Type: "json_schema", JSONSchema: json.RawMessage(`{"name":"out","schema":` + schema + `}`)}
+}
+
+func TestApplyStructuredOutput(t *testing.T) {
+	lookup := model.Tool{Type: "function", Function: model.Function{Name: "lookup", Parameters: json.RawMessage(`{"type":"object"}`)}}
+	tests := []struct {
+		name       string
+		request    model.InternalLLMRequest
+		wantErr    string
+		enabled    bool
+		wrapped    bool
+		schema     string // 结构化输出工具的 input_schema
+		toolChoice string
+	}{
+		{name: "no response format", request: model.InternalLLMRequest{}},
+		{name: "text format", request: model.InternalLLMRequest{ResponseFormat: &model.ResponseFormat{Type: "text"}}},
+		{
+			name:       "json object",
+			request:    model.InternalLLMRequest{ResponseFormat: &model.ResponseFormat{Type: "json_object"}},
+			enabled:    true,
+			schema:     `{"type":"object"}`,
+			toolChoice: "tool",
+		},
+		{
+			name:       "object schema",
+			request:    model.InternalLLMRequest{ResponseFormat: schemaFormat(`{"type":"object","properties":{"a":{"type":"string"}}}`)},
+			enabled:    true,
+			schema:     `{"type":"object","properties":{"a":{"type":"string"}}}`,
+			toolChoice: "tool",
+		},
+		{
+			name:       "array schema is wrapped",
+			request:    model.InternalLLMRequest{ResponseFormat: schemaFormat(`{"type":"array","items":{"$ref":"#/$defs/item"},"$defs":{"item":{"type":"integer"}}}`)},
+			enabled:    true,
+			wrapped:    true,
+			schema:     `{"$defs":{"item":{"type":"integer"}},"properties":{"value":{"items":{"$ref":"#/$defs/item"},"type":"array"}},"required":["value"],"type":"object"}`,
+			toolChoice: "tool",
+		},
+		{
+			name:       "boolean schema is wrapped",
+			request:    model.InternalLLMRequest{ResponseFormat: schemaFormat(`true`)},
+			enabled:    true,
+			wrapped:    true,
+			schema:     `{"properties":{"value":true},"required":["value"],"type":"object"}`,
+			toolChoice: "tool",
+		},
+		{
+			name:       "other tools",
+			request:    model.InternalLLMRequest{ResponseFormat: &model.ResponseFormat{Type: "json_object"}, Tools: []model.Tool{lookup}, ToolChoice: &model.ToolChoice{ToolChoice: strPtr("required")}},
+			enabled:    true,
+			schema:     `{"type":"object"}`,
+			toolChoice: "any",
+		},
+		{
+			name:    "thinking",
+			request: model.InternalLLMRequest{ResponseFormat: &model.ResponseFormat{Type: "json_object"}, ReasoningEffort: "high"},
+			wantErr: "extended thinking",
+		},
+		{
+			name:    "tool choice none",
+			request: model.InternalLLMRequest{ResponseFormat: &model.ResponseFormat{Type: "json_object"}, Tools: []model.Tool{lookup}, ToolChoice: &model.ToolChoice{ToolChoice: strPtr("none")}},
+			wantErr: "tool_choice",
+		},
+		{
+			name: "named tool choice",
+			request: model.InternalLLMRequest{ResponseFormat: schemaFormat(`{"type":"object"}`), Tools: []model.Tool{lookup}, ToolChoice: &model.ToolChoice{NamedToolChoice: &model.NamedToolChoice{
+				Type: "function", Function: model.ToolFunction{Name: "lookup"},
+			}}},
+			wantErr: "tool_choice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.request.Model = "claude"
+			result := convertToAnthropicRequest(&tt.request)
+			so, err := applyStructuredOutput(&tt.request, result)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (so != nil) != tt.enabled {
+				t.Fatalf("expected enabled %v, got %v", tt.enabled, so != nil)
+			}
+			if so == nil {
+				return
+			}
+			if so.wrapped != tt.wrapped {
+				t.Errorf("expected wrapped %v, got %v", tt.wrapped, so.wrapped)
+			}
+			tool := result.Tools[len(result.Tools)-1]
+			if tool.Name != structuredOutputToolName || string(tool.InputSchema) != tt.schema {
+				t.Errorf("expected %s, got %s %s", tt.schema, tool.Name, tool.InputSchema)
+			}
+			if result.ToolChoice == nil || result.ToolChoice.Type != tt.toolChoice {
+				t.Errorf("expected tool_choice %v, got %+v", tt.toolChoice, result.ToolChoice)
+			}
+		})
+	}
+}
+
+func TestStructuredOutputUnwrap(t *testing.T) {
+	call := func(name, args string) model.ToolCall {
+		return model.ToolCall{ID: name, Type: "function", Function: model.FunctionCall{Name: name, Arguments: args}}
+	}
+	tests := []struct {
+		name     string
+		wrapped  bool
+		calls    []model.ToolCall
+		content  string
+		finish   string
+		remained int
+	}{
+		{name: "object", calls: []model.ToolCall{call(structuredOutputToolName, `{"a":1}`)}, content: `{"a":1}`, finish: "stop"},
+		{name: "wrapped array", wrapped: true, calls: []model.ToolCall{call(structuredOutputToolName, `{"value": [1, 2]}`)}, content: `[1, 2]`, finish: "stop"},
+		{name: "wrapped without value", wrapped: true, calls: []model.ToolCall{call(structuredOutputToolName, `{"other":1}`)}, content: `{"other":1}`, finish: "stop"},
+		{name: "with other tool", calls: []model.ToolCall{call("lookup", `{}`), call(structuredOutputToolName, `{"a":1}`)}, content: `{"a":1}`, finish: "tool_calls", remained: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &model.InternalLLMResponse{Choices: []model.Choice{{
+				Message:      &model.Message{Role: "assistant", ToolCalls: tt.calls},
+				FinishReason: strPtr("tool_calls"),
+			}}}
+			(&structuredOutput{wrapped: tt.wrapped}).unwrap(resp)
+			choice := resp.Choices[0]
+			if choice.Message.Content.Content == nil || *choice.Message.Content.Content != tt.content {
+				t.Errorf("expected content %s, got %+v", tt.content, choice.Message.Content)
+			}
+			if *choice.FinishReason != tt.finish || len(choice.Message.ToolCalls) != tt.remained {
+				t.Errorf("expected finish %s with %d tool calls, got %s %+v", tt.finish, tt.remained, *choice.FinishReason, choice.Message.ToolCalls)
+			}
+		})
+	}
+}
+
+func TestStructuredOutputStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","model":"claude","usage":{"input_tokens":5,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"structured_output","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"val"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"ue\": [1, 2]}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop"}`,
+	}
+	tests := []struct {
+		name     string
+		schema   string
+		chunks   int // 含内容的 chunk 数
+		expected string
+	}{
+		{name: "object schema streams input", schema: `{"type":"object"}`, chunks: 2, expected: `{"value": [1, 2]}`},
+		{name: "wrapped schema sends value once", schema: `{"type":"array"}`, chunks: 1, expected: `[1, 2]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &MessageOutbound{}
+			request := &model.InternalLLMRequest{Model: "claude", Stream: new(bool), ResponseFormat: schemaFormat(tt.schema)}
+			*request.Stream = true
+			if _, err := o.TransformRequest(context.Background(), request, "https://api.anthropic.com/v1", "sk"); err != nil {
+				t.Fatal(err)
+			}
+
+			var content strings.Builder
+			chunks := 0
+			finish := ""
+			for _, ev := range events {
+				resp, err := o.TransformStream(context.Background(), []byte(ev))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp == nil {
+					continue
+				}
+				for _, choice := range resp.Choices {
+					if choice.Delta != nil && choice.Delta.Content.Content != nil {
+						content.WriteString(*choice.Delta.Content.Content)
+						chunks++
+					}
+					if choice.Delta != nil && len(choice.Delta.ToolCalls) > 0 {
+						t.Fatalf("expected no tool call deltas, got %+v", choice.Delta.ToolCalls)
+					}
+					if choice.FinishReason != nil {
+						finish = *choice.FinishReason
+					}
+				}
+			}
+			if content.String() != tt.expected || chunks != tt.chunks {
+				t.Errorf("expected %s in %d chunks, got %s in %d", tt.expected, tt.chunks, content.String(), chunks)
+			}
+			if finish != "stop" {
+				t.Errorf("expected finish reason stop, got %q", finish)
+			}
+		})
+	}
+}
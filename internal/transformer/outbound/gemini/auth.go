@@ -0,0 +1,345 @@
+package gemini
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// 渠道 key 支持以下几种形式：
+//   - API Key：通过 x-goog-api-key 请求头发送
+//   - OAuth access token（ya29. 开头）：直接作为 Bearer token
+//   - 服务账号或 authorized_user 凭据的 JSON 内容：换取 access token 并缓存
+//   - adc：使用 Application Default Credentials（GOOGLE_APPLICATION_CREDENTIALS、
+//     gcloud auth application-default login 生成的文件或 GCE 元数据服务）
+
+const (
+	defaultTokenURI       = "https://oauth2.googleapis.com/token"
+	metadataTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	oauthScopes           = "https://www.googleapis.com/auth/cloud-platform https://www.googleapis.com/auth/generative-language"
+	tokenRefreshThreshold = 5 * time.Minute
+	tokenTimeout          = 30 * time.Second
+)
+
+type credentials struct {
+	Type           string `json:"type"`
+	ClientEmail    string `json:"client_email"`
+	PrivateKey     string `json:"private_key"`
+	TokenURI       string `json:"token_uri"`
+	ClientID       string `json:"client_id"`
+	ClientSecret   string `json:"client_secret"`
+	RefreshToken   string `json:"refresh_token"`
+	QuotaProjectID string `json:"quota_project_id"`
+}
+
+type cachedToken struct {
+	accessToken  string
+	quotaProject string
+	expiresAt    time.Time
+}
+
+var globalTokenCache = struct {
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+	group  singleflight.Group // 同一凭据并发获取 token 时只请求一次
+}{
+	tokens: make(map[string]cachedToken),
+}
+
+// adcCache 缓存解析后的 ADC 文件，文件路径或修改时间变化时重新读取
+var adcCache struct {
+	mu      sync.Mutex
+	path    string
+	modTime time.Time
+	creds   *credentials
+}
+
+// metadataClient 元数据服务只能在本机直接访问，不经过渠道代理
+var metadataClient = &http.Client{}
+
+type httpClientKey struct{}
+
+// WithHTTPClient 在 ctx 中携带渠道的 HTTP 客户端，换取 access token 与创建上下文缓存时使用，
+// 使渠道的代理与录制设置同样生效。未携带时使用默认客户端
+func WithHTTPClient(ctx context.Context, client *http.Client) context.Context {
+	return context.WithValue(ctx, httpClientKey{}, client)
+}
+
+func httpClient(ctx context.Context) *http.Client {
+	if client, ok := ctx.Value(httpClientKey{}).(*http.Client); ok && client != nil {
+		return client
+	}
+	return http.DefaultClient
+}
+
+// Authorize 按渠道 key 的形式为请求设置认证信息
+func Authorize(ctx context.Context, req *http.Request, key string) error {
+	key = strings.TrimSpace(key)
+	switch {
+	case key == "":
+		return errors.New("empty key provided")
+	case strings.HasPrefix(key, "ya29."):
+		req.Header.Set("Authorization", "Bearer "+key)
+		return nil
+	case strings.HasPrefix(key, "{") || strings.EqualFold(key, "adc"):
+		token, quotaProject, err := accessToken(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to get gemini access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if quotaProject != "" {
+			req.Header.Set("X-Goog-User-Project", quotaProject)
+		}
+		return nil
+	default:
+		req.Header.Set("X-Goog-Api-Key", key)
+		return nil
+	}
+}
+
+// accessToken 返回缓存的 access token，过期前 tokenRefreshThreshold 重新获取。
+// 获取 token 时不持有锁，同一凭据的并发请求共享一次获取，不随发起请求的取消而中断
+func accessToken(ctx context.Context, key string) (string, string, error) {
+	sum := sha256.Sum256([]byte(key))
+	cacheKey := string(sum[:])
+	if cached, ok := lookupToken(cacheKey); ok {
+		return cached.accessToken, cached.quotaProject, nil
+	}
+
+	ch := globalTokenCache.group.DoChan(cacheKey, func() (any, error) {
+		// 等待期间其他请求可能已经完成获取
+		if cached, ok := lookupToken(cacheKey); ok {
+			return cached, nil
+		}
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenTimeout)
+		defer cancel()
+		cached, err := fetchToken(fetchCtx, key)
+		if err != nil {
+			return nil, err
+		}
+		globalTokenCache.mu.Lock()
+		globalTokenCache.tokens[cacheKey] = cached
+		globalTokenCache.mu.Unlock()
+		return cached, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return "", "", res.Err
+		}
+		cached := res.Val.(cachedToken)
+		return cached.accessToken, cached.quotaProject, nil
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	}
+}
+
+func lookupToken(cacheKey string) (cachedToken, bool) {
+	globalTokenCache.mu.Lock()
+	defer globalTokenCache.mu.Unlock()
+	cached, ok := globalTokenCache.tokens[cacheKey]
+	return cached, ok && time.Now().Add(tokenRefreshThreshold).Before(cached.expiresAt)
+}
+
+// fetchToken 按凭据类型获取新的 access token
+func fetchToken(ctx context.Context, key string) (cachedToken, error) {
+	var creds *credentials
+	if strings.EqualFold(key, "adc") {
+		var err error
+		if creds, err = loadADC(); err != nil {
+			return cachedToken{}, err
+		}
+	} else {
+		creds = &credentials{}
+		if err := json.Unmarshal([]byte(key), creds); err != nil {
+			return cachedToken{}, fmt.Errorf("invalid credentials json: %w", err)
+		}
+	}
+
+	var token *tokenResponse
+	var err error
+	switch {
+	case creds == nil:
+		token, err = metadataToken(ctx)
+	case creds.Type == "service_account":
+		token, err = serviceAccountToken(ctx, creds)
+	case creds.Type == "authorized_user":
+		token, err = refreshToken(ctx, creds)
+	default:
+		err = fmt.Errorf("unsupported credentials type %q", creds.Type)
+	}
+	if err != nil {
+		return cachedToken{}, err
+	}
+	cached := cachedToken{
+		accessToken: token.AccessToken,
+		expiresAt:   time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}
+	if creds != nil {
+		cached.quotaProject = creds.QuotaProjectID
+	}
+	return cached, nil
+}
+
+// loadADC 查找 Application Default Credentials 文件，都不存在时返回 nil 表示使用 GCE 元数据服务。
+// 解析结果按文件路径与修改时间缓存
+func loadADC() (*credentials, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if runtime.GOOS == "windows" {
+			path = filepath.Join(os.Getenv("APPDATA"), "gcloud", "application_default_credentials.json")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+		}
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	adcCache.mu.Lock()
+	defer adcCache.mu.Unlock()
+	if adcCache.creds != nil && adcCache.path == path && adcCache.modTime.Equal(info.ModTime()) {
+		return adcCache.creds, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials file %s: %w", path, err)
+	}
+	adcCache.path, adcCache.modTime, adcCache.creds = path, info.ModTime(), &creds
+	return &creds, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
+}
+
+// serviceAccountToken 使用服务账号私钥签名的 JWT 换取 access token
+func serviceAccountToken(ctx context.Context, creds *credentials) (*tokenResponse, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("invalid service account private key: %w", err)
+		}
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account private key is not an RSA key")
+	}
+
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultTokenURI
+	}
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   creds.ClientEmail,
+		"scope": oauthScopes,
+		"aud":   tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", unsigned+"."+base64.RawURLEncoding.EncodeToString(signature))
+	return postToken(ctx, tokenURI, form)
+}
+
+// refreshToken 使用 authorized_user 凭据中的 refresh token 换取 access token
+func refreshToken(ctx context.Context, creds *credentials) (*tokenResponse, error) {
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = defaultTokenURI
+	}
+	form := url.Values{}
+	form.Set("client_id", creds.ClientID)
+	form.Set("client_secret", creds.ClientSecret)
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", creds.RefreshToken)
+	return postToken(ctx, tokenURI, form)
+}
+
+func postToken(ctx context.Context, tokenURI string, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doTokenRequest(httpClient(ctx), req)
+}
+
+// metadataToken 从 GCE/Cloud Run 等环境的元数据服务获取默认服务账号的 access token
+func metadataToken(ctx context.Context) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL+"?scopes="+url.QueryEscape(strings.ReplaceAll(oauthScopes, " ", ",")), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := doTokenRequest(metadataClient, req)
+	if err != nil {
+		return nil, fmt.Errorf("no application default credentials found: %w", err)
+	}
+	return token, nil
+}
+
+func doTokenRequest(client *http.Client, req *http.Request) (*tokenResponse, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed: %d: %s", resp.StatusCode, string(body))
+	}
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, err
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("token response has no access_token")
+	}
+	return &token, nil
+}
@@ -0,0 +1,182 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// tokenClient 模拟渠道的 HTTP 客户端，所有请求都返回 access token 并计数
+func tokenClient(calls *atomic.Int32, release <-chan struct{}) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		if release != nil {
+			<-release
+		}
+		body := `{"access_token":"token-from-channel-client","expires_in":3600}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+}
+
+// authorizedUser 返回 authorized_user 凭据，不同的 clientID 对应不同的 token 缓存
+func authorizedUser(clientID string) string {
+	return fmt.Sprintf(`{"type":"authorized_user","client_id":%q,"client_secret":"s","refresh_token":"r","token_uri":"https://oauth.invalid/token","quota_project_id":"proj"}`, clientID)
+}
+
+func TestAuthorize(t *testing.T) {
+	var calls atomic.Int32
+	ctx := WithHTTPClient(context.Background(), tokenClient(&calls, nil))
+	tests := []struct {
+		name     string
+		key      string
+		wantErr  string
+		header   string
+		expected string
+		project  string
+	}{
+		{name: "api key", key: " AIza-key ", header: "X-Goog-Api-Key", expected: "AIza-key"},
+		{name: "oauth token", key: "ya29.token", header: "Authorization", expected: "Bearer ya29.token"},
+		{name: "authorized user", key: authorizedUser(t.Name()), header: "Authorization", expected: "Bearer token-from-channel-client", project: "proj"},
+		{name: "empty", key: "  ", wantErr: "empty key"},
+		{name: "invalid json", key: "{", wantErr: "invalid credentials json"},
+		{name: "unsupported type", key: `{"type":"external_account"}`, wantErr: "unsupported credentials type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "https://generativelanguage.googleapis.com/v1beta/models", nil)
+			err := Authorize(ctx, req, tt.key)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Header.Get(tt.header); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+			if got := req.Header.Get("X-Goog-User-Project"); got != tt.project {
+				t.Errorf("expected quota project %q, got %q", tt.project, got)
+			}
+		})
+	}
+}
+
+func TestAccessTokenSingleFlight(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	ctx := WithHTTPClient(context.Background(), tokenClient(&calls, release))
+	key := authorizedUser(t.Name())
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, _, err := accessToken(ctx, key)
+			if err == nil && token != "token-from-channel-client" {
+				err = fmt.Errorf("unexpected token %q", token)
+			}
+			errs <- err
+		}()
+	}
+
+	// 获取 token 期间不持有锁，其他凭据的缓存仍然可以读取
+	for calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	done := make(chan struct{})
+	go func() {
+		lookupToken("other")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the token cache to stay unlocked while fetching")
+	}
+
+	// 发起获取的请求取消后，等待中的请求仍然得到结果
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := accessToken(cancelled, key); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled waiter to return context.Canceled, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected 1 token request, got %d", got)
+	}
+	// 之后的请求使用缓存
+	if _, _, err := accessToken(ctx, key); err != nil || calls.Load() != 1 {
+		t.Fatalf("expected cached token without another request, got %d requests, err %v", calls.Load(), err)
+	}
+}
+
+func TestLoadADC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "adc.json")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+	write := func(project string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(`{"type":"authorized_user","quota_project_id":"`+project+`"}`), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	base := time.Now().Add(-time.Hour)
+	write("first", base)
+	first, err := loadADC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := loadADC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != first {
+		t.Fatal("expected the parsed credentials to be reused while the file is unchanged")
+	}
+
+	write("second", base.Add(time.Minute))
+	changed, err := loadADC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.QuotaProjectID != "second" {
+		t.Fatalf("expected %v, got %v", "second", changed.QuotaProjectID)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadADC(); err == nil {
+		t.Fatal("expected an error when GOOGLE_APPLICATION_CREDENTIALS points to a missing file")
+	}
+}
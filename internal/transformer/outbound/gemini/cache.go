@@ -0,0 +1,271 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"octopus/internal/transformer/model"
+	"octopus/internal/utils/log"
+	"octopus/internal/utils/tokenizer"
+)
+
+// 显式上下文缓存：请求中稳定且足够大的前缀（system、tools 以及最后一个带 cache_control 标记的消息之前的内容）
+// 通过 cachedContents API 创建缓存，之后按内容哈希复用，请求只发送剩余的内容。
+// 缓存读取的 token 会在响应的 cachedContentTokenCount 中返回，按模型价格的 CacheRead 计费
+
+const (
+	// cacheMinTokens 前缀估算 token 数低于该值时不创建缓存（Gemini 对缓存内容有最小 token 数要求）
+	cacheMinTokens = 4096
+	// cacheExpireMargin 缓存剩余时间小于该值时不再复用，避免请求到达时缓存已过期
+	cacheExpireMargin = 30 * time.Second
+	cacheDefaultTTL   = 5 * time.Minute
+	cacheLongTTL      = time.Hour
+	cacheTimeout      = 60 * time.Second
+)
+
+type cacheEntry struct {
+	ready     chan struct{}
+	name      string // 为空表示创建失败，在 expiresAt 前不再重试
+	expiresAt time.Time
+}
+
+var globalContentCache = struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}{
+	entries: make(map[string]*cacheEntry),
+}
+
+type noContextCacheKey struct{}
+
+// WithoutContextCache 返回的 ctx 中发出的请求不创建也不复用 cachedContents，始终完整发送
+func WithoutContextCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noContextCacheKey{}, true)
+}
+
+// cachePrefix 描述可以缓存的请求前缀
+type cachePrefix struct {
+	contents int // 缓存的 contents 条数
+	ttl      time.Duration
+}
+
+// selectCachePrefix 根据 cache_control 标记选择缓存前缀，没有标记时只缓存 system 与 tools
+func selectCachePrefix(request *model.InternalLLMRequest, geminiReq *model.GeminiGenerateContentRequest) *cachePrefix {
+	prefix := &cachePrefix{ttl: cacheDefaultTTL}
+	mark := func(cc *model.CacheControl) bool {
+		if cc == nil {
+			return false
+		}
+		if cc.TTL == "1h" {
+			prefix.ttl = cacheLongTTL
+		}
+		return true
+	}
+
+	for _, tool := range request.Tools {
+		mark(tool.CacheControl)
+	}
+	index := 0
+	for _, msg := range request.Messages {
+		marked := mark(msg.CacheControl)
+		for _, part := range msg.Content.MultipleContent {
+			if mark(part.CacheControl) {
+				marked = true
+			}
+		}
+		switch msg.Role {
+		case "user", "assistant", "tool":
+			// 与 convertLLMToGeminiRequest 一致，每条非 system 消息对应一条 content
+			index++
+			if marked {
+				prefix.contents = index
+			}
+		}
+	}
+
+	// 至少保留一条 content 随请求发送
+	if prefix.contents > len(geminiReq.Contents)-1 {
+		prefix.contents = max(len(geminiReq.Contents)-1, 0)
+	}
+	if prefix.contents == 0 && geminiReq.SystemInstruction == nil && len(geminiReq.Tools) == 0 {
+		return nil
+	}
+	return prefix
+}
+
+// applyContentCache 为请求前缀创建或复用缓存，成功时从请求中移除已缓存的部分。
+// 任何失败都只记录日志，请求按原样完整发送
+func applyContentCache(ctx context.Context, request *model.InternalLLMRequest, geminiReq *model.GeminiGenerateContentRequest, baseUrl, key string) {
+	if !strings.HasPrefix(baseUrl, "http://") && !strings.HasPrefix(baseUrl, "https://") {
+		return
+	}
+	if disabled, _ := ctx.Value(noContextCacheKey{}).(bool); disabled {
+		return
+	}
+	prefix := selectCachePrefix(request, geminiReq)
+	if prefix == nil {
+		return
+	}
+
+	modelName := request.Model
+	if !strings.Contains(modelName, "/") {
+		modelName = "models/" + modelName
+	}
+	content := &model.GeminiCachedContent{
+		Model:             modelName,
+		Contents:          geminiReq.Contents[:prefix.contents],
+		SystemInstruction: geminiReq.SystemInstruction,
+		Tools:             geminiReq.Tools,
+		ToolConfig:        geminiReq.ToolConfig,
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return
+	}
+	if tokenizer.CountTokens(string(data), request.Model) < cacheMinTokens {
+		return
+	}
+
+	keyHash := sha256.Sum256([]byte(key))
+	hash := sha256.New()
+	hash.Write([]byte(strings.TrimSuffix(baseUrl, "/")))
+	hash.Write([]byte{0})
+	hash.Write(keyHash[:])
+	hash.Write(data)
+	hashKey := hex.EncodeToString(hash.Sum(nil))
+
+	name := getContentCache(ctx, hashKey, func() (string, time.Time, error) {
+		content.TTL = fmt.Sprintf("%ds", int(prefix.ttl.Seconds()))
+		// 缓存由后续请求共享，不随当前请求取消
+		createCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheTimeout)
+		defer cancel()
+		return createContentCache(createCtx, content, baseUrl, key)
+	}, prefix.ttl)
+	if name == "" {
+		return
+	}
+
+	geminiReq.CachedContent = name
+	geminiReq.Contents = geminiReq.Contents[prefix.contents:]
+	geminiReq.SystemInstruction = nil
+	geminiReq.Tools = nil
+	geminiReq.ToolConfig = nil
+}
+
+// getContentCache 返回可用的缓存名称，同一前缀并发请求时只创建一次
+func getContentCache(ctx context.Context, hashKey string, create func() (string, time.Time, error), ttl time.Duration) string {
+	for {
+		globalContentCache.mu.Lock()
+		entry, ok := globalContentCache.entries[hashKey]
+		if !ok {
+			entry = &cacheEntry{ready: make(chan struct{})}
+			globalContentCache.entries[hashKey] = entry
+			sweepContentCache()
+			globalContentCache.mu.Unlock()
+
+			name, expiresAt, err := create()
+			globalContentCache.mu.Lock()
+			if err != nil {
+				log.Warnf("failed to create gemini cached content: %v", err)
+				entry.expiresAt = time.Now().Add(ttl)
+			} else {
+				entry.name = name
+				entry.expiresAt = expiresAt
+			}
+			close(entry.ready)
+			globalContentCache.mu.Unlock()
+			return entry.name
+		}
+		globalContentCache.mu.Unlock()
+
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return ""
+		}
+
+		globalContentCache.mu.Lock()
+		if time.Now().Add(cacheExpireMargin).Before(entry.expiresAt) {
+			globalContentCache.mu.Unlock()
+			return entry.name
+		}
+		// 已过期，移除后重新创建
+		if globalContentCache.entries[hashKey] == entry {
+			delete(globalContentCache.entries, hashKey)
+		}
+		globalContentCache.mu.Unlock()
+	}
+}
+
+// sweepContentCache 清理已过期的缓存记录，调用方需持有锁
+func sweepContentCache() {
+	now := time.Now()
+	for k, entry := range globalContentCache.entries {
+		select {
+		case <-entry.ready:
+			if now.After(entry.expiresAt) {
+				delete(globalContentCache.entries, k)
+			}
+		default:
+		}
+	}
+}
+
+// createContentCache 调用 cachedContents API 创建缓存
+func createContentCache(ctx context.Context, content *model.GeminiCachedContent, baseUrl, key string) (string, time.Time, error) {
+	body, err := json.Marshal(content)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	parsedUrl, err := url.Parse(strings.TrimSuffix(baseUrl, "/"))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	parsedUrl.Path += "/cachedContents"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parsedUrl.String(), bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := Authorize(ctx, req, key); err != nil {
+		return "", time.Time{}, err
+	}
+
+	resp, err := httpClient(ctx).Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("%d: %s", resp.StatusCode, string(data))
+	}
+
+	var created model.GeminiCachedContent
+	if err := json.Unmarshal(data, &created); err != nil {
+		return "", time.Time{}, err
+	}
+	if created.Name == "" {
+		return "", time.Time{}, fmt.Errorf("response has no name: %s", string(data))
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, created.ExpireTime)
+	if err != nil {
+		ttl, _ := time.ParseDuration(content.TTL)
+		expiresAt = time.Now().Add(ttl)
+	}
+	return created.Name, expiresAt, nil
+}
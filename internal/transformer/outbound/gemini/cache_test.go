@@ -0,0 +1,129 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"octopus/internal/transformer/model"
+)
+
+func TestSelectCachePrefix(t *testing.T) {
+	text := func(s string) model.MessageContent { return model.MessageContent{Content: &s} }
+	tests := []struct {
+		name     string
+		request  model.InternalLLMRequest
+		expected *cachePrefix
+	}{
+		{
+			name:     "nothing to cache",
+			request:  model.InternalLLMRequest{Messages: []model.Message{{Role: "user", Content: text("hi")}}},
+			expected: nil,
+		},
+		{
+			name:     "system only",
+			request:  model.InternalLLMRequest{Messages: []model.Message{{Role: "system", Content: text("s")}, {Role: "user", Content: text("hi")}}},
+			expected: &cachePrefix{contents: 0, ttl: cacheDefaultTTL},
+		},
+		{
+			name: "last marked message",
+			request: model.InternalLLMRequest{Messages: []model.Message{
+				{Role: "user", Content: text("a"), CacheControl: &model.CacheControl{Type: "ephemeral"}},
+				{Role: "assistant", Content: text("b"), CacheControl: &model.CacheControl{Type: "ephemeral", TTL: "1h"}},
+				{Role: "user", Content: text("c")},
+			}},
+			expected: &cachePrefix{contents: 2, ttl: cacheLongTTL},
+		},
+		{
+			name: "keeps one content",
+			request: model.InternalLLMRequest{Messages: []model.Message{
+				{Role: "system", Content: text("s")},
+				{Role: "user", Content: text("a"), CacheControl: &model.CacheControl{Type: "ephemeral"}},
+			}},
+			expected: &cachePrefix{contents: 0, ttl: cacheDefaultTTL},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := selectCachePrefix(&tt.request, convertLLMToGeminiRequest(&tt.request))
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// cacheRequest 返回系统提示词足够大、可以创建缓存的请求，不同的 system 对应不同的缓存
+func cacheRequest(system string) *model.InternalLLMRequest {
+	long := system + strings.Repeat(" lorem ipsum dolor sit amet", 2000)
+	question := "question"
+	return &model.InternalLLMRequest{
+		Model: "gemini-2.5-flash",
+		Messages: []model.Message{
+			{Role: "system", Content: model.MessageContent{Content: &long}},
+			{Role: "user", Content: model.MessageContent{Content: &question}},
+		},
+	}
+}
+
+// cachedContentsClient 模拟 cachedContents API，status 不为 200 时返回错误
+func cachedContentsClient(calls *atomic.Int32, status int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		body := `{"error":{"message":"cached content is too small"}}`
+		if status == http.StatusOK {
+			var content model.GeminiCachedContent
+			json.NewDecoder(req.Body).Decode(&content)
+			body = `{"name":"cachedContents/abc","model":"` + content.Model + `","expireTime":"` + time.Now().Add(time.Hour).Format(time.RFC3339Nano) + `"}`
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})}
+}
+
+func TestApplyContentCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseUrl  string
+		status   int
+		disabled bool
+		calls    int32 // 两次相同请求发出的 cachedContents 请求数
+		cached   bool
+	}{
+		{name: "created and reused", baseUrl: "https://generativelanguage.googleapis.com/v1beta", status: http.StatusOK, calls: 1, cached: true},
+		{name: "creation fails", baseUrl: "https://generativelanguage.googleapis.com/v1beta", status: http.StatusBadRequest, calls: 1},
+		{name: "disabled", baseUrl: "https://generativelanguage.googleapis.com/v1beta", status: http.StatusOK, disabled: true},
+		{name: "non http base url", baseUrl: "mock://local", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			ctx := WithHTTPClient(context.Background(), cachedContentsClient(&calls, tt.status))
+			if tt.disabled {
+				ctx = WithoutContextCache(ctx)
+			}
+			for i := 0; i < 2; i++ {
+				request := cacheRequest(t.Name())
+				geminiReq := convertLLMToGeminiRequest(request)
+				applyContentCache(ctx, request, geminiReq, tt.baseUrl, "AIza-key")
+
+				if cached := geminiReq.CachedContent != ""; cached != tt.cached {
+					t.Fatalf("expected cached %v, got %v", tt.cached, cached)
+				}
+				if tt.cached && (geminiReq.SystemInstruction != nil || len(geminiReq.Contents) != 1) {
+					t.Fatalf("expected the cached prefix to be removed, got %+v", geminiReq)
+				}
+				if !tt.cached && geminiReq.SystemInstruction == nil {
+					t.Fatal("expected the request to be sent in full")
+				}
+			}
+			if got := calls.Load(); got != tt.calls {
+				t.Fatalf("expected %d cachedContents requests, got %d", tt.calls, got)
+			}
+		})
+	}
+}
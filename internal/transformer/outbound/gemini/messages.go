@@ -21,6 +21,7 @@ type MessagesOutbound struct{}
 func (o *MessagesOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
 	// Convert internal request to Gemini format
 	geminiReq := convertLLMToGeminiRequest(request)
+	applyContentCache(ctx, request, geminiReq, baseUrl, key)
 
 	body, err := json.Marshal(geminiReq)
 	if err != nil {
@@ -47,13 +48,11 @@ func (o *MessagesOutbound) TransformRequest(ctx context.Context, request *model.
 	}
 	parsedUrl.Path = fmt.Sprintf("%s/%s:%s", parsedUrl.Path, modelName, method)
 
-	// Add API key as query parameter
-	q := parsedUrl.Query()
-	q.Set("key", key)
 	if isStream {
+		q := parsedUrl.Query()
 		q.Set("alt", "sse")
+		parsedUrl.RawQuery = q.Encode()
 	}
-	parsedUrl.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, parsedUrl.String(), bytes.NewReader(body))
 	if err != nil {
@@ -62,6 +61,9 @@ func (o *MessagesOutbound) TransformRequest(ctx context.Context, request *model.
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	if err := Authorize(ctx, req, key); err != nil {
+		return nil, err
+	}
 
 	return req, nil
 }
@@ -105,20 +107,13 @@ func (o *MessagesOutbound) TransformStream(ctx context.Context, eventData []byte
 
 // Helper functions
 
-// reasoningToThinkingBudget maps reasoning effort levels to thinking budget in tokens
+// thinkingBudgets maps reasoning effort levels to thinking budget in tokens,
+// unknown levels use dynamic thinking (-1)
 // https://ai.google.dev/gemini-api/docs/thinking
-func reasoningToThinkingBudget(effort string) int32 {
-	switch strings.ToLower(effort) {
-	case "low":
-		return 1024
-	case "medium":
-		return 4096
-	case "high":
-		return 24576
-	default:
-		// 防御性：未知值走动态
-		return -1
-	}
+var thinkingBudgets = map[string]int64{
+	"low":    1024,
+	"medium": 4096,
+	"high":   24576,
 }
 
 func audioTypeToMimeType(format string) string {
@@ -288,8 +283,8 @@ func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiG
 		hasConfig = true
 	}
 
-	if request.ReasoningEffort != "" {
-		budget := reasoningToThinkingBudget(request.ReasoningEffort)
+	if request.ReasoningEffort != "" || request.ReasoningBudget != nil {
+		budget := int32(request.ThinkingBudget(thinkingBudgets, -1))
 
 		config.ThinkingConfig = &model.GeminiThinkingConfig{
 			ThinkingBudget:  &budget,
@@ -306,7 +301,12 @@ func convertLLMToGeminiRequest(request *model.InternalLLMRequest) *model.GeminiG
 			hasConfig = true
 		case "json_schema":
 			config.ResponseMimeType = "application/json"
-			// TODO: Convert JSON schema to Gemini schema format if schema is provided
+			if schema := request.ResponseFormat.GetJSONSchema(); schema != nil && len(schema.Schema) > 0 {
+				var jsonSchema map[string]any
+				if err := json.Unmarshal(schema.Schema, &jsonSchema); err == nil {
+					config.ResponseJsonSchema = jsonSchema
+				}
+			}
 			hasConfig = true
 		case "text":
 			config.ResponseMimeType = "text/plain"
@@ -0,0 +1,39 @@
+package gemini
+
+import (
+	"testing"
+
+	"octopus/internal/transformer/model"
+)
+
+func TestThinkingConfig(t *testing.T) {
+	budget := int64(5000)
+	tests := []struct {
+		name     string
+		request  model.InternalLLMRequest
+		expected *int32 // nil 表示不设置 thinkingConfig
+	}{
+		{name: "no reasoning", request: model.InternalLLMRequest{}},
+		{name: "effort", request: model.InternalLLMRequest{ReasoningEffort: "medium"}, expected: int32Ptr(4096)},
+		{name: "unknown effort is dynamic", request: model.InternalLLMRequest{ReasoningEffort: "max"}, expected: int32Ptr(-1)},
+		{name: "policy budget", request: model.InternalLLMRequest{ReasoningEffort: "low", ReasoningBudget: &budget}, expected: int32Ptr(5000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := "hi"
+			tt.request.Messages = []model.Message{{Role: "user", Content: model.MessageContent{Content: &text}}}
+			config := convertLLMToGeminiRequest(&tt.request).GenerationConfig
+			var got *int32
+			if config != nil && config.ThinkingConfig != nil {
+				got = config.ThinkingConfig.ThinkingBudget
+			}
+			if (got == nil) != (tt.expected == nil) || (got != nil && *got != *tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
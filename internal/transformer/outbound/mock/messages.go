@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"context"
+	"net/http"
+
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound/openai"
+)
+
+// MessageOutbound 本地模拟渠道，请求按 OpenAI 格式构建，由 Transport 在进程内生成响应，不访问网络
+type MessageOutbound struct {
+	embedding bool
+}
+
+func (o *MessageOutbound) TransformRequest(ctx context.Context, request *model.InternalLLMRequest, baseUrl, key string) (*http.Request, error) {
+	if request.IsEmbeddingRequest() {
+		o.embedding = true
+		return (&openai.EmbeddingOutbound{}).TransformRequest(ctx, request, baseUrl, key)
+	}
+	return (&openai.ChatOutbound{}).TransformRequest(ctx, request, baseUrl, key)
+}
+
+func (o *MessageOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
+	if o.embedding {
+		return (&openai.EmbeddingOutbound{}).TransformResponse(ctx, response)
+	}
+	return (&openai.ChatOutbound{}).TransformResponse(ctx, response)
+}
+
+func (o *MessageOutbound) TransformStream(ctx context.Context, eventData []byte) (*model.InternalLLMResponse, error) {
+	return (&openai.ChatOutbound{}).TransformStream(ctx, eventData)
+}
@@ -0,0 +1,509 @@
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"octopus/internal/transformer/model"
+	"octopus/internal/transformer/outbound/openai"
+	"octopus/internal/utils/tokenizer"
+)
+
+// OptionsHeader 请求级别的模拟参数，格式与 base url 的查询参数相同，会覆盖 base url 中的同名参数
+const OptionsHeader = "X-Mock-Options"
+
+const (
+	defaultText       = "Hello! This is a mock response from octopus."
+	defaultDimensions = 8
+)
+
+// Transport 在进程内模拟 OpenAI 兼容的上游，行为由 base url 的查询参数控制，例如
+// mock://local?mode=echo&latency_ms=200&first_token_ms=500&chunk_ms=50&error_rate=0.1
+//
+//	mode            auto(默认，请求带工具时调用工具，否则回显) | echo | text | tool
+//	text            mode=text 时返回的内容
+//	tool_args       工具调用的参数(JSON)，默认 {}
+//	latency_ms      返回响应头之前的延迟
+//	first_token_ms  流式响应首个 chunk 之前的额外延迟
+//	chunk_ms        流式响应每个 chunk 之间的延迟
+//	chunk_words     流式响应每个 chunk 包含的单词数，默认 1
+//	fail_after      流式响应输出 N 个 chunk 后返回错误事件，模拟中途失败
+//	status          固定返回的 HTTP 状态码，非 2xx 时返回错误响应
+//	error_rate      按概率(0~1)返回 error_status 状态码(默认 500)的错误响应
+//	models          模型列表接口返回的模型(逗号分隔)
+//
+// status、error_rate 只作用于对话与向量接口，模型列表与延迟探测始终成功
+type Transport struct{}
+
+var mockSeq atomic.Int64
+
+type options struct {
+	mode          string
+	text          string
+	toolArgs      string
+	latency       time.Duration
+	firstToken    time.Duration
+	chunkDelay    time.Duration
+	chunkWords    int
+	failAfter     int
+	status        int
+	errorRate     float64
+	errorStatus   int
+	models        []string
+	dimensionsSet bool
+	dimensions    int
+}
+
+func parseOptions(req *http.Request) (options, error) {
+	values := req.URL.Query()
+	if h := req.Header.Get(OptionsHeader); h != "" {
+		override, err := url.ParseQuery(h)
+		if err != nil {
+			return options{}, fmt.Errorf("invalid %s header: %w", OptionsHeader, err)
+		}
+		for k, v := range override {
+			values[k] = v
+		}
+	}
+
+	opts := options{
+		mode:        strings.ToLower(values.Get("mode")),
+		text:        values.Get("text"),
+		toolArgs:    values.Get("tool_args"),
+		chunkWords:  1,
+		errorStatus: http.StatusInternalServerError,
+		models:      []string{"mock-chat", "mock-embedding"},
+		dimensions:  defaultDimensions,
+	}
+	if opts.mode == "" {
+		opts.mode = "auto"
+	}
+	if opts.text == "" {
+		opts.text = defaultText
+	}
+	if opts.toolArgs == "" {
+		opts.toolArgs = "{}"
+	}
+	if m := values.Get("models"); m != "" {
+		opts.models = nil
+		for _, name := range strings.Split(m, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts.models = append(opts.models, name)
+			}
+		}
+	}
+
+	ints := []struct {
+		key string
+		dst *int
+	}{
+		{"chunk_words", &opts.chunkWords},
+		{"fail_after", &opts.failAfter},
+		{"status", &opts.status},
+		{"error_status", &opts.errorStatus},
+		{"dimensions", &opts.dimensions},
+	}
+	for _, p := range ints {
+		if v := values.Get(p.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return options{}, fmt.Errorf("invalid mock option %s=%q", p.key, v)
+			}
+			*p.dst = n
+		}
+	}
+	opts.dimensionsSet = values.Get("dimensions") != ""
+	if opts.chunkWords < 1 {
+		opts.chunkWords = 1
+	}
+
+	durations := []struct {
+		key string
+		dst *time.Duration
+	}{
+		{"latency_ms", &opts.latency},
+		{"first_token_ms", &opts.firstToken},
+		{"chunk_ms", &opts.chunkDelay},
+	}
+	for _, p := range durations {
+		if v := values.Get(p.key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return options{}, fmt.Errorf("invalid mock option %s=%q", p.key, v)
+			}
+			*p.dst = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	if v := values.Get("error_rate"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return options{}, fmt.Errorf("invalid mock option error_rate=%q", v)
+		}
+		opts.errorRate = rate
+	}
+	return opts, nil
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	// 部分调用方直接在 base url 字符串后拼接路径（如 /models），此时路径落在查询参数的末尾
+	if i := strings.LastIndex(req.URL.RawQuery, "/"); i >= 0 && !strings.ContainsAny(req.URL.RawQuery[i:], "=&") {
+		u := *req.URL
+		u.Path = strings.TrimSuffix(u.Path, "/") + u.RawQuery[i:]
+		u.RawQuery = u.RawQuery[:i]
+		req = req.Clone(req.Context())
+		req.URL = &u
+	}
+	opts, err := parseOptions(req)
+	if err != nil {
+		return errorResponse(req, http.StatusBadRequest, err.Error()), nil
+	}
+	if err := sleep(req.Context(), opts.latency); err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSuffix(req.URL.Path, "/")
+	inference := req.Method == http.MethodPost &&
+		(strings.HasSuffix(path, "/chat/completions") || strings.HasSuffix(path, "/embeddings"))
+	if inference {
+		if opts.status != 0 && (opts.status < 200 || opts.status >= 300) {
+			return errorResponse(req, opts.status, fmt.Sprintf("mock upstream returned status %d", opts.status)), nil
+		}
+		if opts.errorRate > 0 && rand.Float64() < opts.errorRate {
+			return errorResponse(req, opts.errorStatus, "mock upstream random failure"), nil
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(path, "/models"):
+		return t.models(req, opts)
+	case inference && strings.HasSuffix(path, "/chat/completions"):
+		return t.chat(req, opts)
+	case inference && strings.HasSuffix(path, "/embeddings"):
+		return t.embeddings(req, opts)
+	default:
+		return jsonResponse(req, http.StatusOK, map[string]string{"status": "ok"})
+	}
+}
+
+func (t *Transport) models(req *http.Request, opts options) (*http.Response, error) {
+	type modelObject struct {
+		ID      string `json:"id"`
+		Object  string `json:"object"`
+		OwnedBy string `json:"owned_by"`
+	}
+	data := make([]modelObject, 0, len(opts.models))
+	for _, name := range opts.models {
+		data = append(data, modelObject{ID: name, Object: "model", OwnedBy: "octopus-mock"})
+	}
+	return jsonResponse(req, http.StatusOK, map[string]any{"object": "list", "data": data})
+}
+
+func (t *Transport) chat(req *http.Request, opts options) (*http.Response, error) {
+	var request model.InternalLLMRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		return errorResponse(req, http.StatusBadRequest, "invalid request body: "+err.Error()), nil
+	}
+
+	message := &model.Message{Role: "assistant"}
+	finishReason := "stop"
+	if tool := pickTool(&request, opts.mode); tool != "" {
+		message.ToolCalls = []model.ToolCall{{
+			ID:       fmt.Sprintf("call_mock_%d", mockSeq.Add(1)),
+			Type:     "function",
+			Function: model.FunctionCall{Name: tool, Arguments: opts.toolArgs},
+		}}
+		finishReason = "tool_calls"
+	} else {
+		text := opts.text
+		if opts.mode == "echo" || opts.mode == "auto" {
+			if echo := lastUserText(request.Messages); echo != "" {
+				text = echo
+			}
+		}
+		message.Content = model.MessageContent{Content: &text}
+	}
+
+	promptTokens := int64(0)
+	for _, m := range request.Messages {
+		promptTokens += int64(tokenizer.CountTokens(messageText(m), request.Model))
+	}
+	completion := ""
+	if message.Content.Content != nil {
+		completion = *message.Content.Content
+	}
+	for _, tc := range message.ToolCalls {
+		completion += tc.Function.Name + tc.Function.Arguments
+	}
+	completionTokens := int64(tokenizer.CountTokens(completion, request.Model))
+	usage := &model.Usage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      promptTokens + completionTokens,
+	}
+
+	id := fmt.Sprintf("chatcmpl-mock-%d", mockSeq.Add(1))
+	created := time.Now().Unix()
+	if request.Stream == nil || !*request.Stream {
+		return jsonResponse(req, http.StatusOK, model.InternalLLMResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   request.Model,
+			Choices: []model.Choice{{Message: message, FinishReason: &finishReason}},
+			Usage:   usage,
+		})
+	}
+
+	chunk := func(delta *model.Message, finish *string, u *model.Usage) model.InternalLLMResponse {
+		resp := model.InternalLLMResponse{ID: id, Object: "chat.completion.chunk", Created: created, Model: request.Model, Usage: u}
+		if delta != nil {
+			resp.Choices = []model.Choice{{Delta: delta, FinishReason: finish}}
+		} else {
+			resp.Choices = []model.Choice{}
+		}
+		return resp
+	}
+	events := []model.InternalLLMResponse{chunk(&model.Message{Role: "assistant"}, nil, nil)}
+	if len(message.ToolCalls) > 0 {
+		events = append(events, chunk(&model.Message{ToolCalls: message.ToolCalls}, nil, nil))
+	} else {
+		for _, part := range splitWords(*message.Content.Content, opts.chunkWords) {
+			events = append(events, chunk(&model.Message{Content: model.MessageContent{Content: &part}}, nil, nil))
+		}
+	}
+	events = append(events, chunk(&model.Message{}, &finishReason, nil))
+	if request.StreamOptions != nil && request.StreamOptions.IncludeUsage {
+		events = append(events, chunk(nil, nil, usage))
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		ctx := req.Context()
+		if err := sleep(ctx, opts.firstToken); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		for i, ev := range events {
+			if i > 0 {
+				if err := sleep(ctx, opts.chunkDelay); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+			}
+			if opts.failAfter > 0 && i == opts.failAfter {
+				data, _ := json.Marshal(map[string]any{"error": model.ErrorDetail{
+					Message: fmt.Sprintf("mock stream failed after %d chunks", opts.failAfter),
+					Type:    "mock_error",
+				}})
+				fmt.Fprintf(pw, "data: %s\n\n", data)
+				pw.Close()
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := fmt.Fprintf(pw, "data: %s\n\n", data); err != nil {
+				return
+			}
+		}
+		fmt.Fprint(pw, "data: [DONE]\n\n")
+		pw.Close()
+	}()
+
+	header := make(http.Header)
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       pr,
+		Request:    req,
+	}, nil
+}
+
+func (t *Transport) embeddings(req *http.Request, opts options) (*http.Response, error) {
+	var request openai.OpenAIEmbeddingRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		return errorResponse(req, http.StatusBadRequest, "invalid request body: "+err.Error()), nil
+	}
+	inputs := request.Input.Multiple
+	if request.Input.Single != nil {
+		inputs = []string{*request.Input.Single}
+	}
+	dimensions := opts.dimensions
+	if request.Dimensions != nil && *request.Dimensions > 0 && !opts.dimensionsSet {
+		dimensions = int(*request.Dimensions)
+	}
+
+	data := make([]model.EmbeddingObject, 0, len(inputs))
+	tokens := int64(0)
+	for i, input := range inputs {
+		data = append(data, model.EmbeddingObject{
+			Object:    "embedding",
+			Index:     i,
+			Embedding: model.Embedding{FloatArray: embed(input, dimensions)},
+		})
+		tokens += int64(tokenizer.CountTokens(input, request.Model))
+	}
+	return jsonResponse(req, http.StatusOK, openai.OpenAIEmbeddingResponse{
+		ID:      fmt.Sprintf("embd-mock-%d", mockSeq.Add(1)),
+		Object:  "list",
+		Created: time.Now().Unix(),
+		Model:   request.Model,
+		Data:    data,
+		Usage:   &model.Usage{PromptTokens: tokens, TotalTokens: tokens},
+	})
+}
+
+// pickTool 返回需要调用的工具名称：mode=tool 时总是调用，mode=auto 时仅在上一条消息不是工具结果时调用
+func pickTool(request *model.InternalLLMRequest, mode string) string {
+	if len(request.Tools) == 0 || (mode != "tool" && mode != "auto") {
+		return ""
+	}
+	if tc := request.ToolChoice; tc != nil {
+		if tc.ToolChoice != nil && *tc.ToolChoice == "none" {
+			return ""
+		}
+		if tc.NamedToolChoice != nil && tc.NamedToolChoice.Function.Name != "" {
+			return tc.NamedToolChoice.Function.Name
+		}
+	}
+	if mode == "auto" && len(request.Messages) > 0 && request.Messages[len(request.Messages)-1].Role == "tool" {
+		return ""
+	}
+	for _, tool := range request.Tools {
+		if tool.Function.Name != "" {
+			return tool.Function.Name
+		}
+	}
+	return ""
+}
+
+func lastUserText(messages []model.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messageText(messages[i])
+		}
+	}
+	return ""
+}
+
+func messageText(m model.Message) string {
+	if m.Content.Content != nil {
+		return *m.Content.Content
+	}
+	var sb strings.Builder
+	for _, part := range m.Content.MultipleContent {
+		if part.Type == "text" && part.Text != nil {
+			sb.WriteString(*part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// splitWords 按单词切分文本，保留单词后的空白，使各个 chunk 拼接后与原文一致
+func splitWords(text string, words int) []string {
+	var parts []string
+	start, count := 0, 0
+	for i := 0; i < len(text); i++ {
+		// 单词连同其后的空白在下一个非空白字符之前结束
+		if isSpace(text[i]) && (i+1 == len(text) || !isSpace(text[i+1])) {
+			if count++; count == words {
+				parts = append(parts, text[start:i+1])
+				start, count = i+1, 0
+			}
+		}
+	}
+	if start < len(text) || len(parts) == 0 {
+		parts = append(parts, text[start:])
+	}
+	return parts
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t'
+}
+
+// embed 根据输入生成确定性的单位向量，相同输入总是得到相同结果
+func embed(input string, dimensions int) []float64 {
+	h := fnv.New64a()
+	h.Write([]byte(input))
+	r := rand.New(rand.NewPCG(h.Sum64(), 0))
+	vec := make([]float64, dimensions)
+	norm := 0.0
+	for i := range vec {
+		vec[i] = r.Float64()*2 - 1
+		norm += vec[i] * vec[i]
+	}
+	if norm = math.Sqrt(norm); norm > 0 {
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func jsonResponse(req *http.Request, status int, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+func errorResponse(req *http.Request, status int, message string) *http.Response {
+	resp, _ := jsonResponse(req, status, map[string]any{"error": model.ErrorDetail{
+		Message: message,
+		Type:    "mock_error",
+		Code:    strconv.Itoa(status),
+	}})
+	return resp
+}
@@ -0,0 +1,333 @@
+package mock
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"octopus/internal/transformer/model"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		header  string
+		wantErr bool
+		check   func(t *testing.T, o options)
+	}{
+		{
+			name: "defaults",
+			url:  "mock://local",
+			check: func(t *testing.T, o options) {
+				if o.mode != "auto" || o.text != defaultText || o.toolArgs != "{}" || o.chunkWords != 1 || o.errorStatus != http.StatusInternalServerError {
+					t.Errorf("unexpected defaults: %+v", o)
+				}
+			},
+		},
+		{
+			name:   "header overrides query",
+			url:    "mock://local?mode=text&latency_ms=100",
+			header: "mode=echo&chunk_words=3",
+			check: func(t *testing.T, o options) {
+				if o.mode != "echo" || o.latency != 100*time.Millisecond || o.chunkWords != 3 {
+					t.Errorf("unexpected options: %+v", o)
+				}
+			},
+		},
+		{
+			name: "models list",
+			url:  "mock://local?models=a,%20b,,c",
+			check: func(t *testing.T, o options) {
+				if strings.Join(o.models, "|") != "a|b|c" {
+					t.Errorf("expected %v, got %v", "a|b|c", o.models)
+				}
+			},
+		},
+		{name: "negative int", url: "mock://local?status=-1", wantErr: true},
+		{name: "invalid duration", url: "mock://local?chunk_ms=x", wantErr: true},
+		{name: "error rate above 1", url: "mock://local?error_rate=1.5", wantErr: true},
+		{name: "invalid header", url: "mock://local", header: "mode=%zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.header != "" {
+				req.Header.Set(OptionsHeader, tt.header)
+			}
+			o, err := parseOptions(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.check != nil {
+				tt.check(t, o)
+			}
+		})
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		words    int
+		expected []string
+	}{
+		{name: "one word per chunk", text: "a b  c", words: 1, expected: []string{"a ", "b  ", "c"}},
+		{name: "two words per chunk", text: "a b c\nd", words: 2, expected: []string{"a b ", "c\nd"}},
+		{name: "trailing space", text: "a b ", words: 1, expected: []string{"a ", "b "}},
+		{name: "empty", text: "", words: 1, expected: []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitWords(tt.text, tt.words)
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+			if strings.Join(got, "") != tt.text {
+				t.Errorf("expected chunks to rebuild %q, got %q", tt.text, strings.Join(got, ""))
+			}
+		})
+	}
+}
+
+func TestEmbed(t *testing.T) {
+	a := embed("hello", 16)
+	b := embed("hello", 16)
+	c := embed("world", 16)
+	norm := 0.0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("expected identical vectors for the same input")
+		}
+		norm += a[i] * a[i]
+	}
+	if math.Abs(norm-1) > 1e-9 {
+		t.Errorf("expected unit vector, got norm %v", norm)
+	}
+	if a[0] == c[0] && a[1] == c[1] {
+		t.Errorf("expected different vectors for different inputs")
+	}
+}
+
+func TestPickTool(t *testing.T) {
+	tools := []model.Tool{{Type: "function", Function: model.Function{Name: "lookup"}}, {Type: "function", Function: model.Function{Name: "other"}}}
+	tests := []struct {
+		name     string
+		request  model.InternalLLMRequest
+		mode     string
+		expected string
+	}{
+		{name: "no tools", request: model.InternalLLMRequest{}, mode: "tool", expected: ""},
+		{name: "auto picks first tool", request: model.InternalLLMRequest{Tools: tools}, mode: "auto", expected: "lookup"},
+		{name: "echo never calls", request: model.InternalLLMRequest{Tools: tools}, mode: "echo", expected: ""},
+		{
+			name:     "named tool choice",
+			request:  model.InternalLLMRequest{Tools: tools, ToolChoice: &model.ToolChoice{NamedToolChoice: &model.NamedToolChoice{Type: "function", Function: model.ToolFunction{Name: "other"}}}},
+			mode:     "auto",
+			expected: "other",
+		},
+		{name: "tool choice none", request: model.InternalLLMRequest{Tools: tools, ToolChoice: &model.ToolChoice{ToolChoice: strPtr("none")}}, mode: "tool", expected: ""},
+		{
+			name:     "auto answers tool results",
+			request:  model.InternalLLMRequest{Tools: tools, Messages: []model.Message{{Role: "user"}, {Role: "tool"}}},
+			mode:     "auto",
+			expected: "",
+		},
+		{
+			name:     "tool mode ignores tool results",
+			request:  model.InternalLLMRequest{Tools: tools, Messages: []model.Message{{Role: "user"}, {Role: "tool"}}},
+			mode:     "tool",
+			expected: "lookup",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickTool(&tt.request, tt.mode); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// roundTrip 通过 MessageOutbound 构建请求并交给 Transport，返回响应
+func roundTrip(t *testing.T, baseURL string, request *model.InternalLLMRequest) (*MessageOutbound, *http.Response) {
+	t.Helper()
+	out := &MessageOutbound{}
+	req, err := out.TransformRequest(context.Background(), request, baseURL, "sk-mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&Transport{}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out, resp
+}
+
+func TestTransportChat(t *testing.T) {
+	userMessage := []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("ping pong")}}}
+	tests := []struct {
+		name      string
+		baseURL   string
+		request   model.InternalLLMRequest
+		status    int
+		content   string
+		toolCall  string
+		finish    string
+		wantUsage bool
+	}{
+		{name: "echo", baseURL: "mock://local", request: model.InternalLLMRequest{Model: "m", Messages: userMessage}, status: 200, content: "ping pong", finish: "stop", wantUsage: true},
+		{name: "text", baseURL: "mock://local?mode=text&text=fixed", request: model.InternalLLMRequest{Model: "m", Messages: userMessage}, status: 200, content: "fixed", finish: "stop", wantUsage: true},
+		{
+			name:     "tool call",
+			baseURL:  "mock://local?tool_args=%7B%22q%22%3A1%7D",
+			request:  model.InternalLLMRequest{Model: "m", Messages: userMessage, Tools: []model.Tool{{Type: "function", Function: model.Function{Name: "lookup"}}}},
+			status:   200,
+			toolCall: `lookup{"q":1}`,
+			finish:   "tool_calls",
+		},
+		{name: "fixed status", baseURL: "mock://local?status=429", request: model.InternalLLMRequest{Model: "m", Messages: userMessage}, status: 429},
+		{name: "error rate", baseURL: "mock://local?error_rate=1&error_status=503", request: model.InternalLLMRequest{Model: "m", Messages: userMessage}, status: 503},
+		{name: "invalid option", baseURL: "mock://local?latency_ms=-5", request: model.InternalLLMRequest{Model: "m", Messages: userMessage}, status: 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, resp := roundTrip(t, tt.baseURL, &tt.request)
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			res, err := out.TransformResponse(context.Background(), resp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			msg := res.Choices[0].Message
+			if tt.content != "" && (msg.Content.Content == nil || *msg.Content.Content != tt.content) {
+				t.Errorf("expected content %q, got %v", tt.content, msg.Content.Content)
+			}
+			if tt.toolCall != "" {
+				if len(msg.ToolCalls) != 1 || msg.ToolCalls[0].Function.Name+msg.ToolCalls[0].Function.Arguments != tt.toolCall {
+					t.Errorf("expected tool call %s, got %+v", tt.toolCall, msg.ToolCalls)
+				}
+			}
+			if res.Choices[0].FinishReason == nil || *res.Choices[0].FinishReason != tt.finish {
+				t.Errorf("expected finish reason %q, got %v", tt.finish, res.Choices[0].FinishReason)
+			}
+			if tt.wantUsage && (res.Usage == nil || res.Usage.PromptTokens == 0 || res.Usage.CompletionTokens == 0) {
+				t.Errorf("expected token usage, got %+v", res.Usage)
+			}
+		})
+	}
+}
+
+func TestTransportStream(t *testing.T) {
+	stream := true
+	tests := []struct {
+		name      string
+		baseURL   string
+		content   string
+		wantError bool
+	}{
+		{name: "full stream", baseURL: "mock://local?chunk_words=1", content: "one two three"},
+		{name: "fail after", baseURL: "mock://local?fail_after=2", content: "one ", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := &model.InternalLLMRequest{
+				Model:    "m",
+				Stream:   &stream,
+				Messages: []model.Message{{Role: "user", Content: model.MessageContent{Content: strPtr("one two three")}}},
+			}
+			out, resp := roundTrip(t, tt.baseURL, request)
+			defer resp.Body.Close()
+
+			var content strings.Builder
+			gotError, gotUsage, gotDone := false, false, false
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+				if !ok {
+					continue
+				}
+				if data == "[DONE]" {
+					gotDone = true
+					continue
+				}
+				if strings.Contains(data, `"mock_error"`) {
+					gotError = true
+					continue
+				}
+				chunk, err := out.TransformStream(context.Background(), []byte(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if chunk.Usage != nil {
+					gotUsage = true
+				}
+				for _, c := range chunk.Choices {
+					if c.Delta != nil && c.Delta.Content.Content != nil {
+						content.WriteString(*c.Delta.Content.Content)
+					}
+				}
+			}
+			if content.String() != tt.content {
+				t.Errorf("expected content %q, got %q", tt.content, content.String())
+			}
+			if gotError != tt.wantError {
+				t.Errorf("expected error event %v, got %v", tt.wantError, gotError)
+			}
+			if !tt.wantError && (!gotUsage || !gotDone) {
+				t.Errorf("expected usage chunk and [DONE], got usage=%v done=%v", gotUsage, gotDone)
+			}
+		})
+	}
+}
+
+func TestTransportModelsAndEmbeddings(t *testing.T) {
+	// base url 后直接拼接路径时，路径落在查询参数之后
+	req, err := http.NewRequest(http.MethodGet, "mock://local?models=a,b/models", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&Transport{}).RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := new(strings.Builder)
+	bufio.NewReader(resp.Body).WriteTo(body)
+	resp.Body.Close()
+	if !strings.Contains(body.String(), `"id":"a"`) || !strings.Contains(body.String(), `"id":"b"`) {
+		t.Fatalf("expected models a and b, got %s", body.String())
+	}
+
+	dims := int64(4)
+	request := &model.InternalLLMRequest{
+		Model:               "mock-embedding",
+		EmbeddingInput:      &model.EmbeddingInput{Multiple: []string{"x", "y"}},
+		EmbeddingDimensions: &dims,
+	}
+	out, resp := roundTrip(t, "mock://local", request)
+	defer resp.Body.Close()
+	res, err := out.TransformResponse(context.Background(), resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.EmbeddingData) != 2 || len(res.EmbeddingData[0].Embedding.FloatArray) != 4 {
+		t.Fatalf("expected 2 embeddings of 4 dimensions, got %+v", res.EmbeddingData)
+	}
+}
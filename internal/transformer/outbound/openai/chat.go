@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"octopus/internal/transformer/model"
@@ -33,7 +34,15 @@ func (o *ChatOutbound) TransformRequest(ctx context.Context, request *model.Inte
 		}
 	}
 
-	body, err := json.Marshal(request)
+	// Chat Completions 没有 web_search 等内置工具，丢弃这些工具定义
+	payload := request
+	if slices.ContainsFunc(request.Tools, isBuiltinTool) {
+		cp := *request
+		cp.Tools = slices.DeleteFunc(slices.Clone(request.Tools), isBuiltinTool)
+		payload = &cp
+	}
+
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
@@ -57,6 +66,10 @@ func (o *ChatOutbound) TransformRequest(ctx context.Context, request *model.Inte
 	return req, nil
 }
 
+func isBuiltinTool(tool model.Tool) bool {
+	return tool.Type == "web_search" || tool.Type == "web_fetch"
+}
+
 func (o *ChatOutbound) TransformResponse(ctx context.Context, response *http.Response) (*model.InternalLLMResponse, error) {
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
@@ -380,9 +380,11 @@ type ResponsesTextOptions struct {
 }
 
 type ResponsesTextFormat struct {
-	Type   string          `json:"type,omitempty"`
-	Name   string          `json:"name,omitempty"`
-	Schema json.RawMessage `json:"schema,omitempty"`
+	Type        string          `json:"type,omitempty"`
+	Name        string          `json:"name,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
 }
 
 type ResponsesReasoning struct {
@@ -476,6 +478,12 @@ func ConvertToResponsesRequest(req *model.InternalLLMRequest) *ResponsesRequest
 				Type: req.ResponseFormat.Type,
 			},
 		}
+		if schema := req.ResponseFormat.GetJSONSchema(); schema != nil {
+			result.Text.Format.Name = schema.Name
+			result.Text.Format.Description = schema.Description
+			result.Text.Format.Schema = schema.Schema
+			result.Text.Format.Strict = schema.Strict
+		}
 	}
 
 	// Convert reasoning
@@ -673,6 +681,8 @@ func convertToolsToResponses(tools []model.Tool) []ResponsesTool {
 				}
 			}
 			result = append(result, rt)
+		case "web_search":
+			result = append(result, ResponsesTool{Type: "web_search"})
 		case "image_generation":
 			rt := ResponsesTool{
 				Type: "image_generation",
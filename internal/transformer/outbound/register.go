@@ -5,6 +5,7 @@ import (
 	"octopus/internal/transformer/outbound/antigravity"
 	"octopus/internal/transformer/outbound/authropic"
 	"octopus/internal/transformer/outbound/gemini"
+	"octopus/internal/transformer/outbound/mock"
 	"octopus/internal/transformer/outbound/openai"
 	"octopus/internal/transformer/outbound/volcengine"
 )
@@ -19,11 +20,13 @@ const (
 	OutboundTypeVolcengine
 	OutboundTypeOpenAIEmbedding
 	OutboundTypeAntigravity
+	OutboundTypeMock
 )
 
 // EmbeddingChannelTypes 定义支持 embedding 请求的 channel 类型集合
 var EmbeddingChannelTypes = map[OutboundType]bool{
 	OutboundTypeOpenAIEmbedding: true,
+	OutboundTypeMock:            true,
 }
 
 // ChatChannelTypes 定义支持 chat 请求的 channel 类型集合
@@ -34,6 +37,7 @@ var ChatChannelTypes = map[OutboundType]bool{
 	OutboundTypeGemini:         true,
 	OutboundTypeVolcengine:     true,
 	OutboundTypeAntigravity:    true,
+	OutboundTypeMock:           true,
 }
 
 // IsEmbeddingChannelType 判断 channel 类型是否支持 embedding 请求
@@ -54,6 +58,7 @@ var outboundFactories = map[OutboundType]func() model.Outbound{
 	OutboundTypeGemini:          func() model.Outbound { return &gemini.MessagesOutbound{} },
 	OutboundTypeVolcengine:      func() model.Outbound { return &volcengine.ResponseOutbound{} },
 	OutboundTypeAntigravity:     func() model.Outbound { return &antigravity.MessageOutbound{} },
+	OutboundTypeMock:            func() model.Outbound { return &mock.MessageOutbound{} },
 }
 
 func Get(outboundType OutboundType) model.Outbound {
@@ -1,5 +1,11 @@
 package diff
 
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
 type Elem interface {
 	~string | ~byte | ~int | ~int8 | ~int16 | ~int64 | ~float32 | ~float64
 }
@@ -23,3 +29,61 @@ func Diff[T Elem](old []T, new []T) (deleted []T, added []T) {
 	}
 	return
 }
+
+// Change 描述单个字段的变更
+type Change struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Fields 按 JSON 字段比较两个对象，返回有变化的字段（按字段名排序）
+// before 或 after 为 nil 时视为创建或删除，所有字段都会被记录
+func Fields(before, after any) ([]Change, error) {
+	oldMap, err := toMap(before)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := toMap(after)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0, len(oldMap)+len(newMap))
+	for k := range oldMap {
+		fields = append(fields, k)
+	}
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := make([]Change, 0)
+	for _, f := range fields {
+		o, n := oldMap[f], newMap[f]
+		if reflect.DeepEqual(o, n) {
+			continue
+		}
+		changes = append(changes, Change{Field: f, Before: o, After: n})
+	}
+	return changes, nil
+}
+
+func toMap(v any) (map[string]any, error) {
+	m := map[string]any{}
+	if v == nil {
+		return m, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return m, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
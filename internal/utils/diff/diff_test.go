@@ -0,0 +1,67 @@
+package diff
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		old, new    []string
+		wantDeleted []string
+		wantAdded   []string
+	}{
+		{name: "unchanged", old: []string{"a", "b"}, new: []string{"b", "a"}},
+		{name: "added and deleted", old: []string{"a", "b"}, new: []string{"b", "c"}, wantDeleted: []string{"a"}, wantAdded: []string{"c"}},
+		{name: "duplicates", old: []string{"a", "a"}, new: []string{"a"}, wantDeleted: []string{"a"}},
+		{name: "from empty", new: []string{"a"}, wantAdded: []string{"a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, added := Diff(tt.old, tt.new)
+			sort.Strings(deleted)
+			if !reflect.DeepEqual(deleted, tt.wantDeleted) {
+				t.Errorf("expected deleted %v, got %v", tt.wantDeleted, deleted)
+			}
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("expected added %v, got %v", tt.wantAdded, added)
+			}
+		})
+	}
+}
+
+func TestFields(t *testing.T) {
+	type item struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+		Note    string `json:"note,omitempty"`
+	}
+	tests := []struct {
+		name          string
+		before, after any
+		want          []string
+	}{
+		{name: "no change", before: item{Name: "a"}, after: item{Name: "a"}, want: []string{}},
+		{name: "changed field", before: item{Name: "a"}, after: item{Name: "a", Enabled: true}, want: []string{"enabled"}},
+		{name: "sorted fields", before: item{Name: "a"}, after: item{Name: "b", Note: "x"}, want: []string{"name", "note"}},
+		{name: "create", before: nil, after: item{Name: "a"}, want: []string{"enabled", "name"}},
+		{name: "delete with typed nil", before: &item{Name: "a"}, after: (*item)(nil), want: []string{"enabled", "name"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Fields(tt.before, tt.after)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := make([]string, 0, len(changes))
+			for _, c := range changes {
+				got = append(got, c.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
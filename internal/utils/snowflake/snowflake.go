@@ -5,11 +5,24 @@ import (
 	"time"
 )
 
+// nodeBits 集群模式下 ID 低位保存的节点号位数
+const nodeBits = 10
+
 var (
 	sfMutex    sync.Mutex
 	sfLastTime int64
+	sfNode     int64 = -1
 )
 
+// SetNode 设置节点号(0-1023)。多个实例共享数据库时各自设置不同的节点号，
+// 生成的 ID 低 10 位为节点号，避免不同实例在同一毫秒内生成相同的 ID
+func SetNode(node int64) {
+	sfMutex.Lock()
+	defer sfMutex.Unlock()
+	sfNode = node & (1<<nodeBits - 1)
+	sfLastTime = 0
+}
+
 // GenerateID 生成唯一ID
 // 基于毫秒时间戳，当同一毫秒内调用时等待到下一毫秒
 func GenerateID() int64 {
@@ -17,9 +30,14 @@ func GenerateID() int64 {
 	defer sfMutex.Unlock()
 
 	now := time.Now().UnixMilli()
+	step := int64(1)
+	if sfNode >= 0 {
+		now = now<<nodeBits | sfNode
+		step = 1 << nodeBits
+	}
 
 	if now <= sfLastTime {
-		sfLastTime++
+		sfLastTime += step
 		return sfLastTime
 	}
 
@@ -52,8 +52,8 @@ func main() {
 	}
 
 	// 手动初始化数据库（不使用 db.InitDB 避免自动迁移）
-	dbType := conf.AppConfig.Database.Type
-	dbPath := conf.AppConfig.Database.Path
+	dbType := conf.Get().Database.Type
+	dbPath := conf.Get().Database.Path
 
 	fmt.Println("=== LLM Table Migration: ChannelType -> ChannelID ===")
 	fmt.Printf("Database: %s (%s)\n", dbPath, dbType)
@@ -0,0 +1,93 @@
+import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query';
+import { apiClient } from '../client';
+import { logger } from '@/lib/logger';
+
+/**
+ * 模型别名匹配方式
+ */
+export type ModelAliasMatchType = 'exact' | 'wildcard' | 'regex';
+
+/**
+ * 模型别名
+ */
+export interface ModelAlias {
+    id?: number;
+    pattern: string;
+    match_type: ModelAliasMatchType;
+    group_id: number;
+    rewrite_response: boolean;
+    priority: number;
+    enabled: boolean;
+}
+
+/**
+ * 获取模型别名列表 Hook
+ */
+export function useModelAliasList() {
+    return useQuery({
+        queryKey: ['model-alias', 'list'],
+        queryFn: async () => {
+            return apiClient.get<ModelAlias[]>('/api/v1/model-alias/list');
+        },
+        refetchOnMount: 'always',
+    });
+}
+
+/**
+ * 创建模型别名 Hook
+ *
+ * @example
+ * const createAlias = useCreateModelAlias();
+ *
+ * createAlias.mutate({ pattern: 'gpt-4o-*', match_type: 'wildcard', group_id: 1, rewrite_response: false, priority: 0, enabled: true });
+ */
+export function useCreateModelAlias() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async (data: ModelAlias) => {
+            return apiClient.post<ModelAlias>('/api/v1/model-alias/create', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['model-alias', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('模型别名创建失败:', error);
+        },
+    });
+}
+
+/**
+ * 更新模型别名 Hook
+ */
+export function useUpdateModelAlias() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async (data: ModelAlias) => {
+            return apiClient.post<ModelAlias>('/api/v1/model-alias/update', data);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['model-alias', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('模型别名更新失败:', error);
+        },
+    });
+}
+
+/**
+ * 删除模型别名 Hook
+ */
+export function useDeleteModelAlias() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async (id: number) => {
+            return apiClient.delete<null>(`/api/v1/model-alias/delete/${id}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['model-alias', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('模型别名删除失败:', error);
+        },
+    });
+}
@@ -11,7 +11,10 @@ import { formatCount, formatMoney, formatTime } from '@/lib/utils';
 export interface APIKey {
     id: number;
     name: string;
-    api_key: string;
+    api_key?: string; // 完整密钥仅在创建/轮换时返回一次
+    key_prefix?: string;
+    prev_key_prefix?: string;
+    prev_key_expire_at?: number; // 轮换后旧密钥失效的 Unix 时间戳（秒）
     enabled: boolean;
     expire_at?: number; // Unix 时间戳（秒），不传表示永不过期
     max_cost?: number; // 不传表示无限制
@@ -168,6 +171,33 @@ export function useUpdateAPIKey() {
     });
 }
 
+/**
+ * 轮换 API Key Hook
+ *
+ * 旧密钥在 grace_period 秒内仍然可用，返回的新密钥仅展示一次
+ *
+ * @example
+ * const rotateAPIKey = useRotateAPIKey();
+ *
+ * rotateAPIKey.mutate({ id: 1, grace_period: 86400 });
+ */
+export function useRotateAPIKey() {
+    const queryClient = useQueryClient();
+
+    return useMutation({
+        mutationFn: async (data: { id: number; grace_period: number }) => {
+            return apiClient.post<APIKey>('/api/v1/apikey/rotate', data);
+        },
+        onSuccess: () => {
+            logger.log('API Key 轮换成功');
+            queryClient.invalidateQueries({ queryKey: ['apikeys', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('API Key 轮换失败:', error);
+        },
+    });
+}
+
 /**
  * 删除 API Key Hook
  * 
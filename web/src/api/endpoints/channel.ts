@@ -43,6 +43,10 @@ export type ChannelKey = {
     status_code: number;
     last_use_time_stamp: number;
     total_cost: number;
+    health_status?: 'valid' | 'quota_exhausted' | 'revoked' | 'rate_limited' | 'error';
+    health_checked_at?: number;
+    auto_disabled?: boolean;
+    disabled_reason?: string;
 };
 
 /**
@@ -355,4 +359,55 @@ export function useSyncChannel() {
             logger.error('渠道同步失败:', error);
         },
     });
-}
\ No newline at end of file
+}
+/**
+ * 模型同步待审核变更
+ */
+export type ModelSyncPending = {
+    id: number;
+    channel_id: number;
+    channel_name: string;
+    added: string[] | null;
+    removed: string[] | null;
+    protected?: string[] | null;
+    created_at: number;
+    updated_at: number;
+};
+
+/**
+ * 获取模型同步待审核变更列表 Hook
+ */
+export function useModelSyncPendingList() {
+    return useQuery({
+        queryKey: ['model-sync', 'pending'],
+        queryFn: async () => {
+            return apiClient.get<ModelSyncPending[]>('/api/v1/model-sync/pending/list');
+        },
+        refetchInterval: 30000,
+    });
+}
+
+/**
+ * 审核模型同步变更 Hook（通过或拒绝）
+ *
+ * @example
+ * const reviewPending = useReviewModelSyncPending();
+ *
+ * reviewPending.mutate({ id: 1, approve: true });
+ */
+export function useReviewModelSyncPending() {
+    const queryClient = useQueryClient();
+    return useMutation({
+        mutationFn: async ({ id, approve }: { id: number; approve: boolean }) => {
+            return apiClient.post<null>(`/api/v1/model-sync/pending/${approve ? 'approve' : 'reject'}/${id}`);
+        },
+        onSuccess: () => {
+            queryClient.invalidateQueries({ queryKey: ['model-sync', 'pending'] });
+            queryClient.invalidateQueries({ queryKey: ['channels', 'list'] });
+            queryClient.invalidateQueries({ queryKey: ['groups', 'list'] });
+        },
+        onError: (error) => {
+            logger.error('模型同步变更审核失败:', error);
+        },
+    });
+}
@@ -16,6 +16,9 @@ export const SettingKey = {
     StatsSaveInterval: 'stats_save_interval',
     ModelInfoUpdateInterval: 'model_info_update_interval',
     SyncLLMInterval: 'sync_llm_interval',
+    ModelSyncReviewEnabled: 'model_sync_review_enabled',
+    ModelSyncGracePeriod: 'model_sync_grace_period',
+    ModelSyncProtectDays: 'model_sync_protect_days',
     RelayLogKeepEnabled: 'relay_log_keep_enabled',
     RelayLogKeepPeriod: 'relay_log_keep_period',
     CORSAllowOrigins: 'cors_allow_origins',
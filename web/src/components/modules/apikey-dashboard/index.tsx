@@ -38,7 +38,9 @@ import dayjs from 'dayjs';
 export function APIKeyDashboard() {
     const t = useTranslations('apiKeyDashboard');
     const { data, error } = useAPIKeyDashboardStats();
-    const { logout } = useAuthStore();
+    const { logout, token } = useAuthStore();
+    // 完整密钥不再由服务端返回，使用当前登录所用的密钥
+    const apiKey = token ?? '';
     const { theme, setTheme } = useTheme();
     const { locale, setLocale } = useSettingStore();
     const [, copyToClipboard] = useCopyToClipboard();
@@ -135,10 +137,10 @@ export function APIKeyDashboard() {
                                 <h2 className="text-2xl font-bold truncate pr-16">{info.name}</h2>
                                 <div className="mt-4 flex items-center gap-2 rounded-xl border border-border/50 bg-muted/50 p-3">
                                     <code className="flex-1 font-mono text-sm truncate">
-                                        {info.api_key.slice(0, 11)}********{info.api_key.slice(-4)}
+                                        {apiKey.slice(0, 11)}********{apiKey.slice(-4)}
                                     </code>
                                     <CopyIconButton
-                                        text={info.api_key}
+                                        text={apiKey}
                                         className="flex size-8 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
                                         copyIconClassName="size-4"
                                         checkIconClassName="size-4"
@@ -352,6 +352,23 @@ export function CardContent({ channel, stats }: { channel: Channel; stats: Stats
                                                 </span>
 
                                                 <div className="flex items-center gap-2 shrink-0">
+                                                    {key.health_status && (
+                                                        <Badge
+                                                            variant="secondary"
+                                                            title={key.disabled_reason || undefined}
+                                                            className={cn(
+                                                                "h-5 px-1.5 text-[10px]",
+                                                                key.health_status === 'valid'
+                                                                    ? "bg-green-500/15 text-green-700 dark:text-green-400"
+                                                                    : key.health_status === 'revoked' || key.health_status === 'quota_exhausted'
+                                                                        ? "bg-red-500/15 text-red-700 dark:text-red-400"
+                                                                        : "bg-orange-500/15 text-orange-700 dark:text-orange-400"
+                                                            )}
+                                                        >
+                                                            {key.auto_disabled ? t('keyHealth.autoDisabled') : t(`keyHealth.${key.health_status}`)}
+                                                        </Badge>
+                                                    )}
+
                                                     {key.last_use_time_stamp > 0 && (
                                                         <span className="text-xs text-muted-foreground whitespace-nowrap hidden sm:inline-block">
                                                             {new Date(key.last_use_time_stamp * 1000).toLocaleString()}
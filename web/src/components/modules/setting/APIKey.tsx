@@ -2,7 +2,7 @@
 
 import { useCallback, useEffect, useId, useMemo, useRef, useState } from 'react';
 import { useTranslations } from 'next-intl';
-import { KeyRound, Plus, Loader, Trash2, Check, X, Info, CalendarDays, Pencil, Maximize2 } from 'lucide-react';
+import { KeyRound, Plus, Loader, Trash2, Check, X, Info, CalendarDays, Pencil, Maximize2, RotateCw } from 'lucide-react';
 import { motion, AnimatePresence } from 'motion/react';
 import { Input } from '@/components/ui/input';
 import { Calendar } from '@/components/ui/calendar';
@@ -21,6 +21,7 @@ import {
     useCreateAPIKey,
     useUpdateAPIKey,
     useDeleteAPIKey,
+    useRotateAPIKey,
     type APIKey,
 } from '@/api/endpoints/apikey';
 import { useGroupList } from '@/api/endpoints/group';
@@ -460,23 +461,32 @@ function APIKeyStatsCard({
     );
 }
 
+// 轮换后旧密钥的默认保留时间（秒）
+const ROTATE_GRACE_PERIOD = 24 * 60 * 60;
+
 function APIKeyKeyItem({
     apiKey,
+    revealedKey,
     statsLayoutId,
     editLayoutId,
     deleteLayoutId,
     onViewStats,
     onEdit,
+    onRotate,
     onDelete,
+    isRotating,
     isDeleting,
 }: {
     apiKey: APIKey;
+    revealedKey?: string;
     statsLayoutId: string;
     editLayoutId: string;
     deleteLayoutId: string;
     onViewStats: () => void;
     onEdit: () => void;
+    onRotate: () => void;
     onDelete: () => void;
+    isRotating: boolean;
     isDeleting: boolean;
 }) {
     const t = useTranslations('setting');
@@ -491,7 +501,12 @@ function APIKeyKeyItem({
             transition={{ type: 'spring', stiffness: 500, damping: 30 }}
             className="group relative flex items-center justify-between gap-3 p-3 rounded-xl bg-muted/50 overflow-hidden origin-top"
         >
-            <span className="text-sm font-medium truncate">{apiKey.name}</span>
+            <div className="flex flex-col min-w-0">
+                <span className="text-sm font-medium truncate">{apiKey.name}</span>
+                <code className="text-xs text-muted-foreground font-mono truncate">
+                    {revealedKey ?? `${apiKey.key_prefix ?? ''}********`}
+                </code>
+            </div>
 
             <div className="flex items-center gap-1.5">
                 <motion.button
@@ -512,12 +527,23 @@ function APIKeyKeyItem({
                 >
                     <Pencil className="size-4" />
                 </motion.button>
-                <CopyIconButton
-                    text={apiKey.api_key}
-                    className="flex size-8 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
-                    copyIconClassName="size-4"
-                    checkIconClassName="size-4"
-                />
+                <button
+                    type="button"
+                    onClick={onRotate}
+                    disabled={isRotating}
+                    className="flex size-8 items-center justify-center rounded-lg bg-muted/60 text-muted-foreground transition-colors hover:bg-muted hover:text-foreground active:scale-95 disabled:opacity-50"
+                    title={t('apiKey.rotate')}
+                >
+                    <RotateCw className={cn('size-4', isRotating && 'animate-spin')} />
+                </button>
+                {revealedKey && (
+                    <CopyIconButton
+                        text={revealedKey}
+                        className="flex size-8 items-center justify-center rounded-lg bg-primary/10 text-primary transition-all hover:bg-primary hover:text-primary-foreground active:scale-95"
+                        copyIconClassName="size-4"
+                        checkIconClassName="size-4"
+                    />
+                )}
 
                 {!confirmDelete && (
                     <motion.button
@@ -577,6 +603,7 @@ function APIKeyPanelBase({
     const createAPIKey = useCreateAPIKey();
     const updateAPIKey = useUpdateAPIKey();
     const deleteAPIKey = useDeleteAPIKey();
+    const rotateAPIKey = useRotateAPIKey();
 
     const instanceId = useId();
     const addLayoutId = `add-btn-${idPrefix}-${instanceId}`;
@@ -588,6 +615,15 @@ function APIKeyPanelBase({
     const [viewingStats, setViewingStats] = useState<{ apiKey: APIKey; layoutId: string } | null>(null);
     const [editingKey, setEditingKey] = useState<{ apiKey: APIKey; layoutId: string } | null>(null);
     const [deletingId, setDeletingId] = useState<number | null>(null);
+    const [rotatingId, setRotatingId] = useState<number | null>(null);
+    // 完整密钥只在创建/轮换时返回一次，仅在当前会话中保留
+    const [revealedKeys, setRevealedKeys] = useState<Record<number, string>>({});
+
+    const revealKey = useCallback((apiKey: APIKey) => {
+        const key = apiKey.api_key;
+        if (!key) return;
+        setRevealedKeys((cur) => ({ ...cur, [apiKey.id]: key }));
+    }, []);
 
     const sortedApiKeys = useMemo(() => {
         if (!apiKeys) return [];
@@ -608,6 +644,21 @@ function APIKeyPanelBase({
         });
     }, [deleteAPIKey, t]);
 
+    const handleRotate = useCallback((id: number) => {
+        setRotatingId(id);
+        rotateAPIKey.mutate({ id, grace_period: ROTATE_GRACE_PERIOD }, {
+            onSuccess: (data) => {
+                revealKey(data);
+                toast.success(t('apiKey.toast.rotateSuccess'), { description: t('apiKey.showOnce') });
+            },
+            onError: (error) => {
+                const msg = (error as unknown as ApiError)?.message;
+                toast.error(t('apiKey.toast.rotateError'), { description: msg });
+            },
+            onSettled: () => setRotatingId((cur) => (cur === id ? null : cur)),
+        });
+    }, [revealKey, rotateAPIKey, t]);
+
     const closeAllOverlays = useCallback(() => {
         setIsAdding(false);
         setViewingStats(null);
@@ -618,8 +669,9 @@ function APIKeyPanelBase({
 
     const handleCreate = useCallback((data: Omit<APIKey, 'id' | 'api_key'>) => {
         createAPIKey.mutate(data, {
-            onSuccess: () => {
-                toast.success(t('apiKey.toast.createSuccess'));
+            onSuccess: (created) => {
+                revealKey(created);
+                toast.success(t('apiKey.toast.createSuccess'), { description: t('apiKey.showOnce') });
                 setIsAdding(false);
             },
             onError: (error) => {
@@ -627,7 +679,7 @@ function APIKeyPanelBase({
                 toast.error(t('apiKey.toast.createError'), { description: msg });
             },
         });
-    }, [createAPIKey, t]);
+    }, [createAPIKey, revealKey, t]);
 
     const handleUpdate = useCallback((apiKey: APIKey, data: Omit<APIKey, 'id' | 'api_key'>) => {
         updateAPIKey.mutate({ id: apiKey.id, ...data }, {
@@ -722,6 +774,7 @@ function APIKeyPanelBase({
                                 <APIKeyKeyItem
                                     key={apiKey.id}
                                     apiKey={apiKey}
+                                    revealedKey={revealedKeys[apiKey.id]}
                                     statsLayoutId={statsLayoutId}
                                     editLayoutId={editLayoutId}
                                     deleteLayoutId={deleteLayoutId}
@@ -733,7 +786,9 @@ function APIKeyPanelBase({
                                         closeAllOverlays();
                                         setEditingKey({ apiKey, layoutId: editLayoutId });
                                     }}
+                                    onRotate={() => handleRotate(apiKey.id)}
                                     onDelete={() => handleDelete(apiKey.id)}
+                                    isRotating={rotateAPIKey.isPending && rotatingId === apiKey.id}
                                     isDeleting={deleteAPIKey.isPending && deletingId === apiKey.id}
                                 />
                             );
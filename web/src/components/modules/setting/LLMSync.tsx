@@ -2,11 +2,12 @@
 
 import { useEffect, useState, useRef } from 'react';
 import { useTranslations } from 'next-intl';
-import { RefreshCw, Clock } from 'lucide-react';
+import { RefreshCw, Clock, ShieldCheck, Hourglass, ListChecks } from 'lucide-react';
 import { Input } from '@/components/ui/input';
 import { Button } from '@/components/ui/button';
+import { Switch } from '@/components/ui/switch';
 import { useSettingList, useSetSetting, SettingKey } from '@/api/endpoints/setting';
-import { useLastSyncTime, useSyncChannel } from '@/api/endpoints/channel';
+import { useLastSyncTime, useSyncChannel, useModelSyncPendingList, useReviewModelSyncPending } from '@/api/endpoints/channel';
 import { toast } from '@/components/common/Toast';
 
 export function SettingLLMSync() {
@@ -15,27 +16,63 @@ export function SettingLLMSync() {
     const setSetting = useSetSetting();
     const syncChannel = useSyncChannel();
     const { data: lastSyncTime } = useLastSyncTime();
+    const { data: pendingList } = useModelSyncPendingList();
+    const reviewPending = useReviewModelSyncPending();
 
     const [syncInterval, setSyncInterval] = useState('');
-    const initialSyncInterval = useRef('');
+    const [reviewEnabled, setReviewEnabled] = useState(false);
+    const [gracePeriod, setGracePeriod] = useState('');
+    const [protectDays, setProtectDays] = useState('');
+    const initialValues = useRef<Record<string, string>>({});
 
     useEffect(() => {
         if (settings) {
             const interval = settings.find(s => s.key === SettingKey.SyncLLMInterval);
+            const review = settings.find(s => s.key === SettingKey.ModelSyncReviewEnabled);
+            const grace = settings.find(s => s.key === SettingKey.ModelSyncGracePeriod);
+            const protect = settings.find(s => s.key === SettingKey.ModelSyncProtectDays);
             if (interval) {
                 queueMicrotask(() => setSyncInterval(interval.value));
-                initialSyncInterval.current = interval.value;
+                initialValues.current[SettingKey.SyncLLMInterval] = interval.value;
+            }
+            if (review) {
+                queueMicrotask(() => setReviewEnabled(review.value === 'true'));
+                initialValues.current[SettingKey.ModelSyncReviewEnabled] = review.value;
+            }
+            if (grace) {
+                queueMicrotask(() => setGracePeriod(grace.value));
+                initialValues.current[SettingKey.ModelSyncGracePeriod] = grace.value;
+            }
+            if (protect) {
+                queueMicrotask(() => setProtectDays(protect.value));
+                initialValues.current[SettingKey.ModelSyncProtectDays] = protect.value;
             }
         }
     }, [settings]);
 
-    const handleSave = (key: string, value: string, initialValue: string) => {
-        if (value === initialValue) return;
+    const handleSave = (key: string, value: string) => {
+        if (value === initialValues.current[key]) return;
 
         setSetting.mutate({ key, value }, {
             onSuccess: () => {
                 toast.success(t('saved'));
-                initialSyncInterval.current = value;
+                initialValues.current[key] = value;
+            }
+        });
+    };
+
+    const handleReviewEnabledChange = (checked: boolean) => {
+        setReviewEnabled(checked);
+        handleSave(SettingKey.ModelSyncReviewEnabled, checked ? 'true' : 'false');
+    };
+
+    const handleReview = (id: number, approve: boolean) => {
+        reviewPending.mutate({ id, approve }, {
+            onSuccess: () => {
+                toast.success(approve ? t('llmSync.review.approveSuccess') : t('llmSync.review.rejectSuccess'));
+            },
+            onError: () => {
+                toast.error(t('llmSync.review.failed'));
             }
         });
     };
@@ -75,12 +112,85 @@ export function SettingLLMSync() {
                     type="number"
                     value={syncInterval}
                     onChange={(e) => setSyncInterval(e.target.value)}
-                    onBlur={() => handleSave(SettingKey.SyncLLMInterval, syncInterval, initialSyncInterval.current)}
+                    onBlur={() => handleSave(SettingKey.SyncLLMInterval, syncInterval)}
                     placeholder={t('llmSync.syncInterval.placeholder')}
                     className="w-48 rounded-xl"
                 />
             </div>
 
+            {/* 变更审核 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <ListChecks className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmSync.review.enabled')}</span>
+                </div>
+                <Switch
+                    checked={reviewEnabled}
+                    onCheckedChange={handleReviewEnabledChange}
+                />
+            </div>
+
+            {/* 宽限期 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <Hourglass className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmSync.review.gracePeriod')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={gracePeriod}
+                    onChange={(e) => setGracePeriod(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.ModelSyncGracePeriod, gracePeriod)}
+                    className="w-48 rounded-xl"
+                    disabled={!reviewEnabled}
+                />
+            </div>
+
+            {/* 删除保护 */}
+            <div className="flex items-center justify-between gap-4">
+                <div className="flex items-center gap-3">
+                    <ShieldCheck className="h-5 w-5 text-muted-foreground" />
+                    <span className="text-sm font-medium">{t('llmSync.review.protectDays')}</span>
+                </div>
+                <Input
+                    type="number"
+                    value={protectDays}
+                    onChange={(e) => setProtectDays(e.target.value)}
+                    onBlur={() => handleSave(SettingKey.ModelSyncProtectDays, protectDays)}
+                    className="w-48 rounded-xl"
+                />
+            </div>
+
+            {/* 待审核变更 */}
+            {pendingList && pendingList.length > 0 && (
+                <div className="rounded-2xl border divide-y">
+                    {pendingList.map((p) => (
+                        <div key={p.id} className="flex items-start justify-between gap-4 p-3">
+                            <div className="min-w-0 space-y-1 text-xs">
+                                <div className="text-sm font-medium">{p.channel_name || `#${p.channel_id}`}</div>
+                                {(p.added?.length ?? 0) > 0 && (
+                                    <div className="text-green-700 dark:text-green-400 break-all">+ {p.added?.join(', ')}</div>
+                                )}
+                                {(p.removed?.length ?? 0) > 0 && (
+                                    <div className="text-red-700 dark:text-red-400 break-all">- {p.removed?.join(', ')}</div>
+                                )}
+                                {(p.protected?.length ?? 0) > 0 && (
+                                    <div className="text-muted-foreground break-all">{t('llmSync.review.protected')}: {p.protected?.join(', ')}</div>
+                                )}
+                            </div>
+                            <div className="flex shrink-0 gap-2">
+                                <Button variant="outline" size="sm" className="rounded-xl" disabled={reviewPending.isPending} onClick={() => handleReview(p.id, false)}>
+                                    {t('llmSync.review.reject')}
+                                </Button>
+                                <Button size="sm" className="rounded-xl" disabled={reviewPending.isPending} onClick={() => handleReview(p.id, true)}>
+                                    {t('llmSync.review.approve')}
+                                </Button>
+                            </div>
+                        </div>
+                    ))}
+                </div>
+            )}
+
             {/* 手动同步 */}
             <div className="flex items-center justify-between gap-4">
                 <div className="flex flex-col gap-1">
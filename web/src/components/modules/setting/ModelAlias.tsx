@@ -0,0 +1,162 @@
+'use client';
+
+import { useState } from 'react';
+import { useTranslations } from 'next-intl';
+import { Shuffle, Plus, Trash2 } from 'lucide-react';
+import { Input } from '@/components/ui/input';
+import { Button } from '@/components/ui/button';
+import { Switch } from '@/components/ui/switch';
+import { Select, SelectContent, SelectItem, SelectTrigger, SelectValue } from '@/components/ui/select';
+import {
+    useModelAliasList,
+    useCreateModelAlias,
+    useUpdateModelAlias,
+    useDeleteModelAlias,
+    type ModelAlias,
+    type ModelAliasMatchType,
+} from '@/api/endpoints/alias';
+import { useGroupList } from '@/api/endpoints/group';
+import { toast } from '@/components/common/Toast';
+
+export function SettingModelAlias() {
+    const t = useTranslations('setting.modelAlias');
+    const { data: aliases } = useModelAliasList();
+    const { data: groups } = useGroupList();
+    const createAlias = useCreateModelAlias();
+    const updateAlias = useUpdateModelAlias();
+    const deleteAlias = useDeleteModelAlias();
+
+    const [pattern, setPattern] = useState('');
+    const [matchType, setMatchType] = useState<ModelAliasMatchType>('exact');
+    const [groupID, setGroupID] = useState('');
+    const [rewriteResponse, setRewriteResponse] = useState(false);
+
+    const groupName = (id: number) => groups?.find(g => g.id === id)?.name ?? `#${id}`;
+
+    const handleCreate = () => {
+        if (!pattern.trim() || !groupID) return;
+        createAlias.mutate({
+            pattern: pattern.trim(),
+            match_type: matchType,
+            group_id: Number(groupID),
+            rewrite_response: rewriteResponse,
+            priority: 0,
+            enabled: true,
+        }, {
+            onSuccess: () => {
+                toast.success(t('toast.createSuccess'));
+                setPattern('');
+                setRewriteResponse(false);
+            },
+            onError: (error) => {
+                toast.error(t('toast.createError'), { description: error.message });
+            }
+        });
+    };
+
+    const handleToggle = (alias: ModelAlias, enabled: boolean) => {
+        updateAlias.mutate({ ...alias, enabled }, {
+            onError: (error) => {
+                toast.error(t('toast.updateError'), { description: error.message });
+            }
+        });
+    };
+
+    const handleDelete = (id: number) => {
+        deleteAlias.mutate(id, {
+            onSuccess: () => {
+                toast.success(t('toast.deleteSuccess'));
+            },
+            onError: (error) => {
+                toast.error(t('toast.deleteError'), { description: error.message });
+            }
+        });
+    };
+
+    return (
+        <div className="rounded-3xl border border-border bg-card p-6 custom-shadow space-y-5">
+            <h2 className="text-lg font-bold text-card-foreground flex items-center gap-2">
+                <Shuffle className="h-5 w-5" />
+                {t('title')}
+            </h2>
+
+            {/* 新增别名 */}
+            <div className="space-y-3">
+                <div className="flex gap-2">
+                    <Input
+                        value={pattern}
+                        onChange={(e) => setPattern(e.target.value)}
+                        placeholder={t('pattern.placeholder')}
+                        className="flex-1 rounded-xl"
+                    />
+                    <Select value={matchType} onValueChange={(v) => setMatchType(v as ModelAliasMatchType)}>
+                        <SelectTrigger className="w-32 rounded-xl">
+                            <SelectValue />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            <SelectItem value="exact" className="rounded-xl">{t('matchType.exact')}</SelectItem>
+                            <SelectItem value="wildcard" className="rounded-xl">{t('matchType.wildcard')}</SelectItem>
+                            <SelectItem value="regex" className="rounded-xl">{t('matchType.regex')}</SelectItem>
+                        </SelectContent>
+                    </Select>
+                </div>
+                <div className="flex items-center gap-2">
+                    <Select value={groupID} onValueChange={setGroupID}>
+                        <SelectTrigger className="flex-1 rounded-xl">
+                            <SelectValue placeholder={t('group.placeholder')} />
+                        </SelectTrigger>
+                        <SelectContent className="rounded-xl">
+                            {groups?.map(g => (
+                                <SelectItem key={g.id} value={String(g.id)} className="rounded-xl">{g.name}</SelectItem>
+                            ))}
+                        </SelectContent>
+                    </Select>
+                    <div className="flex items-center gap-2 text-xs text-muted-foreground">
+                        <Switch checked={rewriteResponse} onCheckedChange={setRewriteResponse} />
+                        {t('rewriteResponse')}
+                    </div>
+                    <Button
+                        size="sm"
+                        onClick={handleCreate}
+                        disabled={createAlias.isPending || !pattern.trim() || !groupID}
+                        className="rounded-xl"
+                    >
+                        <Plus className="h-4 w-4" />
+                    </Button>
+                </div>
+            </div>
+
+            {/* 别名列表 */}
+            {aliases && aliases.length > 0 ? (
+                <div className="rounded-2xl border divide-y">
+                    {aliases.map(alias => (
+                        <div key={alias.id} className="flex items-center gap-3 p-3">
+                            <Switch
+                                checked={alias.enabled}
+                                onCheckedChange={(checked) => handleToggle(alias, checked)}
+                            />
+                            <div className="min-w-0 flex-1 text-sm">
+                                <div className="font-mono truncate">{alias.pattern}</div>
+                                <div className="text-xs text-muted-foreground truncate">
+                                    {t(`matchType.${alias.match_type}`)} → {groupName(alias.group_id)}
+                                    {alias.rewrite_response && ` · ${t('rewriteResponse')}`}
+                                </div>
+                            </div>
+                            <Button
+                                variant="ghost"
+                                size="sm"
+                                onClick={() => alias.id && handleDelete(alias.id)}
+                                disabled={deleteAlias.isPending}
+                                className="rounded-xl text-destructive"
+                            >
+                                <Trash2 className="h-4 w-4" />
+                            </Button>
+                        </div>
+                    ))}
+                </div>
+            ) : (
+                <div className="text-sm text-muted-foreground text-center">{t('empty')}</div>
+            )}
+        </div>
+    );
+}
@@ -8,6 +8,7 @@ import { SettingLLMPrice } from './LLMPrice';
 import { SettingAccount } from './Account';
 import { SettingInfo } from './Info';
 import { SettingLLMSync } from './LLMSync';
+import { SettingModelAlias } from './ModelAlias';
 import { SettingLog } from './Log';
 import { SettingBackup } from './Backup';
 
@@ -38,6 +39,9 @@ export function Setting() {
             <div>
                 <SettingLLMSync key="setting-llmsync" />
             </div>
+            <div>
+                <SettingModelAlias key="setting-modelalias" />
+            </div>
             <div>
                 <SettingBackup key="setting-backup" />
             </div>